@@ -21,8 +21,10 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -47,14 +49,23 @@ const serviceHelp = `service <action> [args]
 	stop	<ServiceID>
 	list
 	status	<ServiceID>
+	autostart	<ServiceID> <on|off>
 
 	example: service start 0x7d5ee3557775aed0b85d691b036769c17349db23 openvpn --openvpn.port=1194 --openvpn.proto=UDP`
 
-// NewCommand constructs CLI based Mysterium UI with possibility to control quiting
+const earningsHelp = `earnings [action] [args]
+	summary
+	csv	<FilePath>
+
+	example: earnings csv earnings.csv`
+
+// NewCommand constructs CLI based Mysterium UI with possibility to control quiting.
+// Every shell command is also available as a sub-command running it non-interactively.
 func NewCommand() *cli.Command {
 	return &cli.Command{
-		Name:  cliCommandName,
-		Usage: "Starts a CLI client with a Tequilapi",
+		Name:        cliCommandName,
+		Usage:       "Starts a CLI client with a Tequilapi",
+		Subcommands: newSubcommands(newClientFromFlags),
 		Action: func(ctx *cli.Context) error {
 			nodeOptions := cmd.ParseFlagsNode(ctx)
 			cmdCLI := &cliApp{
@@ -80,6 +91,8 @@ type cliApp struct {
 const redColor = "\033[31m%s\033[0m"
 const identityDefaultPassphrase = ""
 const statusConnected = "Connected"
+const statusNotConnected = "NotConnected"
+const healthStatusFailing = "failing"
 
 var versionSummary = metadata.VersionAsSummary(metadata.LicenseCopyright(
 	"type 'license --warranty'",
@@ -152,8 +165,10 @@ func (c *cliApp) handleActions(line string) {
 		{command: "version", handler: c.version},
 		{command: "license", handler: c.license},
 		{command: "registration", handler: c.registration},
+		{command: "account", handler: c.account},
 		{command: "proposals", handler: c.proposals},
 		{command: "service", handler: c.service},
+		{command: "earnings", handler: c.earnings},
 	}
 
 	for _, cmd := range staticCmds {
@@ -203,6 +218,12 @@ func (c *cliApp) service(argsString string) {
 			return
 		}
 		c.serviceGet(args[1])
+	case "autostart":
+		if len(args) < 3 {
+			fmt.Println(serviceHelp)
+			return
+		}
+		c.serviceAutoStart(args[1], args[2])
 	case "list":
 		c.serviceList()
 	default:
@@ -239,6 +260,31 @@ func (c *cliApp) serviceStop(id string) {
 	status("Stopping", "ID: "+id)
 }
 
+func (c *cliApp) serviceAutoStart(id, value string) {
+	var autoStart bool
+	switch value {
+	case "on":
+		autoStart = true
+	case "off":
+		autoStart = false
+	default:
+		fmt.Println(serviceHelp)
+		return
+	}
+
+	service, err := c.tequilapi.ServiceAutoStart(id, autoStart)
+	if err != nil {
+		info("Failed to change service autostart: ", err)
+		return
+	}
+
+	status(service.Status,
+		"ID: "+service.ID,
+		"ProviderID: "+service.Proposal.ProviderID,
+		"Type: "+service.Proposal.ServiceType,
+		fmt.Sprintf("Autostart: %t", service.AutoStart))
+}
+
 func (c *cliApp) serviceList() {
 	services, err := c.tequilapi.Services()
 	if err != nil {
@@ -264,7 +310,87 @@ func (c *cliApp) serviceGet(id string) {
 	status(service.Status,
 		"ID: "+service.ID,
 		"ProviderID: "+service.Proposal.ProviderID,
-		"Type: "+service.Proposal.ServiceType)
+		"Type: "+service.Proposal.ServiceType,
+		fmt.Sprintf("Restarts: %d", service.Restarts))
+	if service.LastError != "" {
+		warn("Last error:", service.LastError)
+	}
+}
+
+func (c *cliApp) earnings(argsString string) {
+	args := strings.Fields(argsString)
+	if len(args) == 0 {
+		c.earningsSummary()
+		return
+	}
+
+	action := args[0]
+	switch action {
+	case "summary":
+		c.earningsSummary()
+	case "csv":
+		if len(args) < 2 {
+			fmt.Println(earningsHelp)
+			return
+		}
+		c.earningsExport(args[1])
+	default:
+		info(fmt.Sprintf("Unknown action provided: %s", action))
+		fmt.Println(earningsHelp)
+	}
+}
+
+func (c *cliApp) earningsSummary() {
+	earnings, err := c.tequilapi.Earnings()
+	if err != nil {
+		warn("Failed to get earnings: ", err)
+		return
+	}
+
+	info("Total:", formatEarnings(earnings.Total))
+	for _, serviceType := range sortedKeys(earnings.ByServiceType) {
+		info(fmt.Sprintf("Service %s:", serviceType), formatEarnings(earnings.ByServiceType[serviceType]))
+	}
+	for _, consumerID := range sortedKeys(earnings.ByConsumer) {
+		info(fmt.Sprintf("Consumer %s:", consumerID), formatEarnings(earnings.ByConsumer[consumerID]))
+	}
+	for _, day := range sortedKeys(earnings.ByDay) {
+		info(fmt.Sprintf("Day %s:", day), formatEarnings(earnings.ByDay[day]))
+	}
+}
+
+func (c *cliApp) earningsExport(path string) {
+	report, err := c.tequilapi.EarningsCSV()
+	if err != nil {
+		warn("Failed to get earnings: ", err)
+		return
+	}
+
+	if err := ioutil.WriteFile(path, report, 0644); err != nil {
+		warn("Failed to write earnings report: ", err)
+		return
+	}
+
+	success("Earnings exported to:", path)
+}
+
+func formatEarnings(summary tequilapi_client.EarningsSummaryDTO) string {
+	return fmt.Sprintf(
+		"received %d, consumed %d, unconsumed %d, cleared %d",
+		summary.Received,
+		summary.Consumed,
+		summary.Unconsumed,
+		summary.Cleared,
+	)
+}
+
+func sortedKeys(summaries map[string]tequilapi_client.EarningsSummaryDTO) []string {
+	keys := make([]string, 0, len(summaries))
+	for key := range summaries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func (c *cliApp) connect(argsString string) {
@@ -384,6 +510,18 @@ func (c *cliApp) healthcheck() {
 	info(fmt.Sprintf("Version: %v", healthcheck.Version))
 	buildString := metadata.FormatString(healthcheck.BuildInfo.Commit, healthcheck.BuildInfo.Branch, healthcheck.BuildInfo.BuildNumber)
 	info(buildString)
+
+	info(fmt.Sprintf("Status: %v", healthcheck.Status))
+	for _, component := range healthcheck.Components {
+		if component.Error != "" {
+			warn(fmt.Sprintf("%v: %v (%v)", component.Name, component.Status, component.Error))
+			continue
+		}
+		info(fmt.Sprintf("%v: %v", component.Name, component.Status))
+		if component.LastError != "" {
+			warn(fmt.Sprintf("%v last error at %v: %v", component.Name, component.LastErrorAt, component.LastError))
+		}
+	}
 }
 
 func (c *cliApp) proposals(filter string) {
@@ -397,20 +535,39 @@ func (c *cliApp) proposals(filter string) {
 	info(fmt.Sprintf("Found %v proposals %s", len(proposals), filterMsg))
 
 	for _, proposal := range proposals {
-		country := proposal.ServiceDefinition.LocationOriginate.Country
-		if country == "" {
-			country = "Unknown"
+		if proposalMatches(proposal, filter) {
+			info(formatProposal(proposal))
 		}
+	}
+}
 
-		msg := fmt.Sprintf("- provider id: %v, proposal id: %v, country: %v", proposal.ProviderID, proposal.ID, country)
+func proposalCountry(proposal tequilapi_client.ProposalDTO) string {
+	country := proposal.ServiceDefinition.LocationOriginate.Country
+	if country == "" {
+		country = "Unknown"
+	}
+	return country
+}
 
-		if filter == "" ||
-			strings.Contains(proposal.ProviderID, filter) ||
-			strings.Contains(country, filter) {
+func proposalMatches(proposal tequilapi_client.ProposalDTO, filter string) bool {
+	location := proposal.ServiceDefinition.LocationOriginate
+	return filter == "" ||
+		strings.Contains(proposal.ProviderID, filter) ||
+		strings.Contains(proposalCountry(proposal), filter) ||
+		strings.Contains(location.City, filter) ||
+		strings.Contains(location.ISP, filter)
+}
 
-			info(msg)
-		}
+func formatProposal(proposal tequilapi_client.ProposalDTO) string {
+	location := proposal.ServiceDefinition.LocationOriginate
+	msg := fmt.Sprintf("- provider id: %v, proposal id: %v, country: %v", proposal.ProviderID, proposal.ID, proposalCountry(proposal))
+	if location.City != "" {
+		msg += fmt.Sprintf(", city: %v", location.City)
 	}
+	if location.ASN != "" {
+		msg += fmt.Sprintf(", network: %v %v", location.ASN, location.ISP)
+	}
+	return msg
 }
 
 func (c *cliApp) fetchProposals() []tequilapi_client.ProposalDTO {
@@ -523,7 +680,11 @@ func (c *cliApp) registration(argsString string) {
 	info("           R ->", status.Signature.R)
 	info("           V ->", status.Signature.V)
 	info("OR proceed with direct link:")
-	infof(" https://wallet.mysterium.network/?part1=%s&part2=%s&s=%s&r=%s&v=%d\n",
+	infof(" %s\n", registrationLink(status))
+}
+
+func registrationLink(status tequilapi_client.RegistrationDataDTO) string {
+	return fmt.Sprintf("https://wallet.mysterium.network/?part1=%s&part2=%s&s=%s&r=%s&v=%d",
 		status.PublicKey.Part1,
 		status.PublicKey.Part2,
 		status.Signature.S,
@@ -531,6 +692,29 @@ func (c *cliApp) registration(argsString string) {
 		status.Signature.V)
 }
 
+func (c *cliApp) account(argsString string) {
+	if argsString == "" {
+		warn("Please supply identity")
+		return
+	}
+	registration, err := c.tequilapi.IdentityRegistrationStatus(argsString)
+	if err != nil {
+		warn("Something went wrong: ", err)
+		return
+	}
+	balance, err := c.tequilapi.IdentityBalance(argsString)
+	if err != nil {
+		warn("Something went wrong: ", err)
+		return
+	}
+	status("Identity:", argsString)
+	status("Registered:", registration.Registered)
+	status("Balance:", balance.Balance)
+	if !registration.Registered {
+		infof("Identity is not registered yet. Use 'registration %s' to get registration data\n", argsString)
+	}
+}
+
 func (c *cliApp) stopClient() {
 	err := c.tequilapi.Stop()
 	if err != nil {
@@ -601,6 +785,12 @@ func newAutocompleter(tequilapi *tequilapi_client.Client, proposals []tequilapi_
 			readline.PcItem("stop"),
 			readline.PcItem("list"),
 			readline.PcItem("status"),
+			readline.PcItem("autostart"),
+		),
+		readline.PcItem(
+			"earnings",
+			readline.PcItem("summary"),
+			readline.PcItem("csv"),
 		),
 		readline.PcItem(
 			"identities",
@@ -632,16 +822,18 @@ func newAutocompleter(tequilapi *tequilapi_client.Client, proposals []tequilapi_
 				getIdentityOptionList(tequilapi),
 			),
 		),
+		readline.PcItem(
+			"account",
+			readline.PcItemDynamic(
+				getIdentityOptionList(tequilapi),
+			),
+		),
 	)
 }
 
 func parseServiceOptions(serviceType string, args ...string) (service.Options, error) {
-	var flags []cli.Flag
-	openvpn_service.RegisterFlags(&flags)
-	wireguard_service.RegisterFlags(&flags)
-
 	set := flag.NewFlagSet("", flag.ContinueOnError)
-	for _, f := range flags {
+	for _, f := range serviceOptionFlags() {
 		f.Apply(set)
 	}
 
@@ -649,8 +841,17 @@ func parseServiceOptions(serviceType string, args ...string) (service.Options, e
 		return nil, err
 	}
 
-	ctx := cli.NewContext(nil, set, nil)
+	return serviceOptionsFromContext(serviceType, cli.NewContext(nil, set, nil))
+}
+
+func serviceOptionFlags() []cli.Flag {
+	var flags []cli.Flag
+	openvpn_service.RegisterFlags(&flags)
+	wireguard_service.RegisterFlags(&flags)
+	return flags
+}
 
+func serviceOptionsFromContext(serviceType string, ctx *cli.Context) (service.Options, error) {
 	switch serviceType {
 	case noop.ServiceType:
 		return noop.ParseFlags(ctx), nil
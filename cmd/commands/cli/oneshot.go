@@ -0,0 +1,592 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/mysteriumnetwork/node/cmd"
+	tequilapi_client "github.com/mysteriumnetwork/node/tequilapi/client"
+	"github.com/urfave/cli"
+)
+
+const (
+	// exitCodeFailure is returned when the node failed to fulfil the command
+	exitCodeFailure = 1
+	// exitCodeUsage is returned when the command was given invalid arguments
+	exitCodeUsage = 2
+	// exitCodeTimeout is returned when the awaited state was not reached in time
+	exitCodeTimeout = 3
+)
+
+const waitPollInterval = 500 * time.Millisecond
+
+var (
+	jsonFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print result as JSON",
+	}
+	proposalsCountryFlag = cli.StringFlag{
+		Name:  "country",
+		Usage: "List proposals of providers from the given country only",
+	}
+	proposalsServiceTypeFlag = cli.StringFlag{
+		Name:  "service-type",
+		Usage: "List proposals of the given service type only",
+	}
+	proposalsProviderFlag = cli.StringFlag{
+		Name:  "provider",
+		Usage: "List proposals of the given provider only",
+	}
+	connectDisableKillSwitchFlag = cli.BoolFlag{
+		Name:  "disable-kill-switch",
+		Usage: "Disable kill switch of the connection",
+	}
+	connectWaitFlag = cli.BoolFlag{
+		Name:  "wait",
+		Usage: "Wait until connection is established",
+	}
+	connectWaitTimeoutFlag = cli.DurationFlag{
+		Name:  "wait-timeout",
+		Usage: "Maximum time to wait for connection to be established",
+		Value: time.Minute,
+	}
+	serviceDrainFlag = cli.DurationFlag{
+		Name:  "drain",
+		Usage: "Wait up to the given duration for sessions to end before stopping the service",
+	}
+)
+
+// clientFactory creates Tequilapi client of the node the command is run against
+type clientFactory func(ctx *cli.Context) *tequilapi_client.Client
+
+// oneShotAction runs single shell command non-interactively
+type oneShotAction func(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error
+
+func newClientFromFlags(ctx *cli.Context) *tequilapi_client.Client {
+	nodeOptions := cmd.ParseFlagsNode(ctx)
+	return tequilapi_client.NewClient(nodeOptions.TequilapiAddress, nodeOptions.TequilapiPort)
+}
+
+// newSubcommands returns shell commands runnable one at a time, e.g. from scripts
+func newSubcommands(newClient clientFactory) []cli.Command {
+	oneShot := func(action oneShotAction) func(ctx *cli.Context) error {
+		return func(ctx *cli.Context) error {
+			out := &printer{writer: ctx.App.Writer, json: ctx.Bool(jsonFlag.Name)}
+			return toExitError(action(ctx, newClient(ctx), out))
+		}
+	}
+	command := func(name, usage, argsUsage string, action oneShotAction, flags ...cli.Flag) cli.Command {
+		return cli.Command{
+			Name:      name,
+			Usage:     usage,
+			ArgsUsage: argsUsage,
+			Flags:     append(flags, jsonFlag),
+			Action:    oneShot(action),
+		}
+	}
+
+	return []cli.Command{
+		command("status", "Show connection status", " ", statusOneShot),
+		command("healthcheck", "Show health of the node", " ", healthcheckOneShot),
+		command("ip", "Show public IP of the node", " ", ipOneShot),
+		command("connect", "Connect to the provider", "<consumer-identity|new> <provider-identity> <service-type>", connectOneShot,
+			connectDisableKillSwitchFlag, connectWaitFlag, connectWaitTimeoutFlag),
+		command("disconnect", "Disconnect from the provider", " ", disconnectOneShot),
+		command("unlock", "Unlock identity", "<identity> [passphrase]", unlockOneShot),
+		{
+			Name:  "identities",
+			Usage: "Manage identities",
+			Subcommands: []cli.Command{
+				command("list", "List identities", " ", identitiesListOneShot),
+				command("new", "Create new identity", "[passphrase]", identitiesNewOneShot),
+			},
+		},
+		command("registration", "Show registration data of identity", "<identity>", registrationOneShot),
+		command("account", "Show registration status and balance of identity", "<identity>", accountOneShot),
+		command("proposals", "List proposals", "[filter]", proposalsOneShot,
+			proposalsCountryFlag, proposalsServiceTypeFlag, proposalsProviderFlag),
+		{
+			Name:  "service",
+			Usage: "Manage services",
+			Subcommands: []cli.Command{
+				command("start", "Start service", "<provider-identity> <service-type>", serviceStartOneShot, serviceOptionFlags()...),
+				command("stop", "Stop service", "<service-id>", serviceStopOneShot, serviceDrainFlag),
+				command("list", "List running services", " ", serviceListOneShot),
+				command("status", "Show service status", "<service-id>", serviceStatusOneShot),
+				command("autostart", "Turn restoring of service after node restart on or off", "<service-id> <on|off>", serviceAutoStartOneShot),
+			},
+		},
+		{
+			Name:  "earnings",
+			Usage: "Show earnings of services",
+			Subcommands: []cli.Command{
+				command("summary", "Show earnings summary", " ", earningsSummaryOneShot),
+				command("csv", "Export earnings report to CSV file", "<file-path>", earningsCSVOneShot),
+			},
+		},
+		command("stop", "Stop the node", " ", stopOneShot),
+	}
+}
+
+// printer prints results of one-shot commands either as JSON or human readable text
+type printer struct {
+	writer io.Writer
+	json   bool
+}
+
+func (p *printer) print(result interface{}, lines ...string) error {
+	if p.json {
+		encoder := json.NewEncoder(p.writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(p.writer, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type messageResult struct {
+	Message string `json:"message"`
+}
+
+func (p *printer) printMessage(message string) error {
+	return p.print(messageResult{Message: message}, message)
+}
+
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func newUsageError(ctx *cli.Context, message string) error {
+	return &usageError{message: fmt.Sprintf("%s. Usage: %s %s", message, ctx.Command.HelpName, ctx.Command.ArgsUsage)}
+}
+
+type timeoutError struct {
+	message string
+}
+
+func (e *timeoutError) Error() string {
+	return e.message
+}
+
+func toExitError(err error) error {
+	switch err.(type) {
+	case nil:
+		return nil
+	case *usageError:
+		return cli.NewExitError(err.Error(), exitCodeUsage)
+	case *timeoutError:
+		return cli.NewExitError(err.Error(), exitCodeTimeout)
+	default:
+		return cli.NewExitError(err.Error(), exitCodeFailure)
+	}
+}
+
+type connectionResult struct {
+	tequilapi_client.StatusDTO
+	Statistics *tequilapi_client.StatisticsDTO `json:"statistics,omitempty"`
+}
+
+func statusOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	status, err := client.Status()
+	if err != nil {
+		return err
+	}
+
+	result := connectionResult{StatusDTO: status}
+	lines := []string{"Status: " + status.Status, "SID: " + status.SessionID}
+	if status.Status == statusConnected {
+		statistics, err := client.ConnectionStatistics()
+		if err != nil {
+			return err
+		}
+		result.Statistics = &statistics
+		lines = append(lines,
+			fmt.Sprintf("Proposal: %v", status.Proposal),
+			fmt.Sprintf("Connection duration: %ds", statistics.Duration),
+			fmt.Sprintf("Bytes sent: %d", statistics.BytesSent),
+			fmt.Sprintf("Bytes received: %d", statistics.BytesReceived),
+		)
+	}
+	return out.print(result, lines...)
+}
+
+func healthcheckOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	healthcheck, err := client.Healthcheck()
+	if err != nil {
+		return err
+	}
+
+	lines := []string{
+		"Uptime: " + healthcheck.Uptime,
+		fmt.Sprintf("Process: %d", healthcheck.Process),
+		"Version: " + healthcheck.Version,
+		"Status: " + healthcheck.Status,
+	}
+	for _, component := range healthcheck.Components {
+		line := fmt.Sprintf("%s: %s", component.Name, component.Status)
+		if component.Error != "" {
+			line += fmt.Sprintf(" (%s)", component.Error)
+		}
+		lines = append(lines, line)
+	}
+	if err := out.print(healthcheck, lines...); err != nil {
+		return err
+	}
+	if healthcheck.Status == healthStatusFailing {
+		return errors.New("node is failing")
+	}
+	return nil
+}
+
+type ipResult struct {
+	IP string `json:"ip"`
+}
+
+func ipOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	ip, err := client.GetIP()
+	if err != nil {
+		return err
+	}
+	return out.print(ipResult{IP: ip}, "IP: "+ip)
+}
+
+func connectOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() != 3 {
+		return newUsageError(ctx, "Consumer identity, provider identity and service type are required")
+	}
+	consumerID, providerID, serviceType := ctx.Args().Get(0), ctx.Args().Get(1), ctx.Args().Get(2)
+
+	if consumerID == "new" {
+		id, err := client.NewIdentity(identityDefaultPassphrase)
+		if err != nil {
+			return err
+		}
+		consumerID = id.Address
+	}
+
+	connectOptions := tequilapi_client.ConnectOptions{DisableKillSwitch: ctx.Bool(connectDisableKillSwitchFlag.Name)}
+	status, err := client.Connect(consumerID, providerID, serviceType, connectOptions)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool(connectWaitFlag.Name) {
+		status, err = waitForConnection(client, status, ctx.Duration(connectWaitTimeoutFlag.Name))
+		if err != nil {
+			return err
+		}
+	}
+	return out.print(status, "Status: "+status.Status, "Consumer: "+consumerID, "SID: "+status.SessionID)
+}
+
+// waitForConnection polls connection status until the connection is established
+func waitForConnection(client *tequilapi_client.Client, status tequilapi_client.StatusDTO, timeout time.Duration) (tequilapi_client.StatusDTO, error) {
+	deadline := time.After(timeout)
+	for status.Status != statusConnected {
+		if status.Status == statusNotConnected {
+			return status, fmt.Errorf("connection failed, status: %s", status.Status)
+		}
+
+		select {
+		case <-deadline:
+			return status, &timeoutError{message: fmt.Sprintf("connection was not established in %s, status: %s", timeout, status.Status)}
+		case <-time.After(waitPollInterval):
+		}
+
+		var err error
+		if status, err = client.Status(); err != nil {
+			return status, err
+		}
+	}
+	return status, nil
+}
+
+func disconnectOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if err := client.Disconnect(); err != nil {
+		return err
+	}
+	return out.printMessage("Disconnected")
+}
+
+func unlockOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return newUsageError(ctx, "Identity and optional passphrase are required")
+	}
+
+	identity := ctx.Args().Get(0)
+	if err := client.Unlock(identity, ctx.Args().Get(1)); err != nil {
+		return err
+	}
+	return out.printMessage(fmt.Sprintf("Identity %s unlocked", identity))
+}
+
+func identitiesListOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	ids, err := client.GetIdentities()
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, len(ids))
+	for i, id := range ids {
+		lines[i] = id.Address
+	}
+	return out.print(ids, lines...)
+}
+
+func identitiesNewOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() > 1 {
+		return newUsageError(ctx, "Only passphrase is expected")
+	}
+
+	id, err := client.NewIdentity(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	return out.print(id, "New identity created: "+id.Address)
+}
+
+func registrationOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() != 1 {
+		return newUsageError(ctx, "Identity is required")
+	}
+
+	status, err := client.IdentityRegistrationStatus(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if status.Registered {
+		return out.print(status, "Already registered")
+	}
+	return out.print(status,
+		"Identity is not registered yet, call payments contract with the following data",
+		"Public key: part1 -> "+status.PublicKey.Part1,
+		"            part2 -> "+status.PublicKey.Part2,
+		"Signature: S -> "+status.Signature.S,
+		"           R -> "+status.Signature.R,
+		fmt.Sprintf("           V -> %d", status.Signature.V),
+		"OR proceed with direct link: "+registrationLink(status),
+	)
+}
+
+type accountResult struct {
+	Identity   string `json:"identity"`
+	Registered bool   `json:"registered"`
+	Balance    uint64 `json:"balance"`
+}
+
+func accountOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() != 1 {
+		return newUsageError(ctx, "Identity is required")
+	}
+
+	identity := ctx.Args().Get(0)
+	registration, err := client.IdentityRegistrationStatus(identity)
+	if err != nil {
+		return err
+	}
+	balance, err := client.IdentityBalance(identity)
+	if err != nil {
+		return err
+	}
+
+	result := accountResult{Identity: identity, Registered: registration.Registered, Balance: balance.Balance}
+	return out.print(result,
+		"Identity: "+identity,
+		fmt.Sprintf("Registered: %t", result.Registered),
+		fmt.Sprintf("Balance: %d", result.Balance),
+	)
+}
+
+func proposalsOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	var proposals []tequilapi_client.ProposalDTO
+	var err error
+	if serviceType := ctx.String(proposalsServiceTypeFlag.Name); serviceType != "" {
+		proposals, err = client.ProposalsByType(serviceType)
+	} else {
+		proposals, err = client.Proposals()
+	}
+	if err != nil {
+		return err
+	}
+
+	country, provider, filter := ctx.String(proposalsCountryFlag.Name), ctx.String(proposalsProviderFlag.Name), ctx.Args().First()
+	filtered := make([]tequilapi_client.ProposalDTO, 0)
+	var lines []string
+	for _, proposal := range proposals {
+		if country != "" && !strings.EqualFold(proposal.ServiceDefinition.LocationOriginate.Country, country) {
+			continue
+		}
+		if provider != "" && !strings.EqualFold(proposal.ProviderID, provider) {
+			continue
+		}
+		if !proposalMatches(proposal, filter) {
+			continue
+		}
+		filtered = append(filtered, proposal)
+		lines = append(lines, formatProposal(proposal))
+	}
+	return out.print(filtered, lines...)
+}
+
+func serviceStartOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() != 2 {
+		return newUsageError(ctx, "Provider identity and service type are required")
+	}
+
+	providerID, serviceType := ctx.Args().Get(0), ctx.Args().Get(1)
+	opts, err := serviceOptionsFromContext(serviceType, ctx)
+	if err != nil {
+		return &usageError{message: err.Error()}
+	}
+
+	service, err := client.ServiceStart(providerID, serviceType, opts)
+	if err != nil {
+		return err
+	}
+	return out.print(service, formatService(service)...)
+}
+
+func serviceStopOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() != 1 {
+		return newUsageError(ctx, "Service ID is required")
+	}
+
+	id := ctx.Args().Get(0)
+	if drain := ctx.Duration(serviceDrainFlag.Name); drain > 0 {
+		if err := client.ServiceDrain(id, drain); err != nil {
+			return err
+		}
+		return out.printMessage("Draining service " + id)
+	}
+
+	if err := client.ServiceStop(id); err != nil {
+		return err
+	}
+	return out.printMessage("Stopping service " + id)
+}
+
+func serviceListOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	services, err := client.Services()
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, service := range services {
+		lines = append(lines, fmt.Sprintf("[%s] ID: %s ProviderID: %s Type: %s", service.Status, service.ID, service.ProviderID, service.ServiceType))
+	}
+	return out.print(services, lines...)
+}
+
+func serviceStatusOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() != 1 {
+		return newUsageError(ctx, "Service ID is required")
+	}
+
+	service, err := client.Service(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	return out.print(service, formatService(service)...)
+}
+
+func serviceAutoStartOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() != 2 || (ctx.Args().Get(1) != "on" && ctx.Args().Get(1) != "off") {
+		return newUsageError(ctx, "Service ID and on or off are required")
+	}
+
+	service, err := client.ServiceAutoStart(ctx.Args().Get(0), ctx.Args().Get(1) == "on")
+	if err != nil {
+		return err
+	}
+	return out.print(service, formatService(service)...)
+}
+
+func formatService(service tequilapi_client.ServiceInfoDTO) []string {
+	lines := []string{
+		"Status: " + service.Status,
+		"ID: " + service.ID,
+		"ProviderID: " + service.ProviderID,
+		"Type: " + service.ServiceType,
+		fmt.Sprintf("Autostart: %t", service.AutoStart),
+		fmt.Sprintf("Restarts: %d", service.Restarts),
+	}
+	if service.LastError != "" {
+		lines = append(lines, "Last error: "+service.LastError)
+	}
+	return lines
+}
+
+func earningsSummaryOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	earnings, err := client.Earnings()
+	if err != nil {
+		return err
+	}
+
+	lines := []string{"Total: " + formatEarnings(earnings.Total)}
+	for _, serviceType := range sortedKeys(earnings.ByServiceType) {
+		lines = append(lines, fmt.Sprintf("Service %s: %s", serviceType, formatEarnings(earnings.ByServiceType[serviceType])))
+	}
+	for _, consumerID := range sortedKeys(earnings.ByConsumer) {
+		lines = append(lines, fmt.Sprintf("Consumer %s: %s", consumerID, formatEarnings(earnings.ByConsumer[consumerID])))
+	}
+	for _, day := range sortedKeys(earnings.ByDay) {
+		lines = append(lines, fmt.Sprintf("Day %s: %s", day, formatEarnings(earnings.ByDay[day])))
+	}
+	return out.print(earnings, lines...)
+}
+
+func earningsCSVOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if ctx.NArg() != 1 {
+		return newUsageError(ctx, "File path is required")
+	}
+
+	report, err := client.EarningsCSV()
+	if err != nil {
+		return err
+	}
+
+	path := ctx.Args().Get(0)
+	if err := ioutil.WriteFile(path, report, 0644); err != nil {
+		return err
+	}
+	return out.printMessage("Earnings exported to: " + path)
+}
+
+func stopOneShot(ctx *cli.Context, client *tequilapi_client.Client, out *printer) error {
+	if err := client.Stop(); err != nil {
+		return err
+	}
+	return out.printMessage("Client stopped")
+}
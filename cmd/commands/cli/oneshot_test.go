@@ -0,0 +1,148 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	tequilapi_client "github.com/mysteriumnetwork/node/tequilapi/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func runOneShot(t *testing.T, handler http.HandlerFunc, args ...string) (string, int) {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	assert.NoError(t, err)
+	newClient := func(*cli.Context) *tequilapi_client.Client {
+		return tequilapi_client.NewClient(host, portNumber)
+	}
+
+	exitCode := 0
+	osExiter, errWriter := cli.OsExiter, cli.ErrWriter
+	cli.OsExiter = func(code int) { exitCode = code }
+	cli.ErrWriter = ioutil.Discard
+	defer func() { cli.OsExiter, cli.ErrWriter = osExiter, errWriter }()
+
+	output := &bytes.Buffer{}
+	app := cli.NewApp()
+	app.Writer = output
+	app.Commands = []cli.Command{{Name: cliCommandName, Subcommands: newSubcommands(newClient)}}
+	app.Run(append([]string{"myst", cliCommandName}, args...))
+
+	return output.String(), exitCode
+}
+
+func respondJSON(writer http.ResponseWriter, status int, body string) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	writer.Write([]byte(body))
+}
+
+func TestProposalsOneShotFiltersByCountryAsJSON(t *testing.T) {
+	handler := func(writer http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/proposals", req.URL.Path)
+		respondJSON(writer, http.StatusOK, `{"proposals": [
+			{"id": 1, "providerId": "0x1", "serviceType": "openvpn", "serviceDefinition": {"locationOriginate": {"country": "DE"}}},
+			{"id": 2, "providerId": "0x2", "serviceType": "openvpn", "serviceDefinition": {"locationOriginate": {"country": "LT"}}}
+		]}`)
+	}
+
+	output, exitCode := runOneShot(t, handler, "proposals", "--country=de", "--json")
+
+	assert.Equal(t, 0, exitCode)
+	var proposals []tequilapi_client.ProposalDTO
+	assert.NoError(t, json.Unmarshal([]byte(output), &proposals))
+	assert.Len(t, proposals, 1)
+	assert.Equal(t, "0x1", proposals[0].ProviderID)
+}
+
+func TestIPOneShotPrintsHumanReadableOutput(t *testing.T) {
+	handler := func(writer http.ResponseWriter, req *http.Request) {
+		respondJSON(writer, http.StatusOK, `{"ip": "1.2.3.4"}`)
+	}
+
+	output, exitCode := runOneShot(t, handler, "ip")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "IP: 1.2.3.4\n", output)
+}
+
+func TestHealthcheckOneShotPrintsComponentsOfFailingNode(t *testing.T) {
+	handler := func(writer http.ResponseWriter, req *http.Request) {
+		respondJSON(writer, http.StatusServiceUnavailable, `{"uptime": "1m0s", "process": 10, "version": "0.0.6", "status": "failing", "components": [
+			{"name": "storage", "critical": true, "status": "failing", "error": "database not open"}
+		]}`)
+	}
+
+	output, exitCode := runOneShot(t, handler, "healthcheck")
+
+	assert.Equal(t, exitCodeFailure, exitCode)
+	assert.Equal(t, "Uptime: 1m0s\nProcess: 10\nVersion: 0.0.6\nStatus: failing\nstorage: failing (database not open)\n", output)
+}
+
+func TestOneShotExitCodes(t *testing.T) {
+	failing := func(writer http.ResponseWriter, req *http.Request) {
+		respondJSON(writer, http.StatusNotFound, `{"message": "service not found"}`)
+	}
+
+	_, exitCode := runOneShot(t, failing, "service", "status", "unknown-id", "--json")
+	assert.Equal(t, exitCodeFailure, exitCode)
+
+	_, exitCode = runOneShot(t, failing, "connect", "0x1")
+	assert.Equal(t, exitCodeUsage, exitCode)
+}
+
+func TestConnectOneShotWaitsForConnection(t *testing.T) {
+	handler := func(writer http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPut {
+			respondJSON(writer, http.StatusCreated, `{"status": "Connecting"}`)
+			return
+		}
+		respondJSON(writer, http.StatusOK, `{"status": "Connected", "sessionId": "session-1"}`)
+	}
+
+	output, exitCode := runOneShot(t, handler, "connect", "--wait", "--json", "0x1", "0x2", "openvpn")
+
+	assert.Equal(t, 0, exitCode)
+	var status tequilapi_client.StatusDTO
+	assert.NoError(t, json.Unmarshal([]byte(output), &status))
+	assert.Equal(t, "Connected", status.Status)
+	assert.Equal(t, "session-1", status.SessionID)
+}
+
+func TestConnectOneShotTimesOut(t *testing.T) {
+	handler := func(writer http.ResponseWriter, req *http.Request) {
+		respondJSON(writer, http.StatusOK, `{"status": "Connecting"}`)
+	}
+
+	_, exitCode := runOneShot(t, handler, "connect", "--wait", "--wait-timeout=10ms", "0x1", "0x2", "openvpn")
+
+	assert.Equal(t, exitCodeTimeout, exitCode)
+}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"strings"
 
+	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/cmd"
 	"github.com/mysteriumnetwork/node/cmd/commands/license"
 	"github.com/mysteriumnetwork/node/core/service"
@@ -134,7 +135,18 @@ func (sc *serviceCommand) unlockIdentity(identityOptions service.OptionsIdentity
 }
 
 func (sc *serviceCommand) runServices(ctx *cli.Context, providerID string, serviceTypes []string) error {
+	// services persisted during previous runs are already restored after identity unlock
+	running, err := sc.tequilapi.Services()
+	if err != nil {
+		return err
+	}
+
 	for _, serviceType := range serviceTypes {
+		if isServiceRunning(running, providerID, serviceType) {
+			log.Info("Service already running: ", serviceType)
+			continue
+		}
+
 		options, err := parseFlagsByServiceType(ctx, serviceType)
 		if err != nil {
 			return err
@@ -145,6 +157,15 @@ func (sc *serviceCommand) runServices(ctx *cli.Context, providerID string, servi
 	return nil
 }
 
+func isServiceRunning(services client.ServiceListDTO, providerID, serviceType string) bool {
+	for _, service := range services {
+		if strings.EqualFold(service.Proposal.ProviderID, providerID) && service.Proposal.ServiceType == serviceType {
+			return true
+		}
+	}
+	return false
+}
+
 func (sc *serviceCommand) runService(providerID, serviceType string, options service.Options) {
 	_, err := sc.tequilapi.ServiceStart(providerID, serviceType, options)
 	if err != nil {
@@ -18,6 +18,7 @@
 package cmd
 
 import (
+	"context"
 	"path/filepath"
 	"time"
 
@@ -28,11 +29,13 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/mysteriumnetwork/node/blockchain"
 	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/communication/nats"
 	nats_dialog "github.com/mysteriumnetwork/node/communication/nats/dialog"
 	nats_discovery "github.com/mysteriumnetwork/node/communication/nats/discovery"
 	consumer_session "github.com/mysteriumnetwork/node/consumer/session"
 	"github.com/mysteriumnetwork/node/consumer/statistics"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/health"
 	"github.com/mysteriumnetwork/node/core/ip"
 	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/core/node"
@@ -46,25 +49,43 @@ import (
 	market_metrics "github.com/mysteriumnetwork/node/market/metrics"
 	"github.com/mysteriumnetwork/node/market/metrics/oracle"
 	"github.com/mysteriumnetwork/node/market/mysterium"
+	"github.com/mysteriumnetwork/node/market/proposals/registry"
 	"github.com/mysteriumnetwork/node/metadata"
 	"github.com/mysteriumnetwork/node/metrics"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/nat"
+	"github.com/mysteriumnetwork/node/nat/traversal"
 	service_noop "github.com/mysteriumnetwork/node/services/noop"
 	service_openvpn "github.com/mysteriumnetwork/node/services/openvpn"
 	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/session/balance"
+	"github.com/mysteriumnetwork/node/session/earnings"
 	session_payment "github.com/mysteriumnetwork/node/session/payment"
 	payment_factory "github.com/mysteriumnetwork/node/session/payment/factory"
 	payments_noop "github.com/mysteriumnetwork/node/session/payment/noop"
 	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/mysteriumnetwork/node/session/promise/settlement"
 	"github.com/mysteriumnetwork/node/session/promise/validators"
 	"github.com/mysteriumnetwork/node/tequilapi"
 	tequilapi_endpoints "github.com/mysteriumnetwork/node/tequilapi/endpoints"
 	"github.com/mysteriumnetwork/node/utils"
 )
 
+const (
+	// healthCheckTimeout limits how long healthcheck waits for a single component probe
+	healthCheckTimeout = 10 * time.Second
+	// healthCheckInterval is the period of component checks, healthcheck endpoint reports the latest of them
+	healthCheckInterval = time.Minute
+	// brokerConnectionsHealthComponent is the name of the component reporting shared broker connections
+	brokerConnectionsHealthComponent = "broker-connections"
+	// stunTimeout limits how long STUN server response is awaited when detecting public IP
+	stunTimeout = 5 * time.Second
+	// natDetectionTimeout limits how long STUN servers are awaited when detecting public endpoint of the port for NAT traversal,
+	// consumer connection waits for it before the session is created
+	natDetectionTimeout = 2 * time.Second
+)
+
 // Storage stores persistent objects for future usage
 type Storage interface {
 	Store(issuer string, data interface{}) error
@@ -74,6 +95,7 @@ type Storage interface {
 	GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error
 	GetLast(bucket string, to interface{}) error
 	GetBuckets() []string
+	Check() error
 	Close() error
 }
 
@@ -87,15 +109,18 @@ type Dependencies struct {
 	EtherClient          *ethclient.Client
 
 	NATService           nat.NATService
+	NATPuncher           *traversal.Puncher
 	Storage              Storage
 	Keystore             *keystore.KeyStore
 	PromiseStorage       *promise.Storage
+	PromiseSettler       *settlement.Settler
 	IdentityManager      identity.Manager
 	SignerFactory        identity.SignerFactory
 	IdentityRegistry     identity_registry.IdentityRegistry
 	IdentityRegistration identity_registry.RegistrationDataProvider
+	IdentityBalance      identity.Balance
 
-	IPResolver       ip.Resolver
+	IPResolver       *ip.CachedResolver
 	LocationResolver location.Resolver
 	LocationDetector location.Detector
 	LocationOriginal location.Cache
@@ -104,14 +129,19 @@ type Dependencies struct {
 	StatisticsReporter *statistics.SessionStatisticsReporter
 	SessionStorage     *consumer_session.Storage
 
-	EventBus EventBus.Bus
+	EventBus          EventBus.Bus
+	BrokerConnections *nats.ConnectionManager
+	MetricsSender     *metrics.Sender
+	HealthRegistry    *health.Registry
 
 	ConnectionManager  connection.Manager
 	ConnectionRegistry *connection.Registry
 
-	ServicesManager       *service.Manager
-	ServiceRegistry       *service.Registry
-	ServiceSessionStorage *session.StorageMemory
+	ServicesManager            *service.Manager
+	ServiceRegistry            *service.Registry
+	ServiceSessionStorage      *session.StorageMemory
+	ServiceLocationWatcher     *service.LocationWatcher
+	ServiceReachabilityChecker *service.ReachabilityChecker
 }
 
 // Bootstrap initiates all container dependencies
@@ -137,10 +167,14 @@ func (di *Dependencies) Bootstrap(nodeOptions node.Options) error {
 		return err
 	}
 
+	di.EventBus = EventBus.New()
+	di.BrokerConnections = nats.NewConnectionManager(di.EventBus)
+
 	di.bootstrapIdentityComponents(nodeOptions)
 	di.bootstrapLocationComponents(nodeOptions.Location, nodeOptions.Directories.Config)
 
 	di.bootstrapServices(nodeOptions)
+	di.bootstrapHealthChecks(nodeOptions)
 	di.bootstrapNodeComponents(nodeOptions)
 
 	di.registerConnections(nodeOptions)
@@ -187,6 +221,21 @@ func (di *Dependencies) Shutdown() (err error) {
 		}
 	}()
 
+	if di.PromiseSettler != nil {
+		di.PromiseSettler.Stop()
+	}
+	if di.HealthRegistry != nil {
+		di.HealthRegistry.Stop()
+	}
+	if di.IPResolver != nil {
+		di.IPResolver.Stop()
+	}
+	if di.ServiceLocationWatcher != nil {
+		di.ServiceLocationWatcher.Stop()
+	}
+	if di.ServiceReachabilityChecker != nil {
+		di.ServiceReachabilityChecker.Stop()
+	}
 	if di.ServicesManager != nil {
 		if err := di.ServicesManager.Kill(); err != nil {
 			errs = append(errs, err)
@@ -244,19 +293,95 @@ func (di *Dependencies) subscribeEventConsumers() error {
 		return err
 	}
 
+	// payment events
+	err = di.EventBus.Subscribe(session_payment.PromiseIssuedTopic, di.SessionStorage.ConsumePromiseIssuedEvent)
+	if err != nil {
+		return err
+	}
+
 	// statistics events
 	err = di.EventBus.Subscribe(connection.StatisticsEventTopic, di.StatisticsTracker.ConsumeStatisticsEvent)
 	if err != nil {
 		return err
 	}
 
+	// metrics events
+	metricsConsumer := metrics.NewEventsConsumer(di.MetricsSender)
+	err = di.EventBus.Subscribe(connection.SessionEventTopic, metricsConsumer.ConsumeSessionEvent)
+	if err != nil {
+		return err
+	}
+	err = di.EventBus.Subscribe(connection.FailureEventTopic, metricsConsumer.ConsumeFailureEvent)
+	if err != nil {
+		return err
+	}
+	err = di.EventBus.Subscribe(registry.ProposalEventTopic, metricsConsumer.ConsumeProposalEvent)
+	if err != nil {
+		return err
+	}
+	err = di.EventBus.Subscribe(nats_dialog.RejectionTopic, metricsConsumer.ConsumeDialogRejectionEvent)
+	if err != nil {
+		return err
+	}
+
+	// public IP seen through the consumer connection is the one of the tunnel, provider location is not changed by it
+	err = di.EventBus.Subscribe(connection.StateEventTopic, func(event connection.StateEvent) {
+		switch event.State {
+		case connection.NotConnected:
+			di.setTunneled(false)
+		case connection.Connecting, connection.Connected, connection.Reconnecting, connection.Disconnecting:
+			di.setTunneled(true)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// lost broker connection is recorded as the last error of the health check, even if it is reconnected before the next check
+	err = di.EventBus.Subscribe(nats.ConnectionStatusTopic, func(nats.ConnectionEvent) {
+		go di.HealthRegistry.CheckComponent(brokerConnectionsHealthComponent)
+	})
+	if err != nil {
+		return err
+	}
+
+	// running services follow the changed public IP
+	if di.ServiceLocationWatcher != nil {
+		err = di.EventBus.Subscribe(ip.PublicIPChangeTopic, func(ip.ChangeEvent) {
+			go di.ServiceLocationWatcher.Check()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// identity events
+	if di.ServicesManager != nil {
+		// persisted services can only be restored when provider identity is unlocked
+		err = di.EventBus.Subscribe(identity.IdentityUnlockTopic, func(address string) {
+			di.ServicesManager.Restore(identity.FromAddress(address))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// setTunneled tells IP resolution and provider location watching whether node traffic goes through the consumer tunnel
+func (di *Dependencies) setTunneled(tunneled bool) {
+	di.IPResolver.SetTunneled(tunneled)
+	if di.ServiceLocationWatcher != nil {
+		di.ServiceLocationWatcher.SetTunneled(tunneled)
+	}
+}
+
 func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) {
-	dialogFactory := func(consumerID, providerID identity.Identity, contact market.Contact) (communication.Dialog, error) {
-		dialogEstablisher := nats_dialog.NewDialogEstablisher(consumerID, di.SignerFactory(consumerID))
-		return dialogEstablisher.EstablishDialog(providerID, contact)
+	dialogProtocol := newDialogProtocol(nodeOptions)
+	dialogFactory := func(ctx context.Context, consumerID, providerID identity.Identity, contact market.Contact) (communication.Dialog, error) {
+		dialogEstablisher := nats_dialog.NewDialogEstablisher(consumerID, di.SignerFactory(consumerID), di.BrokerConnections, dialogProtocol)
+		return dialogEstablisher.EstablishDialog(ctx, providerID, contact)
 	}
 
 	di.StatisticsTracker = statistics.NewSessionStatisticsTracker(time.Now)
@@ -269,17 +394,49 @@ func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) {
 	)
 	di.SessionStorage = consumer_session.NewSessionStorage(di.Storage, di.StatisticsTracker)
 	di.PromiseStorage = promise.NewStorage(di.Storage)
-	di.EventBus = EventBus.New()
+	if nodeOptions.ExperimentPayments {
+		di.bootstrapPromiseSettlement()
+	}
 
+	var disabledMetricsCategories []metrics.Category
+	for _, category := range nodeOptions.MetricsDisabledCategories {
+		disabledMetricsCategories = append(disabledMetricsCategories, metrics.Category(category))
+	}
+	di.MetricsSender = metrics.CreateSender(
+		nodeOptions.DisableMetrics,
+		nodeOptions.MetricsAddress,
+		metadata.VersionAsString(),
+		disabledMetricsCategories,
+		di.Storage,
+	)
+
+	var consumerBalance identity.Balance
+	if nodeOptions.ExperimentPayments {
+		consumerBalance = di.IdentityBalance
+	}
 	di.ConnectionRegistry = connection.NewRegistry()
 	di.ConnectionManager = connection.NewManager(
 		dialogFactory,
-		payment_factory.PaymentIssuerFactoryFunc(nodeOptions, di.SignerFactory),
+		payment_factory.PaymentIssuerFactoryFunc(nodeOptions, di.SignerFactory, di.EventBus),
 		di.ConnectionRegistry.CreateConnection,
 		di.EventBus,
+		consumerBalance,
+		connection.BalancePolicy{
+			MinimumBalance:   nodeOptions.Payments.ConsumerMinBalance,
+			RefuseLowBalance: nodeOptions.Payments.ConsumerRefuseLowBalance,
+		},
 	)
 
-	router := tequilapi.NewAPIRouter()
+	var httpAPIServer tequilapi.APIServer
+	if !nodeOptions.DisableTequilapi {
+		httpAPIServer = di.bootstrapTequilapi(nodeOptions)
+	}
+	di.Node = node.NewNode(di.ConnectionManager, httpAPIServer, di.LocationOriginal, di.MetricsSender)
+}
+
+// bootstrapTequilapi creates http server of Tequilapi with all the node endpoints
+func (di *Dependencies) bootstrapTequilapi(nodeOptions node.Options) tequilapi.APIServer {
+	router := tequilapi.NewAPIRouter(di.HealthRegistry)
 	tequilapi_endpoints.AddRouteForStop(router, utils.SoftKiller(di.Shutdown))
 	tequilapi_endpoints.AddRoutesForIdentities(router, di.IdentityManager, di.SignerFactory)
 	tequilapi_endpoints.AddRoutesForConnection(router, di.ConnectionManager, di.IPResolver, di.StatisticsTracker, di.MysteriumAPI)
@@ -287,14 +444,77 @@ func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) {
 	tequilapi_endpoints.AddRoutesForProposals(router, di.MysteriumAPI, di.MysteriumMorqaClient)
 	tequilapi_endpoints.AddRoutesForSession(router, di.SessionStorage)
 	tequilapi_endpoints.AddRoutesForService(router, di.ServicesManager, serviceTypesRequestParser)
+	tequilapi_endpoints.AddRoutesForEarnings(router, earnings.NewLedger(di.PromiseStorage))
 
 	identity_registry.AddIdentityRegistrationEndpoint(router, di.IdentityRegistration, di.IdentityRegistry)
+	identity_registry.AddIdentityBalanceEndpoint(router, di.IdentityBalance)
 
 	corsPolicy := tequilapi.NewMysteriumCorsPolicy()
-	httpAPIServer := tequilapi.NewServer(nodeOptions.TequilapiAddress, nodeOptions.TequilapiPort, router, corsPolicy)
-	metricsSender := metrics.CreateSender(nodeOptions.DisableMetrics, nodeOptions.MetricsAddress)
+	return tequilapi.NewServer(nodeOptions.TequilapiAddress, nodeOptions.TequilapiPort, router, corsPolicy)
+}
 
-	di.Node = node.NewNode(di.ConnectionManager, httpAPIServer, di.LocationOriginal, metricsSender)
+// bootstrapPromiseSettlement starts clearing of the received promises against payments contract
+func (di *Dependencies) bootstrapPromiseSettlement() {
+	clearer, err := settlement.NewContractClearer(
+		di.EtherClient,
+		di.NetworkDefinition.PaymentsContractAddress,
+		settlement.NewKeystoreTransactorFactory(di.Keystore),
+	)
+	if err != nil {
+		log.Warn("Failed to setup promise settlement: ", err)
+		return
+	}
+
+	// TODO: the batch size and times here need to be passed in as options
+	di.PromiseSettler = settlement.NewSettler(
+		di.PromiseStorage,
+		clearer,
+		di.SignerFactory,
+		10,
+		10*time.Minute,
+		30*time.Minute,
+		time.Hour,
+	)
+	di.PromiseSettler.Start()
+}
+
+// bootstrapHealthChecks registers probes of the components reported by healthcheck endpoint
+func (di *Dependencies) bootstrapHealthChecks(nodeOptions node.Options) {
+	di.HealthRegistry = health.NewRegistry(healthCheckTimeout)
+	di.HealthRegistry.Register("storage", true, di.Storage.Check)
+
+	// broker connection is kept open between the checks and is reconnected by NATS client itself
+	brokerAddress, err := nats_discovery.NewAddressForBroker(di.NetworkDefinition.BrokerAddress)
+	if err != nil {
+		log.Warn("Failed to setup broker health check: ", err)
+	} else {
+		brokerAddress.SetConnector(di.BrokerConnections)
+		di.HealthRegistry.Register("broker", true, brokerAddress.CheckConnection)
+	}
+	di.HealthRegistry.Register(brokerConnectionsHealthComponent, false, di.BrokerConnections.HealthCheck)
+
+	di.HealthRegistry.Register("discovery", false, di.MysteriumAPI.HealthCheck)
+
+	if nodeOptions.ExperimentIdentityCheck {
+		di.HealthRegistry.Register("identity-registry", false, func() error {
+			_, err := di.IdentityRegistry.IsRegistered(identity.FromAddress(common.Address{}.Hex()))
+			return err
+		})
+	}
+
+	if di.ServicesManager != nil {
+		di.HealthRegistry.Register("services", false, di.ServicesManager.HealthCheck)
+	}
+	di.HealthRegistry.Start(healthCheckInterval)
+}
+
+// newDialogProtocol returns dialog protocol of the node, promise payments are supported only if they are enabled
+func newDialogProtocol(nodeOptions node.Options) communication.Protocol {
+	capabilities := append([]communication.Capability(nil), communication.DefaultCapabilities...)
+	if nodeOptions.ExperimentPayments {
+		capabilities = append(capabilities, communication.CapabilityPromisePayments)
+	}
+	return communication.NewProtocol(capabilities...)
 }
 
 func newSessionManagerFactory(
@@ -309,6 +529,9 @@ func newSessionManagerFactory(
 			if !nodeOptions.ExperimentPayments {
 				return payments_noop.NewSessionBalance(), nil
 			}
+			if !dialog.PeerProtocol().Supports(communication.CapabilityPromisePayments) {
+				return nil, session.ErrorPaymentsNotSupported
+			}
 
 			timeTracker := session.NewTracker(time.Now)
 			// TODO: set the time and proper payment info
@@ -331,13 +554,14 @@ func newSessionManagerFactory(
 			// TODO: the ints and times here need to be passed in as well, or defined as constants
 			tracker := balance.NewBalanceTracker(&timeTracker, amountCalc, 0)
 			validator := validators.NewIssuedPromiseValidator(consumerID, receiverID, issuerID)
-			return session_payment.NewSessionBalance(sender, tracker, promiseChan, time.Second*5, time.Second*1, validator, promiseStorage, consumerID, receiverID, issuerID), nil
+			return session_payment.NewSessionBalance(sender, tracker, promiseChan, time.Second*5, time.Second*1, validator, promiseStorage, proposal.ServiceType, consumerID, receiverID, issuerID), nil
 		}
 		return session.NewManager(
 			proposal,
 			session.GenerateUUID,
 			sessionStorage,
 			providerBalanceTrackerFactory,
+			session.NewTerminationSender(dialog),
 		)
 	}
 }
@@ -381,6 +605,9 @@ func (di *Dependencies) bootstrapNetworkComponents(options node.OptionsNetwork)
 	}
 
 	log.Info("Using Eth contract at address: ", network.PaymentsContractAddress.String())
+	if di.IdentityBalance, err = identity_registry.NewIdentityBalanceContract(di.EtherClient, network.PaymentsContractAddress); err != nil {
+		return err
+	}
 	if options.ExperimentIdentityCheck {
 		if di.IdentityRegistry, err = identity_registry.NewIdentityRegistryContract(di.EtherClient, network.PaymentsContractAddress); err != nil {
 			return err
@@ -394,7 +621,7 @@ func (di *Dependencies) bootstrapNetworkComponents(options node.OptionsNetwork)
 
 func (di *Dependencies) bootstrapIdentityComponents(options node.Options) {
 	di.Keystore = identity.NewKeystoreFilesystem(options.Directories.Keystore, options.Keystore.UseLightweight)
-	di.IdentityManager = identity.NewIdentityManager(di.Keystore)
+	di.IdentityManager = identity.NewIdentityManager(di.Keystore, di.EventBus)
 	di.SignerFactory = func(id identity.Identity) identity.Signer {
 		return identity.NewSigner(di.Keystore, id)
 	}
@@ -402,7 +629,18 @@ func (di *Dependencies) bootstrapIdentityComponents(options node.Options) {
 }
 
 func (di *Dependencies) bootstrapLocationComponents(options node.OptionsLocation, configDirectory string) {
-	di.IPResolver = ip.NewResolver(options.IpifyUrl)
+	ipSources := []ip.Resolver{ip.NewResolver(options.IpifyUrl)}
+	for _, url := range options.IPSourceUrls {
+		ipSources = append(ipSources, ip.NewResolver(url))
+	}
+	for _, server := range options.STUNServers {
+		ipSources = append(ipSources, ip.NewStunResolver(server, stunTimeout))
+	}
+	di.IPResolver = ip.NewCachedResolver(ip.NewConsensusResolver(ipSources...), options.IPCacheTTL, di.EventBus)
+	if options.IPCheckInterval > 0 {
+		di.IPResolver.Start(options.IPCheckInterval)
+	}
+	di.NATPuncher = traversal.NewPuncher(options.STUNServers, natDetectionTimeout)
 
 	switch {
 	case options.Country != "":
@@ -412,6 +650,9 @@ func (di *Dependencies) bootstrapLocationComponents(options node.OptionsLocation
 	default:
 		di.LocationResolver = location.NewBuiltInResolver()
 	}
+	if options.ExternalAsnDb != "" {
+		di.LocationResolver = location.NewASNResolver(di.LocationResolver, filepath.Join(configDirectory, options.ExternalAsnDb))
+	}
 
 	di.LocationDetector = location.NewDetector(di.IPResolver, di.LocationResolver)
 	di.LocationOriginal = location.NewLocationCache(di.LocationDetector)
@@ -18,6 +18,9 @@
 package cmd
 
 import (
+	"strings"
+	"time"
+
 	"github.com/mysteriumnetwork/node/core/node"
 	"github.com/urfave/cli"
 )
@@ -28,11 +31,41 @@ var (
 		Usage: "Address (URL form) of ipify service",
 		Value: "https://api.ipify.org/",
 	}
+	ipURLsFlag = cli.StringFlag{
+		Name:  "ip.urls",
+		Usage: "Comma separated list of additional ipify compatible services used to detect public IP",
+		Value: "",
+	}
+	ipStunServersFlag = cli.StringFlag{
+		Name:  "ip.stun-servers",
+		Usage: "Comma separated list of STUN servers used to detect public IP",
+		Value: "stun.l.google.com:19302,stun1.l.google.com:19302",
+	}
+	ipCacheTTLFlag = cli.DurationFlag{
+		Name:  "ip.cache-ttl",
+		Usage: "Time for which detected public IP is reused",
+		Value: 5 * time.Minute,
+	}
+	ipCheckIntervalFlag = cli.DurationFlag{
+		Name:  "ip.check-interval",
+		Usage: "Interval of public IP change detection, 0 disables it",
+		Value: 5 * time.Minute,
+	}
 	locationDatabaseFlag = cli.StringFlag{
 		Name:  "location.database",
-		Usage: "Service location autodetect database of GeoLite2 format e.g. http://dev.maxmind.com/geoip/geoip2/geolite2/",
+		Usage: "Service location autodetect database of GeoLite2 format e.g. http://dev.maxmind.com/geoip/geoip2/geolite2/, city database also resolves region and city",
 		Value: "",
 	}
+	locationAsnDatabaseFlag = cli.StringFlag{
+		Name:  "location.asn-database",
+		Usage: "Service autonomous system autodetect database of GeoLite2 ASN format e.g. http://dev.maxmind.com/geoip/geoip2/geolite2/",
+		Value: "",
+	}
+	locationCheckIntervalFlag = cli.DurationFlag{
+		Name:  "location.check-interval",
+		Usage: "Interval of service location detection, proposals of running services are updated when location changes, 0 disables it",
+		Value: 10 * time.Minute,
+	}
 	// LocationCountryFlag allows to configure service country manually
 	LocationCountryFlag = cli.StringFlag{
 		Name:  "location.country",
@@ -43,14 +76,35 @@ var (
 
 // RegisterFlagsLocation function register location flags to flag list
 func RegisterFlagsLocation(flags *[]cli.Flag) {
-	*flags = append(*flags, ipifyURLFlag, locationDatabaseFlag, LocationCountryFlag)
+	*flags = append(
+		*flags,
+		ipifyURLFlag, ipURLsFlag, ipStunServersFlag, ipCacheTTLFlag, ipCheckIntervalFlag,
+		locationDatabaseFlag, locationAsnDatabaseFlag, locationCheckIntervalFlag, LocationCountryFlag,
+	)
 }
 
 // ParseFlagsLocation function fills in location options from CLI context
 func ParseFlagsLocation(ctx *cli.Context) node.OptionsLocation {
 	return node.OptionsLocation{
-		IpifyUrl:   ctx.GlobalString(ipifyURLFlag.Name),
-		ExternalDb: ctx.GlobalString(locationDatabaseFlag.Name),
-		Country:    ctx.GlobalString(LocationCountryFlag.Name),
+		IpifyUrl:        ctx.GlobalString(ipifyURLFlag.Name),
+		IPSourceUrls:    splitList(ctx.GlobalString(ipURLsFlag.Name)),
+		STUNServers:     splitList(ctx.GlobalString(ipStunServersFlag.Name)),
+		IPCacheTTL:      ctx.GlobalDuration(ipCacheTTLFlag.Name),
+		IPCheckInterval: ctx.GlobalDuration(ipCheckIntervalFlag.Name),
+		CheckInterval:   ctx.GlobalDuration(locationCheckIntervalFlag.Name),
+		ExternalDb:      ctx.GlobalString(locationDatabaseFlag.Name),
+		ExternalAsnDb:   ctx.GlobalString(locationAsnDatabaseFlag.Name),
+		Country:         ctx.GlobalString(LocationCountryFlag.Name),
+	}
+}
+
+// splitList splits comma separated flag value skipping empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
+	return list
 }
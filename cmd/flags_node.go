@@ -18,6 +18,8 @@
 package cmd
 
 import (
+	"strings"
+
 	"github.com/mysteriumnetwork/node/core/node"
 	openvpn_core "github.com/mysteriumnetwork/node/services/openvpn/core"
 	"github.com/urfave/cli"
@@ -48,6 +50,11 @@ var (
 		Usage: "Address of metrics service",
 		Value: "http://metrics.mysterium.network:8091",
 	}
+	metricsDisabledCategoriesFlag = cli.StringFlag{
+		Name:  "metrics.disabled-categories",
+		Usage: "Comma separated list of metrics event categories to opt-out from (startup, session, connection, proposal, dialog)",
+		Value: "",
+	}
 )
 
 // ParseKeystoreFlags parses the keystore options for node
@@ -64,11 +71,15 @@ func RegisterFlagsNode(flags *[]cli.Flag) error {
 	}
 
 	*flags = append(*flags, tequilapiAddressFlag, tequilapiPortFlag, keystoreLightweightFlag, metricsDisableFlag,
-		metricsAddressFlag)
+		metricsAddressFlag, metricsDisabledCategoriesFlag)
 
 	RegisterFlagsNetwork(flags)
 	openvpn_core.RegisterFlags(flags)
 	RegisterFlagsLocation(flags)
+	RegisterFlagsPayments(flags)
+	RegisterFlagsServiceRestart(flags)
+	RegisterFlagsServiceReachability(flags)
+	RegisterFlagsServiceSession(flags)
 
 	return nil
 }
@@ -81,15 +92,30 @@ func ParseFlagsNode(ctx *cli.Context) node.Options {
 		TequilapiAddress: ctx.GlobalString(tequilapiAddressFlag.Name),
 		TequilapiPort:    ctx.GlobalInt(tequilapiPortFlag.Name),
 
-		DisableMetrics: ctx.GlobalBool(metricsDisableFlag.Name),
-		MetricsAddress: ctx.GlobalString(metricsAddressFlag.Name),
+		DisableMetrics:            ctx.GlobalBool(metricsDisableFlag.Name),
+		MetricsAddress:            ctx.GlobalString(metricsAddressFlag.Name),
+		MetricsDisabledCategories: parseCommaSeparatedList(ctx.GlobalString(metricsDisabledCategoriesFlag.Name)),
 
 		Keystore: ParseKeystoreFlags(ctx),
 
-		Openvpn:        wrapper{nodeOptions: openvpn_core.ParseFlags(ctx)},
-		Location:       ParseFlagsLocation(ctx),
-		OptionsNetwork: ParseFlagsNetwork(ctx),
+		Openvpn:             wrapper{nodeOptions: openvpn_core.ParseFlags(ctx)},
+		Location:            ParseFlagsLocation(ctx),
+		Payments:            ParseFlagsPayments(ctx),
+		ServiceRestart:      ParseFlagsServiceRestart(ctx),
+		ServiceReachability: ParseFlagsServiceReachability(ctx),
+		ServiceSession:      ParseFlagsServiceSession(ctx),
+		OptionsNetwork:      ParseFlagsNetwork(ctx),
+	}
+}
+
+func parseCommaSeparatedList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
 
 // TODO this struct will disappear when we unify go-openvpn embedded lib and external process based session creation/handling
@@ -0,0 +1,48 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"github.com/mysteriumnetwork/node/core/node"
+	"github.com/urfave/cli"
+)
+
+var (
+	paymentsConsumerMinBalanceFlag = cli.Uint64Flag{
+		Name:  "payments.consumer.min-balance",
+		Usage: "Balance needed to cover the minimum session. Consumer is warned when connecting with lower balance",
+		Value: 100,
+	}
+	paymentsConsumerRefuseLowBalanceFlag = cli.BoolFlag{
+		Name:  "payments.consumer.refuse-low-balance",
+		Usage: "Refuse to connect when consumer balance is lower than minimum instead of only warning",
+	}
+)
+
+// RegisterFlagsPayments function register payments flags to flag list
+func RegisterFlagsPayments(flags *[]cli.Flag) {
+	*flags = append(*flags, paymentsConsumerMinBalanceFlag, paymentsConsumerRefuseLowBalanceFlag)
+}
+
+// ParseFlagsPayments function fills in payments options from CLI context
+func ParseFlagsPayments(ctx *cli.Context) node.OptionsPayments {
+	return node.OptionsPayments{
+		ConsumerMinBalance:       ctx.GlobalUint64(paymentsConsumerMinBalanceFlag.Name),
+		ConsumerRefuseLowBalance: ctx.GlobalBool(paymentsConsumerRefuseLowBalanceFlag.Name),
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/node"
+	"github.com/urfave/cli"
+)
+
+var (
+	serviceRestartMaxFlag = cli.IntFlag{
+		Name:  "service.restart.max",
+		Usage: "Number of consecutive restarts of failing service before giving up. 0 disables restarts",
+		Value: 5,
+	}
+	serviceRestartBackoffFlag = cli.DurationFlag{
+		Name:  "service.restart.backoff",
+		Usage: "Delay before the first restart of failed service, doubled after every consecutive failure",
+		Value: 5 * time.Second,
+	}
+	serviceRestartMaxBackoffFlag = cli.DurationFlag{
+		Name:  "service.restart.max-backoff",
+		Usage: "Maximum delay between restarts of failed service, 0 means no limit",
+		Value: 5 * time.Minute,
+	}
+	serviceReachabilityCheckIntervalFlag = cli.DurationFlag{
+		Name:  "service.reachability.check-interval",
+		Usage: "Interval of checking that services can be reached on their public endpoints from outside of the provider network, 0 disables it",
+		Value: 5 * time.Minute,
+	}
+	serviceReachabilityTimeoutFlag = cli.DurationFlag{
+		Name:  "service.reachability.timeout",
+		Usage: "Time to wait for the probe sent to the public endpoint of service when checking its reachability",
+		Value: 10 * time.Second,
+	}
+	serviceSessionIdleTimeoutFlag = cli.DurationFlag{
+		Name:  "service.session.idle-timeout",
+		Usage: "Destroy session when neither keepalives nor traffic of the consumer are seen for this long, 0 disables it",
+		Value: 10 * time.Minute,
+	}
+)
+
+// RegisterFlagsServiceRestart function register service restart flags to flag list
+func RegisterFlagsServiceRestart(flags *[]cli.Flag) {
+	*flags = append(*flags, serviceRestartMaxFlag, serviceRestartBackoffFlag, serviceRestartMaxBackoffFlag)
+}
+
+// ParseFlagsServiceRestart function fills in service restart options from CLI context
+func ParseFlagsServiceRestart(ctx *cli.Context) node.OptionsServiceRestart {
+	return node.OptionsServiceRestart{
+		MaxRestarts: ctx.GlobalInt(serviceRestartMaxFlag.Name),
+		Backoff:     ctx.GlobalDuration(serviceRestartBackoffFlag.Name),
+		MaxBackoff:  ctx.GlobalDuration(serviceRestartMaxBackoffFlag.Name),
+	}
+}
+
+// RegisterFlagsServiceReachability function register service reachability flags to flag list
+func RegisterFlagsServiceReachability(flags *[]cli.Flag) {
+	*flags = append(*flags, serviceReachabilityCheckIntervalFlag, serviceReachabilityTimeoutFlag)
+}
+
+// ParseFlagsServiceReachability function fills in service reachability options from CLI context
+func ParseFlagsServiceReachability(ctx *cli.Context) node.OptionsServiceReachability {
+	return node.OptionsServiceReachability{
+		CheckInterval: ctx.GlobalDuration(serviceReachabilityCheckIntervalFlag.Name),
+		Timeout:       ctx.GlobalDuration(serviceReachabilityTimeoutFlag.Name),
+	}
+}
+
+// RegisterFlagsServiceSession function register service session flags to flag list
+func RegisterFlagsServiceSession(flags *[]cli.Flag) {
+	*flags = append(*flags, serviceSessionIdleTimeoutFlag)
+}
+
+// ParseFlagsServiceSession function fills in service session options from CLI context
+func ParseFlagsServiceSession(ctx *cli.Context) node.OptionsServiceSession {
+	return node.OptionsServiceSession{
+		IdleTimeout: ctx.GlobalDuration(serviceSessionIdleTimeoutFlag.Name),
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/mysteriumnetwork/node/core/service"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/mysterium"
 	"github.com/mysteriumnetwork/node/market/proposals/registry"
 	"github.com/mysteriumnetwork/node/nat"
 	"github.com/mysteriumnetwork/node/nat/mapping"
@@ -53,6 +54,10 @@ func (di *Dependencies) bootstrapServices(nodeOptions node.Options) {
 }
 
 func (di *Dependencies) bootstrapServiceWireguard(nodeOptions node.Options) {
+	newProposal := func(_ service.Options, locationInfo location.ServiceLocationInfo) market.ServiceProposal {
+		natTraversal := wireguard_service.NATTraversalSupported(locationInfo, di.NATPuncher)
+		return wireguard_service.GetProposal(proposalLocation(locationInfo), natTraversal)
+	}
 	di.ServiceRegistry.Register(
 		wireguard.ServiceType,
 		func(serviceOptions service.Options) (service.Service, market.ServiceProposal, error) {
@@ -63,23 +68,16 @@ func (di *Dependencies) bootstrapServiceWireguard(nodeOptions node.Options) {
 
 			wgOptions := serviceOptions.(wireguard_service.Options)
 
-			mapPort := func(port int) func() {
-				return mapping.GetPortMappingFunc(
-					locationInfo.PubIP,
-					locationInfo.OutIP,
-					"UDP",
-					port,
-					"Myst node wireguard(tm) port mapping")
-			}
-
-			return wireguard_service.NewManager(locationInfo, di.NATService, mapPort, wgOptions),
-				wireguard_service.GetProposal(locationInfo.Country), nil
+			portMapper := mapping.NewPortMapper(di.EventBus)
+			return wireguard_service.NewManager(locationInfo, di.NATService, portMapper, di.NATPuncher, wgOptions),
+				newProposal(serviceOptions, locationInfo), nil
 		},
 	)
+	di.ServiceRegistry.RegisterProposalFactory(wireguard.ServiceType, newProposal)
 }
 
 func (di *Dependencies) resolveIPsAndLocation() (loc location.ServiceLocationInfo, err error) {
-	pubIP, err := di.IPResolver.GetPublicIP()
+	pubIP, err := di.IPResolver.GetDirectPublicIP()
 	if err != nil {
 		return
 	}
@@ -91,44 +89,79 @@ func (di *Dependencies) resolveIPsAndLocation() (loc location.ServiceLocationInf
 	}
 	loc.OutIP = outboundIP
 
-	currentCountry, err := di.LocationResolver.ResolveCountry(pubIP)
+	currentLocation, err := di.LocationResolver.ResolveLocation(pubIP)
 	if err != nil {
 		log.Warn(logPrefix, "Failed to detect service country. ", err)
 		err = service.ErrorLocation
 		return
 	}
-	loc.Country = currentCountry
+	loc.Country = currentLocation.Country
+	loc.Region = currentLocation.Region
+	loc.City = currentLocation.City
+	loc.ASN = currentLocation.ASN
+	loc.ISP = currentLocation.ISP
 
 	log.Info(logPrefix, "Detected service country: ", loc.Country)
+	if loc.City != "" || loc.ASN != "" {
+		log.Info(logPrefix, "Detected service city: ", loc.City, ", region: ", loc.Region, ", network: ", loc.ASN, " ", loc.ISP)
+	}
 	return
 }
 
+// probeEndpoint asks discovery to reach the service endpoint from outside of the provider network
+func (di *Dependencies) probeEndpoint(request service.ProbeRequest) error {
+	err := di.MysteriumAPI.ProbeEndpoint(
+		mysterium.ProbeEndpointRequest{
+			ProviderID:  request.ProviderID,
+			ServiceType: request.ServiceType,
+			Network:     request.Network,
+			Address:     request.Address,
+			Token:       request.Token,
+		},
+		di.SignerFactory(identity.FromAddress(request.ProviderID)),
+	)
+	if err == mysterium.ErrEndpointProbeUnsupported {
+		return service.ErrProbeUnsupported
+	}
+	return err
+}
+
+// proposalLocation returns location of the service published in its proposal
+func proposalLocation(loc location.ServiceLocationInfo) market.Location {
+	return market.Location{
+		Country: loc.Country,
+		Region:  loc.Region,
+		City:    loc.City,
+		ASN:     loc.ASN,
+		ISP:     loc.ISP,
+	}
+}
+
 func (di *Dependencies) bootstrapServiceOpenvpn(nodeOptions node.Options) {
+	newProposal := func(serviceOptions service.Options, locationInfo location.ServiceLocationInfo) market.ServiceProposal {
+		transportOptions := serviceOptions.(openvpn_service.Options)
+		return openvpn_discovery.NewServiceProposalWithLocation(proposalLocation(locationInfo), transportOptions.Protocol)
+	}
 	createService := func(serviceOptions service.Options) (service.Service, market.ServiceProposal, error) {
 		locationInfo, err := di.resolveIPsAndLocation()
 		if err != nil {
 			return nil, market.ServiceProposal{}, err
 		}
 
-		currentLocation := market.Location{Country: locationInfo.Country}
 		transportOptions := serviceOptions.(openvpn_service.Options)
 
-		mapPort := func() func() {
-			return mapping.GetPortMappingFunc(
-				locationInfo.PubIP,
-				locationInfo.OutIP,
-				transportOptions.Protocol,
-				transportOptions.Port,
-				"Myst node OpenVPN port mapping")
-		}
-
-		proposal := openvpn_discovery.NewServiceProposalWithLocation(currentLocation, transportOptions.Protocol)
-		return openvpn_service.NewManager(nodeOptions, transportOptions, locationInfo, di.ServiceSessionStorage, di.NATService, mapPort), proposal, nil
+		portMapper := mapping.NewPortMapper(di.EventBus)
+		proposal := newProposal(serviceOptions, locationInfo)
+		return openvpn_service.NewManager(nodeOptions, transportOptions, locationInfo, di.ServiceSessionStorage, di.NATService, portMapper), proposal, nil
 	}
 	di.ServiceRegistry.Register(service_openvpn.ServiceType, createService)
+	di.ServiceRegistry.RegisterProposalFactory(service_openvpn.ServiceType, newProposal)
 }
 
 func (di *Dependencies) bootstrapServiceNoop(nodeOptions node.Options) {
+	newProposal := func(_ service.Options, locationInfo location.ServiceLocationInfo) market.ServiceProposal {
+		return service_noop.GetProposal(proposalLocation(locationInfo))
+	}
 	di.ServiceRegistry.Register(
 		service_noop.ServiceType,
 		func(serviceOptions service.Options) (service.Service, market.ServiceProposal, error) {
@@ -137,9 +170,10 @@ func (di *Dependencies) bootstrapServiceNoop(nodeOptions node.Options) {
 				return nil, market.ServiceProposal{}, err
 			}
 
-			return service_noop.NewManager(), service_noop.GetProposal(locationInfo.Country), nil
+			return service_noop.NewManager(), newProposal(serviceOptions, locationInfo), nil
 		},
 	)
+	di.ServiceRegistry.RegisterProposalFactory(service_noop.ServiceType, newProposal)
 }
 
 // bootstrapServiceComponents initiates ServicesManager dependency
@@ -156,24 +190,57 @@ func (di *Dependencies) bootstrapServiceComponents(nodeOptions node.Options) {
 		if err != nil {
 			return nil, err
 		}
+		address.SetConnector(di.BrokerConnections)
 
 		return nats_dialog.NewDialogWaiter(
 			address,
 			di.SignerFactory(providerID),
 			di.IdentityRegistry,
+			newDialogProtocol(nodeOptions),
+			nats_dialog.DefaultLimits(),
+			di.EventBus,
 		), nil
 	}
 	newDialogHandler := func(proposal market.ServiceProposal, configProvider session.ConfigNegotiator) communication.DialogHandler {
 		sessionManagerFactory := newSessionManagerFactory(proposal, di.ServiceSessionStorage, di.PromiseStorage, nodeOptions)
-		return session.NewDialogHandler(sessionManagerFactory, configProvider.ProvideConfig, di.PromiseStorage, identity.FromAddress(proposal.ProviderID))
+		activityMonitor, _ := configProvider.(session.ActivityMonitor)
+		return session.NewDialogHandler(
+			sessionManagerFactory,
+			configProvider.ProvideConfig,
+			di.PromiseStorage,
+			identity.FromAddress(proposal.ProviderID),
+			activityMonitor,
+			nodeOptions.ServiceSession.IdleTimeout,
+		)
 	}
 	newDiscovery := func() service.Discovery {
-		return registry.NewService(di.IdentityRegistry, di.IdentityRegistration, di.MysteriumAPI, di.SignerFactory)
+		return registry.NewService(di.IdentityRegistry, di.IdentityRegistration, di.MysteriumAPI, di.SignerFactory, di.EventBus)
 	}
 	di.ServicesManager = service.NewManager(
 		di.ServiceRegistry,
 		newDialogWaiter,
 		newDialogHandler,
 		newDiscovery,
+		service.NewStorage(di.Storage, serviceTypesRequestParser),
+		service.RestartPolicy{
+			MaxRestarts: nodeOptions.ServiceRestart.MaxRestarts,
+			Backoff:     nodeOptions.ServiceRestart.Backoff,
+			MaxBackoff:  nodeOptions.ServiceRestart.MaxBackoff,
+		},
 	)
+
+	di.ServiceLocationWatcher = service.NewLocationWatcher(di.resolveIPsAndLocation, di.ServicesManager.UpdateLocation)
+	if nodeOptions.Location.CheckInterval > 0 {
+		di.ServiceLocationWatcher.Start(nodeOptions.Location.CheckInterval)
+	}
+
+	di.ServiceReachabilityChecker = service.NewReachabilityChecker(
+		di.ServicesManager.List,
+		di.probeEndpoint,
+		nodeOptions.ServiceReachability.Timeout,
+		di.EventBus,
+	)
+	if nodeOptions.ServiceReachability.CheckInterval > 0 {
+		di.ServiceReachabilityChecker.Start(nodeOptions.ServiceReachability.CheckInterval)
+	}
 }
@@ -0,0 +1,53 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package communication
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+// NewCodecMsgpack returns codec which:
+//   - encodes/decodes payloads forward & backward MessagePack binary format
+//   - names structure fields by their JSON tags, so payloads stay interchangeable with JSON codec
+func NewCodecMsgpack() *codecMsgpack {
+	return &codecMsgpack{}
+}
+
+type codecMsgpack struct{}
+
+func (codec *codecMsgpack) Pack(payloadPtr interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := msgpack.NewEncoder(&buffer).UseJSONTag(true).UseCompactEncoding(true).Encode(payloadPtr); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (codec *codecMsgpack) Unpack(data []byte, payloadPtr interface{}) (err error) {
+	// payloads come from peers, decoder must not crash the node on malformed ones
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("msgpack: malformed payload: %v", r)
+		}
+	}()
+
+	return msgpack.NewDecoder(bytes.NewReader(data)).UseJSONTag(true).Decode(payloadPtr)
+}
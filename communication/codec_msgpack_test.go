@@ -0,0 +1,222 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package communication
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var _ Codec = &codecMsgpack{}
+
+type msgpackPayload struct {
+	ID       string            `json:"id"`
+	Amount   uint64            `json:"amount"`
+	Balance  int64             `json:"balance"`
+	Rate     float64           `json:"rate"`
+	Accepted bool              `json:"accepted"`
+	Config   json.RawMessage   `json:"config"`
+	Tags     []string          `json:"tags,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Nested   *customPayload    `json:"nested,omitempty"`
+	Created  time.Time         `json:"created"`
+	Ignored  string            `json:"-"`
+	hidden   string
+}
+
+func TestCodecMsgpackPack(t *testing.T) {
+	table := []struct {
+		payload      interface{}
+		expectedData []byte
+	}{
+		{`hello`, []byte{0xa5, 'h', 'e', 'l', 'l', 'o'}},
+		{true, []byte{0xc3}},
+		{nil, []byte{0xc0}},
+		{10, []byte{0x0a}},
+		{-10, []byte{0xf6}},
+		{300, []byte{0xcd, 0x01, 0x2c}},
+		{-300, []byte{0xd1, 0xfe, 0xd4}},
+		{10.20, []byte{0xcb, 0x40, 0x24, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66}},
+		{[]byte{1, 2}, []byte{0xc4, 0x02, 0x01, 0x02}},
+		{json.RawMessage(`{}`), []byte{0xc4, 0x02, '{', '}'}},
+		{[]int{1, 2}, []byte{0x92, 0x01, 0x02}},
+		{&customPayload{123}, []byte{0x81, 0xa5, 'F', 'i', 'e', 'l', 'd', 0x7b}},
+		{&customPayload{}, []byte{0x81, 0xa5, 'F', 'i', 'e', 'l', 'd', 0x00}},
+	}
+
+	codec := codecMsgpack{}
+	for _, tt := range table {
+		data, err := codec.Pack(tt.payload)
+
+		assert.NoError(t, err)
+		assert.Exactly(t, tt.expectedData, data)
+	}
+}
+
+func TestCodecMsgpackUnpack(t *testing.T) {
+	codec := codecMsgpack{}
+
+	var text string
+	assert.NoError(t, codec.Unpack([]byte{0xa5, 'h', 'e', 'l', 'l', 'o'}, &text))
+	assert.Exactly(t, "hello", text)
+
+	var number int
+	assert.NoError(t, codec.Unpack([]byte{0xd1, 0xfe, 0xd4}, &number))
+	assert.Exactly(t, -300, number)
+
+	var generic interface{}
+	assert.NoError(t, codec.Unpack([]byte{0x81, 0xa5, 'F', 'i', 'e', 'l', 'd', 0x7b}, &generic))
+	assert.Exactly(t, map[string]interface{}{"Field": int8(123)}, generic)
+
+	var custom *customPayload
+	assert.NoError(t, codec.Unpack([]byte{0x81, 0xa5, 'F', 'i', 'e', 'l', 'd', 0x7b}, &custom))
+	assert.Exactly(t, &customPayload{123}, custom)
+
+	var config json.RawMessage
+	assert.NoError(t, codec.Unpack([]byte{0xc4, 0x02, '{', '}'}, &config))
+	assert.Exactly(t, json.RawMessage(`{}`), config)
+}
+
+func TestCodecMsgpackUnpackErrors(t *testing.T) {
+	codec := codecMsgpack{}
+
+	var text string
+	assert.Error(t, codec.Unpack([]byte{0xa5, 'h', 'e'}, &text))
+	assert.Error(t, codec.Unpack([]byte{0x0a}, &text))
+
+	var generic interface{}
+	err := codec.Unpack([]byte{0x81, 0xc0, 0xc0}, &generic)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "msgpack: malformed payload")
+}
+
+func TestCodecMsgpackRoundTrip(t *testing.T) {
+	payload := msgpackPayload{
+		ID:       "0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68",
+		Amount:   1 << 40,
+		Balance:  -1 << 40,
+		Rate:     0.125,
+		Accepted: true,
+		Config:   json.RawMessage(`{"port":1194}`),
+		Tags:     []string{"vpn", "wireguard"},
+		Labels:   map[string]string{"country": "LT"},
+		Nested:   &customPayload{Field: -1},
+		Created:  time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		Ignored:  "ignored",
+		hidden:   "hidden",
+	}
+
+	codec := codecMsgpack{}
+	data, err := codec.Pack(&payload)
+	assert.NoError(t, err)
+
+	var unpacked msgpackPayload
+	assert.NoError(t, codec.Unpack(data, &unpacked))
+
+	assert.True(t, payload.Created.Equal(unpacked.Created))
+	payload.Ignored, payload.hidden = "", ""
+	payload.Created, unpacked.Created = time.Time{}, time.Time{}
+	assert.Equal(t, payload, unpacked)
+}
+
+func TestCodecMsgpackIsInterchangeableWithJSON(t *testing.T) {
+	payload := msgpackPayload{
+		ID:      "session",
+		Amount:  100,
+		Config:  json.RawMessage(`{"port":1194}`),
+		Created: time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	data, err := NewCodecMsgpack().Pack(&payload)
+	assert.NoError(t, err)
+
+	var generic interface{}
+	assert.NoError(t, NewCodecMsgpack().Unpack(data, &generic))
+	dataJSON, err := json.Marshal(generic)
+	assert.NoError(t, err)
+
+	expectedJSON, err := json.Marshal(&payload)
+	assert.NoError(t, err)
+
+	var fromMsgpack, fromJSON map[string]interface{}
+	assert.NoError(t, json.Unmarshal(dataJSON, &fromMsgpack))
+	assert.NoError(t, json.Unmarshal(expectedJSON, &fromJSON))
+	// raw messages are kept as binary data
+	assert.Equal(t, []byte(`{"port":1194}`), generic.(map[string]interface{})["config"])
+	delete(fromMsgpack, "config")
+	delete(fromJSON, "config")
+	assert.Equal(t, fromJSON, fromMsgpack)
+}
+
+// TestCodecMsgpackUnpackCorruptedData feeds the decoder with randomly corrupted payloads, it must fail without panicking
+func TestCodecMsgpackUnpackCorruptedData(t *testing.T) {
+	codec := codecMsgpack{}
+	corpus := [][]byte{
+		{0xc0},
+		{0xdc, 0xff, 0xff},
+		{0xdf, 0xff, 0xff, 0xff, 0xff},
+		{0xc7, 0xff, 0x01},
+		{0xd9, 0xff},
+		append(bytes.Repeat([]byte{0x81, 0xa1, 'a'}, 100), 0xc0),
+	}
+	for _, payload := range []interface{}{
+		&msgpackPayload{
+			ID:      "0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68",
+			Amount:  1 << 40,
+			Rate:    0.125,
+			Config:  json.RawMessage(`{"port":1194}`),
+			Tags:    []string{"vpn", "wireguard"},
+			Labels:  map[string]string{"country": "LT"},
+			Nested:  &customPayload{Field: -1},
+			Created: time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		},
+		map[string]interface{}{"list": []interface{}{1, "two", 3.5, nil, map[string]interface{}{"deep": true}}},
+	} {
+		data, err := codec.Pack(payload)
+		assert.NoError(t, err)
+		corpus = append(corpus, data)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		data := append([]byte(nil), corpus[random.Intn(len(corpus))]...)
+		switch random.Intn(3) {
+		case 0:
+			data[random.Intn(len(data))] = byte(random.Intn(256))
+		case 1:
+			data = data[:random.Intn(len(data))]
+		default:
+			at := random.Intn(len(data) + 1)
+			data = append(data[:at], append([]byte{byte(random.Intn(256))}, data[at:]...)...)
+		}
+
+		var payload msgpackPayload
+		var generic interface{}
+		var list []interface{}
+		var object map[string]string
+		_ = codec.Unpack(data, &payload)
+		_ = codec.Unpack(data, &generic)
+		_ = codec.Unpack(data, &list)
+		_ = codec.Unpack(data, &object)
+	}
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package communication
+
+import "context"
+
+// DialogWithContext returns dialog, which binds all messages and requests sent through it to the given context
+func DialogWithContext(ctx context.Context, dialog Dialog) Dialog {
+	return &contextDialog{Dialog: dialog, ctx: ctx}
+}
+
+type contextDialog struct {
+	Dialog
+	ctx context.Context
+}
+
+// Send sends message bound to the context of the dialog
+func (dialog *contextDialog) Send(producer MessageProducer) error {
+	return dialog.Dialog.SendContext(dialog.ctx, producer)
+}
+
+// Request sends request bound to the context of the dialog
+func (dialog *contextDialog) Request(producer RequestProducer) (responsePtr interface{}, err error) {
+	return dialog.Dialog.RequestContext(dialog.ctx, producer)
+}
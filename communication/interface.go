@@ -18,6 +18,8 @@
 package communication
 
 import (
+	"context"
+
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 )
@@ -41,13 +43,14 @@ type DialogHandler interface {
 //   - initiates Dialog requests to network
 //   - creates Dialog, when it is negotiated
 type DialogEstablisher interface {
-	EstablishDialog(peerID identity.Identity, peerContact market.Contact) (Dialog, error)
+	EstablishDialog(ctx context.Context, peerID identity.Identity, peerContact market.Contact) (Dialog, error)
 }
 
 // Dialog represent established connection between 2 peers in network.
 // Enables bidirectional communication with another peer.
 type Dialog interface {
 	PeerID() identity.Identity
+	PeerProtocol() Protocol
 	Sender
 	Receiver
 	Close() error
@@ -65,7 +68,11 @@ type Receiver interface {
 // Sender represents interface for:
 //   - sending asynchronous messages
 //   - sending and HTTP-like request and waiting for response
+//
+// Context variants are aborted once the context is done, others are limited by the default timeout of the sender
 type Sender interface {
 	Send(producer MessageProducer) error
+	SendContext(ctx context.Context, producer MessageProducer) error
 	Request(producer RequestProducer) (responsePtr interface{}, err error)
+	RequestContext(ctx context.Context, producer RequestProducer) (responsePtr interface{}, err error)
 }
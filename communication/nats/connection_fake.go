@@ -18,8 +18,10 @@
 package nats
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/nats-io/go-nats"
@@ -44,9 +46,10 @@ func StartConnectionFake() *connectionFake {
 }
 
 type connectionFake struct {
-	subscriptions map[string][]nats.MsgHandler
-	queue         chan *nats.Msg
-	queueShutdown chan bool
+	subscriptions     map[string][]nats.MsgHandler
+	subscriptionsLock sync.Mutex
+	queue             chan *nats.Msg
+	queueShutdown     chan bool
 
 	messageLast *nats.Msg
 	requestLast *nats.Msg
@@ -111,6 +114,17 @@ func (conn *connectionFake) Subscribe(subject string, handler nats.MsgHandler) (
 }
 
 func (conn *connectionFake) Request(subject string, payload []byte, timeout time.Duration) (*nats.Msg, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	msg, err := conn.RequestWithContext(ctx, subject, payload)
+	if err == context.DeadlineExceeded {
+		return nil, fmt.Errorf("request '%s' timeout", subject)
+	}
+	return msg, err
+}
+
+func (conn *connectionFake) RequestWithContext(ctx context.Context, subject string, payload []byte) (*nats.Msg, error) {
 	if conn.errorMock != nil {
 		return nil, conn.errorMock
 	}
@@ -131,8 +145,8 @@ func (conn *connectionFake) Request(subject string, payload []byte, timeout time
 	select {
 	case response := <-responseCh:
 		return response, nil
-	case <-time.After(timeout):
-		return nil, fmt.Errorf("request '%s' timeout", subject)
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
@@ -145,15 +159,16 @@ func (conn *connectionFake) Close() {
 }
 
 func (conn *connectionFake) subscriptionAdd(subject string, handler nats.MsgHandler) {
-	subscriptions, exist := conn.subscriptions[subject]
-	if exist {
-		subscriptions = append(subscriptions, handler)
-	} else {
-		conn.subscriptions[subject] = []nats.MsgHandler{handler}
-	}
+	conn.subscriptionsLock.Lock()
+	defer conn.subscriptionsLock.Unlock()
+
+	conn.subscriptions[subject] = append(conn.subscriptions[subject], handler)
 }
 
 func (conn *connectionFake) subscriptionsGet(subject string) (*[]nats.MsgHandler, bool) {
+	conn.subscriptionsLock.Lock()
+	defer conn.subscriptionsLock.Unlock()
+
 	subscriptions, exist := conn.subscriptions[subject]
 	return &subscriptions, exist
 }
@@ -18,6 +18,7 @@
 package nats
 
 import (
+	"context"
 	"time"
 
 	"github.com/nats-io/go-nats"
@@ -28,5 +29,6 @@ type Connection interface {
 	Publish(subject string, payload []byte) error
 	Subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error)
 	Request(subject string, payload []byte, timeout time.Duration) (*nats.Msg, error)
+	RequestWithContext(ctx context.Context, subject string, payload []byte) (*nats.Msg, error)
 	Close()
 }
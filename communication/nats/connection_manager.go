@@ -0,0 +1,295 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/nats-io/go-nats"
+)
+
+const connectionManagerLogPrefix = "[NATS.ConnectionManager] "
+
+// ConnectionStatusTopic is the topic of events published when status of the shared broker connection changes
+const ConnectionStatusTopic = "NATS connection status"
+
+// ConnectionStatus represents state of the broker connection
+type ConnectionStatus string
+
+const (
+	// ConnectionStatusConnected means that broker connection is established
+	ConnectionStatusConnected = ConnectionStatus("Connected")
+	// ConnectionStatusDisconnected means that broker connection was lost and NATS client is reconnecting it
+	ConnectionStatusDisconnected = ConnectionStatus("Disconnected")
+	// ConnectionStatusClosed means that broker connection is closed and will not be reconnected anymore
+	ConnectionStatusClosed = ConnectionStatus("Closed")
+)
+
+// ConnectionEvent is published when shared broker connection is established, lost, reconnected or closed
+type ConnectionEvent struct {
+	Servers []string
+	Status  ConnectionStatus
+}
+
+// ConnectionInfo describes the shared broker connection
+type ConnectionInfo struct {
+	Servers []string
+	Status  ConnectionStatus
+	// Users is the count of addresses and dialogs sharing the connection
+	Users int
+}
+
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
+// Connector opens connections to the broker
+type Connector interface {
+	Connect(options nats.Options) (Connection, error)
+}
+
+// NewConnectionManager returns manager which shares single connection to the same broker servers among all its users
+func NewConnectionManager(publisher Publisher) *ConnectionManager {
+	return &ConnectionManager{
+		publisher:   publisher,
+		connections: make(map[string]*sharedConnection),
+		dial: func(options nats.Options) (Connection, error) {
+			return options.Connect()
+		},
+	}
+}
+
+// ConnectionManager keeps broker connections of the node. Every broker connection is reference counted,
+// subscriptions of all its users are multiplexed through it and it is closed once the last user closes it.
+type ConnectionManager struct {
+	publisher Publisher
+	dial      func(options nats.Options) (Connection, error)
+
+	connections map[string]*sharedConnection
+	lock        sync.Mutex
+}
+
+type sharedConnection struct {
+	key        string
+	servers    []string
+	connection Connection
+	status     ConnectionStatus
+	users      int
+}
+
+// Connect returns connection to the broker servers of given options, connection is shared with other users
+// of the same servers and it is dialed using the options only if there is no connection to these servers yet
+func (manager *ConnectionManager) Connect(options nats.Options) (Connection, error) {
+	key := connectionKey(options.Servers)
+
+	manager.lock.Lock()
+	shared, exists := manager.connections[key]
+	if exists && shared.status == ConnectionStatusClosed {
+		// NATS client has given up reconnecting, users of the closed connection keep it until they close it
+		delete(manager.connections, key)
+		exists = false
+	}
+	if !exists {
+		shared = &sharedConnection{key: key, servers: append([]string(nil), options.Servers...)}
+		options.DisconnectedCB = func(*nats.Conn) {
+			manager.setStatus(shared, ConnectionStatusDisconnected)
+		}
+		options.ReconnectedCB = func(*nats.Conn) {
+			manager.setStatus(shared, ConnectionStatusConnected)
+		}
+		options.ClosedCB = func(*nats.Conn) {
+			manager.setStatus(shared, ConnectionStatusClosed)
+		}
+
+		connection, err := manager.dial(options)
+		if err != nil {
+			manager.lock.Unlock()
+			return nil, err
+		}
+
+		shared.connection = connection
+		shared.status = ConnectionStatusConnected
+		manager.connections[key] = shared
+		log.Info(connectionManagerLogPrefix, "Connected to broker: ", key)
+	}
+	shared.users++
+	manager.lock.Unlock()
+
+	if !exists {
+		manager.publish(shared.servers, ConnectionStatusConnected)
+	}
+	return &connectionHandle{manager: manager, shared: shared}, nil
+}
+
+// Connections returns information of the currently open broker connections
+func (manager *ConnectionManager) Connections() []ConnectionInfo {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	connections := make([]ConnectionInfo, 0, len(manager.connections))
+	for _, shared := range manager.connections {
+		connections = append(connections, ConnectionInfo{
+			Servers: shared.servers,
+			Status:  shared.status,
+			Users:   shared.users,
+		})
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		return connectionKey(connections[i].Servers) < connectionKey(connections[j].Servers)
+	})
+	return connections
+}
+
+// HealthCheck returns an error describing the open broker connections, which are not connected at the moment
+func (manager *ConnectionManager) HealthCheck() error {
+	var failed []string
+	for _, connection := range manager.Connections() {
+		if connection.Status == ConnectionStatusConnected {
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("%s (%s, %d users)", connectionKey(connection.Servers), connection.Status, connection.Users))
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("broker connections not connected: %s", strings.Join(failed, ", "))
+}
+
+func (manager *ConnectionManager) setStatus(shared *sharedConnection, status ConnectionStatus) {
+	manager.lock.Lock()
+	if shared.status == status || shared.status == ConnectionStatusClosed {
+		manager.lock.Unlock()
+		return
+	}
+	shared.status = status
+	manager.lock.Unlock()
+
+	log.Info(connectionManagerLogPrefix, "Broker connection ", shared.key, " is ", status)
+	manager.publish(shared.servers, status)
+}
+
+func (manager *ConnectionManager) release(shared *sharedConnection) {
+	manager.lock.Lock()
+	shared.users--
+	if shared.users > 0 {
+		manager.lock.Unlock()
+		return
+	}
+	if manager.connections[shared.key] == shared {
+		delete(manager.connections, shared.key)
+	}
+	wasClosed := shared.status == ConnectionStatusClosed
+	shared.status = ConnectionStatusClosed
+	manager.lock.Unlock()
+
+	shared.connection.Close()
+	log.Info(connectionManagerLogPrefix, "Disconnected from broker: ", shared.key)
+	if !wasClosed {
+		manager.publish(shared.servers, ConnectionStatusClosed)
+	}
+}
+
+func (manager *ConnectionManager) publish(servers []string, status ConnectionStatus) {
+	if manager.publisher != nil {
+		manager.publisher.Publish(ConnectionStatusTopic, ConnectionEvent{Servers: servers, Status: status})
+	}
+}
+
+func connectionKey(servers []string) string {
+	sorted := append([]string(nil), servers...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// connectionHandle is the connection of a single user of the shared broker connection,
+// closing it unsubscribes subscriptions of the user only
+type connectionHandle struct {
+	manager *ConnectionManager
+	shared  *sharedConnection
+
+	subscriptions []*nats.Subscription
+	closed        bool
+	lock          sync.Mutex
+}
+
+// Publish publishes the payload to the subject
+func (handle *connectionHandle) Publish(subject string, payload []byte) error {
+	return handle.shared.connection.Publish(subject, payload)
+}
+
+// Subscribe subscribes the handler to the subject, subscription is cancelled when the handle is closed
+func (handle *connectionHandle) Subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	subscription, err := handle.shared.connection.Subscribe(subject, handler)
+	if err != nil {
+		return nil, err
+	}
+
+	handle.lock.Lock()
+	handle.subscriptions = append(handle.subscriptions, subscription)
+	handle.lock.Unlock()
+	return subscription, nil
+}
+
+// Request sends request to the subject and waits for response until the timeout
+func (handle *connectionHandle) Request(subject string, payload []byte, timeout time.Duration) (*nats.Msg, error) {
+	return handle.shared.connection.Request(subject, payload, timeout)
+}
+
+// RequestWithContext sends request to the subject and waits for response until the context is done
+func (handle *connectionHandle) RequestWithContext(ctx context.Context, subject string, payload []byte) (*nats.Msg, error) {
+	return handle.shared.connection.RequestWithContext(ctx, subject, payload)
+}
+
+// FlushTimeout verifies that broker responds through the shared connection
+func (handle *connectionHandle) FlushTimeout(timeout time.Duration) error {
+	if flusher, ok := handle.shared.connection.(interface {
+		FlushTimeout(time.Duration) error
+	}); ok {
+		return flusher.FlushTimeout(timeout)
+	}
+	return nil
+}
+
+// Close unsubscribes subscriptions of the handle and releases the shared connection
+func (handle *connectionHandle) Close() {
+	handle.lock.Lock()
+	if handle.closed {
+		handle.lock.Unlock()
+		return
+	}
+	handle.closed = true
+	subscriptions := handle.subscriptions
+	handle.subscriptions = nil
+	handle.lock.Unlock()
+
+	for _, subscription := range subscriptions {
+		if subscription.IsValid() {
+			if err := subscription.Unsubscribe(); err != nil {
+				log.Warn(connectionManagerLogPrefix, "Failed to unsubscribe from ", subscription.Subject, ": ", err)
+			}
+		}
+	}
+	handle.manager.release(handle.shared)
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nats
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nats-io/go-nats"
+	"github.com/stretchr/testify/assert"
+)
+
+type publisherFake struct {
+	events []ConnectionEvent
+}
+
+func (publisher *publisherFake) Publish(topic string, args ...interface{}) {
+	if topic == ConnectionStatusTopic {
+		publisher.events = append(publisher.events, args[0].(ConnectionEvent))
+	}
+}
+
+type closeCountingConnection struct {
+	*connectionFake
+	closed int
+}
+
+func (conn *closeCountingConnection) Close() {
+	conn.closed++
+}
+
+func newTestConnectionManager() (*ConnectionManager, *publisherFake, *[]*closeCountingConnection, *[]nats.Options) {
+	publisher := &publisherFake{}
+	manager := NewConnectionManager(publisher)
+
+	dialed := make([]*closeCountingConnection, 0)
+	dialedOptions := make([]nats.Options, 0)
+	manager.dial = func(options nats.Options) (Connection, error) {
+		connection := &closeCountingConnection{connectionFake: NewConnectionFake()}
+		dialed = append(dialed, connection)
+		dialedOptions = append(dialedOptions, options)
+		return connection, nil
+	}
+	return manager, publisher, &dialed, &dialedOptions
+}
+
+func optionsForServers(servers ...string) nats.Options {
+	options := nats.GetDefaultOptions()
+	options.Servers = servers
+	return options
+}
+
+func TestConnectionManager_SharesConnectionToSameServers(t *testing.T) {
+	manager, _, dialed, _ := newTestConnectionManager()
+
+	first, err := manager.Connect(optionsForServers("nats://a:4222", "nats://b:4222"))
+	assert.NoError(t, err)
+	second, err := manager.Connect(optionsForServers("nats://b:4222", "nats://a:4222"))
+	assert.NoError(t, err)
+	_, err = manager.Connect(optionsForServers("nats://c:4222"))
+	assert.NoError(t, err)
+
+	assert.False(t, first == second, "every user should get its own handle")
+	assert.Len(t, *dialed, 2)
+	assert.Equal(
+		t,
+		[]ConnectionInfo{
+			{Servers: []string{"nats://a:4222", "nats://b:4222"}, Status: ConnectionStatusConnected, Users: 2},
+			{Servers: []string{"nats://c:4222"}, Status: ConnectionStatusConnected, Users: 1},
+		},
+		manager.Connections(),
+	)
+}
+
+func TestConnectionManager_ClosesConnectionAfterLastUser(t *testing.T) {
+	manager, publisher, dialed, _ := newTestConnectionManager()
+
+	first, _ := manager.Connect(optionsForServers("nats://a:4222"))
+	second, _ := manager.Connect(optionsForServers("nats://a:4222"))
+
+	first.Close()
+	first.Close()
+	assert.Equal(t, 0, (*dialed)[0].closed)
+	assert.Equal(t, 1, manager.Connections()[0].Users)
+
+	second.Close()
+	assert.Equal(t, 1, (*dialed)[0].closed)
+	assert.Empty(t, manager.Connections())
+	assert.Equal(
+		t,
+		[]ConnectionEvent{
+			{Servers: []string{"nats://a:4222"}, Status: ConnectionStatusConnected},
+			{Servers: []string{"nats://a:4222"}, Status: ConnectionStatusClosed},
+		},
+		publisher.events,
+	)
+}
+
+func TestConnectionManager_PublishesReconnectEvents(t *testing.T) {
+	manager, publisher, _, dialedOptions := newTestConnectionManager()
+
+	manager.Connect(optionsForServers("nats://a:4222"))
+	options := (*dialedOptions)[0]
+
+	options.DisconnectedCB(nil)
+	options.DisconnectedCB(nil)
+	assert.Equal(t, ConnectionStatusDisconnected, manager.Connections()[0].Status)
+
+	options.ReconnectedCB(nil)
+	assert.Equal(t, ConnectionStatusConnected, manager.Connections()[0].Status)
+
+	assert.Equal(
+		t,
+		[]ConnectionEvent{
+			{Servers: []string{"nats://a:4222"}, Status: ConnectionStatusConnected},
+			{Servers: []string{"nats://a:4222"}, Status: ConnectionStatusDisconnected},
+			{Servers: []string{"nats://a:4222"}, Status: ConnectionStatusConnected},
+		},
+		publisher.events,
+	)
+}
+
+func TestConnectionManager_HealthCheckReportsLostConnections(t *testing.T) {
+	manager, _, _, dialedOptions := newTestConnectionManager()
+	assert.NoError(t, manager.HealthCheck())
+
+	manager.Connect(optionsForServers("nats://a:4222"))
+	manager.Connect(optionsForServers("nats://b:4222"))
+	manager.Connect(optionsForServers("nats://b:4222"))
+	assert.NoError(t, manager.HealthCheck())
+
+	(*dialedOptions)[1].DisconnectedCB(nil)
+	assert.EqualError(t, manager.HealthCheck(), "broker connections not connected: nats://b:4222 (Disconnected, 2 users)")
+
+	(*dialedOptions)[1].ReconnectedCB(nil)
+	assert.NoError(t, manager.HealthCheck())
+}
+
+func TestConnectionManager_RedialsClosedConnection(t *testing.T) {
+	manager, _, dialed, dialedOptions := newTestConnectionManager()
+
+	old, _ := manager.Connect(optionsForServers("nats://a:4222"))
+	(*dialedOptions)[0].ClosedCB(nil)
+
+	_, err := manager.Connect(optionsForServers("nats://a:4222"))
+	assert.NoError(t, err)
+	assert.Len(t, *dialed, 2)
+
+	old.Close()
+	assert.Equal(t, 1, (*dialed)[0].closed)
+	assert.Equal(t, 0, (*dialed)[1].closed)
+	assert.Equal(t, 1, manager.Connections()[0].Users)
+}
+
+func TestConnectionManager_ReturnsDialError(t *testing.T) {
+	manager := NewConnectionManager(nil)
+	manager.dial = func(options nats.Options) (Connection, error) {
+		return nil, errors.New("no servers available")
+	}
+
+	connection, err := manager.Connect(optionsForServers("nats://a:4222"))
+	assert.Nil(t, connection)
+	assert.EqualError(t, err, "no servers available")
+	assert.Empty(t, manager.Connections())
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dialog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/session/balance"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/stretchr/testify/assert"
+)
+
+// messageSamples are typical payloads of the chattiest dialog endpoints
+var messageSamples = []struct {
+	endpoint   string
+	payload    interface{}
+	newPayload func() interface{}
+}{
+	{
+		"session-create request",
+		&session.CreateRequest{
+			ProposalID:   1,
+			Config:       json.RawMessage(`{"PublicKey":"7pyTMv38qCfHGgSBPBd3L8iXbI4sHySCN0TFPbtR7GE="}`),
+			ConsumerInfo: &session.ConsumerInfo{IssuerID: identity.FromAddress("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68")},
+		},
+		func() interface{} { return &session.CreateRequest{} },
+	},
+	{
+		"session-create response",
+		&session.CreateResponse{
+			Success: true,
+			Session: session.SessionDto{
+				ID:     "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				Config: json.RawMessage(`{"Provider":{"PublicKey":"wA+Pbr4tA1fAjZUanyaBnfIXOUCZoe1eZSUPMdSXdlg=","Endpoint":"82.135.11.10:52820"},"Subnet":{"IP":"10.182.47.1","Mask":"////AA=="}}`),
+			},
+			PaymentInfo: &promise.PaymentInfo{
+				LastPromise: promise.LastPromise{SequenceID: 12, Amount: 120000},
+				FreeCredit:  100,
+			},
+		},
+		func() interface{} { return &session.CreateResponse{} },
+	},
+	{
+		"session-balance",
+		&balance.Request{BalanceMessage: balance.Message{Balance: 987654, SequenceID: 42}},
+		func() interface{} { return &balance.Request{} },
+	},
+	{
+		"session-promise",
+		&promise.Request{Message: promise.Message{
+			Amount:     1250000,
+			SequenceID: 42,
+			Signature:  "0x2f2d2a1b76a46ec7e0c0d6f4d8bc9a5b0c1e8a3d9b6f7e5d4c3b2a190817263544f0e1d2c3b4a5968778695a4b3c2d1e0f1a2b3c4d5e6f708192a3b4c5d6e7f801",
+		}},
+		func() interface{} { return &promise.Request{} },
+	},
+}
+
+func TestCodecSecured_MsgpackMessagesAreSmaller(t *testing.T) {
+	codecJSON := newBenchmarkCodec(communication.NewCodecJSON())
+	codecMsgpack := newBenchmarkCodec(communication.NewCodecMsgpack())
+
+	for _, sample := range messageSamples {
+		dataJSON, err := codecJSON.Pack(sample.payload)
+		assert.NoError(t, err)
+		dataMsgpack, err := codecMsgpack.Pack(sample.payload)
+		assert.NoError(t, err)
+
+		t.Logf("%s: JSON %d bytes, MessagePack %d bytes", sample.endpoint, len(dataJSON), len(dataMsgpack))
+		assert.True(t, len(dataMsgpack) < len(dataJSON), sample.endpoint)
+
+		payload := sample.newPayload()
+		assert.NoError(t, codecMsgpack.Unpack(dataMsgpack, payload))
+		assert.Equal(t, sample.payload, payload)
+	}
+}
+
+func BenchmarkCodecSecured_PackJSON(b *testing.B) {
+	benchmarkPack(b, communication.NewCodecJSON())
+}
+
+func BenchmarkCodecSecured_PackMsgpack(b *testing.B) {
+	benchmarkPack(b, communication.NewCodecMsgpack())
+}
+
+func BenchmarkCodecSecured_UnpackJSON(b *testing.B) {
+	benchmarkUnpack(b, communication.NewCodecJSON())
+}
+
+func BenchmarkCodecSecured_UnpackMsgpack(b *testing.B) {
+	benchmarkUnpack(b, communication.NewCodecMsgpack())
+}
+
+func benchmarkPack(b *testing.B, codecPacker communication.Codec) {
+	codec := newBenchmarkCodec(codecPacker)
+	for _, sample := range messageSamples {
+		b.Run(sample.endpoint, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := codec.Pack(sample.payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func benchmarkUnpack(b *testing.B, codecPacker communication.Codec) {
+	codec := newBenchmarkCodec(codecPacker)
+	for _, sample := range messageSamples {
+		data, err := codec.Pack(sample.payload)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(sample.endpoint, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if err := codec.Unpack(data, sample.newPayload()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// newBenchmarkCodec returns secured codec with signatures of real size, which are not really signed nor verified
+func newBenchmarkCodec(codecPacker communication.Codec) *codecSecured {
+	return NewCodecSecured(codecPacker, &fixedSigner{}, &acceptingVerifier{})
+}
+
+type fixedSigner struct{}
+
+func (signer *fixedSigner) Sign(message []byte) (identity.Signature, error) {
+	return identity.SignatureBytes(make([]byte, 65)), nil
+}
+
+type acceptingVerifier struct{}
+
+func (verifier *acceptingVerifier) Verify(message []byte, signature identity.Signature) bool {
+	return true
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dialog
+
+import (
+	"fmt"
+
+	"github.com/mysteriumnetwork/node/communication"
+)
+
+const (
+	codecJSON    = "json"
+	codecMsgpack = "msgpack"
+)
+
+// supportedCodecs lists codecs which dialogs can use for messages, in the order of preference
+var supportedCodecs = []string{codecMsgpack, codecJSON}
+
+// negotiateCodec picks the first of codecs offered by peer which is supported.
+// Empty result means that peer does not negotiate codecs and JSON has to be used.
+func negotiateCodec(offered []string) string {
+	for _, name := range offered {
+		for _, supported := range supportedCodecs {
+			if name == supported {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// newCodecPacker returns packer of the negotiated codec, older peers which do not negotiate codecs get JSON
+func newCodecPacker(name string) (communication.Codec, error) {
+	switch name {
+	case "", codecJSON:
+		return communication.NewCodecJSON(), nil
+	case codecMsgpack:
+		return communication.NewCodecMsgpack(), nil
+	}
+	return nil, fmt.Errorf("unsupported codec '%s'", name)
+}
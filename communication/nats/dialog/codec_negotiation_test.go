@@ -0,0 +1,55 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dialog
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateCodec(t *testing.T) {
+	var tests = []struct {
+		offered       []string
+		expectedCodec string
+	}{
+		{nil, ""},
+		{[]string{"protobuf"}, ""},
+		{[]string{"json", "msgpack"}, "json"},
+		{[]string{"protobuf", "msgpack", "json"}, "msgpack"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expectedCodec, negotiateCodec(test.offered))
+	}
+}
+
+func TestNewCodecPacker(t *testing.T) {
+	packer, err := newCodecPacker("")
+	assert.NoError(t, err)
+	assert.Equal(t, communication.NewCodecJSON(), packer)
+
+	packer, err = newCodecPacker("msgpack")
+	assert.NoError(t, err)
+	assert.Equal(t, communication.NewCodecMsgpack(), packer)
+
+	packer, err = newCodecPacker("protobuf")
+	assert.EqualError(t, err, "unsupported codec 'protobuf'")
+	assert.Nil(t, packer)
+}
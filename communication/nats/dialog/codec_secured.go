@@ -78,6 +78,52 @@ func (codec *codecSecured) Unpack(data []byte, payloadPtr interface{}) error {
 	return codec.codecPacker.Unpack(envelope.Payload, payloadPtr)
 }
 
+// peerMessage is implemented by messages, which tell the peer they are sent by
+type peerMessage interface {
+	peerIdentity() identity.Identity
+}
+
+// newCodecPeerSigned returns secured codec for messages of not yet known peers,
+// which requires each peer message to be signed by the peer it claims to be sent by
+func newCodecPeerSigned(codecPacker communication.Codec, signer identity.Signer) *codecPeerSigned {
+	return &codecPeerSigned{
+		codecSecured: NewCodecSecured(codecPacker, signer, identity.NewVerifierSigned()),
+		extractor:    identity.NewExtractor(),
+	}
+}
+
+type codecPeerSigned struct {
+	*codecSecured
+	extractor identity.Extractor
+}
+
+func (codec *codecPeerSigned) Unpack(data []byte, payloadPtr interface{}) error {
+	envelope := &messageEnvelope{}
+	err := codec.codecPacker.Unpack(data, envelope)
+	if err != nil {
+		return err
+	}
+
+	signerID, err := codec.extractor.Extract(envelope.Payload, identity.SignatureBase64(envelope.Signature))
+	if err != nil {
+		return fmt.Errorf("invalid message signature '%s'", envelope.Signature)
+	}
+
+	err = codec.codecPacker.Unpack(envelope.Payload, payloadPtr)
+	if err != nil {
+		return err
+	}
+
+	message, ok := payloadPtr.(peerMessage)
+	if !ok {
+		return fmt.Errorf("message does not tell its peer")
+	}
+	if message.peerIdentity() != signerID {
+		return fmt.Errorf("message of peer '%s' is signed by '%s'", message.peerIdentity().Address, signerID.Address)
+	}
+	return nil
+}
+
 type messageEnvelope struct {
 	Payload   json.RawMessage `json:"payload"`
 	Signature string          `json:"signature"`
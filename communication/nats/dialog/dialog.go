@@ -25,13 +25,26 @@ import (
 type dialog struct {
 	communication.Sender
 	communication.Receiver
-	peerID identity.Identity
+	peerID       identity.Identity
+	peerProtocol communication.Protocol
+	// release frees the broker connection of the dialog, nil when connection is owned by someone else
+	release func()
 }
 
+// Close unsubscribes from all topics of the dialog and releases its broker connection
 func (dialog *dialog) Close() error {
+	dialog.Receiver.Unsubscribe()
+	if dialog.release != nil {
+		dialog.release()
+	}
 	return nil
 }
 
 func (dialog *dialog) PeerID() identity.Identity {
 	return dialog.peerID
 }
+
+// PeerProtocol returns dialog protocol of the peer, which was negotiated while creating the dialog
+func (dialog *dialog) PeerProtocol() communication.Protocol {
+	return dialog.peerProtocol
+}
@@ -18,6 +18,7 @@
 package dialog
 
 import (
+	"context"
 	"fmt"
 
 	log "github.com/cihub/seelog"
@@ -29,14 +30,21 @@ import (
 )
 
 // NewDialogEstablisher constructs new DialogEstablisher which works thru NATS connection.
-func NewDialogEstablisher(ID identity.Identity, signer identity.Signer) *dialogEstablisher {
+// Broker connections are shared through the connector, nil connector makes every dialog to open its own connection.
+// Dialogs are established with peers of protocol compatible with the given local protocol.
+func NewDialogEstablisher(ID identity.Identity, signer identity.Signer, connector nats.Connector, protocol communication.Protocol) *dialogEstablisher {
 
 	return &dialogEstablisher{
-		ID:     ID,
-		Signer: signer,
+		ID:             ID,
+		Signer:         signer,
+		Protocol:       protocol,
+		minPeerVersion: communication.ProtocolVersionMin,
 		peerAddressFactory: func(contact market.Contact) (*discovery.AddressNATS, error) {
 			address, err := discovery.NewAddressForContact(contact)
 			if err == nil {
+				if connector != nil {
+					address.SetConnector(connector)
+				}
 				err = address.Connect()
 			}
 
@@ -50,10 +58,14 @@ const establisherLogPrefix = "[NATS.DialogEstablisher] "
 type dialogEstablisher struct {
 	ID                 identity.Identity
 	Signer             identity.Signer
+	Protocol           communication.Protocol
+	minPeerVersion     int
 	peerAddressFactory func(contact market.Contact) (*discovery.AddressNATS, error)
 }
 
+// EstablishDialog connects to the peer and negotiates dialog with it, negotiation is aborted once the context is done
 func (establisher *dialogEstablisher) EstablishDialog(
+	ctx context.Context,
 	peerID identity.Identity,
 	peerContact market.Contact,
 ) (communication.Dialog, error) {
@@ -64,40 +76,70 @@ func (establisher *dialogEstablisher) EstablishDialog(
 		return nil, fmt.Errorf("failed to connect to: %#v. %s", peerContact, err)
 	}
 
-	peerCodec := establisher.newCodecForPeer(peerID)
+	peerCodec := establisher.newCodecForPeer(peerID, communication.NewCodecJSON())
 
 	peerSender := establisher.newSenderToPeer(peerAddress, peerCodec)
-	err = establisher.negotiateDialog(peerSender)
+	response, err := establisher.negotiateDialog(ctx, peerSender)
 	if err != nil {
+		peerAddress.Disconnect()
 		return nil, err
 	}
 
-	dialog := establisher.newDialogToPeer(peerID, peerAddress, peerCodec)
+	codecPacker, err := newCodecPacker(response.Codec)
+	if err != nil {
+		peerAddress.Disconnect()
+		return nil, fmt.Errorf("dialog creation error. %s", err)
+	}
+
+	protocol := peerProtocol(response.Version, response.Capabilities)
+	dialog := establisher.newDialogToPeer(peerID, protocol, peerAddress, establisher.newCodecForPeer(peerID, codecPacker))
 	log.Info(establisherLogPrefix, fmt.Sprintf("Dialog established with: %#v", peerContact))
 
 	return dialog, nil
 }
 
-func (establisher *dialogEstablisher) negotiateDialog(sender communication.Sender) error {
-	response, err := sender.Request(&dialogCreateProducer{
+// negotiateDialog asks peer to create the dialog and returns peer response with the chosen codec and peer protocol
+func (establisher *dialogEstablisher) negotiateDialog(ctx context.Context, sender communication.Sender) (*dialogCreateResponse, error) {
+	responsePtr, err := sender.RequestContext(ctx, &dialogCreateProducer{
 		&dialogCreateRequest{
-			PeerID: establisher.ID.Address,
+			PeerID:       establisher.ID.Address,
+			Codecs:       supportedCodecs,
+			Version:      establisher.Protocol.Version,
+			Capabilities: establisher.Protocol.Capabilities,
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("dialog creation error. %s", err)
+		return nil, fmt.Errorf("dialog creation error. %s", err)
 	}
-	if response.(*dialogCreateResponse).Reason != 200 {
-		return fmt.Errorf("dialog creation rejected. %#v", response)
+
+	response := responsePtr.(*dialogCreateResponse)
+	if response.Reason == responseIncompatibleProtocol.Reason {
+		return nil, fmt.Errorf(
+			"dialog creation rejected. protocol version %d is too old for peer, it requires version %d or newer",
+			establisher.Protocol.Version,
+			response.MinVersion,
+		)
+	}
+	if response.Reason != 200 {
+		return nil, fmt.Errorf("dialog creation rejected. %#v", response)
+	}
+
+	protocol := peerProtocol(response.Version, response.Capabilities)
+	if protocol.Version < establisher.minPeerVersion {
+		return nil, fmt.Errorf(
+			"dialog creation rejected. peer protocol version %d is too old, version %d or newer is required",
+			protocol.Version,
+			establisher.minPeerVersion,
+		)
 	}
 
-	return nil
+	return response, nil
 }
 
-func (establisher *dialogEstablisher) newCodecForPeer(peerID identity.Identity) *codecSecured {
+func (establisher *dialogEstablisher) newCodecForPeer(peerID identity.Identity, codecPacker communication.Codec) *codecSecured {
 
 	return NewCodecSecured(
-		communication.NewCodecJSON(),
+		codecPacker,
 		establisher.Signer,
 		identity.NewVerifierIdentity(peerID),
 	)
@@ -117,14 +159,17 @@ func (establisher *dialogEstablisher) newSenderToPeer(
 
 func (establisher *dialogEstablisher) newDialogToPeer(
 	peerID identity.Identity,
+	peerProtocol communication.Protocol,
 	peerAddress *discovery.AddressNATS,
 	peerCodec *codecSecured,
 ) *dialog {
 
 	subTopic := peerAddress.GetTopic() + "." + establisher.ID.Address
 	return &dialog{
-		peerID:   peerID,
-		Sender:   nats.NewSender(peerAddress.GetConnection(), peerCodec, subTopic),
-		Receiver: nats.NewReceiver(peerAddress.GetConnection(), peerCodec, subTopic),
+		peerID:       peerID,
+		peerProtocol: peerProtocol,
+		Sender:       nats.NewSender(peerAddress.GetConnection(), peerCodec, subTopic),
+		Receiver:     nats.NewReceiver(peerAddress.GetConnection(), peerCodec, subTopic),
+		release:      peerAddress.Disconnect,
 	}
 }
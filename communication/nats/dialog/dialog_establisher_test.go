@@ -18,6 +18,7 @@
 package dialog
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mysteriumnetwork/node/communication"
@@ -34,10 +35,11 @@ func TestDialogEstablisher_Factory(t *testing.T) {
 	id := identity.FromAddress("123456")
 	signer := &identity.SignerFake{}
 
-	establisher := NewDialogEstablisher(id, signer)
+	establisher := NewDialogEstablisher(id, signer, nil, communication.NewProtocol())
 	assert.NotNil(t, establisher)
 	assert.Equal(t, id, establisher.ID)
 	assert.Equal(t, signer, establisher.Signer)
+	assert.Equal(t, communication.ProtocolVersion, establisher.Protocol.Version)
 }
 
 func TestDialogEstablisher_EstablishDialog(t *testing.T) {
@@ -57,13 +59,14 @@ func TestDialogEstablisher_EstablishDialog(t *testing.T) {
 	signer := &identity.SignerFake{}
 	establisher := mockEstablisher(myID, connection, signer)
 
-	dialogInstance, err := establisher.EstablishDialog(peerID, market.Contact{})
+	dialogInstance, err := establisher.EstablishDialog(context.Background(), peerID, market.Contact{})
 	defer dialogInstance.Close()
 	assert.NoError(t, err)
 	assert.NotNil(t, dialogInstance)
 
 	dialog, ok := dialogInstance.(*dialog)
 	assert.True(t, ok)
+	assert.Equal(t, communication.LegacyProtocol(), dialog.PeerProtocol())
 
 	expectedCodec := NewCodecSecured(communication.NewCodecJSON(), signer, identity.NewVerifierIdentity(peerID))
 	assert.Equal(
@@ -78,6 +81,109 @@ func TestDialogEstablisher_EstablishDialog(t *testing.T) {
 	)
 }
 
+func TestDialogEstablisher_EstablishDialogWithNegotiatedCodec(t *testing.T) {
+	myID := identity.FromAddress("0x6B21b441D0D2Fa1d86407977A3a5C6eD90Ff1A62")
+	peerID := identity.FromAddress("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68")
+
+	connection := nats.StartConnectionFake()
+	connection.MockResponse(
+		"peer-topic.dialog-create",
+		[]byte(`{
+			"payload": {"reason":200,"reasonMessage":"OK","codec":"msgpack"},
+			"signature": "KRIDb5WJvw6zFaewrqP5vdPptdTs5sTZ0JNV7nAvZNAjkUUbQozuZLd6f39ZkNzen6JXIPXclWcrHbv3mD5S7gA="
+		}`),
+	)
+	defer connection.Close()
+
+	signer := &identity.SignerFake{}
+	establisher := mockEstablisher(myID, connection, signer)
+
+	dialogInstance, err := establisher.EstablishDialog(context.Background(), peerID, market.Contact{})
+	assert.NoError(t, err)
+	defer dialogInstance.Close()
+	assert.Contains(t, string(connection.GetLastRequest()), `"codecs":["msgpack","json"]`)
+
+	expectedCodec := NewCodecSecured(communication.NewCodecMsgpack(), signer, identity.NewVerifierIdentity(peerID))
+	assert.Equal(
+		t,
+		nats.NewSender(connection, expectedCodec, "peer-topic."+myID.Address),
+		dialogInstance.(*dialog).Sender,
+	)
+}
+
+func TestDialogEstablisher_EstablishDialogWithPeerProtocol(t *testing.T) {
+	myID := identity.FromAddress("0x6B21b441D0D2Fa1d86407977A3a5C6eD90Ff1A62")
+	peerID := identity.FromAddress("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68")
+
+	connection := nats.StartConnectionFake()
+	connection.MockResponse(
+		"peer-topic.dialog-create",
+		[]byte(`{
+			"payload": {"reason":200,"reasonMessage":"OK","version":2,"capabilities":["session-keepalive","promise-payments"]},
+			"signature": "BCfirR/npn3twJrf+e4FkeVx3QKJgTQLUnlITA9zFuE4HkBu/FkL5z9CiLznSo+FsGq8g0tZNeFfmQifLU37PAA="
+		}`),
+	)
+	defer connection.Close()
+
+	establisher := mockEstablisher(myID, connection, &identity.SignerFake{})
+	establisher.Protocol = communication.NewProtocol(communication.CapabilitySessionKeepalive)
+
+	dialogInstance, err := establisher.EstablishDialog(context.Background(), peerID, market.Contact{})
+	assert.NoError(t, err)
+	defer dialogInstance.Close()
+	assert.Contains(t, string(connection.GetLastRequest()), `"version":2,"capabilities":["session-keepalive"]`)
+
+	assert.Equal(
+		t,
+		communication.NewProtocol(communication.CapabilitySessionKeepalive, communication.CapabilityPromisePayments),
+		dialogInstance.PeerProtocol(),
+	)
+}
+
+func TestDialogEstablisher_EstablishDialogRejectedForOldProtocol(t *testing.T) {
+	myID := identity.FromAddress("0x6B21b441D0D2Fa1d86407977A3a5C6eD90Ff1A62")
+	peerID := identity.FromAddress("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68")
+
+	connection := nats.StartConnectionFake()
+	connection.MockResponse(
+		"peer-topic.dialog-create",
+		[]byte(`{
+			"payload": {"reason":426,"reasonMessage":"Incompatible Protocol Version","version":3,"minVersion":3},
+			"signature": "B4u0cgjkzKYRctt3ICJmKC3RkxW7hJg9DZQ3/UayUVBAW/c6Bhz3eReyYSLLPYrQyusbTWt9xbTScUWG4RMZuwE="
+		}`),
+	)
+	defer connection.Close()
+
+	establisher := mockEstablisher(myID, connection, &identity.SignerFake{})
+	establisher.Protocol = communication.NewProtocol()
+
+	dialogInstance, err := establisher.EstablishDialog(context.Background(), peerID, market.Contact{})
+	assert.EqualError(t, err, "dialog creation rejected. protocol version 2 is too old for peer, it requires version 3 or newer")
+	assert.Nil(t, dialogInstance)
+}
+
+func TestDialogEstablisher_EstablishDialogRejectsOldPeer(t *testing.T) {
+	myID := identity.FromAddress("0x6B21b441D0D2Fa1d86407977A3a5C6eD90Ff1A62")
+	peerID := identity.FromAddress("0x0d1a35e53b7f3478d00B7C23838C0D48b2a81017")
+
+	connection := nats.StartConnectionFake()
+	connection.MockResponse(
+		"peer-topic.dialog-create",
+		[]byte(`{
+			"payload": {"reason":200,"reasonMessage":"OK"},
+			"signature": "iaV65n3kEve9+EzwWVi65qJFrb4FQZwq4yWdVH++abts3mW/xqKHpPKro7kX/liFRZgV5RHQMjE+TzPPdeJfewA="
+		}`),
+	)
+	defer connection.Close()
+
+	establisher := mockEstablisher(myID, connection, &identity.SignerFake{})
+	establisher.minPeerVersion = 2
+
+	dialogInstance, err := establisher.EstablishDialog(context.Background(), peerID, market.Contact{})
+	assert.EqualError(t, err, "dialog creation rejected. peer protocol version 1 is too old, version 2 or newer is required")
+	assert.Nil(t, dialogInstance)
+}
+
 func TestDialogEstablisher_CreateDialogWhenResponseHijacked(t *testing.T) {
 	myID := identity.FromAddress("0x6B21b441D0D2Fa1d86407977A3a5C6eD90Ff1A62")
 	peerID := identity.FromAddress("0x0d1a35e53b7f3478d00B7C23838C0D48b2a81017")
@@ -94,12 +200,28 @@ func TestDialogEstablisher_CreateDialogWhenResponseHijacked(t *testing.T) {
 
 	establisher := mockEstablisher(myID, connection, &identity.SignerFake{})
 
-	dialogInstance, err := establisher.EstablishDialog(peerID, market.Contact{})
+	dialogInstance, err := establisher.EstablishDialog(context.Background(), peerID, market.Contact{})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "dialog creation error. failed to unpack response 'peer-topic.dialog-create'. invalid message signature ")
 	assert.Nil(t, dialogInstance)
 }
 
+func TestDialogEstablisher_EstablishDialogCancelled(t *testing.T) {
+	myID := identity.FromAddress("0x6B21b441D0D2Fa1d86407977A3a5C6eD90Ff1A62")
+	peerID := identity.FromAddress("0x0d1a35e53b7f3478d00B7C23838C0D48b2a81017")
+
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	establisher := mockEstablisher(myID, connection, &identity.SignerFake{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dialogInstance, err := establisher.EstablishDialog(ctx, peerID, market.Contact{})
+	assert.EqualError(t, err, "dialog creation error. failed to send request 'peer-topic.dialog-create'. context canceled")
+	assert.Nil(t, dialogInstance)
+}
+
 func mockEstablisher(ID identity.Identity, connection nats.Connection, signer identity.Signer) *dialogEstablisher {
 	peerTopic := "peer-topic"
 
@@ -18,6 +18,7 @@
 package dialog
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 
@@ -31,12 +32,26 @@ import (
 )
 
 // NewDialogWaiter constructs new DialogWaiter which works through NATS connection.
-func NewDialogWaiter(address *discovery.AddressNATS, signer identity.Signer, identityRegistry registry.IdentityRegistry) *dialogWaiter {
+// Dialogs are accepted from peers of protocol compatible with the given local protocol,
+// rejected dialog requests are published to the given publisher.
+func NewDialogWaiter(
+	address *discovery.AddressNATS,
+	signer identity.Signer,
+	identityRegistry registry.IdentityRegistry,
+	protocol communication.Protocol,
+	limits Limits,
+	publisher nats.Publisher,
+) *dialogWaiter {
 	return &dialogWaiter{
 		address:          address,
 		signer:           signer,
 		dialogs:          make([]communication.Dialog, 0),
-		identityRegistry: identityRegistry,
+		identityRegistry: newRegistrationCache(identityRegistry, limits.RegisteredTTL, limits.UnregisteredTTL, limits.MaxPeers),
+		protocol:         protocol,
+		minPeerVersion:   communication.ProtocolVersionMin,
+		peerLimiter:      newRateLimiter(limits.PeerRate, limits.PeerBurst, limits.MaxPeers),
+		globalLimiter:    newRateLimiter(limits.GlobalRate, limits.GlobalBurst, 1),
+		publisher:        publisher,
 	}
 }
 
@@ -46,7 +61,13 @@ type dialogWaiter struct {
 	address          *discovery.AddressNATS
 	signer           identity.Signer
 	dialogs          []communication.Dialog
-	identityRegistry registry.IdentityRegistry
+	identityRegistry *registrationCache
+	protocol         communication.Protocol
+	minPeerVersion   int
+	receiver         communication.Receiver
+	peerLimiter      *rateLimiter
+	globalLimiter    *rateLimiter
+	publisher        nats.Publisher
 
 	sync.RWMutex
 }
@@ -71,6 +92,9 @@ func (waiter *dialogWaiter) Stop() error {
 	for _, dialog := range waiter.dialogs {
 		dialog.Close()
 	}
+	if waiter.receiver != nil {
+		waiter.receiver.Unsubscribe()
+	}
 	waiter.address.Disconnect()
 	return nil
 }
@@ -79,18 +103,52 @@ func (waiter *dialogWaiter) Stop() error {
 func (waiter *dialogWaiter) ServeDialogs(dialogHandler communication.DialogHandler) error {
 	createDialog := func(request *dialogCreateRequest) (*dialogCreateResponse, error) {
 
-		valid, err := waiter.validateDialogRequest(request)
+		protocol := peerProtocol(request.Version, request.Capabilities)
+		if protocol.Version < waiter.minPeerVersion {
+			log.Error(waiterLogPrefix, fmt.Sprintf("Rejecting peer '%s' of incompatible protocol version %d", request.PeerID, protocol.Version))
+			waiter.publishRejection(request.peerIdentity(), RejectionIncompatibleProtocol)
+			response := waiter.responseWithProtocol(responseIncompatibleProtocol)
+			response.MinVersion = waiter.minPeerVersion
+			return &response, nil
+		}
+
+		if request.PeerID == "" {
+			log.Error(waiterLogPrefix, "Rejecting empty peerID")
+			return &responseInvalidIdentity, nil
+		}
+
+		// request is signed by the peer itself, it is checked by the codec already
+		peerID := request.peerIdentity()
+		if !waiter.peerLimiter.allow(peerID.Address) {
+			log.Warn(waiterLogPrefix, "Rejecting peer exceeding dialog rate limit: ", request.PeerID)
+			waiter.publishRejection(peerID, RejectionPeerRateLimit)
+			return &responseTooManyRequests, nil
+		}
+
+		valid, err := waiter.validateDialogRequest(peerID)
+		if err == errRegistrationCheckLimited {
+			log.Warn(waiterLogPrefix, "Rejecting peer exceeding registration check rate limit: ", request.PeerID)
+			waiter.publishRejection(peerID, RejectionGlobalRateLimit)
+			return &responseTooManyRequests, nil
+		}
 		if err != nil {
 			log.Error(waiterLogPrefix, "Validation check failed: ", err.Error())
 			return &responseInternalError, nil
 		}
 		if !valid {
 			log.Error(waiterLogPrefix, "Rejecting invalid peerID: ", request.PeerID)
+			waiter.publishRejection(peerID, RejectionUnregistered)
 			return &responseInvalidIdentity, nil
 		}
 
-		peerID := identity.FromAddress(request.PeerID)
-		dialog := waiter.newDialogToPeer(peerID, waiter.newCodecForPeer(peerID))
+		codecName := negotiateCodec(request.Codecs)
+		codecPacker, err := newCodecPacker(codecName)
+		if err != nil {
+			log.Error(waiterLogPrefix, "Codec negotiation failed: ", err.Error())
+			return &responseInternalError, nil
+		}
+
+		dialog := waiter.newDialogToPeer(peerID, protocol, waiter.newCodecForPeer(peerID, codecPacker))
 		err = dialogHandler.Handle(dialog)
 		if err != nil {
 			log.Error(waiterLogPrefix, fmt.Sprintf("Failed dialog from: '%s'. %s", request.PeerID, err))
@@ -102,43 +160,67 @@ func (waiter *dialogWaiter) ServeDialogs(dialogHandler communication.DialogHandl
 		waiter.Unlock()
 
 		log.Info(waiterLogPrefix, fmt.Sprintf("Accepted dialog from: '%s'", request.PeerID))
-		return &responseOK, nil
+		response := waiter.responseWithProtocol(responseOK)
+		response.Codec = codecName
+		return &response, nil
 	}
 
-	codec := NewCodecSecured(communication.NewCodecJSON(), waiter.signer, identity.NewVerifierSigned())
+	codec := newCodecPeerSigned(communication.NewCodecJSON(), waiter.signer)
 	receiver := nats.NewReceiver(waiter.address.GetConnection(), codec, waiter.address.GetTopic())
 
+	waiter.Lock()
+	waiter.receiver = receiver
+	waiter.Unlock()
+
 	return receiver.Respond(&dialogCreateConsumer{createDialog})
 }
 
-func (waiter *dialogWaiter) newCodecForPeer(peerID identity.Identity) *codecSecured {
+func (waiter *dialogWaiter) newCodecForPeer(peerID identity.Identity, codecPacker communication.Codec) *codecSecured {
 
 	return NewCodecSecured(
-		communication.NewCodecJSON(),
+		codecPacker,
 		waiter.signer,
 		identity.NewVerifierIdentity(peerID),
 	)
 }
 
-func (waiter *dialogWaiter) newDialogToPeer(peerID identity.Identity, peerCodec *codecSecured) *dialog {
+func (waiter *dialogWaiter) newDialogToPeer(peerID identity.Identity, protocol communication.Protocol, peerCodec *codecSecured) *dialog {
 	subTopic := waiter.address.GetTopic() + "." + peerID.Address
 
 	return &dialog{
-		peerID:   peerID,
-		Sender:   nats.NewSender(waiter.address.GetConnection(), peerCodec, subTopic),
-		Receiver: nats.NewReceiver(waiter.address.GetConnection(), peerCodec, subTopic),
+		peerID:       peerID,
+		peerProtocol: protocol,
+		Sender:       nats.NewSender(waiter.address.GetConnection(), peerCodec, subTopic),
+		Receiver:     nats.NewReceiver(waiter.address.GetConnection(), peerCodec, subTopic),
 	}
 }
 
-func (waiter *dialogWaiter) validateDialogRequest(request *dialogCreateRequest) (bool, error) {
-	if request.PeerID == "" {
-		return false, nil
+// responseWithProtocol tells peer the local protocol in the given response
+func (waiter *dialogWaiter) responseWithProtocol(response dialogCreateResponse) dialogCreateResponse {
+	response.Version = waiter.protocol.Version
+	response.Capabilities = waiter.protocol.Capabilities
+	return response
+}
+
+var errRegistrationCheckLimited = errors.New("registration check rate limit exceeded")
+
+// validateDialogRequest checks registration of the peer. Only checks missing in the cache
+// hit the registry, and they are limited globally so that random peers do not flood it.
+func (waiter *dialogWaiter) validateDialogRequest(peerID identity.Identity) (bool, error) {
+	if registered, found := waiter.identityRegistry.lookup(peerID); found {
+		return registered, nil
 	}
 
-	registered, err := waiter.identityRegistry.IsRegistered(identity.FromAddress(request.PeerID))
-	if err != nil {
-		return false, err
+	if !waiter.globalLimiter.allow("") {
+		return false, errRegistrationCheckLimited
 	}
 
-	return registered, nil
+	return waiter.identityRegistry.IsRegistered(peerID)
+}
+
+func (waiter *dialogWaiter) publishRejection(peerID identity.Identity, reason RejectionReason) {
+	if waiter.publisher == nil {
+		return
+	}
+	waiter.publisher.Publish(RejectionTopic, RejectionEvent{PeerID: peerID, Reason: reason})
 }
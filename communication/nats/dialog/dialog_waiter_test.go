@@ -18,6 +18,7 @@
 package dialog
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
@@ -36,14 +37,21 @@ func TestDialogWaiter_Factory(t *testing.T) {
 	address := discovery.NewAddress("custom", "nats://far-server:4222")
 	signer := &identity.SignerFake{}
 
-	waiter := NewDialogWaiter(address, signer, &mockedIdentityRegistry{})
+	protocol := communication.NewProtocol(communication.CapabilitySessionKeepalive)
+
+	publisher := &publisherFake{}
+
+	waiter := NewDialogWaiter(address, signer, &mockedIdentityRegistry{}, protocol, DefaultLimits(), publisher)
 	assert.NotNil(t, waiter)
 	assert.Equal(t, address, waiter.address)
 	assert.Equal(t, signer, waiter.signer)
+	assert.Equal(t, protocol, waiter.protocol)
+	assert.Equal(t, communication.ProtocolVersionMin, waiter.minPeerVersion)
+	assert.Equal(t, publisher, waiter.publisher)
 }
 
 func TestDialogWaiter_ServeDialogs(t *testing.T) {
-	peerID := identity.FromAddress("0x28bf83df144ab7a566bc8509d1fff5d5470bd4ea")
+	peerID := identity.FromAddress("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68")
 
 	connection := nats.StartConnectionFake()
 	defer connection.Close()
@@ -53,8 +61,8 @@ func TestDialogWaiter_ServeDialogs(t *testing.T) {
 	defer waiter.Stop()
 
 	dialogAsk(connection, `{
-		"payload": {"peer_id":"0x28bf83df144ab7a566bc8509d1fff5d5470bd4ea"},
-		"signature": "tl+WbYkJdXD5foaIP3bqVGFHfr6kdd5FzmJAmu1GdpINEnNR3bTto6wgEoke/Fpy4zsWOjrulDVfrc32f5ArTgA="
+		"payload": {"peer_id":"0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"},
+		"signature": "YV1ngIhYBEyNLbpXd8lzy8MkM6zwJ+RccM1V67iZAKZfry+y1LRIixoWmkcT273y+BdSnRFJzDJ4mxINj+5WIAE="
 	}`)
 	dialogInstance, err := dialogWait(handler)
 	defer dialogInstance.Close()
@@ -63,20 +71,219 @@ func TestDialogWaiter_ServeDialogs(t *testing.T) {
 
 	dialog, ok := dialogInstance.(*dialog)
 	assert.True(t, ok)
+	assert.Equal(t, communication.LegacyProtocol(), dialog.PeerProtocol())
 
 	expectedCodec := NewCodecSecured(communication.NewCodecJSON(), signer, identity.NewVerifierIdentity(peerID))
 	assert.Equal(
 		t,
-		nats.NewSender(connection, expectedCodec, "my-topic.0x28bf83df144ab7a566bc8509d1fff5d5470bd4ea"),
+		nats.NewSender(connection, expectedCodec, "my-topic.0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"),
 		dialog.Sender,
 	)
 	assert.Equal(
 		t,
-		nats.NewReceiver(connection, expectedCodec, "my-topic.0x28bf83df144ab7a566bc8509d1fff5d5470bd4ea"),
+		nats.NewReceiver(connection, expectedCodec, "my-topic.0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"),
 		dialog.Receiver,
 	)
 }
 
+func TestDialogWaiter_ServeDialogsWithNegotiatedCodec(t *testing.T) {
+	peerID := identity.FromAddress("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68")
+
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	signer := &identity.SignerFake{}
+	waiter, handler := dialogServe(connection, signer)
+	defer waiter.Stop()
+
+	dialogAsk(connection, `{
+		"payload": {"peer_id":"0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68","codecs":["msgpack","json"]},
+		"signature": "PQ79tnjHbwrjbJhO1lY70kkVagyCO41crwSmw7MgbqFYfr+oEi4AhBO66bQpbJH5nwqVcxHM5697YwXLoNlMrgA="
+	}`)
+	dialogInstance, err := dialogWait(handler)
+	assert.NoError(t, err)
+	defer dialogInstance.Close()
+
+	expectedCodec := NewCodecSecured(communication.NewCodecMsgpack(), signer, identity.NewVerifierIdentity(peerID))
+	assert.Equal(
+		t,
+		nats.NewSender(connection, expectedCodec, "my-topic.0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"),
+		dialogInstance.(*dialog).Sender,
+	)
+}
+
+func TestDialogWaiter_ServeDialogsRejectOldProtocol(t *testing.T) {
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	waiter := NewDialogWaiter(
+		discovery.NewAddressWithConnection(connection, "test-topic"),
+		&identity.SignerFake{},
+		&mockedIdentityRegistry{anyIdentityRegistered: true},
+		communication.NewProtocol(communication.CapabilitySessionKeepalive),
+		DefaultLimits(),
+		nil,
+	)
+	waiter.minPeerVersion = 2
+
+	err := waiter.ServeDialogs(&dialogHandler{dialogReceived: make(chan communication.Dialog)})
+	assert.NoError(t, err)
+
+	msg, err := connection.Request("test-topic.dialog-create", []byte(`{
+		"payload": {"peer_id":"0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"},
+		"signature": "YV1ngIhYBEyNLbpXd8lzy8MkM6zwJ+RccM1V67iZAKZfry+y1LRIixoWmkcT273y+BdSnRFJzDJ4mxINj+5WIAE="
+	}`), 100*time.Millisecond)
+	assert.NoError(t, err)
+
+	var envelope struct {
+		Payload dialogCreateResponse `json:"payload"`
+	}
+	assert.NoError(t, json.Unmarshal(msg.Data, &envelope))
+	assert.Equal(
+		t,
+		dialogCreateResponse{
+			Reason:        426,
+			ReasonMessage: "Incompatible Protocol Version",
+			Version:       communication.ProtocolVersion,
+			Capabilities:  []communication.Capability{communication.CapabilitySessionKeepalive},
+			MinVersion:    2,
+		},
+		envelope.Payload,
+	)
+}
+
+func TestDialogWaiter_ServeDialogsRejectPeerExceedingRateLimit(t *testing.T) {
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	limits := DefaultLimits()
+	limits.PeerBurst = 1
+	publisher := &publisherFake{}
+	waiter := NewDialogWaiter(
+		discovery.NewAddressWithConnection(connection, "test-topic"),
+		&identity.SignerFake{},
+		&mockedIdentityRegistry{anyIdentityRegistered: true},
+		communication.Protocol{},
+		limits,
+		publisher,
+	)
+	defer waiter.Stop()
+
+	err := waiter.ServeDialogs(&dialogHandler{dialogReceived: make(chan communication.Dialog, 1)})
+	assert.NoError(t, err)
+
+	assert.Equal(t, uint(200), dialogRequest(t, connection, "test-topic").Reason)
+	assert.Equal(t, responseTooManyRequests, dialogRequest(t, connection, "test-topic"))
+	assert.Equal(
+		t,
+		[]RejectionEvent{{PeerID: identity.FromAddress("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"), Reason: RejectionPeerRateLimit}},
+		publisher.rejections,
+	)
+}
+
+func TestDialogWaiter_ServeDialogsCachesRegistrationChecks(t *testing.T) {
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	limits := DefaultLimits()
+	limits.GlobalBurst = 1
+	mockedRegistry := &mockedIdentityRegistry{anyIdentityRegistered: false}
+	publisher := &publisherFake{}
+	waiter := NewDialogWaiter(
+		discovery.NewAddressWithConnection(connection, "test-topic"),
+		&identity.SignerFake{},
+		mockedRegistry,
+		communication.Protocol{},
+		limits,
+		publisher,
+	)
+	defer waiter.Stop()
+
+	err := waiter.ServeDialogs(&dialogHandler{dialogReceived: make(chan communication.Dialog, 1)})
+	assert.NoError(t, err)
+
+	assert.Equal(t, responseInvalidIdentity, dialogRequest(t, connection, "test-topic"))
+	assert.Equal(t, responseInvalidIdentity, dialogRequest(t, connection, "test-topic"))
+	assert.Equal(t, 1, mockedRegistry.checks)
+}
+
+func TestDialogWaiter_ServeDialogsRejectRegistrationChecksExceedingGlobalRateLimit(t *testing.T) {
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	limits := DefaultLimits()
+	limits.GlobalBurst = 1
+	mockedRegistry := &mockedIdentityRegistry{anyIdentityRegistered: true}
+	publisher := &publisherFake{}
+	waiter := NewDialogWaiter(
+		discovery.NewAddressWithConnection(connection, "test-topic"),
+		&identity.SignerFake{},
+		mockedRegistry,
+		communication.Protocol{},
+		limits,
+		publisher,
+	)
+	defer waiter.Stop()
+
+	// registration check of another peer exhausts the global limit
+	registered, err := waiter.validateDialogRequest(identity.FromAddress("0xc27fc140c1bec70431fa96919b40b7116c33a642"))
+	assert.NoError(t, err)
+	assert.True(t, registered)
+
+	err = waiter.ServeDialogs(&dialogHandler{dialogReceived: make(chan communication.Dialog, 1)})
+	assert.NoError(t, err)
+
+	assert.Equal(t, responseTooManyRequests, dialogRequest(t, connection, "test-topic"))
+	assert.Equal(t, 1, mockedRegistry.checks)
+	assert.Equal(
+		t,
+		[]RejectionEvent{{PeerID: identity.FromAddress("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"), Reason: RejectionGlobalRateLimit}},
+		publisher.rejections,
+	)
+
+	// cached peers are not limited
+	registered, err = waiter.validateDialogRequest(identity.FromAddress("0xc27fc140c1bec70431fa96919b40b7116c33a642"))
+	assert.NoError(t, err)
+	assert.True(t, registered)
+}
+
+func TestDialogWaiter_ServeDialogsRejectRequestsSignedByAnotherPeer(t *testing.T) {
+	connection := nats.StartConnectionFake()
+	defer connection.Close()
+
+	limits := DefaultLimits()
+	limits.PeerBurst = 1
+	mockedRegistry := &mockedIdentityRegistry{anyIdentityRegistered: true}
+	waiter := NewDialogWaiter(
+		discovery.NewAddressWithConnection(connection, "test-topic"),
+		&identity.SignerFake{},
+		mockedRegistry,
+		communication.Protocol{},
+		limits,
+		nil,
+	)
+	defer waiter.Stop()
+
+	handler := &dialogHandler{dialogReceived: make(chan communication.Dialog, 1)}
+	err := waiter.ServeDialogs(handler)
+	assert.NoError(t, err)
+
+	// request claims to be sent by the peer, but it is signed by another key
+	for i := 0; i < 3; i++ {
+		dialogAsk(connection, `{
+			"payload": {"peer_id":"0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"},
+			"signature": "tl+WbYkJdXD5foaIP3bqVGFHfr6kdd5FzmJAmu1GdpINEnNR3bTto6wgEoke/Fpy4zsWOjrulDVfrc32f5ArTgA="
+		}`)
+	}
+	dialogInstance, err := dialogWait(handler)
+	assert.EqualError(t, err, "dialog not received")
+	assert.Nil(t, dialogInstance)
+	assert.Equal(t, 0, mockedRegistry.checks)
+
+	// rate limit of the peer is not exhausted by requests of others
+	assert.Equal(t, uint(200), dialogRequest(t, connection, "test-topic").Reason)
+}
+
 func TestDialogWaiter_ServeDialogsRejectInvalidSignature(t *testing.T) {
 	connection := nats.StartConnectionFake()
 	defer connection.Close()
@@ -86,7 +293,7 @@ func TestDialogWaiter_ServeDialogsRejectInvalidSignature(t *testing.T) {
 	defer waiter.Stop()
 
 	dialogAsk(connection, `{
-		"payload": {"peer_id":"0x28bf83df144ab7a566bc8509d1fff5d5470bd4ea"},
+		"payload": {"peer_id":"0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"},
 		"signature": "malformed"
 	}`)
 	dialogInstance, err := dialogWait(handler)
@@ -108,14 +315,15 @@ func TestDialogWaiter_ServeDialogsRejectUnregisteredConsumers(t *testing.T) {
 		dialogReceived: make(chan communication.Dialog),
 	}
 
-	waiter := NewDialogWaiter(discovery.NewAddressWithConnection(connection, "test-topic"), signer, mockedRegistry)
+	publisher := &publisherFake{}
+	waiter := NewDialogWaiter(discovery.NewAddressWithConnection(connection, "test-topic"), signer, mockedRegistry, communication.Protocol{}, DefaultLimits(), publisher)
 
 	err := waiter.ServeDialogs(mockeDialogHandler)
 	assert.NoError(t, err)
 
 	msg, err := connection.Request("test-topic.dialog-create", []byte(`{
-		"payload": {"peer_id":"0x28bf83df144ab7a566bc8509d1fff5d5470bd4ea"},
-		"signature": "tl+WbYkJdXD5foaIP3bqVGFHfr6kdd5FzmJAmu1GdpINEnNR3bTto6wgEoke/Fpy4zsWOjrulDVfrc32f5ArTgA="
+		"payload": {"peer_id":"0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"},
+		"signature": "YV1ngIhYBEyNLbpXd8lzy8MkM6zwJ+RccM1V67iZAKZfry+y1LRIixoWmkcT273y+BdSnRFJzDJ4mxINj+5WIAE="
 	}`), 100*time.Millisecond)
 	assert.NoError(t, err)
 
@@ -130,17 +338,23 @@ func TestDialogWaiter_ServeDialogsRejectUnregisteredConsumers(t *testing.T) {
 		}`,
 		string(msg.Data),
 	)
+	assert.Equal(
+		t,
+		[]RejectionEvent{{PeerID: identity.FromAddress("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"), Reason: RejectionUnregistered}},
+		publisher.rejections,
+	)
 }
 
 func dialogServe(connection nats.Connection, signer identity.Signer) (waiter *dialogWaiter, handler *dialogHandler) {
 	topic := "my-topic"
-	waiter = &dialogWaiter{
-		address: discovery.NewAddressWithConnection(connection, topic),
-		signer:  signer,
-		identityRegistry: &mockedIdentityRegistry{
-			anyIdentityRegistered: true,
-		},
-	}
+	waiter = NewDialogWaiter(
+		discovery.NewAddressWithConnection(connection, topic),
+		signer,
+		&mockedIdentityRegistry{anyIdentityRegistered: true},
+		communication.Protocol{},
+		DefaultLimits(),
+		nil,
+	)
 	handler = &dialogHandler{
 		dialogReceived: make(chan communication.Dialog),
 	}
@@ -153,6 +367,21 @@ func dialogServe(connection nats.Connection, signer identity.Signer) (waiter *di
 	return waiter, handler
 }
 
+// dialogRequest asks for dialog as peer 0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68 and returns the response
+func dialogRequest(t *testing.T, connection nats.Connection, topic string) dialogCreateResponse {
+	msg, err := connection.Request(topic+".dialog-create", []byte(`{
+		"payload": {"peer_id":"0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68"},
+		"signature": "YV1ngIhYBEyNLbpXd8lzy8MkM6zwJ+RccM1V67iZAKZfry+y1LRIixoWmkcT273y+BdSnRFJzDJ4mxINj+5WIAE="
+	}`), 100*time.Millisecond)
+	assert.NoError(t, err)
+
+	var envelope struct {
+		Payload dialogCreateResponse `json:"payload"`
+	}
+	assert.NoError(t, json.Unmarshal(msg.Data, &envelope))
+	return envelope.Payload
+}
+
 func dialogAsk(connection nats.Connection, payload string) {
 	err := connection.Publish("my-topic.dialog-create", []byte(payload))
 	if err != nil {
@@ -181,10 +410,12 @@ func (handler *dialogHandler) Handle(dialog communication.Dialog) error {
 
 type mockedIdentityRegistry struct {
 	anyIdentityRegistered bool
+	checks                int
 }
 
 // IsRegistered mock
 func (mir *mockedIdentityRegistry) IsRegistered(id identity.Identity) (bool, error) {
+	mir.checks++
 	return mir.anyIdentityRegistered, nil
 }
 
@@ -198,3 +429,13 @@ func (mir *mockedIdentityRegistry) SubscribeToRegistrationEvent(id identity.Iden
 
 //check that we implemented mocked registry correctly
 var _ registry.IdentityRegistry = &mockedIdentityRegistry{}
+
+type publisherFake struct {
+	rejections []RejectionEvent
+}
+
+func (publisher *publisherFake) Publish(topic string, args ...interface{}) {
+	if topic == RejectionTopic {
+		publisher.rejections = append(publisher.rejections, args[0].(RejectionEvent))
+	}
+}
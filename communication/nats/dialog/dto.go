@@ -19,22 +19,54 @@ package dialog
 
 import (
 	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/identity"
 )
 
 // Consume is trying to establish new dialog with Provider
 const endpointDialogCreate = communication.RequestEndpoint("dialog-create")
 
 var (
-	responseOK              = dialogCreateResponse{200, "OK"}
-	responseInvalidIdentity = dialogCreateResponse{400, "Invalid Identity"}
-	responseInternalError   = dialogCreateResponse{500, "Internal Error"}
+	responseOK              = dialogCreateResponse{Reason: 200, ReasonMessage: "OK"}
+	responseInvalidIdentity = dialogCreateResponse{Reason: 400, ReasonMessage: "Invalid Identity"}
+	responseInternalError   = dialogCreateResponse{Reason: 500, ReasonMessage: "Internal Error"}
+	// responseIncompatibleProtocol is sent to peers of protocol version older than the minimal supported one
+	responseIncompatibleProtocol = dialogCreateResponse{Reason: 426, ReasonMessage: "Incompatible Protocol Version"}
+	// responseTooManyRequests is sent to peers exceeding the rate limits of dialog creation
+	responseTooManyRequests = dialogCreateResponse{Reason: 429, ReasonMessage: "Too Many Requests"}
 )
 
 type dialogCreateRequest struct {
 	PeerID string `json:"peer_id"`
+	// Codecs lists message codecs supported by peer, in the order of preference
+	Codecs []string `json:"codecs,omitempty"`
+	// Version and Capabilities describe dialog protocol of peer, legacy peers do not send them
+	Version      int                        `json:"version,omitempty"`
+	Capabilities []communication.Capability `json:"capabilities,omitempty"`
+}
+
+func (request *dialogCreateRequest) peerIdentity() identity.Identity {
+	return identity.FromAddress(request.PeerID)
 }
 
 type dialogCreateResponse struct {
 	Reason        uint   `json:"reason"`
 	ReasonMessage string `json:"reasonMessage"`
+	// Codec is the message codec of the dialog, empty if peer did not offer any codecs
+	Codec string `json:"codec,omitempty"`
+	// Version and Capabilities describe dialog protocol of peer, legacy peers do not send them
+	Version      int                        `json:"version,omitempty"`
+	Capabilities []communication.Capability `json:"capabilities,omitempty"`
+	// MinVersion is the oldest protocol version peer supports, it is sent when dialog is rejected for incompatible protocol
+	MinVersion int `json:"minVersion,omitempty"`
+}
+
+// peerProtocol returns dialog protocol told by peer, peers which do not tell their protocol version are legacy ones
+func peerProtocol(version int, capabilities []communication.Capability) communication.Protocol {
+	if version == 0 {
+		return communication.LegacyProtocol()
+	}
+	return communication.Protocol{
+		Version:      version,
+		Capabilities: capabilities,
+	}
 }
@@ -43,6 +43,16 @@ func TestRequestSerialize(t *testing.T) {
 				"peer_id": ""
 			}`,
 		},
+		{
+			dialogCreateRequest{
+				PeerID: "123",
+				Codecs: []string{"msgpack", "json"},
+			},
+			`{
+				"peer_id": "123",
+				"codecs": ["msgpack", "json"]
+			}`,
+		},
 	}
 
 	for _, test := range tests {
@@ -82,6 +82,19 @@ func TestResponseUnserialize(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			`{
+				"reason": 200,
+				"reasonMessage": "OK",
+				"codec": "msgpack"
+			}`,
+			dialogCreateResponse{
+				Reason:        200,
+				ReasonMessage: "OK",
+				Codec:         "msgpack",
+			},
+			nil,
+		},
 		{
 			`{
 				"reason": true
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dialog
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+// RejectionTopic is the topic of events published when dialog request of a peer is rejected
+const RejectionTopic = "Dialog rejection"
+
+// RejectionReason tells why dialog request of a peer was rejected
+type RejectionReason string
+
+const (
+	// RejectionIncompatibleProtocol is the reason of peers speaking too old protocol
+	RejectionIncompatibleProtocol = RejectionReason("incompatible_protocol")
+	// RejectionUnregistered is the reason of peers not registered in the identity registry
+	RejectionUnregistered = RejectionReason("unregistered")
+	// RejectionPeerRateLimit is the reason of peers requesting dialogs too often
+	RejectionPeerRateLimit = RejectionReason("peer_rate_limit")
+	// RejectionGlobalRateLimit is the reason of requests exceeding the rate of registration checks
+	RejectionGlobalRateLimit = RejectionReason("global_rate_limit")
+)
+
+// RejectionEvent describes rejected dialog request
+type RejectionEvent struct {
+	PeerID identity.Identity
+	Reason RejectionReason
+}
+
+// Limits restrict dialog creation, so that peers flooding the topic do not exhaust the identity registry
+type Limits struct {
+	// PeerRate is the number of dialogs per second a single peer is allowed to create
+	PeerRate float64
+	// PeerBurst is the number of dialogs a single peer is allowed to create at once
+	PeerBurst int
+	// GlobalRate is the number of registration checks per second done for peers not found in the cache
+	GlobalRate float64
+	// GlobalBurst is the number of registration checks done at once for peers not found in the cache
+	GlobalBurst int
+	// RegisteredTTL is how long positive registration checks are cached
+	RegisteredTTL time.Duration
+	// UnregisteredTTL is how long negative registration checks are cached
+	UnregisteredTTL time.Duration
+	// MaxPeers is the number of peers remembered by rate limiter and registration cache
+	MaxPeers int
+}
+
+// DefaultLimits returns limits suitable for a typical provider
+func DefaultLimits() Limits {
+	return Limits{
+		PeerRate:        1,
+		PeerBurst:       5,
+		GlobalRate:      20,
+		GlobalBurst:     50,
+		RegisteredTTL:   time.Hour,
+		UnregisteredTTL: time.Minute,
+		MaxPeers:        10000,
+	}
+}
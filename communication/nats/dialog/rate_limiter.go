@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dialog
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// rateLimiter allows events of each key at the given rate, with bursts up to the given size (token bucket)
+type rateLimiter struct {
+	rate    float64
+	burst   float64
+	maxKeys int
+	buckets map[string]*list.Element
+	recent  *list.List
+	timeNow func() time.Time
+	lock    sync.Mutex
+}
+
+type tokenBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter creates rate limiter, which keeps buckets for not more than maxKeys keys.
+// When there is no room left for a new key, bucket of the least recently seen key is evicted.
+func newRateLimiter(rate float64, burst int, maxKeys int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		maxKeys: maxKeys,
+		buckets: make(map[string]*list.Element),
+		recent:  list.New(),
+		timeNow: time.Now,
+	}
+}
+
+// allow tells if one more event of the key is allowed now and takes a token for it
+func (limiter *rateLimiter) allow(key string) bool {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	now := limiter.timeNow()
+	bucket := limiter.bucket(key, now)
+
+	limiter.refill(bucket, now)
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// bucket returns bucket of the key, marking it as the most recently seen
+func (limiter *rateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	if element, exists := limiter.buckets[key]; exists {
+		limiter.recent.MoveToFront(element)
+		return element.Value.(*tokenBucket)
+	}
+
+	for limiter.recent.Len() > 0 && limiter.recent.Len() >= limiter.maxKeys {
+		oldest := limiter.recent.Back()
+		limiter.recent.Remove(oldest)
+		delete(limiter.buckets, oldest.Value.(*tokenBucket).key)
+	}
+
+	bucket := &tokenBucket{key: key, tokens: limiter.burst, updated: now}
+	limiter.buckets[key] = limiter.recent.PushFront(bucket)
+	return bucket
+}
+
+func (limiter *rateLimiter) refill(bucket *tokenBucket, now time.Time) {
+	elapsed := now.Sub(bucket.updated).Seconds()
+	if elapsed > 0 {
+		bucket.tokens += elapsed * limiter.rate
+		if bucket.tokens > limiter.burst {
+			bucket.tokens = limiter.burst
+		}
+	}
+	bucket.updated = now
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dialog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_AllowsBurstThenRefills(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(2, 2, 10)
+	limiter.timeNow = func() time.Time { return now }
+
+	assert.True(t, limiter.allow("peer"))
+	assert.True(t, limiter.allow("peer"))
+	assert.False(t, limiter.allow("peer"))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, limiter.allow("peer"))
+	assert.False(t, limiter.allow("peer"))
+}
+
+func TestRateLimiter_LimitsKeysSeparately(t *testing.T) {
+	limiter := newRateLimiter(1, 1, 10)
+
+	assert.True(t, limiter.allow("peer-1"))
+	assert.False(t, limiter.allow("peer-1"))
+	assert.True(t, limiter.allow("peer-2"))
+}
+
+func TestRateLimiter_EvictsLeastRecentlySeenKeyWhenFull(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(1, 1, 2)
+	limiter.timeNow = func() time.Time { return now }
+
+	assert.True(t, limiter.allow("peer-1"))
+	assert.True(t, limiter.allow("peer-2"))
+	assert.False(t, limiter.allow("peer-1"))
+
+	assert.True(t, limiter.allow("peer-3"))
+	assert.False(t, limiter.allow("peer-3"), "new keys are limited when limiter is full")
+	assert.Len(t, limiter.buckets, 2)
+
+	assert.False(t, limiter.allow("peer-1"), "recently seen key is kept")
+	assert.True(t, limiter.allow("peer-2"), "least recently seen key is evicted")
+	assert.Len(t, limiter.buckets, 2)
+	assert.Equal(t, 2, limiter.recent.Len())
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dialog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/identity/registry"
+)
+
+// registrationCache remembers results of identity registration checks,
+// so that repeated dialog requests of the same peer do not hit the blockchain
+type registrationCache struct {
+	registry        registry.IdentityRegistry
+	registeredTTL   time.Duration
+	unregisteredTTL time.Duration
+	maxEntries      int
+	entries         map[identity.Identity]registrationEntry
+	timeNow         func() time.Time
+	lock            sync.Mutex
+}
+
+type registrationEntry struct {
+	registered bool
+	expires    time.Time
+}
+
+func newRegistrationCache(identityRegistry registry.IdentityRegistry, registeredTTL, unregisteredTTL time.Duration, maxEntries int) *registrationCache {
+	return &registrationCache{
+		registry:        identityRegistry,
+		registeredTTL:   registeredTTL,
+		unregisteredTTL: unregisteredTTL,
+		maxEntries:      maxEntries,
+		entries:         make(map[identity.Identity]registrationEntry),
+		timeNow:         time.Now,
+	}
+}
+
+// lookup returns cached registration result of the given identity, if it is not expired yet
+func (cache *registrationCache) lookup(id identity.Identity) (registered bool, found bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	entry, found := cache.entries[id]
+	if !found {
+		return false, false
+	}
+	if !cache.timeNow().Before(entry.expires) {
+		delete(cache.entries, id)
+		return false, false
+	}
+	return entry.registered, true
+}
+
+// IsRegistered checks registration of the given identity in the registry and caches the result.
+// Failed checks are not cached.
+func (cache *registrationCache) IsRegistered(id identity.Identity) (bool, error) {
+	if registered, found := cache.lookup(id); found {
+		return registered, nil
+	}
+
+	registered, err := cache.registry.IsRegistered(id)
+	if err != nil {
+		return false, err
+	}
+
+	cache.store(id, registered)
+	return registered, nil
+}
+
+func (cache *registrationCache) store(id identity.Identity, registered bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	now := cache.timeNow()
+	ttl := cache.unregisteredTTL
+	if registered {
+		ttl = cache.registeredTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	if len(cache.entries) >= cache.maxEntries {
+		cache.pruneExpired(now)
+	}
+	if len(cache.entries) >= cache.maxEntries {
+		return
+	}
+	cache.entries[id] = registrationEntry{registered: registered, expires: now.Add(ttl)}
+}
+
+func (cache *registrationCache) pruneExpired(now time.Time) {
+	for id, entry := range cache.entries {
+		if !now.Before(entry.expires) {
+			delete(cache.entries, id)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dialog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/identity/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	registeredID   = identity.FromAddress("0x1")
+	unregisteredID = identity.FromAddress("0x2")
+)
+
+func TestRegistrationCache_CachesResultsForTTL(t *testing.T) {
+	now := time.Now()
+	identityRegistry := &registryStub{registered: map[identity.Identity]bool{registeredID: true}}
+	cache := newRegistrationCache(identityRegistry, time.Hour, time.Minute, 10)
+	cache.timeNow = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		registered, err := cache.IsRegistered(registeredID)
+		assert.NoError(t, err)
+		assert.True(t, registered)
+
+		registered, err = cache.IsRegistered(unregisteredID)
+		assert.NoError(t, err)
+		assert.False(t, registered)
+	}
+	assert.Equal(t, 2, identityRegistry.checks)
+
+	now = now.Add(time.Minute)
+	_, found := cache.lookup(unregisteredID)
+	assert.False(t, found)
+	registered, found := cache.lookup(registeredID)
+	assert.True(t, found)
+	assert.True(t, registered)
+}
+
+func TestRegistrationCache_DoesNotCacheErrors(t *testing.T) {
+	identityRegistry := &registryStub{err: errors.New("rpc unavailable")}
+	cache := newRegistrationCache(identityRegistry, time.Hour, time.Minute, 10)
+
+	_, err := cache.IsRegistered(registeredID)
+	assert.EqualError(t, err, "rpc unavailable")
+	_, found := cache.lookup(registeredID)
+	assert.False(t, found)
+}
+
+func TestRegistrationCache_KeepsLimitedNumberOfEntries(t *testing.T) {
+	now := time.Now()
+	identityRegistry := &registryStub{registered: map[identity.Identity]bool{registeredID: true}}
+	cache := newRegistrationCache(identityRegistry, time.Hour, time.Minute, 1)
+	cache.timeNow = func() time.Time { return now }
+
+	cache.IsRegistered(unregisteredID)
+	cache.IsRegistered(registeredID)
+	_, found := cache.lookup(registeredID)
+	assert.False(t, found)
+
+	now = now.Add(time.Minute)
+	cache.IsRegistered(registeredID)
+	_, found = cache.lookup(registeredID)
+	assert.True(t, found)
+}
+
+type registryStub struct {
+	registered map[identity.Identity]bool
+	err        error
+	checks     int
+}
+
+func (stub *registryStub) IsRegistered(id identity.Identity) (bool, error) {
+	stub.checks++
+	return stub.registered[id], stub.err
+}
+
+func (stub *registryStub) SubscribeToRegistrationEvent(id identity.Identity) (chan registry.RegistrationEvent, func()) {
+	return nil, nil
+}
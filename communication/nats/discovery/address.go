@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/mysteriumnetwork/node/communication/nats"
 	"github.com/mysteriumnetwork/node/identity"
@@ -38,6 +39,26 @@ func NewAddress(topic string, addresses ...string) *AddressNATS {
 
 // NewAddressFromHostAndID generates NATS address for current node
 func NewAddressFromHostAndID(uri string, myID identity.Identity, serviceType string) (*AddressNATS, error) {
+	server, err := brokerURL(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	topic := fmt.Sprintf("%v.%v", myID.Address, serviceType)
+	return NewAddress(topic, server), nil
+}
+
+// NewAddressForBroker generates NATS address without topic, which is only used to reach the broker
+func NewAddressForBroker(uri string) (*AddressNATS, error) {
+	server, err := brokerURL(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAddress("", server), nil
+}
+
+func brokerURL(uri string) (string, error) {
 	// Add scheme first otherwise url.Parse() fails.
 	var rawurl string
 	if strings.HasPrefix(uri, "nats:") {
@@ -48,15 +69,14 @@ func NewAddressFromHostAndID(uri string, myID identity.Identity, serviceType str
 
 	url, err := url.Parse(rawurl)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	if url.Port() == "" {
 		url.Host = fmt.Sprintf("%s:%d", url.Host, BrokerPort)
 	}
 
-	topic := fmt.Sprintf("%v.%v", myID.Address, serviceType)
-	return NewAddress(topic, url.String()), nil
+	return url.String(), nil
 }
 
 // NewAddressForContact extracts NATS address from given contact structure
@@ -89,9 +109,15 @@ type AddressNATS struct {
 	servers []string
 	topic   string
 
+	connector  nats.Connector
 	connection nats.Connection
 }
 
+// SetConnector makes address to connect through the given connector, which shares broker connections with other addresses
+func (address *AddressNATS) SetConnector(connector nats.Connector) {
+	address.connector = connector
+}
+
 // Connect establishes connection to broker
 func (address *AddressNATS) Connect() (err error) {
 	options := nats_lib.GetDefaultOptions()
@@ -100,7 +126,11 @@ func (address *AddressNATS) Connect() (err error) {
 	options.ReconnectWait = BrokerReconnectWait
 	options.Timeout = BrokerTimeout
 
-	address.connection, err = options.Connect()
+	if address.connector != nil {
+		address.connection, err = address.connector.Connect(options)
+	} else {
+		address.connection, err = options.Connect()
+	}
 	if err != nil {
 		address.connection = nil
 	}
@@ -108,6 +138,23 @@ func (address *AddressNATS) Connect() (err error) {
 	return
 }
 
+// CheckConnection establishes connection to broker if it is not established yet
+// and verifies that broker responds through it
+func (address *AddressNATS) CheckConnection() error {
+	if address.connection == nil {
+		if err := address.Connect(); err != nil {
+			return err
+		}
+	}
+
+	if connection, ok := address.connection.(interface {
+		FlushTimeout(time.Duration) error
+	}); ok {
+		return connection.FlushTimeout(BrokerTimeout)
+	}
+	return nil
+}
+
 // Disconnect stops currently established connection
 func (address *AddressNATS) Disconnect() {
 	if address.connection != nil {
@@ -69,6 +69,18 @@ func TestNewAddressFromHostAndID(t *testing.T) {
 	}
 }
 
+func TestNewAddressForBroker(t *testing.T) {
+	address, err := NewAddressForBroker("127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		&AddressNATS{
+			servers: []string{"nats://127.0.0.1:4222"},
+		},
+		address,
+	)
+}
+
 func TestNewAddressForContact(t *testing.T) {
 	address, err := NewAddressForContact(market.Contact{
 		Type: "nats/v1",
@@ -124,6 +136,15 @@ func TestAddress_Disconnect_AfterFailedConnect(t *testing.T) {
 	address.Disconnect()
 }
 
+func TestAddress_CheckConnection_WhenBrokerIsUnreachable(t *testing.T) {
+	address := &AddressNATS{
+		servers: []string{"nats://far-server:4222"},
+	}
+
+	assert.EqualError(t, address.CheckConnection(), "nats: no servers available for connection")
+	assert.Nil(t, address.GetConnection())
+}
+
 func TestAddress_GetConnection(t *testing.T) {
 	expectedConnectin := &nats.Conn{}
 	address := &AddressNATS{connection: expectedConnectin}
@@ -91,6 +91,7 @@ func (receiver *receiverNATS) Unsubscribe() {
 			log.Error(receiverLogPrefix, "failed to unsubscribed from topic: ", topic)
 		}
 		log.Info(receiverLogPrefix, topic, " unsubscribed")
+		delete(receiver.subs, topic)
 	}
 }
 
@@ -18,6 +18,7 @@
 package nats
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -69,6 +70,43 @@ func TestCustomRequest(t *testing.T) {
 	assert.Exactly(t, customResponse{"RESPONSE"}, *response.(*customResponse))
 }
 
+func TestCustomRequestContext_AbortsWhenCancelled(t *testing.T) {
+	connection := StartConnectionFake()
+	defer connection.Close()
+
+	sender := &senderNATS{
+		connection:     connection,
+		codec:          communication.NewCodecJSON(),
+		timeoutRequest: time.Minute,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	started := time.Now()
+	_, err := sender.RequestContext(ctx, &customRequestProducer{
+		&customRequest{"REQUEST"},
+	})
+	assert.EqualError(t, err, "failed to send request 'custom-request'. context canceled")
+	assert.True(t, time.Since(started) < time.Second)
+}
+
+func TestCustomRequest_IsLimitedByDefaultTimeout(t *testing.T) {
+	connection := StartConnectionFake()
+	defer connection.Close()
+
+	sender := &senderNATS{
+		connection:     connection,
+		codec:          communication.NewCodecJSON(),
+		timeoutRequest: 10 * time.Millisecond,
+	}
+
+	_, err := sender.Request(&customRequestProducer{
+		&customRequest{"REQUEST"},
+	})
+	assert.EqualError(t, err, "failed to send request 'custom-request'. context deadline exceeded")
+}
+
 type customRequestConsumer struct {
 	requestReceived interface{}
 }
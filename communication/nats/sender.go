@@ -18,6 +18,7 @@
 package nats
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -47,7 +48,10 @@ type senderNATS struct {
 }
 
 func (sender *senderNATS) Send(producer communication.MessageProducer) error {
+	return sender.SendContext(context.Background(), producer)
+}
 
+func (sender *senderNATS) SendContext(ctx context.Context, producer communication.MessageProducer) error {
 	messageTopic := sender.messageTopic + string(producer.GetMessageEndpoint())
 
 	messageData, err := sender.codec.Pack(producer.Produce())
@@ -56,6 +60,10 @@ func (sender *senderNATS) Send(producer communication.MessageProducer) error {
 		return err
 	}
 
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to send message '%s'. %s", messageTopic, err)
+	}
+
 	log.Debug(senderLogPrefix, fmt.Sprintf("Message '%s' sending: %s", messageTopic, messageData))
 	err = sender.connection.Publish(messageTopic, messageData)
 	if err != nil {
@@ -67,6 +75,17 @@ func (sender *senderNATS) Send(producer communication.MessageProducer) error {
 }
 
 func (sender *senderNATS) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	return sender.RequestContext(context.Background(), producer)
+}
+
+// RequestContext sends request and waits for response until the context is done,
+// request is limited by the default timeout of the sender if context has no deadline
+func (sender *senderNATS) RequestContext(ctx context.Context, producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sender.timeoutRequest)
+		defer cancel()
+	}
 
 	requestTopic := sender.messageTopic + string(producer.GetRequestEndpoint())
 	responsePtr = producer.NewResponse()
@@ -78,7 +97,7 @@ func (sender *senderNATS) Request(producer communication.RequestProducer) (respo
 	}
 
 	log.Debug(senderLogPrefix, fmt.Sprintf("Request '%s' sending: %s", requestTopic, requestData))
-	msg, err := sender.connection.Request(requestTopic, requestData, sender.timeoutRequest)
+	msg, err := sender.connection.RequestWithContext(ctx, requestTopic, requestData)
 	if err != nil {
 		err = fmt.Errorf("failed to send request '%s'. %s", requestTopic, err)
 		return
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package communication
+
+// ProtocolVersion is the version of dialog protocol implemented by this node
+const ProtocolVersion = 2
+
+// ProtocolVersionLegacy is the version of peers which do not tell their protocol version while creating dialogs
+const ProtocolVersionLegacy = 1
+
+// ProtocolVersionMin is the oldest protocol version of peers, this node is able to communicate with.
+// Legacy peers are still served, so no deployed peer is rejected for its version until the minimum is raised.
+const ProtocolVersionMin = ProtocolVersionLegacy
+
+// Capability names optional feature of the dialog protocol, which peer supports
+type Capability string
+
+const (
+	// CapabilitySessionKeepalive means that consumer tells provider about the sessions still in use
+	CapabilitySessionKeepalive = Capability("session-keepalive")
+	// CapabilitySessionTermination means that provider notifies consumer about the sessions it terminates
+	CapabilitySessionTermination = Capability("session-termination")
+	// CapabilitySessionDraining means that provider notifies consumer about the service being stopped
+	CapabilitySessionDraining = Capability("session-draining")
+	// CapabilityPromisePayments means that sessions are paid with promises exchanged through the dialog
+	CapabilityPromisePayments = Capability("promise-payments")
+)
+
+// DefaultCapabilities are the capabilities which every node of the current protocol version supports
+var DefaultCapabilities = []Capability{
+	CapabilitySessionKeepalive,
+	CapabilitySessionTermination,
+	CapabilitySessionDraining,
+}
+
+// Protocol describes version and capabilities of the dialog protocol implemented by peer
+type Protocol struct {
+	Version      int
+	Capabilities []Capability
+}
+
+// NewProtocol returns protocol of the current version with given capabilities
+func NewProtocol(capabilities ...Capability) Protocol {
+	return Protocol{
+		Version:      ProtocolVersion,
+		Capabilities: capabilities,
+	}
+}
+
+// LegacyProtocol returns protocol of peers which do not negotiate protocol version,
+// they pay for sessions with promises if payments are enabled for them
+func LegacyProtocol() Protocol {
+	return Protocol{
+		Version:      ProtocolVersionLegacy,
+		Capabilities: []Capability{CapabilityPromisePayments},
+	}
+}
+
+// Supports checks whether protocol has the given capability
+func (protocol Protocol) Supports(capability Capability) bool {
+	for _, supported := range protocol.Capabilities {
+		if supported == capability {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package communication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtocolSupports(t *testing.T) {
+	protocol := NewProtocol(CapabilitySessionKeepalive)
+
+	assert.Equal(t, ProtocolVersion, protocol.Version)
+	assert.True(t, protocol.Supports(CapabilitySessionKeepalive))
+	assert.False(t, protocol.Supports(CapabilityPromisePayments))
+}
+
+func TestLegacyProtocolSupportsOnlyPayments(t *testing.T) {
+	protocol := LegacyProtocol()
+
+	assert.Equal(t, ProtocolVersionLegacy, protocol.Version)
+	assert.True(t, protocol.Supports(CapabilityPromisePayments))
+	for _, capability := range DefaultCapabilities {
+		assert.False(t, protocol.Supports(capability))
+	}
+}
@@ -55,6 +55,7 @@ type History struct {
 	Status          string
 	Updated         time.Time
 	DataStats       consumer.SessionStatistics // is updated on disconnect event
+	PromisedAmount  uint64                     // is updated on every promise issued during the session
 }
 
 // GetDuration returns delta in seconds (TimeUpdated - TimeStarted)
@@ -25,6 +25,7 @@ import (
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/session/payment"
 )
 
 const sessionStorageLogPrefix = "[session-storage] "
@@ -40,6 +41,7 @@ type Storer interface {
 	Store(bucket string, object interface{}) error
 	Update(bucket string, object interface{}) error
 	GetAllFrom(bucket string, array interface{}) error
+	GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error
 }
 
 // Storage contains functions for storing, getting session objects
@@ -76,6 +78,27 @@ func (repo *Storage) ConsumeSessionEvent(sessionEvent connection.SessionEvent) {
 	}
 }
 
+// ConsumePromiseIssuedEvent adds the amount of the issued promise to the session it was issued in
+func (repo *Storage) ConsumePromiseIssuedEvent(promiseEvent payment.PromiseIssuedEvent) {
+	var se History
+	err := repo.storage.GetOneByField(sessionStorageBucketName, "SessionID", promiseEvent.SessionID, &se)
+	if err != nil {
+		log.Error(sessionStorageLogPrefix, err)
+		return
+	}
+
+	updatedSession := &History{
+		SessionID:      promiseEvent.SessionID,
+		PromisedAmount: se.PromisedAmount + promiseEvent.Amount,
+	}
+	err = repo.storage.Update(sessionStorageBucketName, updatedSession)
+	if err != nil {
+		log.Error(sessionStorageLogPrefix, err)
+	} else {
+		log.Trace(sessionStorageLogPrefix, fmt.Sprintf("Session %v promised amount updated", promiseEvent.SessionID))
+	}
+}
+
 func (repo *Storage) handleEndedEvent(sessionID session.ID) {
 	updatedSession := &History{
 		SessionID: sessionID,
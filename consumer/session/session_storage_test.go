@@ -26,6 +26,7 @@ import (
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	node_session "github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/session/payment"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -115,6 +116,28 @@ func TestSessionStorageConsumeEventConnectedError(t *testing.T) {
 	assert.True(t, storer.SaveCalled)
 }
 
+func TestSessionStorageConsumePromiseIssuedEventAddsAmount(t *testing.T) {
+	storer := &StubSessionStorer{
+		SessionToReturn: History{SessionID: sessionID, PromisedAmount: 100},
+	}
+	storage := NewSessionStorage(storer, stubRetriever)
+	storage.ConsumePromiseIssuedEvent(payment.PromiseIssuedEvent{SessionID: sessionID, Amount: 50})
+
+	assert.True(t, storer.UpdateCalled)
+	assert.Equal(t, &History{SessionID: sessionID, PromisedAmount: 150}, storer.UpdatedObject)
+}
+
+func TestSessionStorageConsumePromiseIssuedEventSkipsUnknownSession(t *testing.T) {
+	storer := &StubSessionStorer{
+		GetOneError: errMock,
+	}
+	storage := NewSessionStorage(storer, stubRetriever)
+	assert.NotPanics(t, func() {
+		storage.ConsumePromiseIssuedEvent(payment.PromiseIssuedEvent{SessionID: sessionID, Amount: 50})
+	})
+	assert.False(t, storer.UpdateCalled)
+}
+
 // StubSessionStorer allows us to get all sessions, save and update them
 type StubSessionStorer struct {
 	SaveError    error
@@ -123,6 +146,10 @@ type StubSessionStorer struct {
 	UpdateCalled bool
 	GetAllCalled bool
 	GetAllError  error
+
+	SessionToReturn History
+	GetOneError     error
+	UpdatedObject   interface{}
 }
 
 func (sss *StubSessionStorer) Store(from string, object interface{}) error {
@@ -132,6 +159,7 @@ func (sss *StubSessionStorer) Store(from string, object interface{}) error {
 
 func (sss *StubSessionStorer) Update(from string, object interface{}) error {
 	sss.UpdateCalled = true
+	sss.UpdatedObject = object
 	return sss.UpdateError
 }
 
@@ -140,6 +168,14 @@ func (sss *StubSessionStorer) GetAllFrom(from string, array interface{}) error {
 	return sss.GetAllError
 }
 
+func (sss *StubSessionStorer) GetOneByField(bucket string, fieldName string, key interface{}, to interface{}) error {
+	if sss.GetOneError != nil {
+		return sss.GetOneError
+	}
+	*to.(*History) = sss.SessionToReturn
+	return nil
+}
+
 type StubRetriever struct {
 	Value consumer.SessionStatistics
 }
@@ -0,0 +1,53 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import "time"
+
+// Spending holds the amounts consumer promised to providers
+type Spending struct {
+	Total         uint64
+	Sessions      int
+	ByProvider    map[string]uint64
+	ByServiceType map[string]uint64
+}
+
+// NewSpending sums up the amounts promised during the sessions started within the given period.
+// Zero from or to leaves the period open on that side.
+func NewSpending(sessions []History, from, to time.Time) Spending {
+	spending := Spending{
+		ByProvider:    make(map[string]uint64),
+		ByServiceType: make(map[string]uint64),
+	}
+
+	for _, se := range sessions {
+		if !from.IsZero() && se.Started.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !se.Started.Before(to) {
+			continue
+		}
+
+		spending.Sessions++
+		spending.Total += se.PromisedAmount
+		spending.ByProvider[se.ProviderID.Address] += se.PromisedAmount
+		spending.ByServiceType[se.ServiceType] += se.PromisedAmount
+	}
+
+	return spending
+}
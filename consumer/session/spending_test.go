@@ -0,0 +1,55 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSpending(t *testing.T) {
+	day := time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)
+	otherProviderID := identity.FromAddress("otherProviderID")
+	sessions := []History{
+		{ProviderID: providerID, ServiceType: "openvpn", Started: day.Add(-time.Hour), PromisedAmount: 1000},
+		{ProviderID: providerID, ServiceType: "openvpn", Started: day, PromisedAmount: 100},
+		{ProviderID: otherProviderID, ServiceType: "wireguard", Started: day.Add(time.Hour), PromisedAmount: 200},
+		{ProviderID: otherProviderID, ServiceType: "openvpn", Started: day.Add(48 * time.Hour), PromisedAmount: 300},
+	}
+
+	spending := NewSpending(sessions, day, day.Add(24*time.Hour))
+	assert.Equal(t, Spending{
+		Total:    300,
+		Sessions: 2,
+		ByProvider: map[string]uint64{
+			providerID.Address:      100,
+			otherProviderID.Address: 200,
+		},
+		ByServiceType: map[string]uint64{
+			"openvpn":   100,
+			"wireguard": 200,
+		},
+	}, spending)
+
+	spending = NewSpending(sessions, time.Time{}, time.Time{})
+	assert.Equal(t, uint64(1600), spending.Total)
+	assert.Equal(t, 4, spending.Sessions)
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+)
+
+// ErrInsufficientBalance indicates that consumer balance can't cover the minimum session
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// BalancePolicy defines how connection manager reacts to consumer balance before connecting
+type BalancePolicy struct {
+	// MinimumBalance is the balance needed to cover the minimum session
+	MinimumBalance uint64
+	// RefuseLowBalance makes manager refuse to connect instead of only warning about the low balance
+	RefuseLowBalance bool
+}
+
+// checkBalance verifies that consumer has enough balance for the minimum session.
+// Balance lookup failures are only logged, so that blockchain issues do not prevent connecting.
+func (manager *connectionManager) checkBalance(consumerID identity.Identity, proposal market.ServiceProposal) error {
+	if manager.balance == nil {
+		return nil
+	}
+
+	balance, err := manager.balance(consumerID)
+	if err != nil {
+		log.Warn(managerLogPrefix, "could not check balance of ", consumerID.Address, ": ", err)
+		return nil
+	}
+	if balance >= manager.balancePolicy.MinimumBalance {
+		return nil
+	}
+
+	if manager.balancePolicy.RefuseLowBalance {
+		return ErrInsufficientBalance
+	}
+
+	log.Warnf("%sbalance %d of %s is lower than minimum %d", managerLogPrefix, balance, consumerID.Address, manager.balancePolicy.MinimumBalance)
+	manager.eventPublisher.Publish(StateEventTopic, StateEvent{
+		State: LowBalance,
+		SessionInfo: SessionInfo{
+			ConsumerID: consumerID,
+			Proposal:   proposal,
+		},
+	})
+	return nil
+}
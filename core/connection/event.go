@@ -17,6 +17,11 @@
 
 package connection
 
+import (
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/session"
+)
+
 // Topic represents the different topics a consumer can subscribe to
 const (
 	// StateEventTopic represents the connection state change topic
@@ -25,12 +30,16 @@ const (
 	StatisticsEventTopic = "Statistics"
 	// SessionEventTopic represents the session event
 	SessionEventTopic = "Session"
+	// FailureEventTopic represents the connection failure topic
+	FailureEventTopic = "Failure"
 )
 
 // StateEvent is the struct we'll emit on a StateEvent topic event
 type StateEvent struct {
 	State       State
 	SessionInfo SessionInfo
+	// Reason is set when the session was terminated by provider
+	Reason session.TerminationReason
 }
 
 const (
@@ -38,6 +47,8 @@ const (
 	SessionCreatedStatus = "Created"
 	// SessionEndedStatus represents a session end
 	SessionEndedStatus = "Ended"
+	// SessionDrainingStatus represents provider stopping the service, session is going to be terminated soon
+	SessionDrainingStatus = "Draining"
 )
 
 // SessionEvent represents a session related event
@@ -45,3 +56,9 @@ type SessionEvent struct {
 	Status      string
 	SessionInfo SessionInfo
 }
+
+// FailureEvent is the struct we'll emit on a FailureEventTopic when connection could not be established
+type FailureEvent struct {
+	Proposal market.ServiceProposal
+	Error    error
+}
@@ -18,14 +18,17 @@
 package connection
 
 import (
+	"context"
+
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 )
 
-// DialogCreator creates new dialog between consumer and provider, using given contact information
-type DialogCreator func(consumerID, providerID identity.Identity, contact market.Contact) (communication.Dialog, error)
+// DialogCreator creates new dialog between consumer and provider, using given contact information.
+// Dialog creation is aborted once the context is done.
+type DialogCreator func(ctx context.Context, consumerID, providerID identity.Identity, contact market.Contact) (communication.Dialog, error)
 
 // ConsumerConfig are the parameters used for the initiation of connection
 type ConsumerConfig interface{}
@@ -35,7 +38,7 @@ type Connection interface {
 	Start(ConnectOptions) error
 	Wait() error
 	Stop()
-	GetConfig() (ConsumerConfig, error)
+	GetConfig(proposal market.ServiceProposal) (ConsumerConfig, error)
 }
 
 // StateChannel is the channel we receive state change events on
@@ -78,7 +78,8 @@ type PaymentIssuerFactory func(
 	paymentDefinition dto.PaymentPerTime,
 	messageChan chan balance.Message,
 	dialog communication.Dialog,
-	consumer, provider identity.Identity) (PaymentIssuer, error)
+	consumer, provider identity.Identity,
+	sessionID session.ID) (PaymentIssuer, error)
 
 type connectionManager struct {
 	//these are passed on creation
@@ -86,14 +87,18 @@ type connectionManager struct {
 	paymentIssuerFactory PaymentIssuerFactory
 	newConnection        Creator
 	eventPublisher       Publisher
+	balance              identity.Balance
+	balancePolicy        BalancePolicy
+	keepaliveInterval    time.Duration
 
 	//these are populated by Connect at runtime
-	ctx         context.Context
-	status      Status
-	statusLock  sync.RWMutex
-	sessionInfo SessionInfo
-	cleanup     []func() error
-	cancel      func()
+	ctx               context.Context
+	status            Status
+	statusLock        sync.RWMutex
+	sessionInfo       SessionInfo
+	terminationReason session.TerminationReason
+	cleanup           []func() error
+	cancel            func()
 
 	discoLock sync.Mutex
 }
@@ -104,6 +109,8 @@ func NewManager(
 	paymentIssuerFactory PaymentIssuerFactory,
 	connectionCreator Creator,
 	eventPublisher Publisher,
+	balance identity.Balance,
+	balancePolicy BalancePolicy,
 ) *connectionManager {
 	return &connectionManager{
 		newDialog:            dialogCreator,
@@ -111,6 +118,9 @@ func NewManager(
 		newConnection:        connectionCreator,
 		status:               statusNotConnected(),
 		eventPublisher:       eventPublisher,
+		balance:              balance,
+		balancePolicy:        balancePolicy,
+		keepaliveInterval:    session.KeepaliveInterval,
 		cleanup:              make([]func() error, 0),
 	}
 }
@@ -122,18 +132,25 @@ func (manager *connectionManager) Connect(consumerID identity.Identity, proposal
 
 	manager.ctx, manager.cancel = context.WithCancel(context.Background())
 
+	manager.setTerminationReason("")
 	manager.setStatus(statusConnecting())
 	defer func() {
 		if err != nil {
 			manager.setStatus(statusNotConnected())
+			manager.eventPublisher.Publish(FailureEventTopic, FailureEvent{Proposal: proposal, Error: err})
 		}
 	}()
 
+	err = manager.checkBalance(consumerID, proposal)
+	if err != nil {
+		return err
+	}
+
 	providerID := identity.FromAddress(proposal.ProviderID)
 
-	dialog, err := manager.createDialog(consumerID, providerID, proposal.ProviderContacts[0])
+	dialog, err := manager.createDialog(manager.ctx, consumerID, providerID, proposal.ProviderContacts[0])
 	if err != nil {
-		return err
+		return manager.cancelledOr(err)
 	}
 
 	stateChannel := make(chan State, 10)
@@ -146,12 +163,12 @@ func (manager *connectionManager) Connect(consumerID identity.Identity, proposal
 
 	sessionDTO, paymentInfo, err := manager.createSession(connection, dialog, consumerID, proposal)
 	if err != nil {
-		return err
+		return manager.cancelledOr(err)
 	}
 
-	err = manager.launchPayments(paymentInfo, dialog, consumerID, providerID)
+	err = manager.launchPayments(paymentInfo, dialog, consumerID, providerID, sessionDTO.ID)
 	if err != nil {
-		return err
+		return manager.cancelledOr(err)
 	}
 
 	err = manager.startConnection(connection, consumerID, proposal, params, sessionDTO, stateChannel, statisticsChannel)
@@ -161,7 +178,15 @@ func (manager *connectionManager) Connect(consumerID identity.Identity, proposal
 	return err
 }
 
-func (manager *connectionManager) launchPayments(paymentInfo *promise.PaymentInfo, dialog communication.Dialog, consumerID, providerID identity.Identity) error {
+// cancelledOr returns ErrConnectionCancelled instead of the given error, if connecting was cancelled meanwhile
+func (manager *connectionManager) cancelledOr(err error) error {
+	if manager.ctx.Err() == context.Canceled {
+		return ErrConnectionCancelled
+	}
+	return err
+}
+
+func (manager *connectionManager) launchPayments(paymentInfo *promise.PaymentInfo, dialog communication.Dialog, consumerID, providerID identity.Identity, sessionID session.ID) error {
 	var promiseState promise.PaymentInfo
 	if paymentInfo != nil {
 		promiseState.FreeCredit = paymentInfo.FreeCredit
@@ -179,7 +204,9 @@ func (manager *connectionManager) launchPayments(paymentInfo *promise.PaymentInf
 		Duration: time.Minute,
 	}
 
-	payments, err := manager.paymentIssuerFactory(promiseState, payment, messageChan, dialog, consumerID, providerID)
+	// promises are exchanged within context of the connection, so that cancelled connection does not wait for them
+	paymentDialog := communication.DialogWithContext(manager.ctx, dialog)
+	payments, err := manager.paymentIssuerFactory(promiseState, payment, messageChan, paymentDialog, consumerID, providerID, sessionID)
 	if err != nil {
 		return err
 	}
@@ -195,7 +222,7 @@ func (manager *connectionManager) launchPayments(paymentInfo *promise.PaymentInf
 
 func (manager *connectionManager) cleanConnection() {
 	manager.cancel()
-	for i := len(manager.cleanup) - 1; i > 0; i-- {
+	for i := len(manager.cleanup) - 1; i >= 0; i-- {
 		err := manager.cleanup[i]()
 		if err != nil {
 			log.Warn(managerLogPrefix, "cleanup error:", err)
@@ -204,8 +231,8 @@ func (manager *connectionManager) cleanConnection() {
 	manager.cleanup = make([]func() error, 0)
 }
 
-func (manager *connectionManager) createDialog(consumerID, providerID identity.Identity, contact market.Contact) (communication.Dialog, error) {
-	dialog, err := manager.newDialog(consumerID, providerID, contact)
+func (manager *connectionManager) createDialog(ctx context.Context, consumerID, providerID identity.Identity, contact market.Contact) (communication.Dialog, error) {
+	dialog, err := manager.newDialog(ctx, consumerID, providerID, contact)
 	if err != nil {
 		return nil, err
 	}
@@ -215,7 +242,7 @@ func (manager *connectionManager) createDialog(consumerID, providerID identity.I
 }
 
 func (manager *connectionManager) createSession(c Connection, dialog communication.Dialog, consumerID identity.Identity, proposal market.ServiceProposal) (session.SessionDto, *promise.PaymentInfo, error) {
-	sessionCreateConfig, err := c.GetConfig()
+	sessionCreateConfig, err := c.GetConfig(proposal)
 	if err != nil {
 		return session.SessionDto{}, nil, err
 	}
@@ -225,12 +252,54 @@ func (manager *connectionManager) createSession(c Connection, dialog communicati
 		IssuerID: consumerID,
 	}
 
-	s, paymentInfo, err := session.RequestSessionCreate(dialog, proposal.ID, sessionCreateConfig, consumerInfo)
+	s, paymentInfo, err := session.RequestSessionCreate(manager.ctx, dialog, proposal.ID, sessionCreateConfig, consumerInfo)
 	if err != nil {
 		return session.SessionDto{}, nil, err
 	}
 
-	manager.cleanup = append(manager.cleanup, func() error { return session.RequestSessionDestroy(dialog, s.ID) })
+	manager.cleanup = append(manager.cleanup, func() error {
+		if manager.getTerminationReason() != "" {
+			// provider has destroyed the session already
+			return nil
+		}
+		return session.RequestSessionDestroy(dialog, s.ID)
+	})
+
+	err = dialog.Receive(session.NewTerminationConsumer(func(message session.TerminateMessage) {
+		if message.SessionID == s.ID {
+			manager.onSessionTerminated(message.Reason)
+		}
+	}))
+	if err != nil {
+		return session.SessionDto{}, nil, err
+	}
+	err = dialog.Receive(session.NewDrainingConsumer(func(message session.DrainingMessage) {
+		if message.SessionID == s.ID {
+			log.Warn(managerLogPrefix, "provider is stopping the service, session ends by ", message.Deadline)
+			manager.eventPublisher.Publish(SessionEventTopic, SessionEvent{
+				Status:      SessionDrainingStatus,
+				SessionInfo: manager.sessionInfo,
+			})
+		}
+	}))
+	if err != nil {
+		return session.SessionDto{}, nil, err
+	}
+	// providers of older protocol versions do not expect keepalives
+	if dialog.PeerProtocol().Supports(communication.CapabilitySessionKeepalive) {
+		keepaliveStop := make(chan struct{})
+		keepaliveDone := make(chan struct{})
+		go func() {
+			defer close(keepaliveDone)
+			manager.keepSessionAlive(keepaliveStop, dialog, s.ID)
+		}()
+		// keepalives are stopped before the dialog is closed by the cleanup
+		manager.cleanup = append(manager.cleanup, func() error {
+			close(keepaliveStop)
+			<-keepaliveDone
+			return nil
+		})
+	}
 
 	// set the session info for future use
 	manager.sessionInfo = SessionInfo{
@@ -333,6 +402,47 @@ func (manager *connectionManager) Disconnect() error {
 	return nil
 }
 
+func (manager *connectionManager) setTerminationReason(reason session.TerminationReason) {
+	manager.statusLock.Lock()
+	manager.terminationReason = reason
+	manager.statusLock.Unlock()
+}
+
+func (manager *connectionManager) getTerminationReason() session.TerminationReason {
+	manager.statusLock.RLock()
+	defer manager.statusLock.RUnlock()
+
+	return manager.terminationReason
+}
+
+// onSessionTerminated disconnects, as provider has terminated the session and will not serve it anymore
+func (manager *connectionManager) onSessionTerminated(reason session.TerminationReason) {
+	log.Warn(managerLogPrefix, "session terminated by provider: ", reason)
+	manager.setTerminationReason(reason)
+
+	// disconnect asynchronously, as cleanup closes the dialog which has delivered the message
+	go func() {
+		logDisconnectError(manager.Disconnect())
+	}()
+}
+
+// keepSessionAlive tells provider periodically that the session is still in use, until it is stopped
+func (manager *connectionManager) keepSessionAlive(stop <-chan struct{}, dialog communication.Dialog, sessionID session.ID) {
+	ticker := time.NewTicker(manager.keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := session.SendKeepalive(dialog, sessionID); err != nil {
+				log.Warn(managerLogPrefix, "failed to send session keepalive: ", err)
+			}
+		}
+	}
+}
+
 func (manager *connectionManager) payForService(payments PaymentIssuer) {
 	err := payments.Start()
 	if err != nil {
@@ -399,6 +509,7 @@ func (manager *connectionManager) onStateChanged(state State) {
 	manager.eventPublisher.Publish(StateEventTopic, StateEvent{
 		State:       state,
 		SessionInfo: manager.sessionInfo,
+		Reason:      manager.getTerminationReason(),
 	})
 
 	switch state {
@@ -18,6 +18,7 @@
 package connection
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"testing"
@@ -42,7 +43,10 @@ type testContext struct {
 	mockDialog            *mockDialog
 	MockPaymentIssuer     *MockPaymentIssuer
 	stubPublisher         *StubPublisher
+	consumerBalance       uint64
 	mockStatistics        consumer.SessionStatistics
+	blockDialogRequests   bool
+	providerProtocol      communication.Protocol
 	sync.RWMutex
 }
 
@@ -66,12 +70,17 @@ func (tc *testContext) SetupTest() {
 	defer tc.Unlock()
 
 	tc.stubPublisher = NewStubPublisher()
-	dialogCreator := func(consumer, provider identity.Identity, contact market.Contact) (communication.Dialog, error) {
+	tc.consumerBalance = 1000
+	tc.blockDialogRequests = false
+	tc.providerProtocol = communication.NewProtocol(communication.DefaultCapabilities...)
+	dialogCreator := func(ctx context.Context, consumer, provider identity.Identity, contact market.Contact) (communication.Dialog, error) {
 		tc.Lock()
 		defer tc.Unlock()
 		tc.mockDialog = &mockDialog{
-			sessionID:   establishedSessionID,
-			paymentInfo: paymentInfo,
+			sessionID:     establishedSessionID,
+			paymentInfo:   paymentInfo,
+			blockRequests: tc.blockDialogRequests,
+			protocol:      tc.providerProtocol,
 		}
 		return tc.mockDialog, nil
 	}
@@ -80,7 +89,8 @@ func (tc *testContext) SetupTest() {
 		paymentDefinition dto.PaymentPerTime,
 		messageChan chan balance.Message,
 		dialog communication.Dialog,
-		consumer, provider identity.Identity) (PaymentIssuer, error) {
+		consumer, provider identity.Identity,
+		sessionID session.ID) (PaymentIssuer, error) {
 		tc.MockPaymentIssuer = &MockPaymentIssuer{
 			initialState:      initialState,
 			paymentDefinition: paymentDefinition,
@@ -123,6 +133,12 @@ func (tc *testContext) SetupTest() {
 		mockPaymentFactory,
 		tc.fakeConnectionFactory.CreateConnection,
 		tc.stubPublisher,
+		func(identity.Identity) (uint64, error) {
+			tc.Lock()
+			defer tc.Unlock()
+			return tc.consumerBalance, nil
+		},
+		BalancePolicy{MinimumBalance: 100},
 	)
 }
 
@@ -254,6 +270,29 @@ func (tc *testContext) TestConnectingInProgressCanBeCanceled() {
 	assert.Equal(tc.T(), ErrConnectionCancelled, err)
 }
 
+func (tc *testContext) TestSessionCreationInProgressCanBeCanceled() {
+	tc.Lock()
+	tc.blockDialogRequests = true
+	tc.Unlock()
+
+	connectWaiter := &sync.WaitGroup{}
+	connectWaiter.Add(1)
+	var err error
+	go func() {
+		defer connectWaiter.Done()
+		err = tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	}()
+
+	waitABit()
+	assert.Equal(tc.T(), statusConnecting(), tc.connManager.Status())
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+
+	connectWaiter.Wait()
+
+	assert.Equal(tc.T(), ErrConnectionCancelled, err)
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+}
+
 func (tc *testContext) TestConnectMethodReturnsErrorIfConnectionExitsDuringConnect() {
 	tc.fakeConnectionFactory.mockConnection.onStartReportStates = []fakeState{}
 	tc.fakeConnectionFactory.mockConnection.onStopReportStates = []fakeState{}
@@ -314,6 +353,27 @@ func (tc *testContext) Test_SessionEndPublished_OnConnectError() {
 	assert.True(tc.T(), found)
 }
 
+func (tc *testContext) Test_FailurePublished_OnConnectError() {
+	tc.stubPublisher.Clear()
+
+	tc.fakeConnectionFactory.mockConnection.onStartReturnError = errors.New("fatal connection error")
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.Error(tc.T(), err)
+
+	found := false
+	for _, v := range tc.stubPublisher.GetEventHistory() {
+		if v.calledWithTopic == FailureEventTopic {
+			found = true
+
+			event := v.calledWithArgs[0].(FailureEvent)
+			assert.Equal(tc.T(), err, event.Error)
+			assert.Equal(tc.T(), activeProposal.ServiceType, event.Proposal.ServiceType)
+		}
+	}
+
+	assert.True(tc.T(), found)
+}
+
 func (tc *testContext) Test_ManagerSetsPaymentInfo() {
 	defer func() {
 		paymentInfo = nil
@@ -370,6 +430,146 @@ func (tc *testContext) Test_ManagerPublishesEvents() {
 	}
 }
 
+func (tc *testContext) Test_ManagerRefusesToConnect_WhenBalanceIsLow() {
+	tc.consumerBalance = 10
+	tc.connManager.balancePolicy.RefuseLowBalance = true
+
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.Equal(tc.T(), ErrInsufficientBalance, err)
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+}
+
+func (tc *testContext) Test_ManagerPublishesLowBalanceState_WhenBalanceIsLow() {
+	tc.stubPublisher.Clear()
+	tc.consumerBalance = 10
+
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(tc.T(), err)
+
+	found := false
+	for _, v := range tc.stubPublisher.GetEventHistory() {
+		if v.calledWithTopic == StateEventTopic {
+			event := v.calledWithArgs[0].(StateEvent)
+			if event.State == LowBalance {
+				found = true
+				assert.Equal(tc.T(), consumerID, event.SessionInfo.ConsumerID)
+				assert.Equal(tc.T(), activeProposal.ProviderID, event.SessionInfo.Proposal.ProviderID)
+			}
+		}
+	}
+	assert.True(tc.T(), found)
+}
+
+func (tc *testContext) Test_ManagerDisconnects_WhenProviderTerminatesSession() {
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(tc.T(), err)
+	tc.stubPublisher.Clear()
+
+	err = tc.mockDialog.deliver(
+		communication.MessageEndpoint("session-terminate"),
+		&session.TerminateMessage{SessionID: "other-session", Reason: session.TerminationReasonPaymentFailed},
+	)
+	assert.NoError(tc.T(), err)
+	waitABit()
+	assert.Equal(tc.T(), Connected, tc.connManager.Status().State)
+
+	err = tc.mockDialog.deliver(
+		communication.MessageEndpoint("session-terminate"),
+		&session.TerminateMessage{SessionID: establishedSessionID, Reason: session.TerminationReasonServiceStopped},
+	)
+	assert.NoError(tc.T(), err)
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+
+	found := false
+	for _, v := range tc.stubPublisher.GetEventHistory() {
+		if v.calledWithTopic == StateEventTopic {
+			event := v.calledWithArgs[0].(StateEvent)
+			assert.Equal(tc.T(), session.TerminationReasonServiceStopped, event.Reason)
+			assert.Equal(tc.T(), establishedSessionID, event.SessionInfo.SessionID)
+			found = true
+		}
+	}
+	assert.True(tc.T(), found)
+}
+
+func (tc *testContext) Test_ManagerPublishesDrainingSessionEvent() {
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(tc.T(), err)
+	tc.stubPublisher.Clear()
+
+	err = tc.mockDialog.deliver(
+		communication.MessageEndpoint("session-draining"),
+		&session.DrainingMessage{SessionID: establishedSessionID, Deadline: time.Now().Add(time.Minute)},
+	)
+	assert.NoError(tc.T(), err)
+
+	var events []SessionEvent
+	for _, v := range tc.stubPublisher.GetEventHistory() {
+		if v.calledWithTopic == SessionEventTopic {
+			events = append(events, v.calledWithArgs[0].(SessionEvent))
+		}
+	}
+	if !assert.Len(tc.T(), events, 1) {
+		return
+	}
+	event := events[0]
+	assert.Equal(tc.T(), SessionDrainingStatus, event.Status)
+	assert.Equal(tc.T(), establishedSessionID, event.SessionInfo.SessionID)
+	assert.Equal(tc.T(), Connected, tc.connManager.Status().State)
+}
+
+func (tc *testContext) Test_ManagerSendsSessionKeepalives() {
+	tc.connManager.keepaliveInterval = 10 * time.Millisecond
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(tc.T(), err)
+
+	time.Sleep(50 * time.Millisecond)
+	keepalives := tc.mockDialog.sentTo(communication.MessageEndpoint("session-keepalive"))
+	assert.NotEmpty(tc.T(), keepalives)
+	assert.Equal(tc.T(), &session.KeepaliveMessage{SessionID: establishedSessionID}, keepalives[0])
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	waitABit()
+	sent := len(tc.mockDialog.sentTo(communication.MessageEndpoint("session-keepalive")))
+	time.Sleep(30 * time.Millisecond)
+	assert.Len(tc.T(), tc.mockDialog.sentTo(communication.MessageEndpoint("session-keepalive")), sent)
+}
+
+func (tc *testContext) Test_ManagerSkipsKeepalivesForLegacyProvider() {
+	tc.Lock()
+	tc.providerProtocol = communication.LegacyProtocol()
+	tc.Unlock()
+	tc.connManager.keepaliveInterval = 10 * time.Millisecond
+
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(tc.T(), err)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(tc.T(), tc.mockDialog.sentTo(communication.MessageEndpoint("session-keepalive")))
+}
+
+func (tc *testContext) Test_ManagerResetsTerminationReason_OnConnect() {
+	err := tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(tc.T(), err)
+	err = tc.mockDialog.deliver(
+		communication.MessageEndpoint("session-terminate"),
+		&session.TerminateMessage{SessionID: establishedSessionID, Reason: session.TerminationReasonPaymentFailed},
+	)
+	assert.NoError(tc.T(), err)
+	waitABit()
+
+	tc.stubPublisher.Clear()
+	err = tc.connManager.Connect(consumerID, activeProposal, ConnectParams{})
+	assert.NoError(tc.T(), err)
+
+	for _, v := range tc.stubPublisher.GetEventHistory() {
+		if v.calledWithTopic == StateEventTopic {
+			assert.Equal(tc.T(), session.TerminationReason(""), v.calledWithArgs[0].(StateEvent).Reason)
+		}
+	}
+}
+
 func TestConnectionManagerSuite(t *testing.T) {
 	suite.Run(t, new(testContext))
 }
@@ -36,6 +36,8 @@ const (
 	Disconnecting = State("Disconnecting")
 	// Reconnecting means that connection is lost but underlying service is trying to reestablish it
 	Reconnecting = State("Reconnecting")
+	// LowBalance means that consumer balance can't cover the minimum session
+	LowBalance = State("LowBalance")
 	// Unknown means that we could not map the underlying transport state to our state
 	Unknown = State("Unknown")
 )
@@ -18,6 +18,7 @@
 package connection
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/session/promise"
 )
@@ -167,7 +169,7 @@ type connectionMock struct {
 	sync.RWMutex
 }
 
-func (foc *connectionMock) GetConfig() (ConsumerConfig, error) {
+func (foc *connectionMock) GetConfig(_ market.ServiceProposal) (ConsumerConfig, error) {
 	return nil, nil
 }
 
@@ -222,6 +224,11 @@ type mockDialog struct {
 	sessionID   session.ID
 	paymentInfo *promise.PaymentInfo
 	closed      bool
+	receivers   map[communication.MessageEndpoint]communication.MessageConsumer
+	sent        []communication.MessageProducer
+	// blockRequests makes requests wait until their context is done
+	blockRequests bool
+	protocol      communication.Protocol
 	sync.RWMutex
 }
 
@@ -232,6 +239,13 @@ func (md *mockDialog) PeerID() identity.Identity {
 	return md.peerID
 }
 
+func (md *mockDialog) PeerProtocol() communication.Protocol {
+	md.RLock()
+	defer md.RUnlock()
+
+	return md.protocol
+}
+
 func (md *mockDialog) assertNotClosed() {
 	md.RLock()
 	defer md.RUnlock()
@@ -252,8 +266,27 @@ func (md *mockDialog) Close() error {
 
 func (md *mockDialog) Receive(consumer communication.MessageConsumer) error {
 	md.assertNotClosed()
+
+	md.Lock()
+	defer md.Unlock()
+	if md.receivers == nil {
+		md.receivers = make(map[communication.MessageEndpoint]communication.MessageConsumer)
+	}
+	md.receivers[consumer.GetMessageEndpoint()] = consumer
 	return nil
 }
+
+// deliver passes message to the consumer receiving messages of the given endpoint
+func (md *mockDialog) deliver(endpoint communication.MessageEndpoint, messagePtr interface{}) error {
+	md.RLock()
+	consumer, ok := md.receivers[endpoint]
+	md.RUnlock()
+
+	if !ok {
+		return errors.New("no consumer of endpoint " + string(endpoint))
+	}
+	return consumer.Consume(messagePtr)
+}
 func (md *mockDialog) Respond(consumer communication.RequestConsumer) error {
 	md.assertNotClosed()
 	return nil
@@ -264,11 +297,47 @@ func (md *mockDialog) Unsubscribe() {
 
 func (md *mockDialog) Send(producer communication.MessageProducer) error {
 	md.assertNotClosed()
+
+	md.Lock()
+	defer md.Unlock()
+	md.sent = append(md.sent, producer)
 	return nil
 }
 
+func (md *mockDialog) sentTo(endpoint communication.MessageEndpoint) []interface{} {
+	md.RLock()
+	defer md.RUnlock()
+
+	var messages []interface{}
+	for _, producer := range md.sent {
+		if producer.GetMessageEndpoint() == endpoint {
+			messages = append(messages, producer.Produce())
+		}
+	}
+	return messages
+}
+
 var ErrUnknownRequest = errors.New("unknown request")
 
+func (md *mockDialog) SendContext(ctx context.Context, producer communication.MessageProducer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return md.Send(producer)
+}
+
+func (md *mockDialog) RequestContext(ctx context.Context, producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	md.RLock()
+	block := md.blockRequests
+	md.RUnlock()
+
+	if block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return md.Request(producer)
+}
+
 func (md *mockDialog) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
 	md.assertNotClosed()
 	if producer.GetRequestEndpoint() == communication.RequestEndpoint("session-destroy") {
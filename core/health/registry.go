@@ -0,0 +1,273 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package health
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrProbeTimeout is returned when component probe did not finish in time
+var ErrProbeTimeout = errors.New("health probe timed out")
+
+// Probe checks a single component and returns an error if component is not healthy
+type Probe func() error
+
+// Status represents health status of component or the whole node
+type Status string
+
+const (
+	// StatusOK means that component is healthy
+	StatusOK = Status("ok")
+	// StatusDegraded means that some of non critical components are failing
+	StatusDegraded = Status("degraded")
+	// StatusFailing means that component (or at least one critical component) is failing
+	StatusFailing = Status("failing")
+)
+
+// ComponentReport describes the result of the latest component check
+type ComponentReport struct {
+	Name        string
+	Critical    bool
+	Status      Status
+	Error       error
+	LastError   error
+	LastErrorAt time.Time
+	CheckedAt   time.Time
+	Duration    time.Duration
+}
+
+// Report describes health of all registered components
+type Report struct {
+	Status     Status
+	Components []ComponentReport
+}
+
+// probeRun holds result of a single probe execution, err is set before done is closed
+type probeRun struct {
+	done chan struct{}
+	err  error
+}
+
+type component struct {
+	name     string
+	critical bool
+	probe    Probe
+
+	run         *probeRun
+	lastError   error
+	lastErrorAt time.Time
+}
+
+// Registry keeps registered component probes and aggregates their results
+type Registry struct {
+	timeout     time.Duration
+	currentTime func() time.Time
+
+	lock       sync.Mutex
+	components []*component
+	last       *Report
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRegistry creates registry which gives each probe the given timeout to finish
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout:     timeout,
+		currentTime: time.Now,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Start checks components right away and then periodically
+func (registry *Registry) Start(interval time.Duration) {
+	go func() {
+		registry.Check()
+		for {
+			select {
+			case <-registry.stop:
+				return
+			case <-time.After(interval):
+				registry.Check()
+			}
+		}
+	}()
+}
+
+// Stop stops periodical checks
+func (registry *Registry) Stop() {
+	registry.stopOnce.Do(func() {
+		close(registry.stop)
+	})
+}
+
+// LastReport returns report of the latest check, components are checked right away if they were never checked yet
+func (registry *Registry) LastReport() Report {
+	registry.lock.Lock()
+	last := registry.last
+	registry.lock.Unlock()
+
+	if last == nil {
+		return registry.Check()
+	}
+	return *last
+}
+
+// Register adds probe of the named component, probe registered under the same name is replaced.
+// Failing critical component makes the whole node failing, while failing non critical one only degrades it.
+func (registry *Registry) Register(name string, critical bool, probe Probe) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	newComponent := &component{name: name, critical: critical, probe: probe}
+	for i, existing := range registry.components {
+		if existing.name == name {
+			registry.components[i] = newComponent
+			return
+		}
+	}
+	registry.components = append(registry.components, newComponent)
+}
+
+// Check runs all registered probes concurrently, keeps aggregated report as the latest one and returns it
+func (registry *Registry) Check() Report {
+	registry.lock.Lock()
+	components := make([]*component, len(registry.components))
+	copy(components, registry.components)
+	registry.lock.Unlock()
+
+	reports := make([]ComponentReport, len(components))
+	var wg sync.WaitGroup
+	for i := range components {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reports[i] = registry.check(components[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := aggregate(reports)
+	registry.lock.Lock()
+	registry.last = &report
+	registry.lock.Unlock()
+	return report
+}
+
+// CheckComponent runs probe of the named component only and updates its result in the latest report
+func (registry *Registry) CheckComponent(name string) {
+	registry.lock.Lock()
+	var checked *component
+	for _, c := range registry.components {
+		if c.name == name {
+			checked = c
+		}
+	}
+	registry.lock.Unlock()
+	if checked == nil {
+		return
+	}
+
+	componentReport := registry.check(checked)
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if registry.last == nil {
+		return
+	}
+	reports := make([]ComponentReport, len(registry.last.Components))
+	copy(reports, registry.last.Components)
+	for i := range reports {
+		if reports[i].Name == name {
+			reports[i] = componentReport
+		}
+	}
+	report := aggregate(reports)
+	registry.last = &report
+}
+
+func aggregate(reports []ComponentReport) Report {
+	report := Report{Status: StatusOK, Components: reports}
+	for _, componentReport := range reports {
+		if componentReport.Status == StatusOK {
+			continue
+		}
+		if componentReport.Critical {
+			report.Status = StatusFailing
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (registry *Registry) check(c *component) ComponentReport {
+	startedAt := registry.currentTime()
+	err := registry.runProbe(c)
+	finishedAt := registry.currentTime()
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	report := ComponentReport{
+		Name:      c.name,
+		Critical:  c.critical,
+		Status:    StatusOK,
+		CheckedAt: finishedAt,
+		Duration:  finishedAt.Sub(startedAt),
+	}
+	if err != nil {
+		c.lastError = err
+		c.lastErrorAt = finishedAt
+		report.Status = StatusFailing
+		report.Error = err
+	}
+	report.LastError = c.lastError
+	report.LastErrorAt = c.lastErrorAt
+	return report
+}
+
+// runProbe waits for the probe no longer than the timeout.
+// Probe which is still running since the previous check is awaited instead of starting it again.
+func (registry *Registry) runProbe(c *component) error {
+	registry.lock.Lock()
+	run := c.run
+	if run == nil {
+		run = &probeRun{done: make(chan struct{})}
+		c.run = run
+		go func() {
+			run.err = c.probe()
+
+			registry.lock.Lock()
+			c.run = nil
+			registry.lock.Unlock()
+			close(run.done)
+		}()
+	}
+	registry.lock.Unlock()
+
+	select {
+	case <-run.done:
+		return run.err
+	case <-time.After(registry.timeout):
+		return ErrProbeTimeout
+	}
+}
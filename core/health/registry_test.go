@@ -0,0 +1,195 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package health
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	probeOK     = func() error { return nil }
+	probeFailed = func() error { return errors.New("boom") }
+)
+
+func TestRegistry_CheckReturnsOKWhenAllComponentsAreHealthy(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("storage", true, probeOK)
+	registry.Register("discovery", false, probeOK)
+
+	report := registry.Check()
+
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Components, 2)
+	assert.Equal(t, "storage", report.Components[0].Name)
+	assert.True(t, report.Components[0].Critical)
+	assert.Equal(t, StatusOK, report.Components[0].Status)
+	assert.Equal(t, "discovery", report.Components[1].Name)
+	assert.Equal(t, StatusOK, report.Components[1].Status)
+}
+
+func TestRegistry_CheckReturnsDegradedWhenNonCriticalComponentFails(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("storage", true, probeOK)
+	registry.Register("discovery", false, probeFailed)
+
+	report := registry.Check()
+
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusFailing, report.Components[1].Status)
+	assert.EqualError(t, report.Components[1].Error, "boom")
+}
+
+func TestRegistry_RegisterReplacesProbeWithTheSameName(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("storage", true, probeFailed)
+	registry.Register("storage", true, probeOK)
+
+	report := registry.Check()
+
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Components, 1)
+}
+
+func TestRegistry_CheckReturnsFailingWhenCriticalComponentFails(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("storage", true, probeFailed)
+	registry.Register("discovery", false, probeFailed)
+
+	report := registry.Check()
+
+	assert.Equal(t, StatusFailing, report.Status)
+}
+
+func TestRegistry_CheckReportsLastErrorOfRecoveredProbe(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	failedAt := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	registry.currentTime = func() time.Time { return failedAt }
+
+	failing := true
+	registry.Register("broker", true, func() error {
+		if failing {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	registry.Check()
+
+	failing = false
+	registry.currentTime = func() time.Time { return failedAt.Add(time.Minute) }
+	report := registry.Check()
+
+	assert.Equal(t, StatusOK, report.Components[0].Status)
+	assert.EqualError(t, report.Components[0].LastError, "connection refused")
+	assert.Equal(t, failedAt, report.Components[0].LastErrorAt)
+}
+
+func TestRegistry_CheckDoesNotWaitForHangingProbe(t *testing.T) {
+	registry := NewRegistry(10 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	var started int32
+	registry.Register("contract", false, func() error {
+		atomic.AddInt32(&started, 1)
+		<-release
+		return nil
+	})
+
+	report := registry.Check()
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, ErrProbeTimeout, report.Components[0].Error)
+
+	report = registry.Check()
+	assert.Equal(t, ErrProbeTimeout, report.Components[0].Error)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&started))
+}
+
+func TestRegistry_LastReportReturnsReportOfLatestCheck(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	var probed int32
+	registry.Register("discovery", false, func() error {
+		atomic.AddInt32(&probed, 1)
+		return nil
+	})
+
+	report := registry.LastReport()
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&probed))
+
+	registry.LastReport()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&probed))
+
+	registry.Check()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&probed))
+}
+
+func TestRegistry_CheckComponentUpdatesLastReport(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	var brokerProbed, storageProbed int32
+	brokerFailing := int32(0)
+	registry.Register("storage", true, func() error {
+		atomic.AddInt32(&storageProbed, 1)
+		return nil
+	})
+	registry.Register("broker-connections", false, func() error {
+		atomic.AddInt32(&brokerProbed, 1)
+		if atomic.LoadInt32(&brokerFailing) == 1 {
+			return errors.New("broker connections not connected")
+		}
+		return nil
+	})
+	registry.Check()
+
+	atomic.StoreInt32(&brokerFailing, 1)
+	registry.CheckComponent("broker-connections")
+
+	report := registry.LastReport()
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusOK, report.Components[0].Status)
+	assert.Equal(t, StatusFailing, report.Components[1].Status)
+	assert.EqualError(t, report.Components[1].Error, "broker connections not connected")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&storageProbed))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&brokerProbed))
+}
+
+func TestRegistry_StartChecksComponentsPeriodically(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	checked := make(chan struct{}, 10)
+	registry.Register("storage", true, func() error {
+		select {
+		case checked <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	registry.Start(time.Millisecond)
+	defer registry.Stop()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-checked:
+		case <-time.After(time.Second):
+			t.Fatal("components were not checked")
+		}
+	}
+}
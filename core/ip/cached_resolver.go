@@ -0,0 +1,186 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ip
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+const cacheLogPrefix = "[ip-cache] "
+
+// ErrDirectIPUnknown is returned when public IP of the node was not resolved before traffic got tunneled
+var ErrDirectIPUnknown = errors.New("public IP of the node outside the tunnel is unknown")
+
+// PublicIPChangeTopic is the topic of events published when public IP of the node changes
+const PublicIPChangeTopic = "public-ip-changed"
+
+// ChangeEvent describes the public IP change
+type ChangeEvent struct {
+	Previous string
+	Current  string
+}
+
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
+// CachedResolver keeps public IP resolved by other resolver for the given TTL.
+// Whenever newly resolved public IP differs from the previous one ChangeEvent is published.
+// While node traffic is tunneled through consumer connection, resolved IP is the one of the tunnel,
+// so it is cached but changes are not published and the direct IP resolved before the tunnel is kept.
+type CachedResolver struct {
+	resolver    Resolver
+	ttl         time.Duration
+	publisher   Publisher
+	currentTime func() time.Time
+
+	lock     sync.Mutex
+	ip       string
+	expires  time.Time
+	directIP string
+	tunneled bool
+	// generation changes whenever tunneling is switched, IP resolved across the switch is not trusted
+	generation int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewCachedResolver returns new instance of CachedResolver
+func NewCachedResolver(resolver Resolver, ttl time.Duration, publisher Publisher) *CachedResolver {
+	return &CachedResolver{
+		resolver:    resolver,
+		ttl:         ttl,
+		publisher:   publisher,
+		currentTime: time.Now,
+		stop:        make(chan struct{}),
+	}
+}
+
+// GetPublicIP returns cached public IP, it is resolved again when cache expires
+func (r *CachedResolver) GetPublicIP() (string, error) {
+	r.lock.Lock()
+	ip := r.ip
+	valid := r.currentTime().Before(r.expires)
+	r.lock.Unlock()
+
+	if ip != "" && valid {
+		return ip, nil
+	}
+	return r.Refresh()
+}
+
+// GetOutboundIP returns outbound IP, it is not cached
+func (r *CachedResolver) GetOutboundIP() (string, error) {
+	return r.resolver.GetOutboundIP()
+}
+
+// GetDirectPublicIP returns public IP of the node outside the consumer tunnel.
+// While traffic is tunneled the IP resolved before the tunnel is returned.
+func (r *CachedResolver) GetDirectPublicIP() (string, error) {
+	r.lock.Lock()
+	directIP, tunneled := r.directIP, r.tunneled
+	r.lock.Unlock()
+
+	if !tunneled {
+		return r.GetPublicIP()
+	}
+	if directIP == "" {
+		return "", ErrDirectIPUnknown
+	}
+	return directIP, nil
+}
+
+// SetTunneled tells whether node traffic goes through the consumer tunnel, public IP is resolved again after the switch
+func (r *CachedResolver) SetTunneled(tunneled bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.expires = time.Time{}
+	if r.tunneled != tunneled {
+		r.tunneled = tunneled
+		r.generation++
+	}
+}
+
+// Refresh resolves public IP bypassing the cache and publishes ChangeEvent if direct public IP has changed
+func (r *CachedResolver) Refresh() (string, error) {
+	r.lock.Lock()
+	generation := r.generation
+	r.lock.Unlock()
+
+	ip, err := r.resolver.GetPublicIP()
+	if err != nil {
+		return "", err
+	}
+
+	r.lock.Lock()
+	if r.generation != generation {
+		r.lock.Unlock()
+		return ip, nil
+	}
+	r.ip = ip
+	r.expires = r.currentTime().Add(r.ttl)
+	previous, direct := r.directIP, !r.tunneled
+	if direct {
+		r.directIP = ip
+	}
+	r.lock.Unlock()
+
+	if direct && previous != "" && previous != ip {
+		log.Info(cacheLogPrefix, "Public IP changed from ", previous, " to ", ip)
+		r.publisher.Publish(PublicIPChangeTopic, ChangeEvent{Previous: previous, Current: ip})
+	}
+	return ip, nil
+}
+
+// Invalidate makes the next GetPublicIP call resolve public IP again
+func (r *CachedResolver) Invalidate() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.expires = time.Time{}
+}
+
+// Start starts periodical detection of public IP changes
+func (r *CachedResolver) Start(interval time.Duration) {
+	go func() {
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-time.After(interval):
+				if _, err := r.Refresh(); err != nil {
+					log.Warn(cacheLogPrefix, "Public IP detection failed, will retry later: ", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops periodical detection of public IP changes
+func (r *CachedResolver) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ip
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sequenceResolver struct {
+	fakeResolver
+	answers []string
+	calls   int
+	lock    sync.Mutex
+}
+
+func (r *sequenceResolver) GetPublicIP() (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	answer := r.answers[r.calls%len(r.answers)]
+	r.calls++
+	if answer == "" {
+		return "", errors.New("resolving failed")
+	}
+	return answer, nil
+}
+
+func (r *sequenceResolver) Calls() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.calls
+}
+
+type publisherFake struct {
+	events []ChangeEvent
+	lock   sync.Mutex
+}
+
+func (p *publisherFake) Publish(topic string, args ...interface{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if topic == PublicIPChangeTopic {
+		p.events = append(p.events, args[0].(ChangeEvent))
+	}
+}
+
+func (p *publisherFake) Events() []ChangeEvent {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.events
+}
+
+func TestCachedResolverCachesPublicIPForTTL(t *testing.T) {
+	source := &sequenceResolver{answers: []string{"1.1.1.1", "2.2.2.2"}}
+	publisher := &publisherFake{}
+	resolver := NewCachedResolver(source, time.Minute, publisher)
+	now := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	resolver.currentTime = func() time.Time { return now }
+
+	ip, err := resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", ip)
+
+	now = now.Add(59 * time.Second)
+	ip, err = resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", ip)
+	assert.Equal(t, 1, source.Calls())
+	assert.Len(t, publisher.Events(), 0)
+
+	now = now.Add(time.Second)
+	ip, err = resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2.2.2", ip)
+	assert.Equal(t, []ChangeEvent{{Previous: "1.1.1.1", Current: "2.2.2.2"}}, publisher.Events())
+}
+
+func TestCachedResolverResolvesAgainAfterInvalidate(t *testing.T) {
+	source := &sequenceResolver{answers: []string{"1.1.1.1"}}
+	publisher := &publisherFake{}
+	resolver := NewCachedResolver(source, time.Hour, publisher)
+
+	resolver.GetPublicIP()
+	resolver.Invalidate()
+	ip, err := resolver.GetPublicIP()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", ip)
+	assert.Equal(t, 2, source.Calls())
+	assert.Len(t, publisher.Events(), 0)
+}
+
+func TestCachedResolverKeepsPreviousIPWhenResolvingFails(t *testing.T) {
+	source := &sequenceResolver{answers: []string{"1.1.1.1", "", "1.1.1.1"}}
+	publisher := &publisherFake{}
+	resolver := NewCachedResolver(source, time.Hour, publisher)
+
+	resolver.Refresh()
+	_, err := resolver.Refresh()
+	assert.Error(t, err)
+
+	ip, err := resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", ip)
+
+	resolver.Refresh()
+	assert.Len(t, publisher.Events(), 0)
+}
+
+func TestCachedResolverDetectsChangesPeriodically(t *testing.T) {
+	source := &sequenceResolver{answers: []string{"1.1.1.1", "2.2.2.2"}}
+	publisher := &publisherFake{}
+	resolver := NewCachedResolver(source, time.Hour, publisher)
+	resolver.GetPublicIP()
+
+	resolver.Start(time.Millisecond)
+	defer resolver.Stop()
+
+	for i := 0; i < 100 && len(publisher.Events()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if assert.NotEmpty(t, publisher.Events()) {
+		assert.Equal(t, ChangeEvent{Previous: "1.1.1.1", Current: "2.2.2.2"}, publisher.Events()[0])
+	}
+}
+
+func TestCachedResolverDoesNotPublishTunnelIP(t *testing.T) {
+	source := &sequenceResolver{answers: []string{"1.1.1.1", "9.9.9.9", "9.9.9.9", "1.1.1.1"}}
+	publisher := &publisherFake{}
+	resolver := NewCachedResolver(source, time.Hour, publisher)
+	resolver.GetPublicIP()
+
+	resolver.SetTunneled(true)
+	ip, err := resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "9.9.9.9", ip)
+	resolver.Refresh()
+
+	ip, err = resolver.GetDirectPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", ip)
+
+	resolver.SetTunneled(false)
+	ip, err = resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", ip)
+	assert.Len(t, publisher.Events(), 0)
+}
+
+func TestCachedResolverDoesNotKnowDirectIPResolvedOnlyThroughTunnel(t *testing.T) {
+	resolver := NewCachedResolver(&sequenceResolver{answers: []string{"9.9.9.9"}}, time.Hour, &publisherFake{})
+	resolver.SetTunneled(true)
+	resolver.GetPublicIP()
+
+	_, err := resolver.GetDirectPublicIP()
+	assert.Equal(t, ErrDirectIPUnknown, err)
+}
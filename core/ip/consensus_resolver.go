@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/cihub/seelog"
+)
+
+const consensusLogPrefix = "[ip-consensus] "
+
+// ErrNoConsensus is returned when sources disagree and none of the IPs is reported by the majority of them
+var ErrNoConsensus = errors.New("sources disagree on public IP")
+
+// NewConsensusResolver creates resolver which asks all the given sources in parallel
+// and returns the public IP reported by the majority of the sources which responded
+func NewConsensusResolver(sources ...Resolver) Resolver {
+	return &consensusResolver{
+		sources: sources,
+	}
+}
+
+type consensusResolver struct {
+	sources []Resolver
+}
+
+type sourceAnswer struct {
+	ip  string
+	err error
+}
+
+func (r *consensusResolver) GetPublicIP() (string, error) {
+	if len(r.sources) == 0 {
+		return "", errors.New("no public IP sources configured")
+	}
+
+	answers := make(chan sourceAnswer, len(r.sources))
+	for _, source := range r.sources {
+		go func(source Resolver) {
+			ip, err := source.GetPublicIP()
+			answers <- sourceAnswer{ip, err}
+		}(source)
+	}
+
+	votes := make(map[string]int)
+	var errs []string
+	for range r.sources {
+		answer := <-answers
+		if answer.err != nil {
+			errs = append(errs, answer.err.Error())
+			continue
+		}
+
+		votes[answer.ip]++
+		// absolute majority is not going to change, no need to wait for the slower sources
+		if votes[answer.ip] > len(r.sources)/2 {
+			return answer.ip, nil
+		}
+	}
+
+	if len(votes) == 0 {
+		return "", fmt.Errorf("all public IP sources failed: %s", strings.Join(errs, "; "))
+	}
+
+	ip, err := majority(votes)
+	if err != nil {
+		log.Warn(consensusLogPrefix, "Public IP sources disagree: ", votes)
+		return "", err
+	}
+	return ip, nil
+}
+
+func (r *consensusResolver) GetOutboundIP() (string, error) {
+	return getOutboundIP()
+}
+
+// majority returns IP which got more than half of the votes
+func majority(votes map[string]int) (string, error) {
+	total := 0
+	for _, count := range votes {
+		total += count
+	}
+
+	for ip, count := range votes {
+		if count*2 > total {
+			return ip, nil
+		}
+	}
+	return "", ErrNoConsensus
+}
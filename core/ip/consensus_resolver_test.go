@@ -0,0 +1,96 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ip
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingResolver struct {
+	fakeResolver
+	release chan struct{}
+}
+
+func (r *blockingResolver) GetPublicIP() (string, error) {
+	<-r.release
+	return r.fakeResolver.GetPublicIP()
+}
+
+func TestConsensusResolverReturnsMajorityAnswer(t *testing.T) {
+	resolver := NewConsensusResolver(
+		NewResolverFake("1.1.1.1"),
+		NewResolverFake("2.2.2.2"),
+		NewResolverFake("2.2.2.2"),
+	)
+
+	ip, err := resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2.2.2", ip)
+}
+
+func TestConsensusResolverIgnoresFailedSources(t *testing.T) {
+	resolver := NewConsensusResolver(
+		NewResolverFakeFailing(errors.New("timeout")),
+		NewResolverFakeFailing(errors.New("timeout")),
+		NewResolverFake("2.2.2.2"),
+	)
+
+	ip, err := resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2.2.2", ip)
+}
+
+func TestConsensusResolverFailsWhenSourcesDisagree(t *testing.T) {
+	resolver := NewConsensusResolver(
+		NewResolverFake("1.1.1.1"),
+		NewResolverFake("2.2.2.2"),
+		NewResolverFakeFailing(errors.New("timeout")),
+	)
+
+	_, err := resolver.GetPublicIP()
+	assert.Equal(t, ErrNoConsensus, err)
+}
+
+func TestConsensusResolverFailsWhenAllSourcesFail(t *testing.T) {
+	resolver := NewConsensusResolver(
+		NewResolverFakeFailing(errors.New("timeout")),
+		NewResolverFakeFailing(errors.New("connection refused")),
+	)
+
+	_, err := resolver.GetPublicIP()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+	assert.Contains(t, err.Error(), "connection refused")
+}
+
+func TestConsensusResolverDoesNotWaitForSlowSourceAfterMajority(t *testing.T) {
+	slow := &blockingResolver{fakeResolver: fakeResolver{ipAddress: "1.1.1.1"}, release: make(chan struct{})}
+	defer close(slow.release)
+	resolver := NewConsensusResolver(
+		NewResolverFake("2.2.2.2"),
+		slow,
+		NewResolverFake("2.2.2.2"),
+	)
+
+	ip, err := resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2.2.2", ip)
+}
@@ -81,6 +81,11 @@ func (client *clientRest) GetPublicIP() (string, error) {
 }
 
 func (client *clientRest) GetOutboundIP() (string, error) {
+	return getOutboundIP()
+}
+
+// getOutboundIP returns local IP of the interface used to reach the internet
+func getOutboundIP() (string, error) {
 	conn, err := net.Dial("udp", "8.8.8.8:53")
 	if err != nil {
 		return "", err
@@ -0,0 +1,245 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ip
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+const stunLogPrefix = "[stun] "
+
+const (
+	stunHeaderSize           = 20
+	stunMagicCookie          = 0x2112A442
+	stunBindingRequest       = 0x0001
+	stunBindingResponse      = 0x0101
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXorMappedAddress = 0x0020
+	stunFamilyIPv4           = 0x01
+	stunFamilyIPv6           = 0x02
+)
+
+// ErrNoMappedAddress is returned when STUN server response carries no mapped address
+var ErrNoMappedAddress = errors.New("STUN response has no mapped address")
+
+// NewStunResolver creates resolver which detects public IP by sending binding request to the given STUN server (RFC 5389)
+func NewStunResolver(server string, timeout time.Duration) Resolver {
+	return &stunResolver{
+		server:  server,
+		timeout: timeout,
+	}
+}
+
+type stunResolver struct {
+	server  string
+	timeout time.Duration
+}
+
+func (r *stunResolver) GetPublicIP() (string, error) {
+	conn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	address, err := GetMappedAddress(conn, r.server, r.timeout)
+	if err != nil {
+		return "", err
+	}
+
+	log.Info(stunLogPrefix, "IP detected by ", r.server, ": ", address.IP)
+	return address.IP.String(), nil
+}
+
+func (r *stunResolver) GetOutboundIP() (string, error) {
+	return getOutboundIP()
+}
+
+// GetMappedAddress sends binding request to the STUN server from the given connection
+// and returns public address which NAT maps the connection to
+func GetMappedAddress(conn net.PacketConn, server string, timeout time.Duration) (*net.UDPAddr, error) {
+	return GetMappedAddressOfAny(conn, []string{server}, timeout)
+}
+
+// GetMappedAddressOfAny sends binding requests to all the given STUN servers at once from the given connection
+// and returns public address from the first valid response received within the timeout
+func GetMappedAddressOfAny(conn net.PacketConn, servers []string, timeout time.Duration) (*net.UDPAddr, error) {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	defer conn.SetDeadline(time.Time{})
+
+	var err error
+	transactions := make(map[string]string)
+	for _, server := range servers {
+		var serverAddress *net.UDPAddr
+		serverAddress, err = net.ResolveUDPAddr("udp", server)
+		if err != nil {
+			log.Warn(stunLogPrefix, "Failed to resolve ", server, ": ", err)
+			continue
+		}
+
+		request, transactionID, requestErr := newStunBindingRequest()
+		if requestErr != nil {
+			return nil, requestErr
+		}
+		if _, err = conn.WriteTo(request, serverAddress); err != nil {
+			log.Warn(stunLogPrefix, "Failed to send binding request to ", server, ": ", err)
+			continue
+		}
+		transactions[string(transactionID)] = server
+	}
+	if len(transactions) == 0 {
+		return nil, err
+	}
+
+	response := make([]byte, 1024)
+	for {
+		n, _, err := conn.ReadFrom(response)
+		if err != nil {
+			return nil, err
+		}
+		if n < stunHeaderSize {
+			continue
+		}
+		transactionID := string(response[8:stunHeaderSize])
+		server, ok := transactions[transactionID]
+		if !ok {
+			continue
+		}
+
+		address, err := parseStunBindingResponse(response[:n], []byte(transactionID))
+		if err == nil {
+			return address, nil
+		}
+		log.Warn(stunLogPrefix, "Invalid response from ", server, ": ", err)
+		delete(transactions, transactionID)
+		if len(transactions) == 0 {
+			return nil, err
+		}
+	}
+}
+
+func newStunBindingRequest() (request []byte, transactionID []byte, err error) {
+	request = make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint16(request[2:4], 0)
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+	if _, err = rand.Read(request[8:stunHeaderSize]); err != nil {
+		return nil, nil, err
+	}
+	return request, request[8:stunHeaderSize], nil
+}
+
+func parseStunBindingResponse(response []byte, transactionID []byte) (*net.UDPAddr, error) {
+	if len(response) < stunHeaderSize {
+		return nil, errors.New("STUN response is too short")
+	}
+	if binary.BigEndian.Uint16(response[0:2]) != stunBindingResponse {
+		return nil, fmt.Errorf("unexpected STUN message type: %#04x", binary.BigEndian.Uint16(response[0:2]))
+	}
+	if binary.BigEndian.Uint32(response[4:8]) != stunMagicCookie {
+		return nil, errors.New("invalid STUN magic cookie")
+	}
+	if !bytes.Equal(response[8:stunHeaderSize], transactionID) {
+		return nil, errors.New("STUN transaction ID mismatch")
+	}
+
+	length := int(binary.BigEndian.Uint16(response[2:4]))
+	if len(response) < stunHeaderSize+length {
+		return nil, errors.New("STUN response is truncated")
+	}
+
+	var mapped *net.UDPAddr
+	attributes := response[stunHeaderSize : stunHeaderSize+length]
+	for len(attributes) >= 4 {
+		attrType := binary.BigEndian.Uint16(attributes[0:2])
+		attrLength := int(binary.BigEndian.Uint16(attributes[2:4]))
+		if len(attributes) < 4+attrLength {
+			return nil, errors.New("STUN attribute is truncated")
+		}
+		value := attributes[4 : 4+attrLength]
+
+		switch attrType {
+		case stunAttrXorMappedAddress:
+			// XOR-MAPPED-ADDRESS is preferred over MAPPED-ADDRESS
+			return parseStunAddress(value, response[4:stunHeaderSize])
+		case stunAttrMappedAddress:
+			address, err := parseStunAddress(value, nil)
+			if err != nil {
+				return nil, err
+			}
+			mapped = address
+		}
+
+		// attributes are padded to the multiple of 4 bytes
+		padded := (attrLength + 3) &^ 3
+		if len(attributes) < 4+padded {
+			break
+		}
+		attributes = attributes[4+padded:]
+	}
+
+	if mapped == nil {
+		return nil, ErrNoMappedAddress
+	}
+	return mapped, nil
+}
+
+// parseStunAddress parses address attribute value, address is XOR-ed with the magic cookie and transaction ID when xorKey is given
+func parseStunAddress(value []byte, xorKey []byte) (*net.UDPAddr, error) {
+	if len(value) < 4 {
+		return nil, errors.New("STUN address attribute is too short")
+	}
+
+	var size int
+	switch value[1] {
+	case stunFamilyIPv4:
+		size = net.IPv4len
+	case stunFamilyIPv6:
+		size = net.IPv6len
+	default:
+		return nil, fmt.Errorf("unknown STUN address family: %#02x", value[1])
+	}
+	if len(value) < 4+size {
+		return nil, errors.New("STUN address attribute is too short")
+	}
+
+	port := binary.BigEndian.Uint16(value[2:4])
+	ip := make(net.IP, size)
+	copy(ip, value[4:4+size])
+	if xorKey != nil {
+		// port is XOR-ed with the most significant 16 bits of the magic cookie
+		port ^= binary.BigEndian.Uint16(xorKey[0:2])
+	}
+	for i := range xorKey {
+		if i >= size {
+			break
+		}
+		ip[i] ^= xorKey[i]
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ip
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startStunServer answers binding requests with XOR-MAPPED-ADDRESS of the requester
+func startStunServer(t *testing.T) (address string, stop func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go func() {
+		request := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(request)
+			if err != nil {
+				return
+			}
+			if n < stunHeaderSize {
+				continue
+			}
+			conn.WriteTo(newStunResponse(request[:stunHeaderSize], stunAttrXorMappedAddress, addr.(*net.UDPAddr), true), addr)
+		}
+	}()
+
+	return conn.LocalAddr().String(), func() { conn.Close() }
+}
+
+func newStunResponse(request []byte, attrType uint16, address *net.UDPAddr, xor bool) []byte {
+	response := make([]byte, stunHeaderSize+12)
+	copy(response, request)
+	binary.BigEndian.PutUint16(response[0:2], stunBindingResponse)
+	binary.BigEndian.PutUint16(response[2:4], 12)
+
+	attribute := response[stunHeaderSize:]
+	binary.BigEndian.PutUint16(attribute[0:2], attrType)
+	binary.BigEndian.PutUint16(attribute[2:4], 8)
+	attribute[5] = stunFamilyIPv4
+	binary.BigEndian.PutUint16(attribute[6:8], uint16(address.Port))
+	copy(attribute[8:12], address.IP.To4())
+	if xor {
+		attribute[6] ^= response[4]
+		attribute[7] ^= response[5]
+		for i := 0; i < net.IPv4len; i++ {
+			attribute[8+i] ^= response[4+i]
+		}
+	}
+	return response
+}
+
+func TestStunResolverReturnsMappedAddress(t *testing.T) {
+	address, stop := startStunServer(t)
+	defer stop()
+
+	ip, err := NewStunResolver(address, time.Second).GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ip)
+}
+
+func TestStunResolverTimesOutWithoutResponse(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = NewStunResolver(conn.LocalAddr().String(), 10*time.Millisecond).GetPublicIP()
+	assert.Error(t, err)
+}
+
+func TestGetMappedAddressReturnsAddressOfConnection(t *testing.T) {
+	address, stop := startStunServer(t)
+	defer stop()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	mapped, err := GetMappedAddress(conn, address, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, conn.LocalAddr().String(), mapped.String())
+}
+
+func TestParseStunBindingResponse(t *testing.T) {
+	request, transactionID, err := newStunBindingRequest()
+	assert.NoError(t, err)
+	publicIP := &net.UDPAddr{IP: net.ParseIP("95.85.39.36"), Port: 52820}
+
+	address, err := parseStunBindingResponse(newStunResponse(request, stunAttrXorMappedAddress, publicIP, true), transactionID)
+	assert.NoError(t, err)
+	assert.Equal(t, "95.85.39.36:52820", address.String())
+
+	address, err = parseStunBindingResponse(newStunResponse(request, stunAttrMappedAddress, publicIP, false), transactionID)
+	assert.NoError(t, err)
+	assert.Equal(t, "95.85.39.36:52820", address.String())
+
+	_, err = parseStunBindingResponse(newStunResponse(request, 0x8022, publicIP, false), transactionID)
+	assert.Equal(t, ErrNoMappedAddress, err)
+
+	otherRequest, _, err := newStunBindingRequest()
+	assert.NoError(t, err)
+	_, err = parseStunBindingResponse(newStunResponse(otherRequest, stunAttrXorMappedAddress, publicIP, true), transactionID)
+	assert.EqualError(t, err, "STUN transaction ID mismatch")
+
+	_, err = parseStunBindingResponse(request, transactionID)
+	assert.EqualError(t, err, "unexpected STUN message type: 0x0001")
+}
+
+func TestGetMappedAddressOfAnyDoesNotWaitForSilentServers(t *testing.T) {
+	address, stop := startStunServer(t)
+	defer stop()
+
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer silent.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	started := time.Now()
+	mapped, err := GetMappedAddressOfAny(conn, []string{silent.LocalAddr().String(), address}, 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, conn.LocalAddr().String(), mapped.String())
+	assert.True(t, time.Since(started) < time.Second)
+
+	_, err = GetMappedAddressOfAny(conn, []string{silent.LocalAddr().String()}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package location
+
+import (
+	"fmt"
+	"net"
+
+	log "github.com/cihub/seelog"
+	"github.com/oschwald/geoip2-golang"
+)
+
+const asnResolverLogPrefix = "[asn-resolver] "
+
+// asnDatabase resolves autonomous system which the ip belongs to
+type asnDatabase interface {
+	ASN(ipAddress net.IP) (*geoip2.ASN, error)
+}
+
+// ASNResolver complements locations resolved by other resolver with autonomous system number and organization
+type ASNResolver struct {
+	Resolver
+	asnReader asnDatabase
+}
+
+// NewASNResolver returns Resolver which uses external ASN database of GeoLite2 format on top of the given resolver.
+// Given resolver is returned as is if the database can not be opened, so locations are resolved without ASN.
+func NewASNResolver(resolver Resolver, databasePath string) Resolver {
+	db, err := geoip2.Open(databasePath)
+	if err != nil {
+		log.Warn(asnResolverLogPrefix, "Failed to open ASN database, locations are resolved without ASN: ", err)
+		return resolver
+	}
+
+	return &ASNResolver{
+		Resolver:  resolver,
+		asnReader: db,
+	}
+}
+
+// ResolveLocation maps given ip to location including its autonomous system.
+// Location is still returned if autonomous system fails to resolve.
+func (r *ASNResolver) ResolveLocation(ip string) (Location, error) {
+	location, err := r.Resolver.ResolveLocation(ip)
+	if err != nil {
+		return location, err
+	}
+
+	ipObject := net.ParseIP(ip)
+	if ipObject == nil {
+		return location, nil
+	}
+
+	asnRecord, err := r.asnReader.ASN(ipObject)
+	if err != nil {
+		log.Warn(asnResolverLogPrefix, "Failed to resolve autonomous system of ", ip, ": ", err)
+		return location, nil
+	}
+
+	if asnRecord.AutonomousSystemNumber != 0 {
+		location.ASN = fmt.Sprintf("AS%d", asnRecord.AutonomousSystemNumber)
+	}
+	location.ISP = asnRecord.AutonomousSystemOrganization
+	return location, nil
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package location
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/stretchr/testify/assert"
+)
+
+type asnDatabaseFake struct {
+	record geoip2.ASN
+	err    error
+}
+
+func (db *asnDatabaseFake) ASN(ipAddress net.IP) (*geoip2.ASN, error) {
+	return &db.record, db.err
+}
+
+func TestASNResolverComplementsLocation(t *testing.T) {
+	resolver := &ASNResolver{
+		Resolver: NewStaticResolver("NL"),
+		asnReader: &asnDatabaseFake{record: geoip2.ASN{
+			AutonomousSystemNumber:       14061,
+			AutonomousSystemOrganization: "DigitalOcean, LLC",
+		}},
+	}
+
+	location, err := resolver.ResolveLocation("95.85.39.36")
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		Location{IP: "95.85.39.36", Country: "NL", ASN: "AS14061", ISP: "DigitalOcean, LLC"},
+		location,
+	)
+
+	country, err := resolver.ResolveCountry("95.85.39.36")
+	assert.NoError(t, err)
+	assert.Equal(t, "NL", country)
+}
+
+func TestASNResolverKeepsLocationWhenASNFails(t *testing.T) {
+	resolver := &ASNResolver{
+		Resolver:  NewStaticResolver("NL"),
+		asnReader: &asnDatabaseFake{err: errors.New("invalid database")},
+	}
+
+	location, err := resolver.ResolveLocation("95.85.39.36")
+	assert.NoError(t, err)
+	assert.Equal(t, Location{IP: "95.85.39.36", Country: "NL"}, location)
+}
+
+func TestASNResolverReturnsLocationError(t *testing.T) {
+	locationErr := errors.New("location DbResolver error")
+	resolver := &ASNResolver{
+		Resolver:  NewFailingResolver(locationErr),
+		asnReader: &asnDatabaseFake{},
+	}
+
+	_, err := resolver.ResolveLocation("95.85.39.36")
+	assert.Equal(t, locationErr, err)
+}
+
+func TestNewASNResolverResolvesLocationWithoutDatabase(t *testing.T) {
+	location, err := NewASNResolver(NewStaticResolver("NL"), "db/missing.mmdb").ResolveLocation("95.85.39.36")
+	assert.NoError(t, err)
+	assert.Equal(t, Location{IP: "95.85.39.36", Country: "NL"}, location)
+}
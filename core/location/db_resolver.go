@@ -24,12 +24,13 @@ import (
 	"github.com/oschwald/geoip2-golang"
 )
 
-// DbResolver struct represents ip -> country resolver which uses geoip2 data reader
+// DbResolver struct represents ip -> location resolver which uses geoip2 data reader.
+// City databases also resolve region and city, country databases resolve only the country.
 type DbResolver struct {
 	dbReader *geoip2.Reader
 }
 
-// NewExternalDbResolver returns Resolver which uses external country database
+// NewExternalDbResolver returns Resolver which uses external country or city database
 func NewExternalDbResolver(databasePath string) Resolver {
 	db, err := geoip2.Open(databasePath)
 	if err != nil {
@@ -43,23 +44,56 @@ func NewExternalDbResolver(databasePath string) Resolver {
 
 // ResolveCountry maps given ip to country
 func (r *DbResolver) ResolveCountry(ip string) (string, error) {
+	location, err := r.ResolveLocation(ip)
+	return location.Country, err
+}
+
+// ResolveLocation maps given ip to country, region and city
+func (r *DbResolver) ResolveLocation(ip string) (Location, error) {
 	ipObject := net.ParseIP(ip)
 	if ipObject == nil {
-		return "", errors.New("failed to parse IP")
+		return Location{}, errors.New("failed to parse IP")
+	}
+
+	cityRecord, err := r.dbReader.City(ipObject)
+	if _, ok := err.(geoip2.InvalidMethodError); ok {
+		return r.resolveCountryOnly(ip, ipObject)
 	}
+	if err != nil {
+		return Location{}, err
+	}
+
+	location := Location{
+		IP:      ip,
+		Country: cityRecord.Country.IsoCode,
+		City:    cityRecord.City.Names["en"],
+	}
+	if location.Country == "" {
+		location.Country = cityRecord.RegisteredCountry.IsoCode
+	}
+	if len(cityRecord.Subdivisions) > 0 {
+		location.Region = cityRecord.Subdivisions[0].Names["en"]
+	}
+	if location.Country == "" {
+		return Location{}, errors.New("failed to resolve country")
+	}
+
+	return location, nil
+}
 
+func (r *DbResolver) resolveCountryOnly(ip string, ipObject net.IP) (Location, error) {
 	countryRecord, err := r.dbReader.Country(ipObject)
 	if err != nil {
-		return "", err
+		return Location{}, err
 	}
 
 	country := countryRecord.Country.IsoCode
 	if country == "" {
 		country = countryRecord.RegisteredCountry.IsoCode
 		if country == "" {
-			return "", errors.New("failed to resolve country")
+			return Location{}, errors.New("failed to resolve country")
 		}
 	}
 
-	return country, nil
+	return Location{IP: ip, Country: country}, nil
 }
@@ -50,3 +50,14 @@ func TestResolverResolveCountry(t *testing.T) {
 		}
 	}
 }
+
+func TestResolverResolveLocationWithCountryDatabase(t *testing.T) {
+	resolver := NewExternalDbResolver("db/GeoLite2-Country.mmdb")
+
+	location, err := resolver.ResolveLocation("95.85.39.36")
+	assert.NoError(t, err)
+	assert.Equal(t, Location{IP: "95.85.39.36", Country: "NL"}, location)
+
+	_, err = resolver.ResolveLocation("127.0.0.1")
+	assert.EqualError(t, err, "failed to resolve country")
+}
@@ -50,18 +50,18 @@ type detector struct {
 	locationResolver Resolver
 }
 
-// Maps current ip to country
+// Maps current ip to location
 func (d *detector) DetectLocation() (Location, error) {
 	ipAddress, err := d.ipResolver.GetPublicIP()
 	if err != nil {
 		return Location{}, err
 	}
 
-	country, err := d.locationResolver.ResolveCountry(ipAddress)
+	location, err := d.locationResolver.ResolveLocation(ipAddress)
 	if err != nil {
 		return Location{}, err
 	}
 
-	location := Location{Country: country, IP: ipAddress}
+	location.IP = ipAddress
 	return location, nil
 }
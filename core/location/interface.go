@@ -20,6 +20,7 @@ package location
 // Resolver allows resolving location by ip
 type Resolver interface {
 	ResolveCountry(ip string) (string, error)
+	ResolveLocation(ip string) (Location, error)
 }
 
 // Detector allows detecting location by current ip
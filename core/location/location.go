@@ -17,7 +17,7 @@
 
 package location
 
-// Location structure represents location information (ip and country)
+// Location structure represents location information (ip, country, city and network operator)
 type Location struct {
 	// IP address
 	// example: 127.0.0.1
@@ -25,4 +25,18 @@ type Location struct {
 
 	// example: NL
 	Country string `json:"country"`
+
+	// example: North Holland
+	Region string `json:"region,omitempty"`
+
+	// example: Amsterdam
+	City string `json:"city,omitempty"`
+
+	// Autonomous System Number
+	// example: AS14061
+	ASN string `json:"asn,omitempty"`
+
+	// organization which operates the autonomous system
+	// example: DigitalOcean, LLC
+	ISP string `json:"isp,omitempty"`
 }
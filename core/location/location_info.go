@@ -22,4 +22,8 @@ type ServiceLocationInfo struct {
 	OutIP   string
 	PubIP   string
 	Country string
+	Region  string
+	City    string
+	ASN     string
+	ISP     string
 }
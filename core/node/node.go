@@ -18,6 +18,8 @@
 package node
 
 import (
+	"sync"
+
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/core/location"
@@ -26,7 +28,8 @@ import (
 	"github.com/mysteriumnetwork/node/tequilapi"
 )
 
-// NewNode function creates new Mysterium node by given options
+// NewNode function creates new Mysterium node by given options,
+// tequilapiServer may be nil when node is controlled in-process (e.g. on mobile)
 func NewNode(
 	connectionManager connection.Manager,
 	tequilapiServer tequilapi.APIServer,
@@ -38,6 +41,7 @@ func NewNode(
 		httpAPIServer:         tequilapiServer,
 		originalLocationCache: originalLocationCache,
 		metricsSender:         metricsSender,
+		stop:                  make(chan struct{}),
 	}
 }
 
@@ -47,10 +51,14 @@ type Node struct {
 	httpAPIServer         tequilapi.APIServer
 	originalLocationCache location.Cache
 	metricsSender         *metrics.Sender
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // Start starts Mysterium node (Tequilapi service, fetches location)
 func (node *Node) Start() error {
+	node.metricsSender.Start()
 	go func() {
 		err := node.metricsSender.SendStartupEvent(metadata.VersionAsString())
 		if err != nil {
@@ -65,6 +73,11 @@ func (node *Node) Start() error {
 		log.Info("Original country detected: ", originalLocation.Country)
 	}
 
+	if node.httpAPIServer == nil {
+		log.Info("Api disabled")
+		return nil
+	}
+
 	err = node.httpAPIServer.StartServing()
 	if err != nil {
 		return err
@@ -82,6 +95,10 @@ func (node *Node) Start() error {
 
 // Wait blocks until Mysterium node is stopped
 func (node *Node) Wait() error {
+	if node.httpAPIServer == nil {
+		<-node.stop
+		return nil
+	}
 	return node.httpAPIServer.Wait()
 }
 
@@ -99,8 +116,15 @@ func (node *Node) Kill() error {
 		log.Info("Connection closed")
 	}
 
-	node.httpAPIServer.Stop()
-	log.Info("Api stopped")
+	if node.httpAPIServer != nil {
+		node.httpAPIServer.Stop()
+		log.Info("Api stopped")
+	}
+
+	node.metricsSender.Stop()
+	node.stopOnce.Do(func() {
+		close(node.stop)
+	})
 
 	return nil
 }
@@ -28,16 +28,23 @@ type Openvpn interface {
 type Options struct {
 	Directories OptionsDirectory
 
+	DisableTequilapi bool
 	TequilapiAddress string
 	TequilapiPort    int
 
-	DisableMetrics bool
-	MetricsAddress string
+	DisableMetrics            bool
+	MetricsAddress            string
+	MetricsDisabledCategories []string
 
 	Keystore OptionsKeystore
 
 	Openvpn  Openvpn
 	Location OptionsLocation
+	Payments OptionsPayments
+
+	ServiceRestart      OptionsServiceRestart
+	ServiceReachability OptionsServiceReachability
+	ServiceSession      OptionsServiceSession
 	OptionsNetwork
 }
 
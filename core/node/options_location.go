@@ -17,9 +17,23 @@
 
 package node
 
+import "time"
+
 // OptionsLocation describes possible parameters of location detection configuration
 type OptionsLocation struct {
-	IpifyUrl   string
-	ExternalDb string
-	Country    string
+	IpifyUrl string
+	// IPSourceUrls are additional ipify compatible services asked for public IP
+	IPSourceUrls []string
+	// STUNServers are asked for public IP along with ipify compatible services
+	STUNServers []string
+	// IPCacheTTL defines how long resolved public IP is reused, zero disables caching
+	IPCacheTTL time.Duration
+	// IPCheckInterval defines how often public IP changes are checked, zero disables checking
+	IPCheckInterval time.Duration
+	// CheckInterval defines how often location of running services is detected again, zero disables detection
+	CheckInterval time.Duration
+
+	ExternalDb    string
+	ExternalAsnDb string
+	Country       string
 }
@@ -0,0 +1,40 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package node
+
+import "time"
+
+// OptionsServiceRestart describes restart policy of the services which failed while serving
+type OptionsServiceRestart struct {
+	MaxRestarts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// OptionsServiceReachability describes checks whether service endpoints can be reached from outside of the provider network
+type OptionsServiceReachability struct {
+	// CheckInterval defines how often reachability of running services is checked, zero disables checking
+	CheckInterval time.Duration
+	Timeout       time.Duration
+}
+
+// OptionsServiceSession describes cleanup of the sessions abandoned by consumers
+type OptionsServiceSession struct {
+	// IdleTimeout defines how long session lives without keepalives and traffic of the consumer, zero disables cleanup
+	IdleTimeout time.Duration
+}
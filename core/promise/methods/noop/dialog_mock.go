@@ -18,6 +18,7 @@
 package noop
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
@@ -39,6 +40,10 @@ func (fd *fakeDialog) PeerID() identity.Identity {
 	return identity.Identity{}
 }
 
+func (fd *fakeDialog) PeerProtocol() communication.Protocol {
+	return communication.NewProtocol()
+}
+
 func (fd *fakeDialog) Close() error {
 	return nil
 }
@@ -68,6 +73,10 @@ func (fd *fakeDialog) Send(producer communication.MessageProducer) error {
 	return nil
 }
 
+func (fd *fakeDialog) SendContext(_ context.Context, producer communication.MessageProducer) error {
+	return fd.Send(producer)
+}
+
 func (fd *fakeDialog) getSendMessage() interface{} {
 	fd.sendMutex.Lock()
 	defer fd.sendMutex.Unlock()
@@ -88,3 +97,7 @@ func (fd *fakeDialog) waitSendMessage() (interface{}, error) {
 func (fd *fakeDialog) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
 	return &promise.Response{Success: true}, nil
 }
+
+func (fd *fakeDialog) RequestContext(_ context.Context, producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	return fd.Request(producer)
+}
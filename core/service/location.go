@@ -0,0 +1,65 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/location"
+)
+
+// LocationAware is implemented by services which follow the changed provider location,
+// i.e. issue session configs with the new public IP
+type LocationAware interface {
+	UpdateLocation(location location.ServiceLocationInfo)
+}
+
+// UpdateLocation hands the new provider location to running services and announces their proposals updated for it.
+// Services being restarted are skipped, they detect the location themselves when started again.
+func (manager *Manager) UpdateLocation(location location.ServiceLocationInfo) {
+	for id, instance := range manager.servicePool.List() {
+		if err := instance.relocate(manager.serviceRegistry, location); err != nil {
+			log.Warn(logPrefix, "Failed to update location of service ", id, ": ", err)
+		}
+	}
+}
+
+// relocate updates location of the running service and its announced proposal
+func (i *Instance) relocate(registry *Registry, location location.ServiceLocationInfo) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.service == nil || i.discovery == nil {
+		return nil
+	}
+
+	proposal, err := registry.CreateProposal(i.proposal.ServiceType, i.options, location)
+	if err != nil {
+		return err
+	}
+	proposal.ID = i.proposal.ID
+	proposal.Format = i.proposal.Format
+	proposal.ProviderID = i.proposal.ProviderID
+	proposal.ProviderContacts = i.proposal.ProviderContacts
+
+	if service, ok := i.service.(LocationAware); ok {
+		service.UpdateLocation(location)
+	}
+	i.proposal = proposal
+	i.discovery.UpdateProposal(proposal)
+	return nil
+}
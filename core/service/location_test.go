@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/core/location"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+type locationAwareServiceFake struct {
+	serviceFake
+	location location.ServiceLocationInfo
+	lock     sync.Mutex
+}
+
+func (service *locationAwareServiceFake) UpdateLocation(location location.ServiceLocationInfo) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+	service.location = location
+}
+
+func (service *locationAwareServiceFake) currentLocation() location.ServiceLocationInfo {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+	return service.location
+}
+
+func TestManager_UpdateLocationAnnouncesRelocatedProposal(t *testing.T) {
+	service := &locationAwareServiceFake{serviceFake: serviceFake{mockProcess: make(chan struct{})}}
+	registry := NewRegistry()
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		return service, fakeProposalFactory(options, location.ServiceLocationInfo{Country: "NL"}), nil
+	})
+	registry.RegisterProposalFactory(serviceType, fakeProposalFactory)
+
+	discovery := mockDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(&discovery),
+		nil,
+		RestartPolicy{},
+	)
+	id, err := manager.Start(identity.FromAddress("0x1"), serviceType, struct{}{})
+	assert.NoError(t, err)
+
+	newLocation := location.ServiceLocationInfo{PubIP: "1.2.3.4", Country: "LT"}
+	manager.UpdateLocation(newLocation)
+
+	proposal := manager.Service(id).Proposal()
+	assert.Equal(t, "LT", proposal.ServiceDefinition.GetLocation().Country)
+	assert.Equal(t, "0x1", proposal.ProviderID)
+	assert.Len(t, proposal.ProviderContacts, 1)
+	assert.Equal(t, proposal, discovery.announcedProposal())
+	assert.Equal(t, newLocation, service.currentLocation())
+
+	assert.NoError(t, manager.Stop(id))
+	discovery.Wait()
+}
+
+func TestManager_UpdateLocationKeepsProposalOfServiceWithoutProposalFactory(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		return &serviceFake{mockProcess: make(chan struct{})}, fakeProposalFactory(options, location.ServiceLocationInfo{Country: "NL"}), nil
+	})
+
+	discovery := mockDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(&discovery),
+		nil,
+		RestartPolicy{},
+	)
+	id, err := manager.Start(identity.FromAddress("0x1"), serviceType, struct{}{})
+	assert.NoError(t, err)
+
+	manager.UpdateLocation(location.ServiceLocationInfo{PubIP: "1.2.3.4", Country: "LT"})
+
+	proposal := manager.Service(id).Proposal()
+	assert.Equal(t, "NL", proposal.ServiceDefinition.GetLocation().Country)
+	assert.Equal(t, proposal, discovery.announcedProposal())
+
+	assert.NoError(t, manager.Stop(id))
+	discovery.Wait()
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/location"
+)
+
+// LocationDetector detects current location of the provider
+type LocationDetector func() (location.ServiceLocationInfo, error)
+
+// LocationWatcher detects provider location and relocates running services whenever it changes
+type LocationWatcher struct {
+	detect   LocationDetector
+	relocate func(location location.ServiceLocationInfo)
+
+	lock     sync.Mutex
+	current  location.ServiceLocationInfo
+	detected bool
+	tunneled bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewLocationWatcher returns new instance of LocationWatcher
+func NewLocationWatcher(detect LocationDetector, relocate func(location location.ServiceLocationInfo)) *LocationWatcher {
+	return &LocationWatcher{
+		detect:   detect,
+		relocate: relocate,
+		stop:     make(chan struct{}),
+	}
+}
+
+// SetTunneled tells whether node traffic goes through the consumer tunnel.
+// Location is not checked while traffic is tunneled, as the tunnel location would be detected instead of provider's one.
+func (watcher *LocationWatcher) SetTunneled(tunneled bool) {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+
+	watcher.tunneled = tunneled
+}
+
+// Check detects provider location and relocates services if it has changed since the previous check
+func (watcher *LocationWatcher) Check() {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+
+	if watcher.tunneled {
+		log.Debug(logPrefix, "Skipping provider location check while consumer connection is active")
+		return
+	}
+
+	detected, err := watcher.detect()
+	if err != nil {
+		log.Warn(logPrefix, "Provider location detection failed, will retry later: ", err)
+		return
+	}
+
+	previous, changed := watcher.current, watcher.detected && watcher.current != detected
+	watcher.current = detected
+	watcher.detected = true
+	if !changed {
+		return
+	}
+
+	log.Infof("%sProvider location changed from %s (%s) to %s (%s), updating services", logPrefix, previous.PubIP, previous.Country, detected.PubIP, detected.Country)
+	watcher.relocate(detected)
+}
+
+// Start detects provider location right away and then periodically
+func (watcher *LocationWatcher) Start(interval time.Duration) {
+	go func() {
+		watcher.Check()
+		for {
+			select {
+			case <-watcher.stop:
+				return
+			case <-time.After(interval):
+				watcher.Check()
+			}
+		}
+	}()
+}
+
+// Stop stops periodical location detection
+func (watcher *LocationWatcher) Stop() {
+	watcher.stopOnce.Do(func() {
+		close(watcher.stop)
+	})
+}
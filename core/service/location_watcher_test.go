@@ -0,0 +1,182 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/ip"
+	"github.com/mysteriumnetwork/node/core/location"
+	"github.com/stretchr/testify/assert"
+)
+
+type locationDetectorFake struct {
+	locations []location.ServiceLocationInfo
+	err       error
+}
+
+func (detector *locationDetectorFake) detect() (location.ServiceLocationInfo, error) {
+	if detector.err != nil {
+		return location.ServiceLocationInfo{}, detector.err
+	}
+	detected := detector.locations[0]
+	if len(detector.locations) > 1 {
+		detector.locations = detector.locations[1:]
+	}
+	return detected, nil
+}
+
+func TestLocationWatcher_CheckRelocatesServicesWhenLocationChanges(t *testing.T) {
+	first := location.ServiceLocationInfo{PubIP: "1.1.1.1", Country: "NL"}
+	second := location.ServiceLocationInfo{PubIP: "2.2.2.2", Country: "LT"}
+	detector := &locationDetectorFake{locations: []location.ServiceLocationInfo{first, first, second, second}}
+
+	var relocated []location.ServiceLocationInfo
+	watcher := NewLocationWatcher(detector.detect, func(location location.ServiceLocationInfo) {
+		relocated = append(relocated, location)
+	})
+
+	watcher.Check()
+	watcher.Check()
+	assert.Empty(t, relocated)
+
+	watcher.Check()
+	watcher.Check()
+	assert.Equal(t, []location.ServiceLocationInfo{second}, relocated)
+}
+
+func TestLocationWatcher_CheckIgnoresDetectionFailures(t *testing.T) {
+	detector := &locationDetectorFake{locations: []location.ServiceLocationInfo{{PubIP: "1.1.1.1"}}}
+
+	var relocated []location.ServiceLocationInfo
+	watcher := NewLocationWatcher(detector.detect, func(location location.ServiceLocationInfo) {
+		relocated = append(relocated, location)
+	})
+
+	watcher.Check()
+	detector.err = errors.New("detection failed")
+	watcher.Check()
+	detector.err = nil
+	watcher.Check()
+
+	assert.Empty(t, relocated)
+}
+
+func TestLocationWatcher_CheckSkipsWhileTunneled(t *testing.T) {
+	first := location.ServiceLocationInfo{PubIP: "1.1.1.1", Country: "NL"}
+	second := location.ServiceLocationInfo{PubIP: "2.2.2.2", Country: "LT"}
+	detector := &locationDetectorFake{locations: []location.ServiceLocationInfo{first, second}}
+
+	var relocated []location.ServiceLocationInfo
+	watcher := NewLocationWatcher(detector.detect, func(location location.ServiceLocationInfo) {
+		relocated = append(relocated, location)
+	})
+
+	watcher.Check()
+	watcher.SetTunneled(true)
+	watcher.Check()
+	assert.Empty(t, relocated)
+
+	watcher.SetTunneled(false)
+	watcher.Check()
+	assert.Equal(t, []location.ServiceLocationInfo{second}, relocated)
+}
+
+// switchableResolver resolves public IP of the tunnel exit while consumer connection is active
+type switchableResolver struct {
+	lock      sync.Mutex
+	direct    string
+	tunnel    string
+	connected bool
+}
+
+func (r *switchableResolver) GetPublicIP() (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.connected {
+		return r.tunnel, nil
+	}
+	return r.direct, nil
+}
+
+func (r *switchableResolver) GetOutboundIP() (string, error) {
+	return "192.168.1.2", nil
+}
+
+func (r *switchableResolver) setConnected(connected bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.connected = connected
+}
+
+type ipChangeRecorder struct {
+	lock   sync.Mutex
+	events []ip.ChangeEvent
+}
+
+func (r *ipChangeRecorder) Publish(topic string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if topic == ip.PublicIPChangeTopic {
+		r.events = append(r.events, args[0].(ip.ChangeEvent))
+	}
+}
+
+func TestLocationWatcher_ProviderKeepsLocationWhileConnectedAsConsumer(t *testing.T) {
+	source := &switchableResolver{direct: "1.1.1.1", tunnel: "9.9.9.9"}
+	changes := &ipChangeRecorder{}
+	resolver := ip.NewCachedResolver(source, time.Hour, changes)
+	countries := map[string]string{"1.1.1.1": "NL", "9.9.9.9": "US"}
+
+	var relocated []location.ServiceLocationInfo
+	watcher := NewLocationWatcher(func() (location.ServiceLocationInfo, error) {
+		pubIP, err := resolver.GetDirectPublicIP()
+		return location.ServiceLocationInfo{PubIP: pubIP, Country: countries[pubIP]}, err
+	}, func(location location.ServiceLocationInfo) {
+		relocated = append(relocated, location)
+	})
+	watcher.Check()
+
+	// consumer connection of the same node is established
+	source.setConnected(true)
+	resolver.SetTunneled(true)
+	watcher.SetTunneled(true)
+
+	consumerIP, err := resolver.GetPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "9.9.9.9", consumerIP)
+	_, err = resolver.Refresh()
+	assert.NoError(t, err)
+	watcher.Check()
+
+	providerIP, err := resolver.GetDirectPublicIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", providerIP)
+
+	// consumer connection is closed
+	source.setConnected(false)
+	resolver.SetTunneled(false)
+	watcher.SetTunneled(false)
+	watcher.Check()
+
+	assert.Empty(t, changes.events)
+	assert.Empty(t, relocated)
+}
@@ -20,6 +20,10 @@ package service
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
@@ -28,6 +32,8 @@ import (
 	"github.com/mysteriumnetwork/node/session"
 )
 
+const logPrefix = "[service-manager] "
+
 var (
 	// ErrorLocation error indicates that action (i.e. disconnect)
 	ErrorLocation = errors.New("failed to detect service location")
@@ -54,6 +60,7 @@ type DiscoveryFactory func() Discovery
 // Discovery registers the service to the discovery api periodically
 type Discovery interface {
 	Start(ownIdentity identity.Identity, proposal market.ServiceProposal)
+	UpdateProposal(proposal market.ServiceProposal)
 	Stop()
 	Wait()
 }
@@ -64,6 +71,8 @@ func NewManager(
 	dialogWaiterFactory DialogWaiterFactory,
 	dialogHandlerFactory DialogHandlerFactory,
 	discoveryFactory DiscoveryFactory,
+	serviceStorage *Storage,
+	restartPolicy RestartPolicy,
 ) *Manager {
 	return &Manager{
 		serviceRegistry:      serviceRegistry,
@@ -71,6 +80,8 @@ func NewManager(
 		dialogWaiterFactory:  dialogWaiterFactory,
 		dialogHandlerFactory: dialogHandlerFactory,
 		discoveryFactory:     discoveryFactory,
+		serviceStorage:       serviceStorage,
+		restartPolicy:        restartPolicy,
 	}
 }
 
@@ -83,67 +94,177 @@ type Manager struct {
 	servicePool     *Pool
 
 	discoveryFactory DiscoveryFactory
+	serviceStorage   *Storage
+	restartPolicy    RestartPolicy
 }
 
 // Start starts an instance of the given service type if knows one in service registry.
 // It passes the options to the start method of the service.
+// Started service is persisted with autostart enabled, so that it is restored after node restart.
 // If an error occurs in the underlying service, the error is then returned.
 func (manager *Manager) Start(providerID identity.Identity, serviceType string, options Options) (id ID, err error) {
-	service, proposal, err := manager.serviceRegistry.Create(serviceType, options)
+	id, err = generateID()
 	if err != nil {
 		return id, err
 	}
 
+	if err = manager.start(id, providerID, serviceType, options, true); err != nil {
+		return id, err
+	}
+
+	manager.persist(id, providerID, serviceType, options, true)
+	return id, nil
+}
+
+// Restore starts the persisted services of the given provider which have autostart enabled.
+// Services which are already running are skipped.
+func (manager *Manager) Restore(providerID identity.Identity) {
+	if manager.serviceStorage == nil {
+		return
+	}
+
+	storedServices, err := manager.serviceStorage.GetAll()
+	if err != nil {
+		log.Error(logPrefix, "Failed to get persisted services: ", err)
+		return
+	}
+
+	for _, stored := range storedServices {
+		if stored.ProviderID != providerID || !stored.AutoStart || manager.isRunning(stored.ID, providerID, stored.Type) {
+			continue
+		}
+
+		options, err := manager.serviceStorage.ParseOptions(stored)
+		if err != nil {
+			log.Error(logPrefix, "Failed to parse options of persisted service ", stored.ID, ": ", err)
+			continue
+		}
+
+		log.Info(logPrefix, "Restoring service ", stored.ID, " of type ", stored.Type)
+		if err := manager.start(stored.ID, providerID, stored.Type, options, true); err != nil {
+			log.Error(logPrefix, "Failed to restore service ", stored.ID, ": ", err)
+		}
+	}
+}
+
+// SetAutoStart enables or disables restoring of the service after node restart.
+func (manager *Manager) SetAutoStart(id ID, autoStart bool) error {
+	instance := manager.servicePool.Instance(id)
+	if instance == nil {
+		return ErrNoSuchInstance
+	}
+
+	if manager.serviceStorage != nil {
+		proposal := instance.Proposal()
+		err := manager.serviceStorage.Store(id, identity.FromAddress(proposal.ProviderID), proposal.ServiceType, instance.Options(), autoStart)
+		if err != nil {
+			return err
+		}
+	}
+
+	instance.setAutoStart(autoStart)
+	return nil
+}
+
+func (manager *Manager) start(id ID, providerID identity.Identity, serviceType string, options Options, autoStart bool) error {
+	instance := &Instance{
+		state:     Starting,
+		options:   options,
+		autoStart: autoStart,
+	}
+
+	service, discovery, err := manager.run(providerID, serviceType, instance)
+	if err != nil {
+		return err
+	}
+
+	if err = manager.servicePool.AddWithID(id, instance); err != nil {
+		if stopErr := instance.stop(); stopErr != nil {
+			log.Error(logPrefix, "Failed to stop service ", id, ": ", stopErr)
+		}
+		return err
+	}
+
+	go manager.supervise(id, providerID, serviceType, instance, service, discovery)
+	return nil
+}
+
+// run creates the service of the instance, starts waiting for dialogs and announces the proposal.
+func (manager *Manager) run(providerID identity.Identity, serviceType string, instance *Instance) (Service, Discovery, error) {
+	service, proposal, err := manager.serviceRegistry.Create(serviceType, instance.Options())
+	if err != nil {
+		return nil, nil, err
+	}
+
 	dialogWaiter, err := manager.dialogWaiterFactory(providerID, serviceType)
 	if err != nil {
-		return id, err
+		stopRunnables(service, nil)
+		return nil, nil, err
 	}
 	providerContact, err := dialogWaiter.Start()
 	if err != nil {
-		return id, err
+		stopRunnables(service, dialogWaiter)
+		return nil, nil, err
 	}
 	proposal.SetProviderContact(providerID, providerContact)
 
 	dialogHandler := manager.dialogHandlerFactory(proposal, service)
 	if err = dialogWaiter.ServeDialogs(dialogHandler); err != nil {
-		return id, err
+		stopRunnables(service, dialogWaiter)
+		return nil, nil, err
 	}
 
 	discovery := manager.discoveryFactory()
 	discovery.Start(providerID, proposal)
 
-	instance := Instance{
-		state:        Starting,
-		options:      options,
-		service:      service,
-		proposal:     proposal,
-		dialogWaiter: dialogWaiter,
-		discovery:    discovery,
+	instance.setRunnables(service, proposal, dialogWaiter, dialogHandler, discovery)
+	return service, discovery, nil
+}
+
+// stopRunnables releases the service and the dialog waiter of the service which failed to start.
+func stopRunnables(service Service, dialogWaiter communication.DialogWaiter) {
+	if dialogWaiter != nil {
+		if err := dialogWaiter.Stop(); err != nil {
+			log.Error(logPrefix, "Failed to stop dialog waiter: ", err)
+		}
+	}
+	if err := service.Stop(); err != nil {
+		log.Error(logPrefix, "Failed to stop service: ", err)
 	}
+}
 
-	id, err = manager.servicePool.Add(&instance)
-	if err != nil {
-		return id, err
+// persist stores the started service and removes other persisted services of the same type,
+// as only one service of each type can run for the provider.
+func (manager *Manager) persist(id ID, providerID identity.Identity, serviceType string, options Options, autoStart bool) {
+	if manager.serviceStorage == nil {
+		return
 	}
 
-	go func() {
-		instance.state = Running
-		serveErr := service.Serve(providerID)
-		if serveErr != nil {
-			log.Error("Service serve failed: ", serveErr)
+	storedServices, err := manager.serviceStorage.GetAll()
+	if err != nil {
+		log.Error(logPrefix, "Failed to get persisted services: ", err)
+	}
+	for _, stored := range storedServices {
+		if stored.ID != id && stored.ProviderID == providerID && stored.Type == serviceType {
+			if err := manager.serviceStorage.Delete(stored.ID); err != nil {
+				log.Error(logPrefix, "Failed to delete persisted service ", stored.ID, ": ", err)
+			}
 		}
+	}
 
-		instance.state = NotRunning
+	if err := manager.serviceStorage.Store(id, providerID, serviceType, options, autoStart); err != nil {
+		log.Error(logPrefix, "Failed to persist service ", id, ": ", err)
+	}
+}
 
-		stopErr := manager.servicePool.Stop(id)
-		if stopErr != nil {
-			log.Error("Service stop failed: ", stopErr)
+func (manager *Manager) isRunning(id ID, providerID identity.Identity, serviceType string) bool {
+	for runningID, instance := range manager.servicePool.List() {
+		proposal := instance.Proposal()
+		if runningID == id || (proposal.ProviderID == providerID.Address && proposal.ServiceType == serviceType) {
+			return true
 		}
-
-		discovery.Wait()
-	}()
-
-	return id, nil
+	}
+	return false
 }
 
 // List returns array of running service instances.
@@ -151,14 +272,70 @@ func (manager *Manager) List() map[ID]*Instance {
 	return manager.servicePool.List()
 }
 
+// HealthCheck returns an error describing services which failed and are waiting to be restarted.
+func (manager *Manager) HealthCheck() error {
+	var failed []string
+	for id, instance := range manager.servicePool.List() {
+		if instance.State() != Restarting {
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("%s (%s): %v", id, instance.Proposal().ServiceType, instance.LastError()))
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	sort.Strings(failed)
+	return fmt.Errorf("services restarting: %s", strings.Join(failed, ", "))
+}
+
 // Kill stops all services.
+// Persisted services are kept, so that they are restored after node restart.
 func (manager *Manager) Kill() error {
 	return manager.servicePool.StopAll()
 }
 
-// Stop stops the service.
+// Stop stops the service and removes it from persisted services.
 func (manager *Manager) Stop(id ID) error {
-	return manager.servicePool.Stop(id)
+	if err := manager.servicePool.Stop(id); err != nil {
+		return err
+	}
+
+	if manager.serviceStorage != nil {
+		return manager.serviceStorage.Delete(id)
+	}
+	return nil
+}
+
+// Drain stops the service gracefully: its proposal is unregistered, new sessions are rejected and consumers are notified.
+// Service is stopped once the sessions end or the timeout passes, meanwhile it stays in Draining state.
+// Service which is not running is stopped right away.
+func (manager *Manager) Drain(id ID, timeout time.Duration) error {
+	instance := manager.servicePool.Instance(id)
+	if instance == nil {
+		return ErrNoSuchInstance
+	}
+
+	draining, err := instance.startDraining()
+	if err != nil {
+		return err
+	}
+	if !draining {
+		return manager.Stop(id)
+	}
+
+	log.Info(logPrefix, "Draining service ", id, " for ", timeout)
+	go func() {
+		instance.drain(timeout)
+		if !manager.isSupervised(id, instance) {
+			// service was stopped meanwhile
+			return
+		}
+		if err := manager.Stop(id); err != nil {
+			log.Error(logPrefix, "Service stop after draining failed: ", err)
+		}
+	}()
+	return nil
 }
 
 // Service returns a service instance by requested id.
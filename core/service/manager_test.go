@@ -20,11 +20,14 @@ package service
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/session"
 )
 
 var (
@@ -46,6 +49,8 @@ func TestManager_StartRemovesServiceFromPoolIfServiceCrashes(t *testing.T) {
 		MockDialogWaiterFactory,
 		MockDialogHandlerFactory,
 		discoveryFactory,
+		newTestStorage(newStorerFake()),
+		RestartPolicy{},
 	)
 	_, err := manager.Start(identity.FromAddress(proposalMock.ProviderID), serviceType, struct{}{})
 	assert.Nil(t, err)
@@ -54,6 +59,49 @@ func TestManager_StartRemovesServiceFromPoolIfServiceCrashes(t *testing.T) {
 	assert.Len(t, manager.servicePool.List(), 0)
 }
 
+type stopRecordingService struct {
+	serviceFake
+	stopped bool
+}
+
+func (service *stopRecordingService) Stop() error {
+	service.stopped = true
+	return nil
+}
+
+func TestManager_StartStopsServiceAndDialogWaiterIfDialogsCannotBeServed(t *testing.T) {
+	tests := map[string]*mockDialogWaiter{
+		"start fails": {startErr: errors.New("start error")},
+		"serve fails": {serveErr: errors.New("serve error")},
+	}
+	for name, dialogWaiter := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := &stopRecordingService{}
+			registry := NewRegistry()
+			registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+				return service, proposalMock, nil
+			})
+			dialogWaiterFactory := func(identity.Identity, string) (communication.DialogWaiter, error) {
+				return dialogWaiter, nil
+			}
+			manager := NewManager(
+				registry,
+				dialogWaiterFactory,
+				MockDialogHandlerFactory,
+				MockDiscoveryFactoryFunc(&mockDiscovery{}),
+				newTestStorage(newStorerFake()),
+				RestartPolicy{},
+			)
+
+			_, err := manager.Start(identity.FromAddress(proposalMock.ProviderID), serviceType, struct{}{})
+			assert.Error(t, err)
+			assert.True(t, service.stopped)
+			assert.True(t, dialogWaiter.stopped)
+			assert.Len(t, manager.servicePool.List(), 0)
+		})
+	}
+}
+
 func TestManager_StartDoesNotCrashIfStoppedByUser(t *testing.T) {
 	registry := NewRegistry()
 	mockCopy := *serviceMock
@@ -69,6 +117,8 @@ func TestManager_StartDoesNotCrashIfStoppedByUser(t *testing.T) {
 		MockDialogWaiterFactory,
 		MockDialogHandlerFactory,
 		discoveryFactory,
+		newTestStorage(newStorerFake()),
+		RestartPolicy{},
 	)
 	id, err := manager.Start(identity.FromAddress(proposalMock.ProviderID), serviceType, struct{}{})
 	assert.Nil(t, err)
@@ -77,3 +127,206 @@ func TestManager_StartDoesNotCrashIfStoppedByUser(t *testing.T) {
 	discovery.Wait()
 	assert.Len(t, manager.servicePool.List(), 0)
 }
+
+func TestManager_StartPersistsServiceAndStopRemovesIt(t *testing.T) {
+	registry := NewRegistry()
+	mockCopy := *serviceMock
+	mockCopy.mockProcess = make(chan struct{})
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		return &mockCopy, proposalMock, nil
+	})
+
+	storer := newStorerFake()
+	discovery := mockDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(&discovery),
+		newTestStorage(storer),
+		RestartPolicy{},
+	)
+	providerID := identity.FromAddress("0x1")
+	id, err := manager.Start(providerID, serviceType, testOptions{Port: 1194})
+	assert.NoError(t, err)
+	assert.True(t, manager.Service(id).AutoStart())
+
+	assert.Len(t, storer.services, 1)
+	assert.Equal(t, providerID, storer.services[id].ProviderID)
+	assert.Equal(t, serviceType, storer.services[id].Type)
+	assert.True(t, storer.services[id].AutoStart)
+
+	assert.NoError(t, manager.Stop(id))
+	discovery.Wait()
+	assert.Len(t, storer.services, 0)
+}
+
+func TestManager_KillKeepsPersistedServices(t *testing.T) {
+	registry := NewRegistry()
+	mockCopy := *serviceMock
+	mockCopy.mockProcess = make(chan struct{})
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		return &mockCopy, proposalMock, nil
+	})
+
+	storer := newStorerFake()
+	discovery := mockDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(&discovery),
+		newTestStorage(storer),
+		RestartPolicy{},
+	)
+	_, err := manager.Start(identity.FromAddress("0x1"), serviceType, testOptions{Port: 1194})
+	assert.NoError(t, err)
+
+	assert.NoError(t, manager.Kill())
+	discovery.Wait()
+	assert.Len(t, storer.services, 1)
+}
+
+func TestManager_RestoreStartsAutoStartServicesOfProvider(t *testing.T) {
+	registry := NewRegistry()
+	var createdWithOptions []Options
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		createdWithOptions = append(createdWithOptions, options)
+		return &serviceFake{mockProcess: make(chan struct{})}, proposalMock, nil
+	})
+
+	providerID := identity.FromAddress("0x1")
+	storage := newTestStorage(newStorerFake())
+	assert.NoError(t, storage.Store("restored", providerID, serviceType, testOptions{Port: 1194}, true))
+	assert.NoError(t, storage.Store("disabled", providerID, "other-type", testOptions{Port: 1195}, false))
+	assert.NoError(t, storage.Store("other-provider", identity.FromAddress("0x2"), serviceType, testOptions{Port: 1196}, true))
+
+	discovery := mockDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(&discovery),
+		storage,
+		RestartPolicy{},
+	)
+	manager.Restore(providerID)
+
+	assert.Equal(t, []Options{testOptions{Port: 1194}}, createdWithOptions)
+	instance := manager.Service("restored")
+	assert.NotNil(t, instance)
+	assert.True(t, instance.AutoStart())
+	assert.Equal(t, providerID.Address, instance.Proposal().ProviderID)
+
+	manager.Restore(providerID)
+	assert.Len(t, createdWithOptions, 1)
+
+	assert.NoError(t, manager.Kill())
+	discovery.Wait()
+}
+
+func TestManager_SetAutoStart(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		return &serviceFake{mockProcess: make(chan struct{})}, proposalMock, nil
+	})
+
+	storer := newStorerFake()
+	discovery := mockDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(&discovery),
+		newTestStorage(storer),
+		RestartPolicy{},
+	)
+	id, err := manager.Start(identity.FromAddress("0x1"), serviceType, testOptions{Port: 1194})
+	assert.NoError(t, err)
+
+	assert.NoError(t, manager.SetAutoStart(id, false))
+	assert.False(t, manager.Service(id).AutoStart())
+	assert.False(t, storer.services[id].AutoStart)
+
+	assert.Equal(t, ErrNoSuchInstance, manager.SetAutoStart("unknown", true))
+
+	assert.NoError(t, manager.Kill())
+	discovery.Wait()
+}
+
+func TestManager_HealthCheckReportsRestartingServices(t *testing.T) {
+	manager := NewManager(
+		NewRegistry(),
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(&mockDiscovery{}),
+		newTestStorage(newStorerFake()),
+		RestartPolicy{},
+	)
+	running := &Instance{state: Running, proposal: proposalMock}
+	restarting := &Instance{state: Restarting, proposal: proposalMock}
+	restarting.setFailed(errors.New("process died"))
+	assert.NoError(t, manager.servicePool.AddWithID("1", running))
+	assert.NoError(t, manager.HealthCheck())
+
+	assert.NoError(t, manager.servicePool.AddWithID("2", restarting))
+	assert.EqualError(
+		t,
+		manager.HealthCheck(),
+		"services restarting: 2 ("+proposalMock.ServiceType+"): process died",
+	)
+}
+
+type drainingDialogHandler struct {
+	mockDialogHandler
+	drained chan time.Duration
+	release chan struct{}
+}
+
+func (handler *drainingDialogHandler) DrainSessions(timeout time.Duration) {
+	handler.drained <- timeout
+	<-handler.release
+}
+
+func TestManager_DrainStopsServiceAfterSessionsEnd(t *testing.T) {
+	registry := NewRegistry()
+	mockCopy := *serviceMock
+	mockCopy.mockProcess = make(chan struct{})
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		return &mockCopy, proposalMock, nil
+	})
+
+	handler := &drainingDialogHandler{drained: make(chan time.Duration, 1), release: make(chan struct{})}
+	discovery := mockDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		func(market.ServiceProposal, session.ConfigNegotiator) communication.DialogHandler {
+			return handler
+		},
+		MockDiscoveryFactoryFunc(&discovery),
+		newTestStorage(newStorerFake()),
+		RestartPolicy{},
+	)
+	id, err := manager.Start(identity.FromAddress(proposalMock.ProviderID), serviceType, struct{}{})
+	assert.NoError(t, err)
+	instance := manager.Service(id)
+	for i := 0; i < 100 && instance.State() != Running; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.NoError(t, manager.Drain(id, time.Minute))
+	assert.Equal(t, time.Minute, <-handler.drained)
+	assert.Equal(t, Draining, instance.State())
+	assert.Equal(t, ErrInstanceDraining, manager.Drain(id, time.Minute))
+	// proposal is unregistered before the sessions end
+	discovery.Wait()
+	assert.NotNil(t, manager.Service(id))
+
+	close(handler.release)
+	for i := 0; i < 100 && manager.Service(id) != nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, manager.Service(id))
+	assert.Equal(t, ErrNoSuchInstance, manager.Drain(id, time.Minute))
+}
@@ -20,11 +20,14 @@ package service
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/market"
 	discovery_registry "github.com/mysteriumnetwork/node/market/proposals/registry"
+	"github.com/mysteriumnetwork/node/nat/mapping"
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/utils"
 )
 
@@ -49,18 +52,30 @@ func NewPool() *Pool {
 	}
 }
 
+// ErrInstanceExists represents the error when we're adding an instance with already used id
+var ErrInstanceExists = errors.New("instance already exists")
+
 // Add registers a service to running instances pool
 func (p *Pool) Add(instance *Instance) (ID, error) {
-	p.Lock()
-	defer p.Unlock()
-
 	id, err := generateID()
 	if err != nil {
 		return id, err
 	}
 
+	return id, p.AddWithID(id, instance)
+}
+
+// AddWithID registers a service to running instances pool with the given id
+func (p *Pool) AddWithID(id ID, instance *Instance) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, exists := p.instances[id]; exists {
+		return ErrInstanceExists
+	}
+
 	p.instances[id] = instance
-	return id, nil
+	return nil
 }
 
 // Del removes a service from running instances pool
@@ -77,6 +92,9 @@ func (p *Pool) del(id ID) {
 // ErrNoSuchInstance represents the error when we're stopping an instance that does not exist
 var ErrNoSuchInstance = errors.New("no such instance")
 
+// ErrInstanceDraining represents the error when we're draining an instance which is being drained already
+var ErrInstanceDraining = errors.New("instance is draining already")
+
 // Stop kills all sub-resources of instance
 func (p *Pool) Stop(id ID) error {
 	p.Lock()
@@ -90,19 +108,9 @@ func (p *Pool) stop(id ID) error {
 		return ErrNoSuchInstance
 	}
 
-	errStop := utils.ErrorCollection{}
-	if instance.discovery != nil {
-		instance.discovery.Stop()
-	}
-	if instance.dialogWaiter != nil {
-		errStop.Add(instance.dialogWaiter.Stop())
-	}
-	if instance.service != nil {
-		errStop.Add(instance.service.Stop())
-	}
-
+	err := instance.stop()
 	p.del(id)
-	return errStop.Errorf("ErrorCollection(%s)", ", ")
+	return err
 }
 
 // StopAll kills all running instances
@@ -121,7 +129,12 @@ func (p *Pool) StopAll() error {
 func (p *Pool) List() map[ID]*Instance {
 	p.Lock()
 	defer p.Unlock()
-	return p.instances
+
+	instances := make(map[ID]*Instance, len(p.instances))
+	for id, instance := range p.instances {
+		instances[id] = instance
+	}
+	return instances
 }
 
 // Instance returns service instance by the requested id.
@@ -131,6 +144,21 @@ func (p *Pool) Instance(id ID) *Instance {
 	return p.instances[id]
 }
 
+// PortMappingReporter is implemented by services which map their ports on the gateway
+type PortMappingReporter interface {
+	PortMappings() []mapping.Status
+}
+
+// SessionTerminator is implemented by dialog handlers which are able to terminate the sessions they serve
+type SessionTerminator interface {
+	TerminateSessions(reason session.TerminationReason)
+}
+
+// SessionDrainer is implemented by dialog handlers which are able to wait for the sessions they serve to end
+type SessionDrainer interface {
+	DrainSessions(timeout time.Duration)
+}
+
 // NewInstance creates new instance of the service.
 func NewInstance(
 	options Options,
@@ -152,12 +180,18 @@ func NewInstance(
 
 // Instance represents a run service
 type Instance struct {
-	state        State
-	options      Options
-	service      RunnableService
-	proposal     market.ServiceProposal
-	dialogWaiter communication.DialogWaiter
-	discovery    Discovery
+	state         State
+	options       Options
+	service       RunnableService
+	proposal      market.ServiceProposal
+	dialogWaiter  communication.DialogWaiter
+	dialogHandler communication.DialogHandler
+	discovery     Discovery
+	autoStart     bool
+	restarts      int
+	lastError     error
+	reachability  *Reachability
+	lock          sync.RWMutex
 }
 
 // Options returns options used to start service
@@ -167,14 +201,150 @@ func (i *Instance) Options() Options {
 
 // Proposal returns service proposal of the running service instance.
 func (i *Instance) Proposal() market.ServiceProposal {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
 	return i.proposal
 }
 
+// AutoStart returns whether the service instance is restored after node restart.
+func (i *Instance) AutoStart() bool {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.autoStart
+}
+
+func (i *Instance) setAutoStart(autoStart bool) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.autoStart = autoStart
+}
+
 // State returns the service instance state.
 func (i *Instance) State() State {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
 	return i.state
 }
 
+func (i *Instance) setState(state State) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.state = state
+}
+
+// Restarts returns how many times the service instance was restarted after failure.
+func (i *Instance) Restarts() int {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.restarts
+}
+
+// LastError returns the last error the service instance failed with.
+func (i *Instance) LastError() error {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.lastError
+}
+
+// PortMappings returns status of the ports mapped on the gateway by the service instance.
+func (i *Instance) PortMappings() []mapping.Status {
+	i.lock.RLock()
+	service := i.service
+	i.lock.RUnlock()
+
+	if reporter, ok := service.(PortMappingReporter); ok {
+		return reporter.PortMappings()
+	}
+	return nil
+}
+
+// startDraining switches running instance to Draining state.
+// False is returned if the instance is not running, so there is nothing to drain.
+func (i *Instance) startDraining() (bool, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	switch i.state {
+	case Draining:
+		return false, ErrInstanceDraining
+	case Running:
+		i.state = Draining
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// drain unregisters the proposal of the instance and waits until its sessions end or the timeout passes
+func (i *Instance) drain(timeout time.Duration) {
+	i.lock.Lock()
+	discovery := i.discovery
+	dialogHandler := i.dialogHandler
+	i.discovery = nil
+	i.lock.Unlock()
+
+	if discovery != nil {
+		discovery.Stop()
+	}
+	if drainer, ok := dialogHandler.(SessionDrainer); ok {
+		drainer.DrainSessions(timeout)
+	}
+}
+
+func (i *Instance) setFailed(err error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.lastError = err
+}
+
+func (i *Instance) setRestarted() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.restarts++
+}
+
+func (i *Instance) setRunnables(
+	service Service,
+	proposal market.ServiceProposal,
+	dialogWaiter communication.DialogWaiter,
+	dialogHandler communication.DialogHandler,
+	discovery Discovery,
+) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.service = service
+	i.proposal = proposal
+	i.dialogWaiter = dialogWaiter
+	i.dialogHandler = dialogHandler
+	i.discovery = discovery
+}
+
+// stop kills all sub-resources of instance, so that they could be recreated on restart
+func (i *Instance) stop() error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	errStop := utils.ErrorCollection{}
+	if i.discovery != nil {
+		i.discovery.Stop()
+	}
+	if terminator, ok := i.dialogHandler.(SessionTerminator); ok {
+		terminator.TerminateSessions(session.TerminationReasonServiceStopped)
+	}
+	if i.dialogWaiter != nil {
+		errStop.Add(i.dialogWaiter.Stop())
+	}
+	if i.service != nil {
+		errStop.Add(i.service.Stop())
+	}
+
+	i.discovery = nil
+	i.dialogWaiter = nil
+	i.dialogHandler = nil
+	i.service = nil
+	return errStop.Errorf("ErrorCollection(%s)", ", ")
+}
+
 func generateID() (ID, error) {
 	uid, err := uuid.NewV4()
 	if err != nil {
@@ -0,0 +1,302 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+// ReachabilityTopic is the topic reachability of the service instance is published to whenever it changes
+const ReachabilityTopic = "service-reachability"
+
+// uncheckedInterval is how often services started since the previous check are looked for, so they are checked soon after start
+const uncheckedInterval = 10 * time.Second
+
+// probeTokenLength is the length of random token sent to the probe listener
+const probeTokenLength = 16
+
+var (
+	// ErrProbeUnsupported indicates that reachability of the endpoint on the given network can not be probed
+	ErrProbeUnsupported = errors.New("reachability probe is not supported for the network")
+	// ErrProbeNotReceived is returned when probe datagram did not reach the listener in time
+	ErrProbeNotReceived = errors.New("probe was not received")
+)
+
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
+// EndpointAdvertiser is implemented by services which accept consumers on a fixed endpoint advertised by the provider
+type EndpointAdvertiser interface {
+	AdvertisedEndpoint() (network, address string)
+}
+
+// ProbeListener receives probe datagrams on the public endpoint opened the same way as the session endpoints of service
+type ProbeListener interface {
+	Endpoint() (network, address string)
+	// WaitProbe waits for the datagram carrying the given token
+	WaitProbe(token []byte, timeout time.Duration) error
+	Close() error
+}
+
+// ProbeListenerOpener is implemented by services which open public endpoint for every session, e.g. wireguard
+type ProbeListenerOpener interface {
+	OpenProbeListener() (ProbeListener, error)
+}
+
+// Reachability is the outcome of the check whether the service endpoint can be reached from outside of the provider network.
+// Reachable is meaningful only when the endpoint is Supported by the probe.
+type Reachability struct {
+	Endpoint  string
+	Supported bool
+	Reachable bool
+	Error     error
+	CheckedAt time.Time
+}
+
+// ReachabilityEvent is published when reachability of the service instance changes
+type ReachabilityEvent struct {
+	ID           ID
+	Reachability Reachability
+}
+
+// ProbeRequest asks to probe the service endpoint from outside of the provider network.
+// Endpoint is connected the way consumers of the service type connect to it,
+// or the Token is sent to it in a UDP datagram if it is given.
+type ProbeRequest struct {
+	ProviderID  string
+	ServiceType string
+	Network     string
+	Address     string
+	Token       []byte
+}
+
+// ReachabilityProbe tries to reach the endpoint from outside of the provider network
+type ReachabilityProbe func(request ProbeRequest) error
+
+// ReachabilityChecker periodically checks whether running services can be reached from outside of the provider network
+type ReachabilityChecker struct {
+	instances func() map[ID]*Instance
+	probe     ReachabilityProbe
+	timeout   time.Duration
+	publisher Publisher
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewReachabilityChecker returns new instance of ReachabilityChecker, probe datagrams are awaited no longer than the timeout
+func NewReachabilityChecker(instances func() map[ID]*Instance, probe ReachabilityProbe, timeout time.Duration, publisher Publisher) *ReachabilityChecker {
+	return &ReachabilityChecker{
+		instances: instances,
+		probe:     probe,
+		timeout:   timeout,
+		publisher: publisher,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Check probes public endpoints of the running services and reports the ones which can not be reached
+func (checker *ReachabilityChecker) Check() {
+	checker.check(false)
+}
+
+// check probes endpoints of the running services, only the services which were not checked yet are probed if uncheckedOnly is set
+func (checker *ReachabilityChecker) check(uncheckedOnly bool) {
+	for id, instance := range checker.instances() {
+		if uncheckedOnly && instance.Reachability() != nil {
+			continue
+		}
+
+		network, address, ok, err := checker.probeInstance(instance)
+		if !ok {
+			continue
+		}
+
+		reachability := Reachability{
+			Endpoint:  address,
+			Supported: err != ErrProbeUnsupported,
+			CheckedAt: time.Now(),
+		}
+		if reachability.Supported {
+			reachability.Reachable = err == nil
+			reachability.Error = err
+		}
+		if reachability.Supported && err != nil {
+			log.Warnf("%sService %s is not reachable on public endpoint %s/%s: %v", logPrefix, id, network, address, err)
+		}
+		if instance.setReachability(reachability) {
+			checker.publisher.Publish(ReachabilityTopic, ReachabilityEvent{ID: id, Reachability: reachability})
+		}
+	}
+}
+
+// probeInstance probes the endpoint advertised by the running service or the probe endpoint opened by it,
+// false is returned if the service has neither of them.
+func (checker *ReachabilityChecker) probeInstance(instance *Instance) (network, address string, ok bool, err error) {
+	if instance.State() != Running {
+		return "", "", false, nil
+	}
+
+	proposal := instance.Proposal()
+	request := ProbeRequest{ProviderID: proposal.ProviderID, ServiceType: proposal.ServiceType}
+	switch service := instance.runningService().(type) {
+	case EndpointAdvertiser:
+		request.Network, request.Address = service.AdvertisedEndpoint()
+		return request.Network, request.Address, true, checker.probe(request)
+	case ProbeListenerOpener:
+		listener, err := service.OpenProbeListener()
+		if err != nil {
+			return "", "", true, err
+		}
+		defer listener.Close()
+
+		request.Network, request.Address = listener.Endpoint()
+		request.Token = make([]byte, probeTokenLength)
+		if _, err := rand.Read(request.Token); err != nil {
+			return request.Network, request.Address, true, err
+		}
+		if err := checker.probe(request); err != nil {
+			return request.Network, request.Address, true, err
+		}
+		return request.Network, request.Address, true, listener.WaitProbe(request.Token, checker.timeout)
+	default:
+		return "", "", false, nil
+	}
+}
+
+// Start checks reachability of the services right away and then periodically.
+// Services started in between are checked soon after they start.
+func (checker *ReachabilityChecker) Start(interval time.Duration) {
+	go func() {
+		checker.Check()
+		checkedAt := time.Now()
+		for {
+			wait := uncheckedInterval
+			if interval < wait {
+				wait = interval
+			}
+
+			select {
+			case <-checker.stop:
+				return
+			case <-time.After(wait):
+				if time.Since(checkedAt) >= interval {
+					checker.Check()
+					checkedAt = time.Now()
+				} else {
+					checker.check(true)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops periodical reachability checks
+func (checker *ReachabilityChecker) Stop() {
+	checker.stopOnce.Do(func() {
+		close(checker.stop)
+	})
+}
+
+// Reachability returns the outcome of the latest reachability check of the service instance,
+// nil is returned when the check was not done.
+func (i *Instance) Reachability() *Reachability {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	if i.reachability == nil {
+		return nil
+	}
+	reachability := *i.reachability
+	return &reachability
+}
+
+// setReachability stores the check outcome and tells whether reachability of the instance has changed
+func (i *Instance) setReachability(reachability Reachability) bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	changed := i.reachability == nil ||
+		i.reachability.Supported != reachability.Supported ||
+		i.reachability.Reachable != reachability.Reachable ||
+		i.reachability.Endpoint != reachability.Endpoint
+	i.reachability = &reachability
+	return changed
+}
+
+func (i *Instance) runningService() RunnableService {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.service
+}
+
+// udpProbeListener receives probe datagrams on the UDP port opened for them
+type udpProbeListener struct {
+	conn     *net.UDPConn
+	endpoint string
+	release  func()
+}
+
+// NewUDPProbeListener returns ProbeListener receiving datagrams on the given connection,
+// endpoint is the public address of it and release is called once the listener is closed.
+func NewUDPProbeListener(conn *net.UDPConn, endpoint string, release func()) ProbeListener {
+	return &udpProbeListener{
+		conn:     conn,
+		endpoint: endpoint,
+		release:  release,
+	}
+}
+
+func (listener *udpProbeListener) Endpoint() (network, address string) {
+	return "udp", listener.endpoint
+}
+
+func (listener *udpProbeListener) WaitProbe(token []byte, timeout time.Duration) error {
+	if err := listener.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	buffer := make([]byte, 1500)
+	for {
+		n, _, err := listener.conn.ReadFromUDP(buffer)
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return ErrProbeNotReceived
+		}
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(buffer[:n], token) {
+			return nil
+		}
+	}
+}
+
+func (listener *udpProbeListener) Close() error {
+	err := listener.conn.Close()
+	listener.release()
+	return err
+}
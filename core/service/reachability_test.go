@@ -0,0 +1,236 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+type advertisingServiceFake struct {
+	serviceFake
+	network string
+	address string
+}
+
+func (service *advertisingServiceFake) AdvertisedEndpoint() (network, address string) {
+	return service.network, service.address
+}
+
+type publisherFake struct {
+	lock   sync.Mutex
+	events []ReachabilityEvent
+}
+
+func (publisher *publisherFake) Publish(topic string, args ...interface{}) {
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+	if topic == ReachabilityTopic {
+		publisher.events = append(publisher.events, args[0].(ReachabilityEvent))
+	}
+}
+
+func TestReachabilityChecker_PublishesReachabilityChanges(t *testing.T) {
+	service := &advertisingServiceFake{network: "tcp", address: "1.2.3.4:1194"}
+	instance := NewInstance(struct{}{}, Running, service, market.ServiceProposal{ServiceType: "openvpn"}, nil, nil)
+	instances := func() map[ID]*Instance {
+		return map[ID]*Instance{"service-1": instance}
+	}
+	probeErr := errors.New("connection refused")
+	var requests []ProbeRequest
+	probe := func(request ProbeRequest) error {
+		requests = append(requests, request)
+		return probeErr
+	}
+	publisher := &publisherFake{}
+	checker := NewReachabilityChecker(instances, probe, time.Second, publisher)
+
+	checker.Check()
+	checker.Check()
+
+	assert.Len(t, publisher.events, 1)
+	assert.Equal(t, ProbeRequest{ServiceType: "openvpn", Network: "tcp", Address: "1.2.3.4:1194"}, requests[0])
+	assert.Equal(t, ID("service-1"), publisher.events[0].ID)
+	assert.True(t, publisher.events[0].Reachability.Supported)
+	assert.False(t, publisher.events[0].Reachability.Reachable)
+	assert.Equal(t, probeErr, publisher.events[0].Reachability.Error)
+	assert.Equal(t, "1.2.3.4:1194", instance.Reachability().Endpoint)
+
+	probeErr = nil
+	checker.Check()
+
+	assert.Len(t, publisher.events, 2)
+	assert.True(t, publisher.events[1].Reachability.Reachable)
+	assert.True(t, instance.Reachability().Reachable)
+}
+
+func TestReachabilityChecker_SkipsServicesWithoutEndpoint(t *testing.T) {
+	instances := func() map[ID]*Instance {
+		return map[ID]*Instance{
+			"no-endpoint": NewInstance(struct{}{}, Running, &serviceFake{}, market.ServiceProposal{}, nil, nil),
+			"restarting":  NewInstance(struct{}{}, Restarting, &advertisingServiceFake{network: "tcp"}, market.ServiceProposal{}, nil, nil),
+		}
+	}
+	probed := 0
+	probe := func(request ProbeRequest) error {
+		probed++
+		return nil
+	}
+	publisher := &publisherFake{}
+
+	NewReachabilityChecker(instances, probe, time.Second, publisher).Check()
+
+	assert.Equal(t, 0, probed)
+	assert.Empty(t, publisher.events)
+	for _, instance := range instances() {
+		assert.Nil(t, instance.Reachability())
+	}
+}
+
+func TestReachabilityChecker_ReportsUnsupportedEndpoints(t *testing.T) {
+	instance := NewInstance(struct{}{}, Running, &advertisingServiceFake{network: "udp", address: "1.2.3.4:51820"}, market.ServiceProposal{}, nil, nil)
+	instances := func() map[ID]*Instance {
+		return map[ID]*Instance{"udp": instance}
+	}
+	probe := func(request ProbeRequest) error {
+		return ErrProbeUnsupported
+	}
+	publisher := &publisherFake{}
+	checker := NewReachabilityChecker(instances, probe, time.Second, publisher)
+
+	checker.Check()
+	checker.Check()
+
+	assert.Len(t, publisher.events, 1)
+	reachability := instance.Reachability()
+	assert.Equal(t, "1.2.3.4:51820", reachability.Endpoint)
+	assert.False(t, reachability.Supported)
+	assert.False(t, reachability.Reachable)
+	assert.Nil(t, reachability.Error)
+}
+
+type probeListeningServiceFake struct {
+	serviceFake
+	opened int
+}
+
+func (service *probeListeningServiceFake) OpenProbeListener() (ProbeListener, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	service.opened++
+	return NewUDPProbeListener(conn, conn.LocalAddr().String(), func() { service.opened-- }), nil
+}
+
+func sendDatagram(request ProbeRequest) error {
+	conn, err := net.Dial(request.Network, request.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(request.Token)
+	return err
+}
+
+func TestReachabilityChecker_ProbesListenerOfServiceWithoutFixedEndpoint(t *testing.T) {
+	service := &probeListeningServiceFake{}
+	instance := NewInstance(struct{}{}, Running, service, market.ServiceProposal{ServiceType: "wireguard"}, nil, nil)
+	instances := func() map[ID]*Instance {
+		return map[ID]*Instance{"wireguard": instance}
+	}
+	var requests []ProbeRequest
+	probe := func(request ProbeRequest) error {
+		requests = append(requests, request)
+		return sendDatagram(request)
+	}
+	checker := NewReachabilityChecker(instances, probe, time.Second, &publisherFake{})
+
+	checker.Check()
+
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "wireguard", requests[0].ServiceType)
+	assert.Equal(t, "udp", requests[0].Network)
+	assert.Len(t, requests[0].Token, probeTokenLength)
+	assert.True(t, instance.Reachability().Supported)
+	assert.True(t, instance.Reachability().Reachable)
+	assert.Equal(t, requests[0].Address, instance.Reachability().Endpoint)
+	assert.Equal(t, 0, service.opened)
+}
+
+func TestReachabilityChecker_ReportsListenerNotReceivingProbe(t *testing.T) {
+	instance := NewInstance(struct{}{}, Running, &probeListeningServiceFake{}, market.ServiceProposal{ServiceType: "wireguard"}, nil, nil)
+	instances := func() map[ID]*Instance {
+		return map[ID]*Instance{"wireguard": instance}
+	}
+	probe := func(request ProbeRequest) error {
+		request.Token = []byte("other token")
+		return sendDatagram(request)
+	}
+
+	NewReachabilityChecker(instances, probe, 50*time.Millisecond, &publisherFake{}).Check()
+
+	assert.False(t, instance.Reachability().Reachable)
+	assert.Equal(t, ErrProbeNotReceived, instance.Reachability().Error)
+}
+
+func TestReachabilityChecker_StartChecksRightAwayAndServicesStartedLater(t *testing.T) {
+	var lock sync.Mutex
+	running := map[ID]*Instance{
+		"first": NewInstance(struct{}{}, Running, &advertisingServiceFake{network: "tcp"}, market.ServiceProposal{}, nil, nil),
+	}
+	instances := func() map[ID]*Instance {
+		lock.Lock()
+		defer lock.Unlock()
+		copied := make(map[ID]*Instance, len(running))
+		for id, instance := range running {
+			copied[id] = instance
+		}
+		return copied
+	}
+	probed := make(chan struct{}, 10)
+	probe := func(request ProbeRequest) error {
+		probed <- struct{}{}
+		return nil
+	}
+	checker := NewReachabilityChecker(instances, probe, time.Second, &publisherFake{})
+
+	checker.Start(time.Hour)
+	defer checker.Stop()
+
+	first := instances()["first"]
+	waitUntil(t, func() bool {
+		return first.Reachability() != nil
+	})
+	assert.Len(t, probed, 1)
+	<-probed
+
+	lock.Lock()
+	running["second"] = NewInstance(struct{}{}, Running, &advertisingServiceFake{network: "tcp"}, market.ServiceProposal{}, nil, nil)
+	lock.Unlock()
+	checker.check(true)
+
+	assert.Len(t, probed, 1)
+	assert.NotNil(t, running["second"].Reachability())
+}
@@ -18,21 +18,27 @@
 package service
 
 import (
+	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/market"
 )
 
 // RegistryFactory initiates instance which is able to serve
 type RegistryFactory func(options Options) (Service, market.ServiceProposal, error)
 
+// ProposalFactory creates proposal of the service for the given provider location
+type ProposalFactory func(options Options, location location.ServiceLocationInfo) market.ServiceProposal
+
 // Registry holds all pluggable services
 type Registry struct {
-	factories map[string]RegistryFactory
+	factories         map[string]RegistryFactory
+	proposalFactories map[string]ProposalFactory
 }
 
 // NewRegistry creates a registry of pluggable services
 func NewRegistry() *Registry {
 	return &Registry{
-		factories: make(map[string]RegistryFactory),
+		factories:         make(map[string]RegistryFactory),
+		proposalFactories: make(map[string]ProposalFactory),
 	}
 }
 
@@ -41,6 +47,12 @@ func (registry *Registry) Register(serviceType string, creator RegistryFactory)
 	registry.factories[serviceType] = creator
 }
 
+// RegisterProposalFactory registers proposal factory of pluggable service,
+// it is used to announce running service again when provider location changes
+func (registry *Registry) RegisterProposalFactory(serviceType string, creator ProposalFactory) {
+	registry.proposalFactories[serviceType] = creator
+}
+
 // Create creates pluggable service
 func (registry *Registry) Create(serviceType string, options Options) (Service, market.ServiceProposal, error) {
 	createService, exists := registry.factories[serviceType]
@@ -50,3 +62,13 @@ func (registry *Registry) Create(serviceType string, options Options) (Service,
 
 	return createService(options)
 }
+
+// CreateProposal creates proposal of pluggable service for the given provider location
+func (registry *Registry) CreateProposal(serviceType string, options Options, location location.ServiceLocationInfo) (market.ServiceProposal, error) {
+	createProposal, exists := registry.proposalFactories[serviceType]
+	if !exists {
+		return market.ServiceProposal{}, ErrUnsupportedServiceType
+	}
+
+	return createProposal(options, location), nil
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/stretchr/testify/assert"
 )
@@ -85,6 +86,38 @@ func TestRegistry_Create_BubblesErrors(t *testing.T) {
 	assert.Exactly(t, fakeErr, err)
 }
 
+func TestRegistry_CreateProposal_NonExisting(t *testing.T) {
+	registry := NewRegistry()
+
+	proposal, err := registry.CreateProposal("missing-service", nil, location.ServiceLocationInfo{})
+	assert.Equal(t, proposalMock, proposal)
+	assert.Equal(t, ErrUnsupportedServiceType, err)
+}
+
+func TestRegistry_CreateProposal_Existing(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterProposalFactory(serviceType, fakeProposalFactory)
+
+	proposal, err := registry.CreateProposal(serviceType, nil, location.ServiceLocationInfo{Country: "LT"})
+	assert.Equal(t, "LT", proposal.ServiceDefinition.GetLocation().Country)
+	assert.NoError(t, err)
+}
+
+func fakeProposalFactory(options Options, location location.ServiceLocationInfo) market.ServiceProposal {
+	return market.ServiceProposal{
+		ServiceType:       serviceType,
+		ServiceDefinition: fakeServiceDefinition{location: market.Location{Country: location.Country}},
+	}
+}
+
+type fakeServiceDefinition struct {
+	location market.Location
+}
+
+func (definition fakeServiceDefinition) GetLocation() market.Location {
+	return definition.location
+}
+
 func mockRegistryEmpty() *Registry {
 	return &Registry{
 		factories: map[string]RegistryFactory{},
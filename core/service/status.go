@@ -27,4 +27,8 @@ const (
 	Starting = State("Starting")
 	// Running means that fully established service exists
 	Running = State("Running")
+	// Restarting means that service has failed and is waiting to be restarted
+	Restarting = State("Restarting")
+	// Draining means that service does not accept new sessions and waits for the existing ones to end before stopping
+	Draining = State("Draining")
 )
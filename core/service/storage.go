@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+const serviceBucketName = "services"
+
+// ErrOptionsParserNotFound indicates that persisted service options can't be parsed for the given service type
+var ErrOptionsParserNotFound = errors.New("options parser not found for service type")
+
+// Storer allows us to get all services, save and delete them
+type Storer interface {
+	Store(bucket string, object interface{}) error
+	Delete(bucket string, object interface{}) error
+	GetAllFrom(bucket string, array interface{}) error
+}
+
+// OptionsParser parses persisted options to service specific options
+type OptionsParser func(*json.RawMessage) (Options, error)
+
+// StoredService is a representation of the started service in storage
+type StoredService struct {
+	ID         ID `storm:"id"`
+	ProviderID identity.Identity
+	Type       string
+	Options    json.RawMessage
+	AutoStart  bool
+}
+
+// Storage persists started services, so that they could be restored after node restart
+type Storage struct {
+	storage        Storer
+	optionsParsers map[string]OptionsParser
+}
+
+// NewStorage creates service storage with given dependencies
+func NewStorage(storage Storer, optionsParsers map[string]OptionsParser) *Storage {
+	return &Storage{
+		storage:        storage,
+		optionsParsers: optionsParsers,
+	}
+}
+
+// Store persists the started service with the given options
+func (s *Storage) Store(id ID, providerID identity.Identity, serviceType string, options Options, autoStart bool) error {
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	return s.storage.Store(serviceBucketName, &StoredService{
+		ID:         id,
+		ProviderID: providerID,
+		Type:       serviceType,
+		Options:    optionsJSON,
+		AutoStart:  autoStart,
+	})
+}
+
+// Delete removes the persisted service
+func (s *Storage) Delete(id ID) error {
+	return s.storage.Delete(serviceBucketName, &StoredService{ID: id})
+}
+
+// GetAll returns all persisted services
+func (s *Storage) GetAll() ([]StoredService, error) {
+	var services []StoredService
+	err := s.storage.GetAllFrom(serviceBucketName, &services)
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// ParseOptions parses persisted options of the service to service specific options
+func (s *Storage) ParseOptions(stored StoredService) (Options, error) {
+	parse, ok := s.optionsParsers[stored.Type]
+	if !ok {
+		return nil, ErrOptionsParserNotFound
+	}
+
+	return parse(&stored.Options)
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOptions struct {
+	Port int `json:"port"`
+}
+
+func parseTestOptions(request *json.RawMessage) (Options, error) {
+	var opts testOptions
+	err := json.Unmarshal(*request, &opts)
+	return opts, err
+}
+
+type storerFake struct {
+	services map[ID]StoredService
+}
+
+func newStorerFake() *storerFake {
+	return &storerFake{services: make(map[ID]StoredService)}
+}
+
+func (sf *storerFake) Store(bucket string, object interface{}) error {
+	stored := object.(*StoredService)
+	sf.services[stored.ID] = *stored
+	return nil
+}
+
+func (sf *storerFake) Delete(bucket string, object interface{}) error {
+	delete(sf.services, object.(*StoredService).ID)
+	return nil
+}
+
+func (sf *storerFake) GetAllFrom(bucket string, array interface{}) error {
+	services := array.(*[]StoredService)
+	for _, stored := range sf.services {
+		*services = append(*services, stored)
+	}
+	return nil
+}
+
+func newTestStorage(storer Storer) *Storage {
+	return NewStorage(storer, map[string]OptionsParser{serviceType: parseTestOptions})
+}
+
+func TestStorage_StoreAndParseOptions(t *testing.T) {
+	storage := newTestStorage(newStorerFake())
+	providerID := identity.FromAddress("0x1")
+
+	err := storage.Store("id-1", providerID, serviceType, testOptions{Port: 1194}, true)
+	assert.NoError(t, err)
+
+	services, err := storage.GetAll()
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, ID("id-1"), services[0].ID)
+	assert.Equal(t, providerID, services[0].ProviderID)
+	assert.Equal(t, serviceType, services[0].Type)
+	assert.True(t, services[0].AutoStart)
+
+	options, err := storage.ParseOptions(services[0])
+	assert.NoError(t, err)
+	assert.Equal(t, testOptions{Port: 1194}, options)
+}
+
+func TestStorage_Delete(t *testing.T) {
+	storage := newTestStorage(newStorerFake())
+	assert.NoError(t, storage.Store("id-1", identity.FromAddress("0x1"), serviceType, nil, true))
+
+	assert.NoError(t, storage.Delete("id-1"))
+
+	services, err := storage.GetAll()
+	assert.NoError(t, err)
+	assert.Len(t, services, 0)
+}
+
+func TestStorage_ParseOptionsOfUnknownType(t *testing.T) {
+	storage := newTestStorage(newStorerFake())
+
+	_, err := storage.ParseOptions(StoredService{Type: "unknown"})
+	assert.Equal(t, ErrOptionsParserNotFound, err)
+}
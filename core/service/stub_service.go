@@ -62,6 +62,7 @@ type mockDialogWaiter struct {
 	stopErr  error
 	serveErr error
 	startErr error
+	stopped  bool
 }
 
 func (mdw *mockDialogWaiter) Start() (market.Contact, error) {
@@ -69,6 +70,7 @@ func (mdw *mockDialogWaiter) Start() (market.Contact, error) {
 }
 
 func (mdw *mockDialogWaiter) Stop() error {
+	mdw.stopped = true
 	return mdw.stopErr
 }
 
@@ -94,12 +96,28 @@ func MockDialogHandlerFactory(market.ServiceProposal, session.ConfigNegotiator)
 }
 
 type mockDiscovery struct {
-	wg sync.WaitGroup
+	wg       sync.WaitGroup
+	lock     sync.Mutex
+	proposal market.ServiceProposal
 }
 
 func (mds *mockDiscovery) Start(ownIdentity identity.Identity, proposal market.ServiceProposal) {
 	mds.wg.Add(1)
+	mds.UpdateProposal(proposal)
 }
+
+func (mds *mockDiscovery) UpdateProposal(proposal market.ServiceProposal) {
+	mds.lock.Lock()
+	defer mds.lock.Unlock()
+	mds.proposal = proposal
+}
+
+func (mds *mockDiscovery) announcedProposal() market.ServiceProposal {
+	mds.lock.Lock()
+	defer mds.lock.Unlock()
+	return mds.proposal
+}
+
 func (mds *mockDiscovery) Stop() {
 	mds.wg.Done()
 }
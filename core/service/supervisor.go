@@ -0,0 +1,141 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+// RestartPolicy defines how the services which failed while serving are restarted
+type RestartPolicy struct {
+	// MaxRestarts is the number of consecutive restarts after which failing service is stopped, zero disables restarts
+	MaxRestarts int
+	// Backoff is the delay before the first restart, it is doubled after every consecutive failure
+	Backoff time.Duration
+	// MaxBackoff limits the delay between restarts, zero means no limit.
+	// Service serving longer than the largest of Backoff and MaxBackoff is considered recovered
+	MaxBackoff time.Duration
+}
+
+// healthyPeriod returns how long service has to serve to have its consecutive failures forgotten
+func (policy RestartPolicy) healthyPeriod() time.Duration {
+	if policy.MaxBackoff > policy.Backoff {
+		return policy.MaxBackoff
+	}
+	return policy.Backoff
+}
+
+// backoff returns the delay before restart after the given number of consecutive failures
+func (policy RestartPolicy) backoff(failures int) time.Duration {
+	backoff := policy.Backoff
+	for i := 1; i < failures; i++ {
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff >= policy.MaxBackoff {
+			break
+		}
+	}
+
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		return policy.MaxBackoff
+	}
+	return backoff
+}
+
+// supervise serves the service instance and restarts it according to restart policy when serving fails.
+// Restarted service keeps the same id, its proposal is registered again through the discovery.
+func (manager *Manager) supervise(id ID, providerID identity.Identity, serviceType string, instance *Instance, service Service, discovery Discovery) {
+	failures := 0
+	for {
+		instance.setState(Running)
+		started := time.Now()
+		serveErr := service.Serve(providerID)
+		if serveErr != nil {
+			log.Error(logPrefix, "Service serve failed: ", serveErr)
+		}
+
+		if serveErr == nil || !manager.isSupervised(id, instance) || instance.State() == Draining {
+			// service was stopped by request or is not restarted while draining
+			manager.stopSupervised(id, instance, discovery)
+			return
+		}
+
+		instance.setFailed(serveErr)
+		if healthyPeriod := manager.restartPolicy.healthyPeriod(); healthyPeriod > 0 && time.Since(started) > healthyPeriod {
+			failures = 0
+		}
+
+		for {
+			failures++
+			if failures > manager.restartPolicy.MaxRestarts {
+				log.Error(logPrefix, "Service ", id, " failed too many times, giving up")
+				manager.stopSupervised(id, instance, discovery)
+				return
+			}
+
+			instance.setState(Restarting)
+			if err := instance.stop(); err != nil {
+				log.Warn(logPrefix, "Failed to stop service ", id, " before restart: ", err)
+			}
+			discovery.Wait()
+
+			backoff := manager.restartPolicy.backoff(failures)
+			log.Info(logPrefix, "Restarting service ", id, " in ", backoff)
+			time.Sleep(backoff)
+			if !manager.isSupervised(id, instance) {
+				return
+			}
+
+			instance.setRestarted()
+			var err error
+			service, discovery, err = manager.run(providerID, serviceType, instance)
+			if err == nil {
+				break
+			}
+			log.Error(logPrefix, "Service ", id, " restart failed: ", err)
+			instance.setFailed(err)
+		}
+
+		if !manager.isSupervised(id, instance) {
+			// service was stopped by request during restart
+			manager.stopSupervised(id, instance, discovery)
+			return
+		}
+	}
+}
+
+// isSupervised checks whether the service instance was not stopped by request
+func (manager *Manager) isSupervised(id ID, instance *Instance) bool {
+	return manager.servicePool.Instance(id) == instance
+}
+
+func (manager *Manager) stopSupervised(id ID, instance *Instance, discovery Discovery) {
+	instance.setState(NotRunning)
+
+	if manager.isSupervised(id, instance) {
+		if err := manager.servicePool.Stop(id); err != nil {
+			log.Error(logPrefix, "Service stop failed: ", err)
+		}
+	} else if err := instance.stop(); err != nil {
+		log.Error(logPrefix, "Service stop failed: ", err)
+	}
+
+	discovery.Wait()
+}
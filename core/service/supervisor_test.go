@@ -0,0 +1,184 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRestartPolicy = RestartPolicy{
+	MaxRestarts: 2,
+	Backoff:     time.Millisecond,
+	MaxBackoff:  time.Second,
+}
+
+type countingDiscovery struct {
+	mockDiscovery
+	started int
+	lock    sync.Mutex
+}
+
+func (cd *countingDiscovery) Start(ownIdentity identity.Identity, proposal market.ServiceProposal) {
+	cd.lock.Lock()
+	cd.started++
+	cd.lock.Unlock()
+	cd.mockDiscovery.Start(ownIdentity, proposal)
+}
+
+func (cd *countingDiscovery) Started() int {
+	cd.lock.Lock()
+	defer cd.lock.Unlock()
+	return cd.started
+}
+
+func waitUntil(t *testing.T, condition func() bool) {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition was not met in time")
+}
+
+func TestRestartPolicy_Backoff(t *testing.T) {
+	policy := RestartPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	assert.Equal(t, time.Second, policy.backoff(1))
+	assert.Equal(t, 2*time.Second, policy.backoff(2))
+	assert.Equal(t, 4*time.Second, policy.backoff(3))
+	assert.Equal(t, 5*time.Second, policy.backoff(4))
+	assert.Equal(t, 5*time.Second, policy.backoff(100))
+}
+
+func TestManager_RestartsFailedServiceWithSameID(t *testing.T) {
+	registry := NewRegistry()
+	var created int
+	var lock sync.Mutex
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		created++
+		if created == 1 {
+			return &serviceFake{onStartReturnError: errors.New("process died")}, proposalMock, nil
+		}
+		return &serviceFake{mockProcess: make(chan struct{})}, proposalMock, nil
+	})
+
+	discovery := &countingDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(discovery),
+		newTestStorage(newStorerFake()),
+		testRestartPolicy,
+	)
+	id, err := manager.Start(identity.FromAddress("0x1"), serviceType, testOptions{})
+	assert.NoError(t, err)
+
+	instance := manager.Service(id)
+	waitUntil(t, func() bool {
+		return instance.Restarts() == 1 && instance.State() == Running
+	})
+	assert.Equal(t, instance, manager.Service(id))
+	assert.EqualError(t, instance.LastError(), "process died")
+	assert.Equal(t, 2, discovery.Started())
+
+	assert.NoError(t, manager.Stop(id))
+	discovery.Wait()
+	assert.Len(t, manager.List(), 0)
+}
+
+func TestManager_StopsServiceAfterTooManyRestarts(t *testing.T) {
+	registry := NewRegistry()
+	var created int
+	var lock sync.Mutex
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		created++
+		return &serviceFake{onStartReturnError: errors.New("process died")}, proposalMock, nil
+	})
+
+	discovery := &countingDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(discovery),
+		newTestStorage(newStorerFake()),
+		testRestartPolicy,
+	)
+	_, err := manager.Start(identity.FromAddress("0x1"), serviceType, testOptions{})
+	assert.NoError(t, err)
+
+	waitUntil(t, func() bool {
+		return len(manager.List()) == 0
+	})
+	discovery.Wait()
+	assert.Equal(t, 3, discovery.Started())
+	lock.Lock()
+	assert.Equal(t, 3, created)
+	lock.Unlock()
+}
+
+func TestManager_StopsServiceAfterTooManyRestartsWithoutBackoffLimit(t *testing.T) {
+	registry := NewRegistry()
+	var created int
+	var lock sync.Mutex
+	registry.Register(serviceType, func(options Options) (Service, market.ServiceProposal, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		created++
+		return &serviceFake{onStartReturnError: errors.New("process died")}, proposalMock, nil
+	})
+
+	discovery := &countingDiscovery{}
+	manager := NewManager(
+		registry,
+		MockDialogWaiterFactory,
+		MockDialogHandlerFactory,
+		MockDiscoveryFactoryFunc(discovery),
+		newTestStorage(newStorerFake()),
+		RestartPolicy{MaxRestarts: 2, Backoff: 20 * time.Millisecond, MaxBackoff: 0},
+	)
+	_, err := manager.Start(identity.FromAddress("0x1"), serviceType, testOptions{})
+	assert.NoError(t, err)
+
+	waitUntil(t, func() bool {
+		return len(manager.List()) == 0
+	})
+	discovery.Wait()
+	lock.Lock()
+	assert.Equal(t, 3, created)
+	lock.Unlock()
+}
+
+func TestRestartPolicy_HealthyPeriod(t *testing.T) {
+	assert.Equal(t, 5*time.Second, RestartPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}.healthyPeriod())
+	assert.Equal(t, time.Second, RestartPolicy{Backoff: time.Second}.healthyPeriod())
+	assert.Equal(t, time.Duration(0), RestartPolicy{}.healthyPeriod())
+}
@@ -74,6 +74,15 @@ func (b *Bolt) GetBuckets() []string {
 	return b.db.Bucket()
 }
 
+// Check verifies that database is open and can be read
+func (b *Bolt) Check() error {
+	tx, err := b.db.Begin(false)
+	if err != nil {
+		return err
+	}
+	return tx.Rollback()
+}
+
 // Close closes database
 func (b *Bolt) Close() error {
 	return b.db.Close()
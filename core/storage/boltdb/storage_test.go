@@ -113,3 +113,15 @@ func Test_StorageGetLast_ErrsOnEmpty(t *testing.T) {
 	err = storage.GetLast(bucket, &result)
 	assert.Equal(t, "not found", err.Error())
 }
+
+func Test_StorageCheck(t *testing.T) {
+	dir := boltdbtest.CreateTempDir(t)
+	defer boltdbtest.RemoveTempDir(t, dir)
+
+	storage, err := NewStorage(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, storage.Check())
+
+	storage.Close()
+	assert.NotNil(t, storage.Check())
+}
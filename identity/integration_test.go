@@ -26,7 +26,7 @@ import (
 func Test_UnlockAndSignAndVerify(t *testing.T) {
 	ks := NewKeystoreFilesystem("test_data", true)
 
-	manager := NewIdentityManager(ks)
+	manager := NewIdentityManager(ks, &publisherFake{})
 	err := manager.Unlock("0x53a835143c0ef3bbcbfa796d7eb738ca7dd28f68", "")
 	assert.NoError(t, err)
 
@@ -27,14 +27,24 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// IdentityUnlockTopic is the topic on which address of the unlocked identity is published
+const IdentityUnlockTopic = "identity-unlocked"
+
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
 type identityManager struct {
 	keystoreManager Keystore
+	eventPublisher  Publisher
 }
 
 // NewIdentityManager creates and returns new identityManager
-func NewIdentityManager(keystore Keystore) *identityManager {
+func NewIdentityManager(keystore Keystore, eventPublisher Publisher) *identityManager {
 	return &identityManager{
 		keystoreManager: keystore,
+		eventPublisher:  eventPublisher,
 	}
 }
 
@@ -93,7 +103,12 @@ func (idm *identityManager) Unlock(address string, passphrase string) error {
 		return err
 	}
 
-	return idm.keystoreManager.Unlock(account, passphrase)
+	if err = idm.keystoreManager.Unlock(account, passphrase); err != nil {
+		return err
+	}
+
+	idm.eventPublisher.Publish(IdentityUnlockTopic, accountToIdentity(account).Address)
+	return nil
 }
 
 func (idm *identityManager) findAccount(address string) (accounts.Account, error) {
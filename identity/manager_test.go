@@ -32,6 +32,7 @@ func newManager(accountValue string) *identityManager {
 				addressToAccount(accountValue),
 			},
 		},
+		eventPublisher: &publisherFake{},
 	}
 }
 
@@ -40,6 +41,7 @@ func newManagerWithError(errorMock error) *identityManager {
 		keystoreManager: &keyStoreFake{
 			ErrorMock: errorMock,
 		},
+		eventPublisher: &publisherFake{},
 	}
 }
 
@@ -95,3 +97,34 @@ func TestManager_HasIdentity(t *testing.T) {
 	assert.True(t, manager.HasIdentity("0x000000000000000000000000000000000000000a"))
 	assert.False(t, manager.HasIdentity("0x000000000000000000000000000000000000000B"))
 }
+
+func TestManager_UnlockPublishesUnlockedIdentity(t *testing.T) {
+	manager := newManager("0x000000000000000000000000000000000000000A")
+
+	err := manager.Unlock("0x000000000000000000000000000000000000000A", "")
+	assert.NoError(t, err)
+
+	publisher := manager.eventPublisher.(*publisherFake)
+	assert.Equal(t, IdentityUnlockTopic, publisher.publishedTopic)
+	assert.Equal(t, []interface{}{"0x000000000000000000000000000000000000000a"}, publisher.publishedArgs)
+}
+
+func TestManager_UnlockNotPublishedOnError(t *testing.T) {
+	manager := newManager("0x000000000000000000000000000000000000000A")
+
+	err := manager.Unlock("0x000000000000000000000000000000000000000B", "")
+	assert.Error(t, err)
+
+	publisher := manager.eventPublisher.(*publisherFake)
+	assert.Empty(t, publisher.publishedTopic)
+}
+
+type publisherFake struct {
+	publishedTopic string
+	publishedArgs  []interface{}
+}
+
+func (publisher *publisherFake) Publish(topic string, args ...interface{}) {
+	publisher.publishedTopic = topic
+	publisher.publishedArgs = args
+}
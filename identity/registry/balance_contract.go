@@ -0,0 +1,48 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package registry
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/payments/contracts/abigen"
+)
+
+// NewIdentityBalanceContract creates identity balance provider which reads balances from payments contract
+func NewIdentityBalanceContract(contractBackend bind.ContractBackend, paymentsAddress common.Address) (identity.Balance, error) {
+	contract, err := abigen.NewIdentityPromisesCaller(paymentsAddress, contractBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	contractSession := &abigen.IdentityPromisesCallerSession{
+		Contract: contract,
+		CallOpts: bind.CallOpts{
+			Pending: false, //we want to find out the confirmed balance - not pending transactions
+		},
+	}
+
+	return func(id identity.Identity) (uint64, error) {
+		balance, err := contractSession.Balances(common.HexToAddress(id.Address))
+		if err != nil {
+			return 0, err
+		}
+		return balance.Uint64(), nil
+	}, nil
+}
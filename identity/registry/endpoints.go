@@ -65,6 +65,14 @@ type RegistrationDataDTO struct {
 	Signature SignatureDTO `json:"signature"`
 }
 
+// BalanceDTO represents identity balance in payments contract
+//
+// swagger:model BalanceDTO
+type BalanceDTO struct {
+	// example: 1000
+	Balance uint64 `json:"balance"`
+}
+
 type registrationEndpoint struct {
 	dataProvider   RegistrationDataProvider
 	statusProvider IdentityRegistry
@@ -126,6 +134,50 @@ func (endpoint *registrationEndpoint) IdentityRegistrationData(resp http.Respons
 	utils.WriteAsJSON(registrationDataDTO, resp)
 }
 
+type balanceEndpoint struct {
+	balance identity.Balance
+}
+
+// swagger:operation GET /identities/{id}/balance Identity identityBalance
+// ---
+// summary: Provide identity balance
+// description: Provides balance of given identity in payments contract
+// parameters:
+//   - in: path
+//     name: id
+//     description: hex address of identity
+//     type: string
+//     required: true
+// responses:
+//   200:
+//     description: Identity balance
+//     schema:
+//       "$ref": "#/definitions/BalanceDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *balanceEndpoint) IdentityBalance(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	id := identity.FromAddress(params.ByName("id"))
+
+	balance, err := endpoint.balance(id)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteAsJSON(&BalanceDTO{Balance: balance}, resp)
+}
+
+// AddIdentityBalanceEndpoint adds identity balance endpoint to given http router
+func AddIdentityBalanceEndpoint(router *httprouter.Router, balance identity.Balance) {
+	balanceEndpoint := &balanceEndpoint{
+		balance: balance,
+	}
+
+	router.GET("/identities/:id/balance", balanceEndpoint.IdentityBalance)
+}
+
 // AddIdentityRegistrationEndpoint adds identity registration data endpoint to given http router
 func AddIdentityRegistrationEndpoint(router *httprouter.Router, dataProvider RegistrationDataProvider, statusProvider IdentityRegistry) {
 
@@ -18,6 +18,7 @@
 package registry
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -121,3 +122,47 @@ func (m *mockRegistrationDataProvider) ProvideRegistrationData(id identity.Ident
 	m.RecordedIdentity = id
 	return m.RegistrationData, nil
 }
+
+func TestIdentityBalanceEndpointReturnsBalance(t *testing.T) {
+	var recordedIdentity identity.Identity
+	endpoint := &balanceEndpoint{
+		balance: func(id identity.Identity) (uint64, error) {
+			recordedIdentity = id
+			return 1000, nil
+		},
+	}
+
+	resp := httptest.NewRecorder()
+	endpoint.IdentityBalance(
+		resp,
+		httptest.NewRequest(http.MethodGet, "/notimportant", nil),
+		httprouter.Params{
+			httprouter.Param{
+				Key:   "id",
+				Value: "0x1231323131",
+			},
+		},
+	)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, identity.FromAddress("0x1231323131"), recordedIdentity)
+	assert.JSONEq(t, `{"balance": 1000}`, resp.Body.String())
+}
+
+func TestIdentityBalanceEndpointReturnsError(t *testing.T) {
+	endpoint := &balanceEndpoint{
+		balance: func(id identity.Identity) (uint64, error) {
+			return 0, errors.New("contract unavailable")
+		},
+	}
+
+	resp := httptest.NewRecorder()
+	endpoint.IdentityBalance(
+		resp,
+		httptest.NewRequest(http.MethodGet, "/notimportant", nil),
+		httprouter.Params{},
+	)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"message": "contract unavailable"}`, resp.Body.String())
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/mysteriumnetwork/payments/contracts/abigen"
+)
+
+var errHeadsNotSupported = errors.New("backend does not provide chain heads")
+
+// registrationFilterer looks up and watches registration events of the registry contract
+type registrationFilterer interface {
+	FilterRegistered(opts *bind.FilterOpts, identity []common.Address) (*abigen.IdentityPromisesRegisteredIterator, error)
+	WatchRegistered(opts *bind.WatchOpts, sink chan<- *abigen.IdentityPromisesRegistered, identity []common.Address) (event.Subscription, error)
+}
+
+// headReader provides the latest block of the chain, i.e. ethclient.Client does
+type headReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// registrationWatcher is shared by all subscribers waiting for registration of their identities.
+// Registration events are followed by subscription when backend supports it, otherwise
+// only the blocks mined since the last checkpoint are scanned periodically.
+type registrationWatcher struct {
+	filterer     registrationFilterer
+	heads        headReader
+	pollInterval time.Duration
+
+	subscribers map[chan RegistrationEvent]common.Address
+	checkpoint  uint64
+	stop        chan struct{}
+	lock        sync.Mutex
+}
+
+// newRegistrationWatcher creates watcher of registration events. Without heads reader
+// the checkpoint can not be advanced, so each scan starts from the beginning of the chain.
+func newRegistrationWatcher(filterer registrationFilterer, heads headReader) *registrationWatcher {
+	return &registrationWatcher{
+		filterer:     filterer,
+		heads:        heads,
+		pollInterval: 10 * time.Second,
+		subscribers:  make(map[chan RegistrationEvent]common.Address),
+	}
+}
+
+// watch waits for registration of the given address, the watcher is started with its first subscriber
+func (watcher *registrationWatcher) watch(address common.Address) (registrationEvent chan RegistrationEvent, unsubscribe func()) {
+	registrationEvent = make(chan RegistrationEvent, 1)
+
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+
+	watcher.subscribers[registrationEvent] = address
+	if watcher.stop == nil {
+		watcher.start()
+	}
+
+	unsubscribe = func() {
+		watcher.deliver(registrationEvent, Cancelled)
+	}
+	return registrationEvent, unsubscribe
+}
+
+// deliver sends the only event to the subscriber and forgets it, the watcher is stopped with its last subscriber
+func (watcher *registrationWatcher) deliver(registrationEvent chan RegistrationEvent, eventType RegistrationEvent) {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+
+	if _, exists := watcher.subscribers[registrationEvent]; !exists {
+		return
+	}
+	delete(watcher.subscribers, registrationEvent)
+	registrationEvent <- eventType
+
+	if len(watcher.subscribers) == 0 && watcher.stop != nil {
+		close(watcher.stop)
+		watcher.stop = nil
+	}
+}
+
+func (watcher *registrationWatcher) start() {
+	// registrations mined before the subscribers came are checked by them, there is no need to scan older blocks
+	if head, err := watcher.head(); err == nil && head > watcher.checkpoint {
+		watcher.checkpoint = head
+	}
+
+	watcher.stop = make(chan struct{})
+	go watcher.run(watcher.stop)
+}
+
+func (watcher *registrationWatcher) run(stop <-chan struct{}) {
+	for {
+		sink := make(chan *abigen.IdentityPromisesRegistered)
+		subscription, err := watcher.filterer.WatchRegistered(&bind.WatchOpts{Context: context.Background()}, sink, nil)
+		if err != nil {
+			log.Trace(logPrefix, "registration events subscription is not available, polling: ", err)
+		}
+
+		// events mined before the subscription was established are picked up by scanning
+		watcher.scan()
+
+		if err == nil {
+			stopped := watcher.follow(subscription, sink, stop)
+			subscription.Unsubscribe()
+			if stopped {
+				return
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(watcher.pollInterval):
+		}
+	}
+}
+
+// follow delivers subscribed events until the watcher is stopped (returns true) or the subscription fails
+func (watcher *registrationWatcher) follow(subscription event.Subscription, sink <-chan *abigen.IdentityPromisesRegistered, stop <-chan struct{}) bool {
+	for {
+		select {
+		case <-stop:
+			return true
+		case registered := <-sink:
+			watcher.notify(registered.Identity)
+		case err := <-subscription.Err():
+			log.Warn(logPrefix, "registration events subscription failed: ", err)
+			return false
+		}
+	}
+}
+
+// scan looks up registrations of the subscribers in the blocks mined since the last checkpoint
+func (watcher *registrationWatcher) scan() {
+	watcher.lock.Lock()
+	start := watcher.checkpoint
+	addresses := make([]common.Address, 0, len(watcher.subscribers))
+	for _, address := range watcher.subscribers {
+		addresses = append(addresses, address)
+	}
+	watcher.lock.Unlock()
+
+	if len(addresses) == 0 {
+		return
+	}
+
+	filterOpts := &bind.FilterOpts{Start: start, Context: context.Background()}
+	if watcher.heads != nil {
+		head, err := watcher.head()
+		if err != nil {
+			log.Warn(logPrefix, "failed to get the latest block: ", err)
+			return
+		}
+		if head < start {
+			return
+		}
+		filterOpts.End = &head
+	}
+
+	logIterator, err := watcher.filterer.FilterRegistered(filterOpts, addresses)
+	if err != nil {
+		log.Warn(logPrefix, "failed to look up registration events: ", err)
+		return
+	}
+	defer logIterator.Close()
+
+	var registered []common.Address
+	for logIterator.Next() {
+		registered = append(registered, logIterator.Event.Identity)
+	}
+	if err := logIterator.Error(); err != nil {
+		log.Warn(logPrefix, "failed to look up registration events: ", err)
+		return
+	}
+
+	if filterOpts.End != nil {
+		watcher.lock.Lock()
+		if *filterOpts.End+1 > watcher.checkpoint {
+			watcher.checkpoint = *filterOpts.End + 1
+		}
+		watcher.lock.Unlock()
+	}
+	for _, address := range registered {
+		watcher.notify(address)
+	}
+}
+
+func (watcher *registrationWatcher) notify(registered common.Address) {
+	watcher.lock.Lock()
+	var registrationEvents []chan RegistrationEvent
+	for registrationEvent, address := range watcher.subscribers {
+		if address == registered {
+			registrationEvents = append(registrationEvents, registrationEvent)
+		}
+	}
+	watcher.lock.Unlock()
+
+	for _, registrationEvent := range registrationEvents {
+		watcher.deliver(registrationEvent, Registered)
+	}
+}
+
+func (watcher *registrationWatcher) head() (uint64, error) {
+	if watcher.heads == nil {
+		return 0, errHeadsNotSupported
+	}
+
+	header, err := watcher.heads.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return header.Number.Uint64(), nil
+}
@@ -18,9 +18,6 @@
 package registry
 
 import (
-	"context"
-	"time"
-
 	log "github.com/cihub/seelog"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
@@ -49,15 +46,17 @@ func NewIdentityRegistryContract(contractBackend bind.ContractBackend, registryA
 		return nil, err
 	}
 
+	heads, _ := contractBackend.(headReader)
+
 	return &contractRegistry{
-		contractSession,
-		filterer,
+		contractSession: contractSession,
+		watcher:         newRegistrationWatcher(filterer, heads),
 	}, nil
 }
 
 type contractRegistry struct {
 	contractSession *abigen.IdentityPromisesCallerSession
-	filterer        *abigen.IdentityPromisesFilterer
+	watcher         *registrationWatcher
 }
 
 func (registry *contractRegistry) IsRegistered(id identity.Identity) (bool, error) {
@@ -80,56 +79,18 @@ func (registry *contractRegistry) SubscribeToRegistrationEvent(id identity.Ident
 	registrationEvent chan RegistrationEvent,
 	unsubscribe func(),
 ) {
-	registrationEvent = make(chan RegistrationEvent)
-
-	stopLoop := make(chan bool)
-	unsubscribe = func() {
-		// cancel (stop) identity registration loop
-		stopLoop <- true
-	}
-
-	identities := []common.Address{
-		common.HexToAddress(id.Address),
-	}
-
-	filterOps := &bind.FilterOpts{
-		Start:   0,
-		End:     nil,
-		Context: context.Background(),
-	}
+	registrationEvent, unsubscribe = registry.watcher.watch(common.HexToAddress(id.Address))
 
+	// identity registered before the subscription is not looked up by watcher
 	go func() {
-		for {
-			select {
-			case <-stopLoop:
-				registrationEvent <- Cancelled
-			case <-time.After(1 * time.Second):
-				logIterator, err := registry.filterer.FilterRegistered(filterOps, identities)
-				if err != nil {
-					registrationEvent <- Cancelled
-					log.Error(logPrefix, err)
-					return
-				}
-				if logIterator == nil {
-					registrationEvent <- Cancelled
-					return
-				}
-				for {
-					next := logIterator.Next()
-					if next {
-						registrationEvent <- Registered
-					} else {
-						err = logIterator.Error()
-						if err != nil {
-							log.Error(logPrefix, err)
-						}
-						break
-					}
-				}
-			case <-time.After(30 * time.Second):
-				log.Trace(logPrefix, "no identity registration, sleeping for 1s")
-			}
+		registered, err := registry.IsRegistered(id)
+		if err != nil {
+			log.Warn(logPrefix, "failed to check identity registration: ", err)
+			return
+		}
+		if registered {
+			registry.watcher.deliver(registrationEvent, Registered)
 		}
 	}()
-	return
+	return registrationEvent, unsubscribe
 }
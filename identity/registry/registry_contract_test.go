@@ -0,0 +1,193 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/payments/mysttoken"
+	"github.com/mysteriumnetwork/payments/promises"
+	"github.com/mysteriumnetwork/payments/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContractRegistry_SubscribeToRegistrationEvent_FollowsSubscription(t *testing.T) {
+	backend := test_utils.NewSimulatedBackend(test_utils.Deployer.Address, 10000000000)
+	contract := deployRegistryContract(t, backend)
+
+	registry, err := NewIdentityRegistryContract(backend, contract.Address)
+	assert.NoError(t, err)
+
+	registered := newTestIdentity(t)
+	registeredEvent, _ := registry.SubscribeToRegistrationEvent(identity.FromAddress(registered.Address.Hex()))
+	unregistered := newTestIdentity(t)
+	cancelledEvent, unsubscribe := registry.SubscribeToRegistrationEvent(identity.FromAddress(unregistered.Address.Hex()))
+
+	assert.NoError(t, contract.RegisterIdentities(registered))
+	backend.Commit()
+
+	assert.Equal(t, Registered, waitRegistrationEvent(t, registeredEvent))
+	assert.Len(t, cancelledEvent, 0)
+
+	unsubscribe()
+	assert.Equal(t, Cancelled, waitRegistrationEvent(t, cancelledEvent))
+	assert.False(t, registry.watcher.isRunning())
+}
+
+func TestContractRegistry_SubscribeToRegistrationEvent_ReportsAlreadyRegisteredIdentity(t *testing.T) {
+	backend := test_utils.NewSimulatedBackend(test_utils.Deployer.Address, 10000000000)
+	contract := deployRegistryContract(t, backend)
+
+	registered := newTestIdentity(t)
+	assert.NoError(t, contract.RegisterIdentities(registered))
+	backend.Commit()
+
+	registry, err := NewIdentityRegistryContract(backend, contract.Address)
+	assert.NoError(t, err)
+
+	registeredEvent, _ := registry.SubscribeToRegistrationEvent(identity.FromAddress(registered.Address.Hex()))
+	assert.Equal(t, Registered, waitRegistrationEvent(t, registeredEvent))
+	assert.False(t, registry.watcher.isRunning())
+}
+
+func TestContractRegistry_SubscribeToRegistrationEvent_PollsBlocksSinceCheckpoint(t *testing.T) {
+	backend := &pollingBackend{TransactionalBackend: test_utils.NewSimulatedBackend(test_utils.Deployer.Address, 10000000000)}
+	contract := deployRegistryContract(t, backend)
+
+	registry, err := NewIdentityRegistryContract(backend, contract.Address)
+	assert.NoError(t, err)
+	registry.watcher.pollInterval = 10 * time.Millisecond
+
+	subscribedAt := backend.blockNumber()
+	first, second := newTestIdentity(t), newTestIdentity(t)
+	firstEvent, _ := registry.SubscribeToRegistrationEvent(identity.FromAddress(first.Address.Hex()))
+	secondEvent, _ := registry.SubscribeToRegistrationEvent(identity.FromAddress(second.Address.Hex()))
+
+	assert.NoError(t, contract.RegisterIdentities(first))
+	backend.Commit()
+	assert.Equal(t, Registered, waitRegistrationEvent(t, firstEvent))
+	assert.True(t, registry.watcher.isRunning())
+
+	assert.NoError(t, contract.RegisterIdentities(second))
+	backend.Commit()
+	assert.Equal(t, Registered, waitRegistrationEvent(t, secondEvent))
+	assert.False(t, registry.watcher.isRunning())
+	assert.Equal(t, backend.blockNumber()+1, registry.watcher.lastCheckpoint())
+
+	fromBlocks := backend.filteredFromBlocks()
+	assert.NotEmpty(t, fromBlocks)
+	for _, fromBlock := range fromBlocks {
+		assert.True(t, fromBlock >= subscribedAt, "blocks mined before subscription are not scanned")
+	}
+}
+
+func deployRegistryContract(t *testing.T, backend test_utils.TransactionalBackend) *promises.PromiseClearing {
+	mystErc20, err := mysttoken.DeployMystERC20(test_utils.Deployer.Transactor, 1000000, backend)
+	assert.NoError(t, err)
+
+	contract, err := promises.DeployPromiseClearer(test_utils.Deployer.Transactor, mystErc20.Address, 1000, backend)
+	assert.NoError(t, err)
+	backend.Commit()
+
+	_, err = mystErc20.Approve(contract.Address, big.NewInt(3000))
+	assert.NoError(t, err)
+	backend.Commit()
+
+	return contract
+}
+
+func newTestIdentity(t *testing.T) *test_utils.MystIdentity {
+	mystIdentity, err := test_utils.NewMystIdentity()
+	assert.NoError(t, err)
+	return mystIdentity
+}
+
+func waitRegistrationEvent(t *testing.T, registrationEvent chan RegistrationEvent) RegistrationEvent {
+	select {
+	case event := <-registrationEvent:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("registration event not received")
+		return Cancelled
+	}
+}
+
+func (watcher *registrationWatcher) isRunning() bool {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+	return watcher.stop != nil
+}
+
+func (watcher *registrationWatcher) lastCheckpoint() uint64 {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+	return watcher.checkpoint
+}
+
+// pollingBackend is simulated backend without event subscriptions, which provides chain heads like HTTP RPC client does
+type pollingBackend struct {
+	test_utils.TransactionalBackend
+
+	head       uint64
+	fromBlocks []uint64
+	lock       sync.Mutex
+}
+
+func (backend *pollingBackend) Commit() {
+	backend.TransactionalBackend.Commit()
+
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+	backend.head++
+}
+
+func (backend *pollingBackend) blockNumber() uint64 {
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+	return backend.head
+}
+
+func (backend *pollingBackend) filteredFromBlocks() []uint64 {
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+	return backend.fromBlocks
+}
+
+func (backend *pollingBackend) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	backend.lock.Lock()
+	backend.fromBlocks = append(backend.fromBlocks, query.FromBlock.Uint64())
+	backend.lock.Unlock()
+
+	return backend.TransactionalBackend.FilterLogs(ctx, query)
+}
+
+func (backend *pollingBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: new(big.Int).SetUint64(backend.blockNumber())}, nil
+}
+
+func (backend *pollingBackend) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, errors.New("notifications not supported")
+}
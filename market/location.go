@@ -20,7 +20,10 @@ package market
 // Location struct represents geographic location of service provider
 type Location struct {
 	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
 	City    string `json:"city,omitempty"`
 	// Autonomous System Number http://www.whatismyip.cx/
 	ASN string `json:"asn,omitempty"`
+	// ISP is the organization which operates the autonomous system
+	ISP string `json:"isp,omitempty"`
 }
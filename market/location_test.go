@@ -31,11 +31,13 @@ func TestLocationSerialize(t *testing.T) {
 		expectedJSON string
 	}{
 		{
-			Location{"XX", "YY", "AS123"},
+			Location{"XX", "ZZ", "YY", "AS123", "ISP"},
 			`{
 				"country": "XX",
+				"region": "ZZ",
 				"city": "YY",
-				"asn": "AS123"
+				"asn": "AS123",
+				"isp": "ISP"
 			}`,
 		},
 		{
@@ -67,10 +69,12 @@ func TestLocationUnserialize(t *testing.T) {
 		{
 			`{
 				"country": "XX",
+				"region": "ZZ",
 				"city": "YY",
-				"asn": "AS123"
+				"asn": "AS123",
+				"isp": "ISP"
 			}`,
-			Location{"XX", "YY", "AS123"},
+			Location{"XX", "ZZ", "YY", "AS123", "ISP"},
 			nil,
 		},
 		{
@@ -43,6 +43,22 @@ type ProposalUnregisterRequest struct {
 	ServiceType string `json:"service_type"`
 }
 
+// ProbeEndpointRequest represents JSON request for probing the service endpoint from outside of the provider network
+type ProbeEndpointRequest struct {
+	ProviderID  string `json:"provider_id"`
+	ServiceType string `json:"service_type"`
+	Network     string `json:"network"`
+	Address     string `json:"address"`
+	// Token is sent to the endpoint in a UDP datagram instead of connecting to the service, if it is given
+	Token []byte `json:"token,omitempty"`
+}
+
+// ProbeEndpointResponse represents JSON response of the endpoint probe
+type ProbeEndpointResponse struct {
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error"`
+}
+
 // ProposalsRequest represents JSON request for the proposals
 type ProposalsRequest struct {
 	NodeKey     string `json:"node_key"`
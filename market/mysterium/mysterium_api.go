@@ -18,6 +18,7 @@
 package mysterium
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -34,6 +35,9 @@ const (
 	mysteriumAPILogPrefix = "[Mysterium.api] "
 )
 
+// ErrEndpointProbeUnsupported is returned when discovery service does not probe endpoints of the given network or service type
+var ErrEndpointProbeUnsupported = errors.New("endpoint probing is not supported by discovery")
+
 // HTTPTransport interface with single method do is extracted from net/transport.Client structure
 type HTTPTransport interface {
 	Do(*http.Request) (*http.Response, error)
@@ -175,6 +179,48 @@ func (mApi *MysteriumAPI) SendSessionStats(sessionID session.ID, sessionStats Se
 	return nil
 }
 
+// ProbeEndpoint asks discovery service to reach the service endpoint from outside of the provider network.
+// Endpoint is connected the way consumers of the service type connect to it, or token is sent to it in a UDP datagram if it is given.
+func (mApi *MysteriumAPI) ProbeEndpoint(request ProbeEndpointRequest, signer identity.Signer) error {
+	req, err := requests.NewSignedPostRequest(mApi.discoveryAPIAddress, "probe_endpoint", request, signer)
+	if err != nil {
+		return err
+	}
+
+	resp, err := mApi.http.Do(req)
+	if err != nil {
+		log.Error(mysteriumAPILogPrefix, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented {
+		return ErrEndpointProbeUnsupported
+	}
+	if err := ParseResponseError(resp); err != nil {
+		return err
+	}
+
+	var probeResponse ProbeEndpointResponse
+	if err := ParseResponseJSON(resp, &probeResponse); err != nil {
+		return err
+	}
+	if !probeResponse.Reachable {
+		return fmt.Errorf("endpoint is not reachable: %s", probeResponse.Error)
+	}
+	return nil
+}
+
+// HealthCheck verifies that discovery service is reachable and responds successfully
+func (mApi *MysteriumAPI) HealthCheck() error {
+	req, err := requests.NewGetRequest(mApi.discoveryAPIAddress, "healthcheck", url.Values{})
+	if err != nil {
+		return err
+	}
+
+	return mApi.doRequest(req)
+}
+
 func (mApi *MysteriumAPI) doRequest(req *http.Request) error {
 	resp, err := mApi.http.Do(req)
 	if err != nil {
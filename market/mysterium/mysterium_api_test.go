@@ -18,11 +18,13 @@
 package mysterium
 
 import (
+	"encoding/json"
 	"net"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/identity"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -54,6 +56,63 @@ func TestHttpTransportDoesntBlockForeverIfServerFailsToSendAnyResponse(t *testin
 	}
 }
 
+func TestHealthCheckReturnsDiscoveryResponseError(t *testing.T) {
+	address, err := createHTTPServer(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/healthcheck" {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	})
+	assert.NoError(t, err)
+
+	api := NewClient("http://" + address)
+	assert.Error(t, api.HealthCheck())
+}
+
+func TestHealthCheckSucceedsWhenDiscoveryResponds(t *testing.T) {
+	address, err := createHTTPServer(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/healthcheck" {
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	})
+	assert.NoError(t, err)
+
+	api := NewClient("http://" + address)
+	assert.NoError(t, api.HealthCheck())
+}
+
+func TestProbeEndpointReportsUnreachableEndpoint(t *testing.T) {
+	var probed ProbeEndpointRequest
+	address, err := createHTTPServer(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/probe_endpoint" {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(request.Body).Decode(&probed)
+		writer.Write([]byte(`{"reachable": false, "error": "i/o timeout"}`))
+	})
+	assert.NoError(t, err)
+
+	api := NewClient("http://" + address)
+	err = api.ProbeEndpoint(ProbeEndpointRequest{ServiceType: "openvpn", Network: "udp", Address: "1.2.3.4:1194"}, &identity.SignerFake{})
+
+	assert.EqualError(t, err, "endpoint is not reachable: i/o timeout")
+	assert.Equal(t, ProbeEndpointRequest{ServiceType: "openvpn", Network: "udp", Address: "1.2.3.4:1194"}, probed)
+}
+
+func TestProbeEndpointIsUnsupportedByDiscoveryWithoutIt(t *testing.T) {
+	address, err := createHTTPServer(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+	})
+	assert.NoError(t, err)
+
+	api := NewClient("http://" + address)
+	err = api.ProbeEndpoint(ProbeEndpointRequest{ServiceType: "wireguard", Network: "udp", Address: "1.2.3.4:52820", Token: []byte("token")}, &identity.SignerFake{})
+
+	assert.Equal(t, ErrEndpointProbeUnsupported, err)
+}
+
 func createHTTPServer(handlerFunc http.HandlerFunc) (address string, err error) {
 	listener, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
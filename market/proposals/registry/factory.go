@@ -32,15 +32,32 @@ type ProposalRegistry interface {
 	UnregisterProposal(proposal market.ServiceProposal, signer identity.Signer) error
 }
 
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
+// ProposalEventTopic represents the proposal registration outcome topic
+const ProposalEventTopic = "ProposalRegistration"
+
+// ProposalEvent is the struct we'll emit on a ProposalEventTopic after each registration attempt
+type ProposalEvent struct {
+	Proposal market.ServiceProposal
+	Error    error
+}
+
 // Discovery structure holds discovery service state
 type Discovery struct {
 	identityRegistry            identity_registry.IdentityRegistry
 	ownIdentity                 identity.Identity
 	identityRegistration        identity_registry.RegistrationDataProvider
 	proposalRegistry            ProposalRegistry
+	eventPublisher              Publisher
 	signerCreate                identity.SignerFactory
 	signer                      identity.Signer
 	proposal                    market.ServiceProposal
+	proposalOutdated            bool
+	registrationLock            sync.Mutex
 	statusChan                  chan Status
 	status                      Status
 	proposalAnnouncementStopped *sync.WaitGroup
@@ -56,11 +73,13 @@ func NewService(
 	identityRegistration identity_registry.RegistrationDataProvider,
 	proposalRegistry ProposalRegistry,
 	signerCreate identity.SignerFactory,
+	eventPublisher Publisher,
 ) *Discovery {
 	return &Discovery{
 		identityRegistry:            identityRegistry,
 		identityRegistration:        identityRegistration,
 		proposalRegistry:            proposalRegistry,
+		eventPublisher:              eventPublisher,
 		signerCreate:                signerCreate,
 		statusChan:                  make(chan Status),
 		status:                      StatusUndefined,
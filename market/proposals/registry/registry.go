@@ -66,6 +66,20 @@ func (d *Discovery) Start(ownIdentity identity.Identity, proposal market.Service
 	go d.mainDiscoveryLoop(stopLoop)
 }
 
+// UpdateProposal replaces the announced proposal, i.e. when location of the provider changes.
+// Already registered proposal is registered again right away, otherwise the updated one is used for upcoming registration.
+func (d *Discovery) UpdateProposal(proposal market.ServiceProposal) {
+	d.Lock()
+	d.proposal = proposal
+	d.proposalOutdated = true
+	registered := d.status == PingProposal
+	d.Unlock()
+
+	if registered {
+		go d.reregisterProposal()
+	}
+}
+
 // Wait wait for proposal announcements to stop / unregister
 func (d *Discovery) Wait() {
 	d.proposalAnnouncementStopped.Wait()
@@ -135,7 +149,12 @@ func (d *Discovery) registerIdentity() {
 }
 
 func (d *Discovery) registerProposal() {
-	err := d.proposalRegistry.RegisterProposal(d.proposal, d.signer)
+	d.registrationLock.Lock()
+	proposal, _ := d.takeProposal()
+	err := d.proposalRegistry.RegisterProposal(proposal, d.signer)
+	d.registrationLock.Unlock()
+
+	d.publishProposalEvent(proposal, err)
 	if err != nil {
 		log.Errorf("%s Failed to register proposal, retrying after 1 min. %s", logPrefix, err.Error())
 		time.Sleep(1 * time.Minute)
@@ -147,15 +166,47 @@ func (d *Discovery) registerProposal() {
 
 func (d *Discovery) pingProposal() {
 	time.Sleep(1 * time.Minute)
-	err := d.proposalRegistry.PingProposal(d.proposal, d.signer)
-	if err != nil {
+	if d.isProposalOutdated() {
+		// previous registration of the updated proposal failed, so it is retried instead of ping
+		d.reregisterProposal()
+	} else if err := d.proposalRegistry.PingProposal(d.currentProposal(), d.signer); err != nil {
 		log.Error(logPrefix, "Failed to ping proposal: ", err)
 	}
 	d.changeStatus(PingProposal)
 }
 
+// reregisterProposal registers the updated proposal unless it is being unregistered already
+func (d *Discovery) reregisterProposal() {
+	d.registrationLock.Lock()
+	defer d.registrationLock.Unlock()
+
+	d.RLock()
+	registered := d.status == PingProposal
+	d.RUnlock()
+	if !registered {
+		return
+	}
+
+	proposal, outdated := d.takeProposal()
+	if !outdated {
+		return
+	}
+
+	log.Info(logPrefix, "Registering updated proposal")
+	err := d.proposalRegistry.RegisterProposal(proposal, d.signer)
+	d.publishProposalEvent(proposal, err)
+	if err != nil {
+		log.Error(logPrefix, "Failed to register updated proposal, retrying on next ping: ", err)
+		d.Lock()
+		d.proposalOutdated = true
+		d.Unlock()
+	}
+}
+
 func (d *Discovery) unregisterProposal() {
-	err := d.proposalRegistry.UnregisterProposal(d.proposal, d.signer)
+	d.registrationLock.Lock()
+	err := d.proposalRegistry.UnregisterProposal(d.currentProposal(), d.signer)
+	d.registrationLock.Unlock()
 	if err != nil {
 		log.Error(logPrefix, "Failed to unregister proposal: ", err)
 		d.changeStatus(UnregisterProposalFailed)
@@ -187,6 +238,35 @@ func (d *Discovery) checkRegistration() {
 	d.changeStatus(RegisterProposal)
 }
 
+func (d *Discovery) currentProposal() market.ServiceProposal {
+	d.RLock()
+	defer d.RUnlock()
+	return d.proposal
+}
+
+func (d *Discovery) isProposalOutdated() bool {
+	d.RLock()
+	defer d.RUnlock()
+	return d.proposalOutdated
+}
+
+// takeProposal returns the proposal to be registered and whether it was updated since the last registration
+func (d *Discovery) takeProposal() (market.ServiceProposal, bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	outdated := d.proposalOutdated
+	d.proposalOutdated = false
+	return d.proposal, outdated
+}
+
+func (d *Discovery) publishProposalEvent(proposal market.ServiceProposal, err error) {
+	if d.eventPublisher == nil {
+		return
+	}
+	d.eventPublisher.Publish(ProposalEventTopic, ProposalEvent{Proposal: proposal, Error: err})
+}
+
 func (d *Discovery) changeStatus(status Status) {
 	d.Lock()
 	defer d.Unlock()
@@ -97,6 +97,68 @@ func TestStartStopUnregisterProposal(t *testing.T) {
 	assert.Equal(t, ProposalUnregistered, actualStatus)
 }
 
+func TestStartPublishesProposalRegistrationEvent(t *testing.T) {
+	publisher := &mockedPublisher{}
+	d := discoveryWithMockedDependencies()
+	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
+	d.eventPublisher = publisher
+
+	d.Start(providerID, proposal)
+
+	observeStatus(d, PingProposal)
+
+	events := publisher.getEvents()
+	assert.Len(t, events, 1)
+	assert.Equal(t, ProposalEvent{Proposal: proposal}, events[0])
+}
+
+func TestUpdateProposalRegistersUpdatedProposal(t *testing.T) {
+	proposalRegistry := &recordingProposalRegistry{}
+	publisher := &mockedPublisher{}
+	d := discoveryWithMockedDependencies()
+	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
+	d.proposalRegistry = proposalRegistry
+	d.eventPublisher = publisher
+
+	d.Start(providerID, proposal)
+	observeStatus(d, PingProposal)
+
+	updatedProposal := proposal
+	updatedProposal.ServiceType = "updated"
+	d.UpdateProposal(updatedProposal)
+
+	observeEvents(publisher, 2)
+	assert.Equal(t, []market.ServiceProposal{proposal, updatedProposal}, proposalRegistry.getRegistered())
+	assert.Equal(t, ProposalEvent{Proposal: updatedProposal}, publisher.getEvents()[1])
+	assert.Equal(t, updatedProposal, d.currentProposal())
+	assert.False(t, d.isProposalOutdated())
+
+	d.Stop()
+	observeStatus(d, ProposalUnregistered)
+	assert.Equal(t, []market.ServiceProposal{updatedProposal}, proposalRegistry.getUnregistered())
+}
+
+func TestUpdateProposalBeforeRegistrationIsUsedForRegistration(t *testing.T) {
+	proposalRegistry := &recordingProposalRegistry{}
+	d := discoveryWithMockedDependencies()
+	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: false}
+	d.proposalRegistry = proposalRegistry
+
+	d.Start(providerID, proposal)
+	observeStatus(d, WaitingForRegistration)
+
+	updatedProposal := proposal
+	updatedProposal.ServiceType = "updated"
+	d.UpdateProposal(updatedProposal)
+
+	assert.Equal(t, updatedProposal, d.currentProposal())
+	assert.Empty(t, proposalRegistry.getRegistered())
+
+	proposal, outdated := d.takeProposal()
+	assert.Equal(t, updatedProposal, proposal)
+	assert.True(t, outdated)
+}
+
 func observeStatus(d *Discovery, status Status) Status {
 	for {
 		d.RLock()
@@ -109,6 +171,12 @@ func observeStatus(d *Discovery, status Status) Status {
 	}
 }
 
+func observeEvents(publisher *mockedPublisher, count int) {
+	for len(publisher.getEvents()) < count {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 type mockedProposalRegistry struct {
 }
 
@@ -125,3 +193,60 @@ func (mockedProposalRegistry) UnregisterProposal(proposal market.ServiceProposal
 }
 
 var _ ProposalRegistry = &mockedProposalRegistry{}
+
+type recordingProposalRegistry struct {
+	registered   []market.ServiceProposal
+	unregistered []market.ServiceProposal
+	lock         sync.Mutex
+}
+
+func (registry *recordingProposalRegistry) RegisterProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.registered = append(registry.registered, proposal)
+	return nil
+}
+
+func (registry *recordingProposalRegistry) PingProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+	return nil
+}
+
+func (registry *recordingProposalRegistry) UnregisterProposal(proposal market.ServiceProposal, signer identity.Signer) error {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.unregistered = append(registry.unregistered, proposal)
+	return nil
+}
+
+func (registry *recordingProposalRegistry) getRegistered() []market.ServiceProposal {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	return registry.registered
+}
+
+func (registry *recordingProposalRegistry) getUnregistered() []market.ServiceProposal {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	return registry.unregistered
+}
+
+type mockedPublisher struct {
+	events []ProposalEvent
+	lock   sync.Mutex
+}
+
+func (publisher *mockedPublisher) Publish(topic string, args ...interface{}) {
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+
+	if topic == ProposalEventTopic {
+		publisher.events = append(publisher.events, args[0].(ProposalEvent))
+	}
+}
+
+func (publisher *mockedPublisher) getEvents() []ProposalEvent {
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+
+	return publisher.events
+}
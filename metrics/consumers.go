@@ -0,0 +1,178 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package metrics
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/communication/nats/dialog"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/market/proposals/registry"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/nats-io/go-nats"
+	"github.com/pkg/errors"
+)
+
+// rejectionsReportInterval is the minimal interval between reports of rejected dialogs
+const rejectionsReportInterval = time.Minute
+
+// NewEventsConsumer creates consumer which translates node events to metrics events
+func NewEventsConsumer(sender *Sender) *EventsConsumer {
+	return &EventsConsumer{
+		sender:             sender,
+		sessionsStarts:     make(map[session.ID]time.Time),
+		dialogRejections:   make(map[dialog.RejectionReason]int),
+		rejectionsInterval: rejectionsReportInterval,
+		timeNow:            time.Now,
+	}
+}
+
+// EventsConsumer listens to node events and reports them to metrics
+type EventsConsumer struct {
+	sender *Sender
+
+	sessionsStarts map[session.ID]time.Time
+	sessionsLock   sync.Mutex
+
+	dialogRejections   map[dialog.RejectionReason]int
+	rejectionsInterval time.Duration
+	rejectionsReported time.Time
+	rejectionsPending  *time.Timer
+	rejectionsLock     sync.Mutex
+
+	timeNow func() time.Time
+}
+
+// ConsumeSessionEvent reports started and ended sessions
+func (consumer *EventsConsumer) ConsumeSessionEvent(sessionEvent connection.SessionEvent) {
+	consumer.sessionsLock.Lock()
+	defer consumer.sessionsLock.Unlock()
+
+	sessionID := sessionEvent.SessionInfo.SessionID
+	serviceType := sessionEvent.SessionInfo.Proposal.ServiceType
+
+	var err error
+	switch sessionEvent.Status {
+	case connection.SessionCreatedStatus:
+		consumer.sessionsStarts[sessionID] = consumer.timeNow()
+		err = consumer.sender.SendSessionStartedEvent(serviceType)
+	case connection.SessionEndedStatus:
+		started, ok := consumer.sessionsStarts[sessionID]
+		if !ok {
+			return
+		}
+		delete(consumer.sessionsStarts, sessionID)
+		err = consumer.sender.SendSessionEndedEvent(serviceType, consumer.timeNow().Sub(started))
+	}
+	logSendError(err)
+}
+
+// ConsumeFailureEvent reports failed connection attempts
+func (consumer *EventsConsumer) ConsumeFailureEvent(failureEvent connection.FailureEvent) {
+	logSendError(consumer.sender.SendConnectionFailedEvent(failureEvent.Proposal.ServiceType, failureEvent.Error))
+}
+
+// ConsumeProposalEvent reports proposal registration outcomes
+func (consumer *EventsConsumer) ConsumeProposalEvent(proposalEvent registry.ProposalEvent) {
+	logSendError(consumer.sender.SendProposalRegistrationEvent(proposalEvent.Proposal.ServiceType, proposalEvent.Error))
+}
+
+// ConsumeDialogRejectionEvent counts rejected dialogs and reports them at most once per interval,
+// so that peers flooding the provider with dialog requests do not flood metrics too.
+// Rejections counted during the interval are reported once it ends.
+func (consumer *EventsConsumer) ConsumeDialogRejectionEvent(rejectionEvent dialog.RejectionEvent) {
+	consumer.rejectionsLock.Lock()
+	defer consumer.rejectionsLock.Unlock()
+
+	consumer.dialogRejections[rejectionEvent.Reason]++
+
+	now := consumer.timeNow()
+	sinceReport := now.Sub(consumer.rejectionsReported)
+	if sinceReport >= consumer.rejectionsInterval {
+		consumer.reportDialogRejections(now)
+		return
+	}
+	if consumer.rejectionsPending == nil {
+		consumer.rejectionsPending = time.AfterFunc(consumer.rejectionsInterval-sinceReport, func() {
+			consumer.rejectionsLock.Lock()
+			defer consumer.rejectionsLock.Unlock()
+			consumer.reportDialogRejections(consumer.timeNow())
+		})
+	}
+}
+
+// reportDialogRejections sends the counted rejections, rejectionsLock has to be held
+func (consumer *EventsConsumer) reportDialogRejections(now time.Time) {
+	if consumer.rejectionsPending != nil {
+		consumer.rejectionsPending.Stop()
+		consumer.rejectionsPending = nil
+	}
+	if len(consumer.dialogRejections) == 0 {
+		return
+	}
+	consumer.rejectionsReported = now
+
+	reasons := make([]string, 0, len(consumer.dialogRejections))
+	for reason := range consumer.dialogRejections {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		count := consumer.dialogRejections[dialog.RejectionReason(reason)]
+		logSendError(consumer.sender.SendDialogsRejectedEvent(reason, count))
+	}
+	consumer.dialogRejections = make(map[dialog.RejectionReason]int)
+}
+
+func logSendError(err error) {
+	if err != nil {
+		log.Warn(metricsLogPrefix, "Failed to send metrics event: ", err)
+	}
+}
+
+// errorClass reduces error to a short class, so that no sensitive details are reported
+func errorClass(err error) string {
+	err = errors.Cause(err)
+	switch err {
+	case nil:
+		return ""
+	case connection.ErrConnectionCancelled, context.Canceled:
+		return "cancelled"
+	case connection.ErrConnectionFailed:
+		return "connection_failed"
+	case connection.ErrUnsupportedServiceType:
+		return "unsupported_service"
+	case nats.ErrTimeout, context.DeadlineExceeded:
+		return "timeout"
+	case nats.ErrNoServers, nats.ErrConnectionClosed:
+		return "broker_unreachable"
+	}
+
+	if netErr, ok := err.(net.Error); ok {
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	}
+	return "other"
+}
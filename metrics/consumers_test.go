@@ -0,0 +1,153 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/communication/nats/dialog"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/market/proposals/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+var sessionInfo = connection.SessionInfo{
+	SessionID: "session-1",
+	Proposal:  market.ServiceProposal{ServiceType: "wireguard"},
+}
+
+func TestEventsConsumer_ReportsSessionDuration(t *testing.T) {
+	queue := &mockEventQueue{}
+	consumer := NewEventsConsumer(NewSender(buildMockEventsTransport(nil), queue, "1", 10, time.Minute, nil))
+
+	now := time.Unix(1000, 0)
+	consumer.timeNow = func() time.Time { return now }
+	consumer.ConsumeSessionEvent(connection.SessionEvent{Status: connection.SessionCreatedStatus, SessionInfo: sessionInfo})
+
+	now = now.Add(5 * time.Minute)
+	consumer.ConsumeSessionEvent(connection.SessionEvent{Status: connection.SessionEndedStatus, SessionInfo: sessionInfo})
+
+	assert.Len(t, queue.events, 2)
+	assert.Equal(t, "session_started", queue.events[0].EventName)
+	assert.Equal(t, sessionContext{ServiceType: "wireguard"}, queue.events[0].Context)
+	assert.Equal(t, "session_ended", queue.events[1].EventName)
+	assert.Equal(t, sessionContext{ServiceType: "wireguard", Duration: 300}, queue.events[1].Context)
+}
+
+func TestEventsConsumer_IgnoresEndOfUnknownSession(t *testing.T) {
+	queue := &mockEventQueue{}
+	consumer := NewEventsConsumer(NewSender(buildMockEventsTransport(nil), queue, "1", 10, time.Minute, nil))
+
+	consumer.ConsumeSessionEvent(connection.SessionEvent{Status: connection.SessionEndedStatus, SessionInfo: sessionInfo})
+
+	assert.Empty(t, queue.events)
+}
+
+func TestEventsConsumer_ReportsFailuresWithErrorClass(t *testing.T) {
+	queue := &mockEventQueue{}
+	consumer := NewEventsConsumer(NewSender(buildMockEventsTransport(nil), queue, "1", 10, time.Minute, nil))
+
+	consumer.ConsumeFailureEvent(connection.FailureEvent{
+		Proposal: market.ServiceProposal{ServiceType: "openvpn"},
+		Error:    connection.ErrConnectionCancelled,
+	})
+	consumer.ConsumeProposalEvent(registry.ProposalEvent{
+		Proposal: market.ServiceProposal{ServiceType: "noop"},
+		Error:    errors.New("discovery is down"),
+	})
+	consumer.ConsumeProposalEvent(registry.ProposalEvent{
+		Proposal: market.ServiceProposal{ServiceType: "noop"},
+	})
+
+	assert.Len(t, queue.events, 3)
+	assert.Equal(t, connectionFailureContext{ServiceType: "openvpn", ErrorClass: "cancelled"}, queue.events[0].Context)
+	assert.Equal(t, "proposal_register_failed", queue.events[1].EventName)
+	assert.Equal(t, proposalContext{ServiceType: "noop", ErrorClass: "other"}, queue.events[1].Context)
+	assert.Equal(t, "proposal_registered", queue.events[2].EventName)
+}
+
+func TestEventsConsumer_ReportsDialogRejectionsInAggregate(t *testing.T) {
+	queue := &mockEventQueue{}
+	consumer := NewEventsConsumer(NewSender(buildMockEventsTransport(nil), queue, "1", 10, time.Minute, nil))
+
+	now := time.Unix(1000, 0)
+	consumer.timeNow = func() time.Time { return now }
+	consumer.ConsumeDialogRejectionEvent(dialog.RejectionEvent{Reason: dialog.RejectionUnregistered})
+	for i := 0; i < 3; i++ {
+		consumer.ConsumeDialogRejectionEvent(dialog.RejectionEvent{Reason: dialog.RejectionPeerRateLimit})
+	}
+	assert.Len(t, queue.events, 1)
+
+	now = now.Add(time.Minute)
+	consumer.ConsumeDialogRejectionEvent(dialog.RejectionEvent{Reason: dialog.RejectionGlobalRateLimit})
+
+	assert.Len(t, queue.events, 3)
+	assert.Equal(t, "dialogs_rejected", queue.events[0].EventName)
+	assert.Equal(t, dialogRejectionsContext{Reason: "unregistered", Count: 1}, queue.events[0].Context)
+	assert.Equal(t, dialogRejectionsContext{Reason: "global_rate_limit", Count: 1}, queue.events[1].Context)
+	assert.Equal(t, dialogRejectionsContext{Reason: "peer_rate_limit", Count: 3}, queue.events[2].Context)
+}
+
+func TestEventsConsumer_ReportsDialogRejectionsAtTheEndOfInterval(t *testing.T) {
+	queue := &mockEventQueue{}
+	consumer := NewEventsConsumer(NewSender(buildMockEventsTransport(nil), queue, "1", 10, time.Minute, nil))
+	consumer.rejectionsInterval = 20 * time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		consumer.ConsumeDialogRejectionEvent(dialog.RejectionEvent{Reason: dialog.RejectionPeerRateLimit})
+	}
+	assert.Len(t, queue.getEvents(), 1)
+
+	time.Sleep(100 * time.Millisecond)
+	events := queue.getEvents()
+	assert.Len(t, events, 2)
+	assert.Equal(t, dialogRejectionsContext{Reason: "peer_rate_limit", Count: 2}, events[1].Context)
+}
+
+func TestSender_DeliversQueuedEventsToHTTPServer(t *testing.T) {
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buffer := new(bytes.Buffer)
+		buffer.ReadFrom(r.Body)
+
+		var events []Event
+		assert.NoError(t, json.Unmarshal(buffer.Bytes(), &events))
+		received = append(received, events...)
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	sender := NewSender(NewElasticSearchTransport(server.URL, time.Second), &mockEventQueue{}, "1", 10, time.Minute, nil)
+	consumer := NewEventsConsumer(sender)
+	consumer.ConsumeSessionEvent(connection.SessionEvent{Status: connection.SessionCreatedStatus, SessionInfo: sessionInfo})
+	consumer.ConsumeSessionEvent(connection.SessionEvent{Status: connection.SessionEndedStatus, SessionInfo: sessionInfo})
+
+	sender.Stop()
+
+	assert.Len(t, received, 2)
+	assert.Equal(t, "session_started", received[0].EventName)
+	assert.Equal(t, "session_ended", received[1].EventName)
+}
@@ -38,8 +38,18 @@ type elasticSearchTransport struct {
 	url  string
 }
 
-func (transport *elasticSearchTransport) sendEvent(event event) error {
-	req, err := requests.NewPostRequest(transport.url, "/", event)
+// SendEvent sends single event to ElasticSearch
+func (transport *elasticSearchTransport) SendEvent(event Event) error {
+	return transport.post(event)
+}
+
+// SendEvents sends batch of events to ElasticSearch as a single request
+func (transport *elasticSearchTransport) SendEvents(events []Event) error {
+	return transport.post(events)
+}
+
+func (transport *elasticSearchTransport) post(payload interface{}) error {
+	req, err := requests.NewPostRequest(transport.url, "/", payload)
 	if err != nil {
 		return err
 	}
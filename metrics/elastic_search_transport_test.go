@@ -52,9 +52,9 @@ func TestElasticSearchTransport_SendEvent_Success(t *testing.T) {
 	transport := NewElasticSearchTransport(server.URL, time.Second)
 
 	app := applicationInfo{Name: "test app", Version: "test version"}
-	event := event{Application: app}
+	event := Event{Application: app}
 
-	err := transport.sendEvent(event)
+	err := transport.SendEvent(event)
 
 	assert.True(t, invoked)
 	assert.NoError(t, err)
@@ -70,7 +70,7 @@ func TestElasticSearchTransport_SendEvent_WithUnexpectedStatus(t *testing.T) {
 
 	transport := NewElasticSearchTransport(server.URL, time.Second)
 
-	err := transport.sendEvent(event{})
+	err := transport.SendEvent(Event{})
 
 	assert.True(t, invoked)
 	assert.EqualError(t, err, "unexpected response status: 500 Internal Server Error, body: something not cool happened")
@@ -85,8 +85,45 @@ func TestElasticSearchTransport_SendEvent_WithUnexpectedResponse(t *testing.T) {
 
 	transport := NewElasticSearchTransport(server.URL, time.Second)
 
-	err := transport.sendEvent(event{})
+	err := transport.SendEvent(Event{})
 
 	assert.True(t, invoked)
 	assert.EqualError(t, err, "unexpected response body: bad")
 }
+
+func TestElasticSearchTransport_SendEvents_SendsBatchInSingleRequest(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buffer := new(bytes.Buffer)
+		buffer.ReadFrom(r.Body)
+
+		assert.JSONEq(t, `[
+			{
+				"application": {"name": "test app", "version": "1"},
+				"createdAt": 1,
+				"eventName": "first",
+				"context": null
+			},
+			{
+				"application": {"name": "test app", "version": "1"},
+				"createdAt": 2,
+				"eventName": "second",
+				"context": {"serviceType": "noop"}
+			}
+		]`, buffer.String())
+
+		fmt.Fprint(w, "ok")
+		requests++
+	}))
+
+	transport := NewElasticSearchTransport(server.URL, time.Second)
+
+	app := applicationInfo{Name: "test app", Version: "1"}
+	err := transport.SendEvents([]Event{
+		{Application: app, EventName: "first", CreatedAt: 1},
+		{Application: app, EventName: "second", CreatedAt: 2, Context: sessionContext{ServiceType: "noop"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+}
@@ -19,14 +19,25 @@ package metrics
 
 import "time"
 
-// CreateSender creates metrics sender with appropriate transport
-func CreateSender(disableMetrics bool, metricsAddress string) *Sender {
-	var transport Transport
+const (
+	eventQueueLimit   = 1000
+	eventBatchSize    = 50
+	eventSendInterval = time.Minute
+)
+
+// CreateSender creates metrics sender with appropriate transport.
+// Events are kept in the given storage until they are delivered.
+func CreateSender(disableMetrics bool, metricsAddress, appVersion string, disabledCategories []Category, storage Storage) *Sender {
 	if disableMetrics {
-		transport = NewNoopTransport()
-	} else {
-		transport = NewElasticSearchTransport(metricsAddress, 10*time.Second)
+		return &Sender{Transport: NewNoopTransport()}
 	}
 
-	return &Sender{Transport: transport}
+	return NewSender(
+		NewElasticSearchTransport(metricsAddress, 10*time.Second),
+		NewStorageQueue(storage, eventQueueLimit),
+		appVersion,
+		eventBatchSize,
+		eventSendInterval,
+		disabledCategories,
+	)
 }
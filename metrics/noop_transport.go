@@ -25,6 +25,12 @@ func NewNoopTransport() Transport {
 type noopTransport struct {
 }
 
-func (transport *noopTransport) sendEvent(event event) error {
+// SendEvent ignores given event
+func (transport *noopTransport) SendEvent(event Event) error {
+	return nil
+}
+
+// SendEvents ignores given events
+func (transport *noopTransport) SendEvents(events []Event) error {
 	return nil
 }
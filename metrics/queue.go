@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package metrics
+
+import (
+	"sort"
+	"sync"
+)
+
+const eventQueueBucketName = "metrics-event-queue"
+
+// EventQueue keeps events until they are delivered
+type EventQueue interface {
+	Push(event Event) error
+	Peek(count int) ([]Event, error)
+	Drop(count int) error
+}
+
+// Storage stores persistent objects for future usage
+type Storage interface {
+	Store(bucket string, data interface{}) error
+	Delete(bucket string, data interface{}) error
+	GetAllFrom(bucket string, data interface{}) error
+}
+
+type queuedEvent struct {
+	ID    int `storm:"id,increment"`
+	Event Event
+}
+
+// NewStorageQueue creates event queue persisted in given storage, so events survive node restarts and offline periods.
+// When queue exceeds given limit, the oldest events are dropped.
+func NewStorageQueue(storage Storage, limit int) *storageQueue {
+	return &storageQueue{storage: storage, limit: limit}
+}
+
+type storageQueue struct {
+	storage Storage
+	limit   int
+	lock    sync.Mutex
+}
+
+// Push appends event to the end of the queue
+func (queue *storageQueue) Push(event Event) error {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	if err := queue.storage.Store(eventQueueBucketName, &queuedEvent{Event: event}); err != nil {
+		return err
+	}
+
+	events, err := queue.getAll()
+	if err != nil {
+		return err
+	}
+	if len(events) <= queue.limit {
+		return nil
+	}
+	return queue.delete(events[:len(events)-queue.limit])
+}
+
+// Peek returns up to given count of the oldest events without removing them
+func (queue *storageQueue) Peek(count int) ([]Event, error) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	queued, err := queue.getAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(queued) > count {
+		queued = queued[:count]
+	}
+
+	events := make([]Event, len(queued))
+	for i := range queued {
+		events[i] = queued[i].Event
+	}
+	return events, nil
+}
+
+// Drop removes given count of the oldest events
+func (queue *storageQueue) Drop(count int) error {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	events, err := queue.getAll()
+	if err != nil {
+		return err
+	}
+	if len(events) > count {
+		events = events[:count]
+	}
+	return queue.delete(events)
+}
+
+func (queue *storageQueue) getAll() ([]queuedEvent, error) {
+	var events []queuedEvent
+	if err := queue.storage.GetAllFrom(eventQueueBucketName, &events); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+func (queue *storageQueue) delete(events []queuedEvent) error {
+	for i := range events {
+		if err := queue.storage.Delete(eventQueueBucketName, &events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
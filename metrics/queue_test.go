@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/core/storage/boltdb"
+	"github.com/mysteriumnetwork/node/core/storage/boltdb/boltdbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageQueue_KeepsEventsInOrder(t *testing.T) {
+	dir := boltdbtest.CreateTempDir(t)
+	defer boltdbtest.RemoveTempDir(t, dir)
+	storage, err := boltdb.NewStorage(dir)
+	assert.NoError(t, err)
+	defer storage.Close()
+
+	queue := NewStorageQueue(storage, 10)
+
+	events, err := queue.Peek(10)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	for _, name := range []string{"first", "second", "third"} {
+		assert.NoError(t, queue.Push(Event{EventName: name}))
+	}
+
+	events, err = queue.Peek(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{EventName: "first"}, {EventName: "second"}}, events)
+
+	assert.NoError(t, queue.Drop(2))
+
+	events, err = queue.Peek(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{EventName: "third"}}, events)
+}
+
+func TestStorageQueue_DropsOldestEventsWhenLimitIsReached(t *testing.T) {
+	dir := boltdbtest.CreateTempDir(t)
+	defer boltdbtest.RemoveTempDir(t, dir)
+	storage, err := boltdb.NewStorage(dir)
+	assert.NoError(t, err)
+	defer storage.Close()
+
+	queue := NewStorageQueue(storage, 2)
+	for _, name := range []string{"first", "second", "third"} {
+		assert.NoError(t, queue.Push(Event{EventName: name}))
+	}
+
+	events, err := queue.Peek(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{EventName: "second"}, {EventName: "third"}}, events)
+}
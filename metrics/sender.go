@@ -18,23 +18,67 @@
 package metrics
 
 import (
+	"sync"
 	"time"
+
+	log "github.com/cihub/seelog"
 )
 
+const metricsLogPrefix = "[metrics] "
+
 const appName = "myst"
-const startupEventName = "startup"
 
-// Sender builds events and sends them using given transport
+const (
+	startupEventName           = "startup"
+	sessionStartedEventName    = "session_started"
+	sessionEndedEventName      = "session_ended"
+	connectionFailedEventName  = "connection_failed"
+	proposalRegisteredName     = "proposal_registered"
+	proposalRegisterFailedName = "proposal_register_failed"
+	dialogsRejectedEventName   = "dialogs_rejected"
+)
+
+// Category groups events, so that user is able to opt-out from some of them
+type Category string
+
+const (
+	// CategoryStartup groups node startup events
+	CategoryStartup = Category("startup")
+	// CategorySession groups session start and end events
+	CategorySession = Category("session")
+	// CategoryConnection groups connection failure events
+	CategoryConnection = Category("connection")
+	// CategoryProposal groups proposal registration events
+	CategoryProposal = Category("proposal")
+	// CategoryDialog groups rejected dialog events
+	CategoryDialog = Category("dialog")
+)
+
+// Sender builds events and sends them using given transport.
+// If queue is given, events are delivered in batches with given interval, otherwise each event is sent immediately.
 type Sender struct {
 	Transport Transport
+
+	appVersion         string
+	queue              EventQueue
+	batchSize          int
+	sendInterval       time.Duration
+	disabledCategories map[Category]bool
+	// flushLock keeps periodical and final deliveries from sending the same queued events twice
+	flushLock sync.Mutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // Transport allows sending events
 type Transport interface {
-	sendEvent(event) error
+	SendEvent(Event) error
+	SendEvents([]Event) error
 }
 
-type event struct {
+// Event represents a single metrics event
+type Event struct {
 	Application applicationInfo `json:"application"`
 	EventName   string          `json:"eventName"`
 	CreatedAt   int64           `json:"createdAt"`
@@ -46,10 +90,178 @@ type applicationInfo struct {
 	Version string `json:"version"`
 }
 
+type sessionContext struct {
+	ServiceType string `json:"serviceType"`
+	Duration    int64  `json:"duration,omitempty"`
+}
+
+type connectionFailureContext struct {
+	ServiceType string `json:"serviceType"`
+	ErrorClass  string `json:"errorClass"`
+}
+
+type proposalContext struct {
+	ServiceType string `json:"serviceType"`
+	ErrorClass  string `json:"errorClass,omitempty"`
+}
+
+type dialogRejectionsContext struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// NewSender creates metrics sender which queues events and sends them in batches
+func NewSender(
+	transport Transport,
+	queue EventQueue,
+	appVersion string,
+	batchSize int,
+	sendInterval time.Duration,
+	disabledCategories []Category,
+) *Sender {
+	disabled := make(map[Category]bool)
+	for _, category := range disabledCategories {
+		disabled[category] = true
+	}
+
+	return &Sender{
+		Transport:          transport,
+		appVersion:         appVersion,
+		queue:              queue,
+		batchSize:          batchSize,
+		sendInterval:       sendInterval,
+		disabledCategories: disabled,
+		stop:               make(chan struct{}),
+	}
+}
+
 // SendStartupEvent sends startup event
 func (sender *Sender) SendStartupEvent(version string) error {
-	appInfo := applicationInfo{Name: appName, Version: version}
-	event := event{Application: appInfo, EventName: startupEventName, CreatedAt: time.Now().Unix()}
+	if sender.isDisabled(CategoryStartup) {
+		return nil
+	}
+
+	event := newEvent(version, startupEventName, nil)
+	err := sender.Transport.SendEvent(event)
+	if err != nil && sender.queue != nil {
+		// keep event for a later delivery, when metrics service is reachable again
+		if queueErr := sender.queue.Push(event); queueErr != nil {
+			log.Warn(metricsLogPrefix, "Failed to queue startup event: ", queueErr)
+		}
+	}
+	return err
+}
+
+// SendSessionStartedEvent sends event about started session of given service type
+func (sender *Sender) SendSessionStartedEvent(serviceType string) error {
+	return sender.send(CategorySession, sessionStartedEventName, sessionContext{ServiceType: serviceType})
+}
+
+// SendSessionEndedEvent sends event about ended session of given service type and its duration
+func (sender *Sender) SendSessionEndedEvent(serviceType string, duration time.Duration) error {
+	context := sessionContext{ServiceType: serviceType, Duration: int64(duration.Seconds())}
+	return sender.send(CategorySession, sessionEndedEventName, context)
+}
+
+// SendConnectionFailedEvent sends event about connection failure with a class of given error
+func (sender *Sender) SendConnectionFailedEvent(serviceType string, err error) error {
+	context := connectionFailureContext{ServiceType: serviceType, ErrorClass: errorClass(err)}
+	return sender.send(CategoryConnection, connectionFailedEventName, context)
+}
+
+// SendProposalRegistrationEvent sends event about proposal registration outcome
+func (sender *Sender) SendProposalRegistrationEvent(serviceType string, err error) error {
+	if err != nil {
+		context := proposalContext{ServiceType: serviceType, ErrorClass: errorClass(err)}
+		return sender.send(CategoryProposal, proposalRegisterFailedName, context)
+	}
+	return sender.send(CategoryProposal, proposalRegisteredName, proposalContext{ServiceType: serviceType})
+}
+
+// SendDialogsRejectedEvent sends event about the number of dialogs rejected for given reason
+func (sender *Sender) SendDialogsRejectedEvent(reason string, count int) error {
+	return sender.send(CategoryDialog, dialogsRejectedEventName, dialogRejectionsContext{Reason: reason, Count: count})
+}
+
+// Start starts periodical delivery of queued events
+func (sender *Sender) Start() {
+	if sender.queue == nil {
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case <-sender.stop:
+				return
+			case <-time.After(sender.sendInterval):
+				if err := sender.Flush(); err != nil {
+					log.Debug(metricsLogPrefix, "Failed to deliver queued events, will retry later: ", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops periodical delivery and makes the last attempt to deliver queued events
+func (sender *Sender) Stop() {
+	if sender.queue == nil {
+		return
+	}
+
+	sender.stopOnce.Do(func() {
+		close(sender.stop)
+		if err := sender.Flush(); err != nil {
+			log.Warn(metricsLogPrefix, "Failed to deliver queued events: ", err)
+		}
+	})
+}
+
+// Flush sends all queued events in batches. Events stay in the queue if transport fails.
+func (sender *Sender) Flush() error {
+	sender.flushLock.Lock()
+	defer sender.flushLock.Unlock()
+
+	for {
+		events, err := sender.queue.Peek(sender.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		if err := sender.Transport.SendEvents(events); err != nil {
+			return err
+		}
+
+		if err := sender.queue.Drop(len(events)); err != nil {
+			return err
+		}
+	}
+}
+
+func (sender *Sender) send(category Category, eventName string, context interface{}) error {
+	if sender.isDisabled(category) {
+		return nil
+	}
+
+	event := newEvent(sender.appVersion, eventName, context)
+	if sender.queue == nil {
+		return sender.Transport.SendEvent(event)
+	}
+	return sender.queue.Push(event)
+}
+
+func (sender *Sender) isDisabled(category Category) bool {
+	return sender.disabledCategories[category]
+}
 
-	return sender.Transport.sendEvent(event)
+func newEvent(version, eventName string, context interface{}) Event {
+	return Event{
+		Application: applicationInfo{Name: appName, Version: version},
+		EventName:   eventName,
+		CreatedAt:   time.Now().Unix(),
+		Context:     context,
+	}
 }
@@ -19,13 +19,16 @@ package metrics
 
 import (
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 type mockEventsTransport struct {
-	sentEvent    event
+	sentEvent    Event
+	sentBatches  [][]Event
 	mockResponse error
 }
 
@@ -33,11 +36,53 @@ func buildMockEventsTransport(mockResponse error) *mockEventsTransport {
 	return &mockEventsTransport{mockResponse: mockResponse}
 }
 
-func (transport *mockEventsTransport) sendEvent(event event) error {
+func (transport *mockEventsTransport) SendEvent(event Event) error {
 	transport.sentEvent = event
 	return transport.mockResponse
 }
 
+func (transport *mockEventsTransport) SendEvents(events []Event) error {
+	if transport.mockResponse != nil {
+		return transport.mockResponse
+	}
+	transport.sentBatches = append(transport.sentBatches, events)
+	return nil
+}
+
+type mockEventQueue struct {
+	events []Event
+	lock   sync.Mutex
+}
+
+func (queue *mockEventQueue) Push(event Event) error {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	queue.events = append(queue.events, event)
+	return nil
+}
+
+func (queue *mockEventQueue) Peek(count int) ([]Event, error) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	if len(queue.events) < count {
+		count = len(queue.events)
+	}
+	return queue.events[:count], nil
+}
+
+func (queue *mockEventQueue) Drop(count int) error {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	queue.events = queue.events[count:]
+	return nil
+}
+
+func (queue *mockEventQueue) getEvents() []Event {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	return queue.events
+}
+
 func TestSender_SendStartupEvent_SendsToTransport(t *testing.T) {
 	mockTransport := buildMockEventsTransport(nil)
 	sender := &Sender{Transport: mockTransport}
@@ -64,3 +109,125 @@ func TestSender_SendStartupEvent_ReturnsTransportErrors(t *testing.T) {
 
 	assert.NotNil(t, sentEvent)
 }
+
+func TestSender_SendStartupEvent_QueuesEventOnTransportError(t *testing.T) {
+	mockTransport := buildMockEventsTransport(errors.New("mock error"))
+	queue := &mockEventQueue{}
+	sender := NewSender(mockTransport, queue, "test version", 10, time.Minute, nil)
+
+	err := sender.SendStartupEvent("test version")
+	assert.Error(t, err)
+
+	assert.Len(t, queue.events, 1)
+	assert.Equal(t, "startup", queue.events[0].EventName)
+}
+
+func TestSender_SendSessionEndedEvent_QueuesEvent(t *testing.T) {
+	mockTransport := buildMockEventsTransport(nil)
+	queue := &mockEventQueue{}
+	sender := NewSender(mockTransport, queue, "test version", 10, time.Minute, nil)
+
+	err := sender.SendSessionEndedEvent("openvpn", 90*time.Second)
+	assert.NoError(t, err)
+
+	assert.Len(t, queue.events, 1)
+	assert.Equal(t, "session_ended", queue.events[0].EventName)
+	assert.Equal(t, applicationInfo{Name: "myst", Version: "test version"}, queue.events[0].Application)
+	assert.Equal(t, sessionContext{ServiceType: "openvpn", Duration: 90}, queue.events[0].Context)
+	assert.Empty(t, mockTransport.sentBatches)
+}
+
+func TestSender_SkipsEventsOfDisabledCategories(t *testing.T) {
+	mockTransport := buildMockEventsTransport(nil)
+	queue := &mockEventQueue{}
+	sender := NewSender(mockTransport, queue, "test version", 10, time.Minute, []Category{CategorySession, CategoryStartup})
+
+	assert.NoError(t, sender.SendStartupEvent("test version"))
+	assert.NoError(t, sender.SendSessionStartedEvent("openvpn"))
+	assert.NoError(t, sender.SendConnectionFailedEvent("openvpn", errors.New("failure")))
+
+	assert.Zero(t, mockTransport.sentEvent)
+	assert.Len(t, queue.events, 1)
+	assert.Equal(t, "connection_failed", queue.events[0].EventName)
+}
+
+func TestSender_Flush_SendsQueuedEventsInBatches(t *testing.T) {
+	mockTransport := buildMockEventsTransport(nil)
+	queue := &mockEventQueue{}
+	sender := NewSender(mockTransport, queue, "test version", 2, time.Minute, nil)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sender.SendSessionStartedEvent("noop"))
+	}
+
+	err := sender.Flush()
+	assert.NoError(t, err)
+
+	assert.Len(t, mockTransport.sentBatches, 2)
+	assert.Len(t, mockTransport.sentBatches[0], 2)
+	assert.Len(t, mockTransport.sentBatches[1], 1)
+	assert.Empty(t, queue.events)
+}
+
+func TestSender_Flush_SendsEachEventOnceWhenCalledConcurrently(t *testing.T) {
+	transport := &slowEventsTransport{}
+	queue := &mockEventQueue{}
+	sender := NewSender(transport, queue, "1", 1, time.Minute, nil)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, sender.SendSessionStartedEvent("openvpn"))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, sender.Flush())
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, transport.sentCount())
+	assert.Empty(t, queue.getEvents())
+}
+
+type slowEventsTransport struct {
+	sent int
+	lock sync.Mutex
+}
+
+func (transport *slowEventsTransport) SendEvent(event Event) error {
+	return transport.SendEvents([]Event{event})
+}
+
+func (transport *slowEventsTransport) SendEvents(events []Event) error {
+	time.Sleep(time.Millisecond)
+	transport.lock.Lock()
+	defer transport.lock.Unlock()
+	transport.sent += len(events)
+	return nil
+}
+
+func (transport *slowEventsTransport) sentCount() int {
+	transport.lock.Lock()
+	defer transport.lock.Unlock()
+	return transport.sent
+}
+
+func TestSender_Flush_KeepsEventsOnTransportError(t *testing.T) {
+	mockTransport := buildMockEventsTransport(errors.New("offline"))
+	queue := &mockEventQueue{}
+	sender := NewSender(mockTransport, queue, "test version", 2, time.Minute, nil)
+
+	assert.NoError(t, sender.SendSessionStartedEvent("noop"))
+
+	err := sender.Flush()
+	assert.EqualError(t, err, "offline")
+	assert.Len(t, queue.events, 1)
+
+	mockTransport.mockResponse = nil
+	err = sender.Flush()
+	assert.NoError(t, err)
+	assert.Empty(t, queue.events)
+	assert.Len(t, mockTransport.sentBatches, 1)
+}
@@ -0,0 +1,303 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mysterium
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+)
+
+// errNoProposals is returned when provider does not offer the requested service
+var errNoProposals = errors.New("provider has no service proposals")
+
+type proposalFinder interface {
+	FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error)
+}
+
+type statisticsTracker interface {
+	Retrieve() consumer.SessionStatistics
+	GetSessionDuration() time.Duration
+}
+
+type eventSubscriber interface {
+	Subscribe(topic string, fn interface{}) error
+}
+
+// IdentityList is a list of identity addresses, it is used since gomobile does not support slices
+type IdentityList struct {
+	addresses []string
+}
+
+// Len returns count of identities in the list
+func (list *IdentityList) Len() int {
+	return len(list.addresses)
+}
+
+// Get returns address of identity at the given index
+func (list *IdentityList) Get(index int) string {
+	return list.addresses[index]
+}
+
+// Proposal describes service offered by provider
+type Proposal struct {
+	ID          int
+	ProviderID  string
+	ServiceType string
+	Country     string
+}
+
+// ProposalList is a list of proposals, it is used since gomobile does not support slices
+type ProposalList struct {
+	proposals []*Proposal
+}
+
+// Len returns count of proposals in the list
+func (list *ProposalList) Len() int {
+	return len(list.proposals)
+}
+
+// Get returns proposal at the given index
+func (list *ProposalList) Get(index int) *Proposal {
+	return list.proposals[index]
+}
+
+// ConnectionStatus describes current state of the connection
+type ConnectionStatus struct {
+	State       string
+	SessionID   string
+	ProviderID  string
+	ServiceType string
+}
+
+// Statistics describes traffic and duration of the current connection
+type Statistics struct {
+	Duration      int64
+	BytesReceived int64
+	BytesSent     int64
+}
+
+// ConnectionStatusCallback is notified about every change of the connection state
+type ConnectionStatusCallback interface {
+	OnChange(state string)
+}
+
+// StatisticsCallback is notified about every statistics update of the current connection
+type StatisticsCallback interface {
+	OnChange(duration int64, bytesReceived int64, bytesSent int64)
+}
+
+// GetIdentities returns addresses of all identities of the node
+func (mobNode *MobileNode) GetIdentities() *IdentityList {
+	identities := mobNode.identityManager.GetIdentities()
+	list := &IdentityList{addresses: make([]string, len(identities))}
+	for i, id := range identities {
+		list.addresses[i] = id.Address
+	}
+	return list
+}
+
+// CreateIdentity creates new identity protected by given passphrase and returns its address
+func (mobNode *MobileNode) CreateIdentity(passphrase string) (string, error) {
+	id, err := mobNode.identityManager.CreateNewIdentity(passphrase)
+	if err != nil {
+		return "", err
+	}
+	return id.Address, nil
+}
+
+// UnlockIdentity unlocks identity for signing with given passphrase
+func (mobNode *MobileNode) UnlockIdentity(address, passphrase string) error {
+	return mobNode.identityManager.Unlock(address, passphrase)
+}
+
+// GetProposals returns proposals of the given service type, empty service type returns proposals of all types
+func (mobNode *MobileNode) GetProposals(serviceType string) (*ProposalList, error) {
+	proposals, err := mobNode.proposalFinder.FindProposals("", serviceType)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &ProposalList{proposals: make([]*Proposal, len(proposals))}
+	for i, proposal := range proposals {
+		list.proposals[i] = proposalFromMarket(proposal)
+	}
+	return list, nil
+}
+
+// Connect connects consumer to the service of given type offered by provider.
+// It returns once connection is established or has failed.
+func (mobNode *MobileNode) Connect(consumerID, providerID, serviceType string, disableKillSwitch bool) error {
+	proposals, err := mobNode.proposalFinder.FindProposals(providerID, serviceType)
+	if err != nil {
+		return err
+	}
+	if len(proposals) == 0 {
+		return errNoProposals
+	}
+
+	return mobNode.connectionManager.Connect(
+		identity.FromAddress(consumerID),
+		proposals[0],
+		connection.ConnectParams{DisableKillSwitch: disableKillSwitch},
+	)
+}
+
+// Disconnect closes current connection
+func (mobNode *MobileNode) Disconnect() error {
+	return mobNode.connectionManager.Disconnect()
+}
+
+// GetStatus returns status of the current connection
+func (mobNode *MobileNode) GetStatus() *ConnectionStatus {
+	status := mobNode.connectionManager.Status()
+	return &ConnectionStatus{
+		State:       string(status.State),
+		SessionID:   string(status.SessionID),
+		ProviderID:  status.Proposal.ProviderID,
+		ServiceType: status.Proposal.ServiceType,
+	}
+}
+
+// GetStatistics returns statistics of the current connection, added up over its reconnects
+func (mobNode *MobileNode) GetStatistics() *Statistics {
+	stats := mobNode.statisticsTracker.Retrieve()
+	return &Statistics{
+		Duration:      int64(mobNode.statisticsTracker.GetSessionDuration().Seconds()),
+		BytesReceived: int64(stats.BytesReceived),
+		BytesSent:     int64(stats.BytesSent),
+	}
+}
+
+// RegisterConnectionStatusCallback registers callback notified about connection state changes
+func (mobNode *MobileNode) RegisterConnectionStatusCallback(callback ConnectionStatusCallback) error {
+	mobNode.callbacksLock.Lock()
+	defer mobNode.callbacksLock.Unlock()
+
+	if err := mobNode.subscribeOnce(connection.StateEventTopic, mobNode.notifyConnectionStatus); err != nil {
+		return err
+	}
+	mobNode.connectionStatusCallbacks = append(mobNode.connectionStatusCallbacks, callback)
+	return nil
+}
+
+// UnregisterConnectionStatusCallback stops notifying the registered callback about connection state changes
+func (mobNode *MobileNode) UnregisterConnectionStatusCallback(callback ConnectionStatusCallback) {
+	mobNode.callbacksLock.Lock()
+	defer mobNode.callbacksLock.Unlock()
+
+	for i, registered := range mobNode.connectionStatusCallbacks {
+		if sameCallback(registered, callback) {
+			mobNode.connectionStatusCallbacks = append(mobNode.connectionStatusCallbacks[:i:i], mobNode.connectionStatusCallbacks[i+1:]...)
+			return
+		}
+	}
+}
+
+// RegisterStatisticsCallback registers callback notified about statistics updates of the current connection
+func (mobNode *MobileNode) RegisterStatisticsCallback(callback StatisticsCallback) error {
+	mobNode.callbacksLock.Lock()
+	defer mobNode.callbacksLock.Unlock()
+
+	if err := mobNode.subscribeOnce(connection.StatisticsEventTopic, mobNode.notifyStatistics); err != nil {
+		return err
+	}
+	mobNode.statisticsCallbacks = append(mobNode.statisticsCallbacks, callback)
+	return nil
+}
+
+// UnregisterStatisticsCallback stops notifying the registered callback about statistics updates
+func (mobNode *MobileNode) UnregisterStatisticsCallback(callback StatisticsCallback) {
+	mobNode.callbacksLock.Lock()
+	defer mobNode.callbacksLock.Unlock()
+
+	for i, registered := range mobNode.statisticsCallbacks {
+		if sameCallback(registered, callback) {
+			mobNode.statisticsCallbacks = append(mobNode.statisticsCallbacks[:i:i], mobNode.statisticsCallbacks[i+1:]...)
+			return
+		}
+	}
+}
+
+// subscribeOnce subscribes the handler to the topic unless it is subscribed already, callbacks lock has to be held
+func (mobNode *MobileNode) subscribeOnce(topic string, handler interface{}) error {
+	if mobNode.subscribedTopics[topic] {
+		return nil
+	}
+	if err := mobNode.eventSubscriber.Subscribe(topic, handler); err != nil {
+		return err
+	}
+	if mobNode.subscribedTopics == nil {
+		mobNode.subscribedTopics = make(map[string]bool)
+	}
+	mobNode.subscribedTopics[topic] = true
+	return nil
+}
+
+func (mobNode *MobileNode) notifyConnectionStatus(event connection.StateEvent) {
+	mobNode.callbacksLock.Lock()
+	callbacks := mobNode.connectionStatusCallbacks
+	mobNode.callbacksLock.Unlock()
+
+	for _, callback := range callbacks {
+		callback.OnChange(string(event.State))
+	}
+}
+
+func (mobNode *MobileNode) notifyStatistics(consumer.SessionStatistics) {
+	mobNode.callbacksLock.Lock()
+	callbacks := mobNode.statisticsCallbacks
+	mobNode.callbacksLock.Unlock()
+
+	stats := mobNode.GetStatistics()
+	for _, callback := range callbacks {
+		callback.OnChange(stats.Duration, stats.BytesReceived, stats.BytesSent)
+	}
+}
+
+// gomobileProxy is implemented by gomobile wrappers of the objects passed from Java or Objective-C
+type gomobileProxy interface {
+	Bind_proxy_refnum__() int32
+}
+
+// sameCallback tells if both callbacks are the same object.
+// gomobile wraps the foreign object into a new proxy on every call, so proxies are compared by the object they refer to.
+func sameCallback(a, b interface{}) bool {
+	proxyA, okA := a.(gomobileProxy)
+	proxyB, okB := b.(gomobileProxy)
+	if okA && okB {
+		return proxyA.Bind_proxy_refnum__() == proxyB.Bind_proxy_refnum__()
+	}
+	return a == b
+}
+
+func proposalFromMarket(proposal market.ServiceProposal) *Proposal {
+	mobileProposal := &Proposal{
+		ID:          proposal.ID,
+		ProviderID:  proposal.ProviderID,
+		ServiceType: proposal.ServiceType,
+	}
+	if proposal.ServiceDefinition != nil {
+		mobileProposal.Country = proposal.ServiceDefinition.GetLocation().Country
+	}
+	return mobileProposal
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mysterium
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/mysteriumnetwork/node/consumer"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/stretchr/testify/assert"
+)
+
+type serviceDefinitionFake struct{}
+
+func (serviceDefinitionFake) GetLocation() market.Location {
+	return market.Location{Country: "LT"}
+}
+
+type proposalFinderFake struct {
+	proposals []market.ServiceProposal
+	err       error
+
+	providerID  string
+	serviceType string
+}
+
+func (finder *proposalFinderFake) FindProposals(providerID string, serviceType string) ([]market.ServiceProposal, error) {
+	finder.providerID = providerID
+	finder.serviceType = serviceType
+	return finder.proposals, finder.err
+}
+
+type connectionManagerFake struct {
+	status connection.Status
+
+	consumerID identity.Identity
+	proposal   market.ServiceProposal
+	params     connection.ConnectParams
+}
+
+func (manager *connectionManagerFake) Connect(consumerID identity.Identity, proposal market.ServiceProposal, params connection.ConnectParams) error {
+	manager.consumerID = consumerID
+	manager.proposal = proposal
+	manager.params = params
+	return nil
+}
+
+func (manager *connectionManagerFake) Status() connection.Status {
+	return manager.status
+}
+
+func (manager *connectionManagerFake) Disconnect() error {
+	return nil
+}
+
+type statisticsTrackerFake struct {
+	stats consumer.SessionStatistics
+}
+
+func (tracker *statisticsTrackerFake) Retrieve() consumer.SessionStatistics {
+	return tracker.stats
+}
+
+func (tracker *statisticsTrackerFake) GetSessionDuration() time.Duration {
+	return time.Minute
+}
+
+type statisticsCallbackFake struct {
+	duration, bytesReceived, bytesSent int64
+}
+
+func (callback *statisticsCallbackFake) OnChange(duration int64, bytesReceived int64, bytesSent int64) {
+	callback.duration = duration
+	callback.bytesReceived = bytesReceived
+	callback.bytesSent = bytesSent
+}
+
+type statusCallbackFake struct {
+	states []string
+}
+
+func (callback *statusCallbackFake) OnChange(state string) {
+	callback.states = append(callback.states, state)
+}
+
+var proposalFake = market.ServiceProposal{
+	ID:                1,
+	ProviderID:        "0xprovider",
+	ServiceType:       "wireguard",
+	ServiceDefinition: serviceDefinitionFake{},
+}
+
+func TestMobileNode_GetProposals(t *testing.T) {
+	finder := &proposalFinderFake{proposals: []market.ServiceProposal{proposalFake}}
+	mobNode := &MobileNode{proposalFinder: finder}
+
+	proposals, err := mobNode.GetProposals("wireguard")
+	assert.NoError(t, err)
+	assert.Equal(t, "wireguard", finder.serviceType)
+	assert.Equal(t, 1, proposals.Len())
+	assert.Equal(t, &Proposal{ID: 1, ProviderID: "0xprovider", ServiceType: "wireguard", Country: "LT"}, proposals.Get(0))
+
+	finder.err = errors.New("discovery unavailable")
+	_, err = mobNode.GetProposals("wireguard")
+	assert.EqualError(t, err, "discovery unavailable")
+}
+
+func TestMobileNode_ConnectUsesProviderProposal(t *testing.T) {
+	finder := &proposalFinderFake{proposals: []market.ServiceProposal{proposalFake}}
+	manager := &connectionManagerFake{}
+	mobNode := &MobileNode{proposalFinder: finder, connectionManager: manager}
+
+	err := mobNode.Connect("0xconsumer", "0xprovider", "wireguard", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "0xprovider", finder.providerID)
+	assert.Equal(t, identity.FromAddress("0xconsumer"), manager.consumerID)
+	assert.Equal(t, proposalFake, manager.proposal)
+	assert.Equal(t, connection.ConnectParams{DisableKillSwitch: true}, manager.params)
+
+	finder.proposals = nil
+	assert.Equal(t, errNoProposals, mobNode.Connect("0xconsumer", "0xprovider", "wireguard", true))
+}
+
+func TestMobileNode_GetStatus(t *testing.T) {
+	manager := &connectionManagerFake{
+		status: connection.Status{State: connection.Connected, SessionID: "session", Proposal: proposalFake},
+	}
+	mobNode := &MobileNode{connectionManager: manager}
+
+	assert.Equal(
+		t,
+		&ConnectionStatus{State: "Connected", SessionID: "session", ProviderID: "0xprovider", ServiceType: "wireguard"},
+		mobNode.GetStatus(),
+	)
+}
+
+func TestMobileNode_CallbacksAreNotifiedAboutEvents(t *testing.T) {
+	bus := EventBus.New()
+	tracker := &statisticsTrackerFake{stats: consumer.SessionStatistics{BytesReceived: 20, BytesSent: 10}}
+	mobNode := &MobileNode{statisticsTracker: tracker, eventSubscriber: bus}
+
+	statusCallback := &statusCallbackFake{}
+	assert.NoError(t, mobNode.RegisterConnectionStatusCallback(statusCallback))
+	statisticsCallback := &statisticsCallbackFake{}
+	assert.NoError(t, mobNode.RegisterStatisticsCallback(statisticsCallback))
+
+	bus.Publish(connection.StateEventTopic, connection.StateEvent{State: connection.Connecting})
+	bus.Publish(connection.StateEventTopic, connection.StateEvent{State: connection.Connected})
+	bus.Publish(connection.StatisticsEventTopic, consumer.SessionStatistics{BytesReceived: 2, BytesSent: 1})
+
+	assert.Equal(t, []string{"Connecting", "Connected"}, statusCallback.states)
+	assert.Equal(t, &statisticsCallbackFake{duration: 60, bytesReceived: 20, bytesSent: 10}, statisticsCallback)
+}
+
+func TestMobileNode_UnregisteredCallbacksAreNotNotified(t *testing.T) {
+	bus := EventBus.New()
+	tracker := &statisticsTrackerFake{}
+	mobNode := &MobileNode{statisticsTracker: tracker, eventSubscriber: bus}
+
+	statusCallback := &statusCallbackFake{}
+	otherStatusCallback := &statusCallbackFake{}
+	assert.NoError(t, mobNode.RegisterConnectionStatusCallback(statusCallback))
+	assert.NoError(t, mobNode.RegisterConnectionStatusCallback(otherStatusCallback))
+	statisticsCallback := &statisticsCallbackFake{}
+	assert.NoError(t, mobNode.RegisterStatisticsCallback(statisticsCallback))
+
+	bus.Publish(connection.StateEventTopic, connection.StateEvent{State: connection.Connecting})
+	mobNode.UnregisterConnectionStatusCallback(statusCallback)
+	mobNode.UnregisterStatisticsCallback(statisticsCallback)
+	bus.Publish(connection.StateEventTopic, connection.StateEvent{State: connection.Connected})
+	bus.Publish(connection.StatisticsEventTopic, consumer.SessionStatistics{})
+
+	assert.Equal(t, []string{"Connecting"}, statusCallback.states)
+	assert.Equal(t, []string{"Connecting", "Connected"}, otherStatusCallback.states)
+	assert.Equal(t, &statisticsCallbackFake{}, statisticsCallback)
+}
+
+type proxyCallbackFake struct {
+	statusCallbackFake
+	refnum int32
+}
+
+func (callback *proxyCallbackFake) Bind_proxy_refnum__() int32 {
+	return callback.refnum
+}
+
+func TestMobileNode_UnregistersCallbackPassedThroughNewProxy(t *testing.T) {
+	mobNode := &MobileNode{eventSubscriber: EventBus.New()}
+
+	assert.NoError(t, mobNode.RegisterConnectionStatusCallback(&proxyCallbackFake{refnum: 1}))
+	assert.NoError(t, mobNode.RegisterConnectionStatusCallback(&proxyCallbackFake{refnum: 2}))
+	mobNode.UnregisterConnectionStatusCallback(&proxyCallbackFake{refnum: 1})
+
+	assert.Len(t, mobNode.connectionStatusCallbacks, 1)
+	assert.Equal(t, int32(2), mobNode.connectionStatusCallbacks[0].(*proxyCallbackFake).refnum)
+}
@@ -19,25 +19,65 @@ package mysterium
 
 import (
 	"path/filepath"
+	"sync"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/mysteriumnetwork/node/cmd"
+	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/core/node"
+	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/metadata"
 )
 
 // MobileNode represents node object tuned for mobile devices
 type MobileNode struct {
 	di cmd.Dependencies
+
+	identityManager   identity.Manager
+	proposalFinder    proposalFinder
+	connectionManager connection.Manager
+	statisticsTracker statisticsTracker
+	eventSubscriber   eventSubscriber
+
+	callbacksLock             sync.Mutex
+	connectionStatusCallbacks []ConnectionStatusCallback
+	statisticsCallbacks       []StatisticsCallback
+	subscribedTopics          map[string]bool
 }
 
 // MobileNetworkOptions alias for node.OptionsNetwork to be visible from mobile framework
 type MobileNetworkOptions node.OptionsNetwork
 
-// NewNode function creates new Node
+// MobileNodeOptions contains options of the node not related to network
+type MobileNodeOptions struct {
+	// TequilapiEnabled starts Tequilapi http server, which is not needed when node is controlled through MobileNode methods
+	TequilapiEnabled bool
+	TequilapiAddress string
+	TequilapiPort    int
+}
+
+// DefaultNodeOptions returns default node options, Tequilapi is served on 127.0.0.1:4050
+func DefaultNodeOptions() *MobileNodeOptions {
+	return &MobileNodeOptions{
+		TequilapiEnabled: true,
+		TequilapiAddress: "127.0.0.1",
+		TequilapiPort:    4050,
+	}
+}
+
+// NewNode function creates new Node with DefaultNodeOptions
 func NewNode(appPath string, optionsNetwork *MobileNetworkOptions) (*MobileNode, error) {
+	return NewNodeWithOptions(appPath, optionsNetwork, DefaultNodeOptions())
+}
+
+// NewNodeWithOptions function creates new Node with the given options, nil nodeOptions stands for DefaultNodeOptions
+func NewNodeWithOptions(appPath string, optionsNetwork *MobileNetworkOptions, nodeOptions *MobileNodeOptions) (*MobileNode, error) {
 	var di cmd.Dependencies
 
+	if nodeOptions == nil {
+		nodeOptions = DefaultNodeOptions()
+	}
+
 	var dataDir, currentDir string
 	if appPath == "" {
 		currentDir, err := homedir.Dir()
@@ -58,8 +98,9 @@ func NewNode(appPath string, optionsNetwork *MobileNetworkOptions) (*MobileNode,
 			Runtime:  currentDir,
 		},
 
-		TequilapiAddress: "127.0.0.1",
-		TequilapiPort:    4050,
+		DisableTequilapi: !nodeOptions.TequilapiEnabled,
+		TequilapiAddress: nodeOptions.TequilapiAddress,
+		TequilapiPort:    nodeOptions.TequilapiPort,
 
 		DisableMetrics: false,
 		MetricsAddress: "http://metrics.mysterium.network:8091",
@@ -80,7 +121,14 @@ func NewNode(appPath string, optionsNetwork *MobileNetworkOptions) (*MobileNode,
 		return nil, err
 	}
 
-	return &MobileNode{di: di}, nil
+	return &MobileNode{
+		di:                di,
+		identityManager:   di.IdentityManager,
+		proposalFinder:    di.MysteriumAPI,
+		connectionManager: di.ConnectionManager,
+		statisticsTracker: di.StatisticsTracker,
+		eventSubscriber:   di.EventBus,
+	}, nil
 }
 
 // DefaultNetworkOptions returns default network options to connect with
@@ -26,6 +26,7 @@ import (
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/services/openvpn"
 	"github.com/mysteriumnetwork/node/services/openvpn/session"
 )
@@ -62,7 +63,7 @@ func (wrapper *sessionWrapper) Wait() error {
 	return errSessionWrapperNotStarted
 }
 
-func (wrapper *sessionWrapper) GetConfig() (connection.ConsumerConfig, error) {
+func (wrapper *sessionWrapper) GetConfig(_ market.ServiceProposal) (connection.ConsumerConfig, error) {
 	return nil, nil
 }
 
@@ -27,6 +27,7 @@ import (
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/services/wireguard"
 	"github.com/mysteriumnetwork/node/services/wireguard/key"
 	"github.com/mysteriumnetwork/wireguard-go/device"
@@ -256,7 +257,7 @@ func (wg *wireguardConnection) Stop() {
 	close(wg.stopChannel)
 }
 
-func (wg *wireguardConnection) GetConfig() (connection.ConsumerConfig, error) {
+func (wg *wireguardConnection) GetConfig(_ market.ServiceProposal) (connection.ConsumerConfig, error) {
 	publicKey, err := key.PrivateKeyToPublicKey(wg.privKey)
 	if err != nil {
 		return nil, err
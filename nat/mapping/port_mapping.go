@@ -18,6 +18,7 @@
 package mapping
 
 import (
+	"sync"
 	"time"
 
 	log "github.com/cihub/seelog"
@@ -31,6 +32,128 @@ const (
 	mapUpdateInterval = 15 * time.Minute
 )
 
+// StatusTopic is the topic port mapping status is published to after every mapping attempt
+const StatusTopic = "port-mapping-status"
+
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
+// Status describes the outcome of the latest attempt to map the port on a gateway
+type Status struct {
+	Name         string
+	Protocol     string
+	InternalPort int
+	ExternalPort int
+	// RouterType describes the gateway and the mapping protocol it was discovered with, e.g. UPnP or NAT-PMP
+	RouterType string
+	Mapped     bool
+	// Lease is the lifetime of the mapping, zero stands for a permanent lease
+	Lease     time.Duration
+	LastError error
+	UpdatedAt time.Time
+}
+
+// PortMapper maps ports on a gateway and keeps track of the mapping status
+type PortMapper interface {
+	Map(protocol string, port int, name string) (release func())
+	Statuses() []Status
+}
+
+// NewPortMapper returns port mapper which publishes status of the mappings to the given publisher
+func NewPortMapper(publisher Publisher) PortMapper {
+	return &portMapper{
+		publisher: publisher,
+		gateway:   portmap.Any,
+	}
+}
+
+type portMapper struct {
+	publisher Publisher
+	gateway   func() portmap.Interface
+
+	lock     sync.Mutex
+	mappings []*portMapping
+}
+
+type portMapping struct {
+	status   Status
+	released bool
+}
+
+// Map maps given port of given protocol from external IP on a gateway to local machine internal IP
+// 'name' denotes rule name added on a gateway.
+func (pm *portMapper) Map(protocol string, port int, name string) (release func()) {
+	gateway := pm.gateway()
+	mapping := &portMapping{}
+	pm.lock.Lock()
+	pm.mappings = append(pm.mappings, mapping)
+	pm.lock.Unlock()
+
+	report := func(status Status) {
+		pm.report(mapping, status)
+	}
+	report(Status{
+		Name:         name,
+		Protocol:     protocol,
+		InternalPort: port,
+		ExternalPort: port,
+		RouterType:   gateway.String(),
+		UpdatedAt:    time.Now(),
+	})
+
+	mapperQuit := make(chan struct{})
+	go mapPort(gateway, mapperQuit, protocol, port, port, name, report)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			pm.release(mapping)
+			close(mapperQuit)
+		})
+	}
+}
+
+// Statuses returns status of the ports currently being mapped
+func (pm *portMapper) Statuses() []Status {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	statuses := make([]Status, len(pm.mappings))
+	for i, mapping := range pm.mappings {
+		statuses[i] = mapping.status
+	}
+	return statuses
+}
+
+func (pm *portMapper) report(mapping *portMapping, status Status) {
+	pm.lock.Lock()
+	if mapping.released {
+		pm.lock.Unlock()
+		return
+	}
+	mapping.status = status
+	pm.lock.Unlock()
+
+	if pm.publisher != nil {
+		pm.publisher.Publish(StatusTopic, status)
+	}
+}
+
+func (pm *portMapper) release(mapping *portMapping) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	mapping.released = true
+	for i := range pm.mappings {
+		if pm.mappings[i] == mapping {
+			pm.mappings = append(pm.mappings[:i], pm.mappings[i+1:]...)
+			return
+		}
+	}
+}
+
 // GetPortMappingFunc returns PortMapping function if service is behind NAT
 func GetPortMappingFunc(pubIP, outIP, protocol string, port int, description string) func() {
 	if pubIP != outIP {
@@ -42,20 +165,13 @@ func GetPortMappingFunc(pubIP, outIP, protocol string, port int, description str
 // PortMapping maps given port of given protocol from external IP on a gateway to local machine internal IP
 // 'name' denotes rule name added on a gateway.
 func PortMapping(protocol string, port int, name string) func() {
-	mapperQuit := make(chan struct{})
-	go mapPort(portmap.Any(),
-		mapperQuit,
-		protocol,
-		port,
-		port,
-		name)
-
-	return func() { close(mapperQuit) }
+	return NewPortMapper(nil).Map(protocol, port, name)
 }
 
 // mapPort adds a port mapping on m and keeps it alive until c is closed.
+// Outcome of every attempt is handed to report.
 // This function is typically invoked in its own goroutine.
-func mapPort(m portmap.Interface, c chan struct{}, protocol string, extPort, intPort int, name string) {
+func mapPort(m portmap.Interface, c chan struct{}, protocol string, extPort, intPort int, name string, report func(Status)) {
 	defer func() {
 		log.Debug(logPrefix, "Deleting port mapping for port: ", extPort)
 
@@ -64,7 +180,7 @@ func mapPort(m portmap.Interface, c chan struct{}, protocol string, extPort, int
 		}
 	}()
 	for {
-		addMapping(m, protocol, extPort, intPort, name)
+		report(addMapping(m, protocol, extPort, intPort, name))
 		select {
 		case <-c:
 			return
@@ -73,14 +189,30 @@ func mapPort(m portmap.Interface, c chan struct{}, protocol string, extPort, int
 	}
 }
 
-func addMapping(m portmap.Interface, protocol string, extPort, intPort int, name string) {
+func addMapping(m portmap.Interface, protocol string, extPort, intPort int, name string) Status {
+	status := Status{
+		Name:         name,
+		Protocol:     protocol,
+		InternalPort: intPort,
+		ExternalPort: extPort,
+		Lease:        mapTimeout,
+	}
+
 	if err := m.AddMapping(protocol, extPort, intPort, name, mapTimeout); err != nil {
-		log.Debugf("%s, Couldn't add port mapping for port %d: %v, retrying with permanent lease", logPrefix, extPort, err)
+		log.Debugf("%sCouldn't add port mapping for port %d: %v, retrying with permanent lease", logPrefix, extPort, err)
+		// some gateways support only permanent leases
+		status.Lease = 0
 		if err := m.AddMapping(protocol, extPort, intPort, name, 0); err != nil {
-			// some gateways support only permanent leases
-			log.Debugf("%s Couldn't add port mapping for port %d: %v", logPrefix, extPort, err)
-			return
+			log.Warnf("%sCouldn't add port mapping for port %d on %s: %v", logPrefix, extPort, m, err)
+			status.LastError = err
 		}
 	}
-	log.Info(logPrefix, "Mapped network port:", extPort)
+	status.Mapped = status.LastError == nil
+	status.RouterType = m.String()
+	status.UpdatedAt = time.Now()
+
+	if status.Mapped {
+		log.Info(logPrefix, "Mapped network port: ", extPort, " on ", m)
+	}
+	return status
 }
@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mapping
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	portmap "github.com/ethereum/go-ethereum/p2p/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortMapper_ReportsMappedPort(t *testing.T) {
+	publisher := &publisherFake{}
+	mapper := &portMapper{publisher: publisher, gateway: func() portmap.Interface { return &gatewayFake{} }}
+
+	release := mapper.Map("UDP", 52820, "test mapping")
+	status := waitForStatus(t, publisher, 2)
+
+	assert.Equal(t, StatusTopic, publisher.topics()[1])
+	assert.True(t, status.Mapped)
+	assert.Equal(t, "UDP", status.Protocol)
+	assert.Equal(t, 52820, status.ExternalPort)
+	assert.Equal(t, mapTimeout, status.Lease)
+	assert.Equal(t, "fake gateway", status.RouterType)
+	assert.NoError(t, status.LastError)
+	assert.Equal(t, []Status{status}, mapper.Statuses())
+
+	release()
+	assert.Empty(t, mapper.Statuses())
+}
+
+func TestPortMapper_FallsBackToPermanentLease(t *testing.T) {
+	publisher := &publisherFake{}
+	gateway := &gatewayFake{leaseErr: errors.New("only permanent lease supported")}
+	mapper := &portMapper{publisher: publisher, gateway: func() portmap.Interface { return gateway }}
+
+	release := mapper.Map("TCP", 1194, "test mapping")
+	defer release()
+	status := waitForStatus(t, publisher, 2)
+
+	assert.True(t, status.Mapped)
+	assert.Equal(t, time.Duration(0), status.Lease)
+}
+
+func TestPortMapper_ReportsFailure(t *testing.T) {
+	publisher := &publisherFake{}
+	gateway := &gatewayFake{leaseErr: errors.New("no router"), permanentErr: errors.New("no router")}
+	mapper := &portMapper{publisher: publisher, gateway: func() portmap.Interface { return gateway }}
+
+	release := mapper.Map("UDP", 52820, "test mapping")
+	defer release()
+	status := waitForStatus(t, publisher, 2)
+
+	assert.False(t, status.Mapped)
+	assert.EqualError(t, status.LastError, "no router")
+	assert.Equal(t, []Status{status}, mapper.Statuses())
+}
+
+func waitForStatus(t *testing.T, publisher *publisherFake, count int) Status {
+	for i := 0; i < 100; i++ {
+		if statuses := publisher.statuses(); len(statuses) >= count {
+			return statuses[count-1]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %d port mapping statuses published", count)
+	return Status{}
+}
+
+type publisherFake struct {
+	lock       sync.Mutex
+	topicList  []string
+	statusList []Status
+}
+
+func (p *publisherFake) Publish(topic string, args ...interface{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.topicList = append(p.topicList, topic)
+	p.statusList = append(p.statusList, args[0].(Status))
+}
+
+func (p *publisherFake) topics() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]string(nil), p.topicList...)
+}
+
+func (p *publisherFake) statuses() []Status {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]Status(nil), p.statusList...)
+}
+
+type gatewayFake struct {
+	leaseErr     error
+	permanentErr error
+}
+
+func (g *gatewayFake) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) error {
+	if lifetime == 0 {
+		return g.permanentErr
+	}
+	return g.leaseErr
+}
+
+func (g *gatewayFake) DeleteMapping(protocol string, extport, intport int) error {
+	return nil
+}
+
+func (g *gatewayFake) ExternalIP() (net.IP, error) {
+	return net.ParseIP("1.2.3.4"), nil
+}
+
+func (g *gatewayFake) String() string {
+	return "fake gateway"
+}
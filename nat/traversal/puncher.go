@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package traversal
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/ip"
+)
+
+const logPrefix = "[nat traversal] "
+
+const punchInterval = 50 * time.Millisecond
+
+// punchMessage is the payload of packets which open NAT for the traffic of the peer
+var punchMessage = []byte("mysterium-punch")
+
+// ErrNoStunServers is returned when public endpoint can not be detected since no STUN servers are configured
+var ErrNoStunServers = errors.New("no STUN servers configured")
+
+// Puncher opens NAT of the local UDP port for the traffic of the peer, so that peers behind NAT
+// without port mapping support are able to connect directly.
+// Peers exchange their public endpoints detected by DetectEndpoint and punch towards each other
+// before the tunnel is started on the port.
+type Puncher struct {
+	stunServers []string
+	timeout     time.Duration
+	interval    time.Duration
+	listen      func(port int) (net.PacketConn, error)
+}
+
+// NewPuncher creates new instance of Puncher, STUN servers are asked for the public endpoint of the port all at once
+// and the first response received within the timeout is used
+func NewPuncher(stunServers []string, timeout time.Duration) *Puncher {
+	return &Puncher{
+		stunServers: stunServers,
+		timeout:     timeout,
+		interval:    punchInterval,
+		listen: func(port int) (net.PacketConn, error) {
+			return net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+		},
+	}
+}
+
+// DetectEndpoint returns public endpoint which NAT maps the local UDP port to.
+// The port has to be free, it is released before returning.
+func (p *Puncher) DetectEndpoint(port int) (*net.UDPAddr, error) {
+	if len(p.stunServers) == 0 {
+		return nil, ErrNoStunServers
+	}
+
+	conn, err := p.listen(port)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	endpoint, err := ip.GetMappedAddressOfAny(conn, p.stunServers, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	log.Info(logPrefix, "Port ", port, " is mapped to public endpoint ", endpoint)
+	return endpoint, nil
+}
+
+// Punch sends packets from the local UDP port to the peer endpoint for the given duration,
+// so that NAT passes the traffic of the peer coming to the port afterwards.
+// Punching stops earlier when packet of the punching peer is received, meaning that the path is open in both directions.
+// The port has to be free, it is released before returning.
+func (p *Puncher) Punch(port int, peer *net.UDPAddr, duration time.Duration) error {
+	conn, err := p.listen(port)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	log.Info(logPrefix, "Punching NAT from port ", port, " to ", peer, " for ", duration)
+	deadline := time.Now().Add(duration)
+	for time.Now().Before(deadline) {
+		if _, err := conn.WriteTo(punchMessage, peer); err != nil {
+			return err
+		}
+
+		if p.receivePunch(conn, peer, minTime(time.Now().Add(p.interval), deadline)) {
+			// the last packet lets the peer know that its punching succeeded too
+			_, err := conn.WriteTo(punchMessage, peer)
+			log.Info(logPrefix, "NAT punched from port ", port, " to ", peer)
+			return err
+		}
+	}
+	return nil
+}
+
+// receivePunch waits until the given deadline for the packet of punching peer
+func (p *Puncher) receivePunch(conn net.PacketConn, peer *net.UDPAddr, deadline time.Time) bool {
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return false
+	}
+
+	buffer := make([]byte, 64)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return false
+		}
+		if addr.String() == peer.String() && bytes.Equal(buffer[:n], punchMessage) {
+			return true
+		}
+	}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
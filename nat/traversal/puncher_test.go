@@ -0,0 +1,157 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package traversal
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// simulatedNAT is port restricted cone NAT of a single local port: the port is mapped to the external socket,
+// incoming packets are passed only from the endpoints the port has sent packets to
+type simulatedNAT struct {
+	external *net.UDPConn
+	lock     sync.Mutex
+	allowed  map[string]bool
+}
+
+func newSimulatedNAT(t *testing.T) *simulatedNAT {
+	external, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	assert.NoError(t, err)
+	return &simulatedNAT{external: external, allowed: make(map[string]bool)}
+}
+
+func (nat *simulatedNAT) endpoint() *net.UDPAddr {
+	return nat.external.LocalAddr().(*net.UDPAddr)
+}
+
+func (nat *simulatedNAT) listen(port int) (net.PacketConn, error) {
+	return &natConn{nat: nat}, nil
+}
+
+func (nat *simulatedNAT) allow(addr net.Addr) {
+	nat.lock.Lock()
+	defer nat.lock.Unlock()
+	nat.allowed[addr.String()] = true
+}
+
+func (nat *simulatedNAT) isAllowed(addr net.Addr) bool {
+	nat.lock.Lock()
+	defer nat.lock.Unlock()
+	return nat.allowed[addr.String()]
+}
+
+func (nat *simulatedNAT) close() {
+	nat.external.Close()
+}
+
+// natConn is the local socket bound to the port behind simulatedNAT, closing it keeps the NAT mapping
+type natConn struct {
+	nat *simulatedNAT
+}
+
+func (conn *natConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := conn.nat.external.ReadFrom(b)
+		if err != nil || conn.nat.isAllowed(addr) {
+			return n, addr, err
+		}
+	}
+}
+
+func (conn *natConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	conn.nat.allow(addr)
+	return conn.nat.external.WriteTo(b, addr)
+}
+
+func (conn *natConn) Close() error                      { return conn.nat.external.SetDeadline(time.Time{}) }
+func (conn *natConn) LocalAddr() net.Addr               { return conn.nat.external.LocalAddr() }
+func (conn *natConn) SetDeadline(t time.Time) error     { return conn.nat.external.SetDeadline(t) }
+func (conn *natConn) SetReadDeadline(t time.Time) error { return conn.nat.external.SetReadDeadline(t) }
+func (conn *natConn) SetWriteDeadline(t time.Time) error {
+	return conn.nat.external.SetWriteDeadline(t)
+}
+
+func newSimulatedPuncher(nat *simulatedNAT) *Puncher {
+	puncher := NewPuncher(nil, time.Second)
+	puncher.interval = 10 * time.Millisecond
+	puncher.listen = nat.listen
+	return puncher
+}
+
+func TestPuncher_PunchOpensBothNATs(t *testing.T) {
+	consumerNAT, providerNAT := newSimulatedNAT(t), newSimulatedNAT(t)
+	defer consumerNAT.close()
+	defer providerNAT.close()
+
+	started := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, newSimulatedPuncher(consumerNAT).Punch(1, providerNAT.endpoint(), 5*time.Second))
+	}()
+	go func() {
+		defer wg.Done()
+		time.Sleep(30 * time.Millisecond)
+		assert.NoError(t, newSimulatedPuncher(providerNAT).Punch(2, consumerNAT.endpoint(), 5*time.Second))
+	}()
+	wg.Wait()
+
+	// both peers stop punching as soon as the packet of each other passes
+	assert.True(t, time.Since(started) < time.Second)
+	assert.True(t, consumerNAT.isAllowed(providerNAT.endpoint()))
+	assert.True(t, providerNAT.isAllowed(consumerNAT.endpoint()))
+}
+
+func TestPuncher_PunchWithoutPeerLastsForDuration(t *testing.T) {
+	consumerNAT, providerNAT := newSimulatedNAT(t), newSimulatedNAT(t)
+	defer consumerNAT.close()
+	defer providerNAT.close()
+
+	started := time.Now()
+	err := newSimulatedPuncher(consumerNAT).Punch(1, providerNAT.endpoint(), 100*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, time.Since(started) >= 100*time.Millisecond)
+
+	// packets of the consumer did not pass the NAT of the provider, which never sent anything to the consumer
+	providerConn, _ := providerNAT.listen(2)
+	assert.NoError(t, providerConn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, _, err = providerConn.ReadFrom(make([]byte, 64))
+	assert.Error(t, err)
+
+	// however NAT of the consumer is open for the traffic of the provider
+	_, err = providerConn.WriteTo([]byte("handshake"), consumerNAT.endpoint())
+	assert.NoError(t, err)
+	consumerConn, _ := consumerNAT.listen(1)
+	assert.NoError(t, consumerConn.SetReadDeadline(time.Now().Add(time.Second)))
+	buffer := make([]byte, 64)
+	n, addr, err := consumerConn.ReadFrom(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "handshake", string(buffer[:n]))
+	assert.Equal(t, providerNAT.endpoint().String(), addr.String())
+}
+
+func TestPuncher_DetectEndpointWithoutStunServers(t *testing.T) {
+	_, err := NewPuncher(nil, time.Second).DetectEndpoint(0)
+	assert.Equal(t, ErrNoStunServers, err)
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/market"
 )
 
 // Connection which does no real tunneling
@@ -66,6 +67,6 @@ func (c *Connection) Stop() {
 }
 
 // GetConfig returns the consumer configuration for session creation
-func (c *Connection) GetConfig() (connection.ConsumerConfig, error) {
+func (c *Connection) GetConfig(_ market.ServiceProposal) (connection.ConsumerConfig, error) {
 	return nil, nil
 }
@@ -64,12 +64,12 @@ func (manager *Manager) Stop() error {
 	return nil
 }
 
-// GetProposal returns the proposal for NOOP service for given country
-func GetProposal(country string) market.ServiceProposal {
+// GetProposal returns the proposal for NOOP service for given location
+func GetProposal(location market.Location) market.ServiceProposal {
 	return market.ServiceProposal{
 		ServiceType: ServiceType,
 		ServiceDefinition: ServiceDefinition{
-			Location: market.Location{Country: country},
+			Location: location,
 		},
 		PaymentMethodType: PaymentMethodNoop,
 		PaymentMethod: PaymentNoop{
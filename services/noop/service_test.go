@@ -35,13 +35,13 @@ var (
 var _ service.Service = NewManager()
 
 func Test_GetProposal(t *testing.T) {
-	country := "LT"
+	location := market.Location{Country: "LT", City: "Vilnius", ASN: "AS8764", ISP: "Telia Lietuva, AB"}
 	assert.Exactly(
 		t,
 		market.ServiceProposal{
 			ServiceType: "noop",
 			ServiceDefinition: ServiceDefinition{
-				Location: market.Location{Country: country},
+				Location: location,
 			},
 
 			PaymentMethodType: "NOOP",
@@ -52,7 +52,7 @@ func Test_GetProposal(t *testing.T) {
 				},
 			},
 		},
-		GetProposal(country),
+		GetProposal(location),
 	)
 }
 
@@ -22,6 +22,7 @@ import (
 
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/market"
 )
 
 // ErrProcessNotStarted represents the error we return when the process is not started yet
@@ -62,7 +63,7 @@ func (c *Client) Stop() {
 }
 
 // GetConfig returns the consumer-side configuration. In openvpn case - it doesn't return anything
-func (c *Client) GetConfig() (connection.ConsumerConfig, error) {
+func (c *Client) GetConfig(_ market.ServiceProposal) (connection.ConsumerConfig, error) {
 	return nil, nil
 }
 
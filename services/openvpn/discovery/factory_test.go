@@ -28,7 +28,7 @@ import (
 )
 
 var (
-	locationLTTelia = market.Location{"LT", "Vilnius", "AS8764"}
+	locationLTTelia = market.Location{Country: "LT", City: "Vilnius", ASN: "AS8764"}
 	protocol        = "tcp"
 )
 
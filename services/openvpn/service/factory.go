@@ -31,6 +31,7 @@ import (
 	"github.com/mysteriumnetwork/node/core/node"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/nat"
+	"github.com/mysteriumnetwork/node/nat/mapping"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
 	openvpn_session "github.com/mysteriumnetwork/node/services/openvpn/session"
 	"github.com/mysteriumnetwork/node/session"
@@ -43,9 +44,11 @@ func NewManager(
 	location location.ServiceLocationInfo,
 	sessionMap openvpn_session.SessionMap,
 	natService nat.NATService,
-	mapPort func() (releasePortMapping func()),
+	portMapper mapping.PortMapper,
 ) *Manager {
-	sessionValidator := openvpn_session.NewValidator(sessionMap, identity.NewExtractor())
+	// without idle timeout session manager does not destroy sessions abandoned by consumers
+	removeOnDisconnect := nodeOptions.ServiceSession.IdleTimeout <= 0
+	sessionValidator := openvpn_session.NewValidator(sessionMap, identity.NewExtractor(), removeOnDisconnect)
 
 	return &Manager{
 		publicIP:                       location.PubIP,
@@ -55,8 +58,9 @@ func NewManager(
 		sessionConfigNegotiatorFactory: newSessionConfigNegotiatorFactory(nodeOptions.OptionsNetwork, serviceOptions),
 		vpnServerConfigFactory:         newServerConfigFactory(nodeOptions, serviceOptions),
 		vpnServerFactory:               newServerFactory(nodeOptions, sessionValidator),
+		sessionValidator:               sessionValidator,
 		serviceOptions:                 serviceOptions,
-		mapPort:                        mapPort,
+		portMapper:                     portMapper,
 	}
 }
 
@@ -19,14 +19,21 @@ package service
 
 import (
 	"encoding/json"
+	"net"
+	"strconv"
+	"sync"
+	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/tls"
+	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/nat"
+	"github.com/mysteriumnetwork/node/nat/mapping"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
+	openvpn_session "github.com/mysteriumnetwork/node/services/openvpn/session"
 	"github.com/mysteriumnetwork/node/session"
 	"github.com/pkg/errors"
 )
@@ -48,7 +55,7 @@ type SessionConfigNegotiatorFactory func(secPrimitives *tls.Primitives, outbound
 // Manager represents entrypoint for Openvpn service with top level components
 type Manager struct {
 	natService   nat.NATService
-	mapPort      func() (releasePortMapping func())
+	portMapper   mapping.PortMapper
 	releasePorts func()
 
 	sessionConfigNegotiatorFactory SessionConfigNegotiatorFactory
@@ -56,6 +63,9 @@ type Manager struct {
 	vpnServerConfigFactory   ServerConfigFactory
 	vpnServiceConfigProvider session.ConfigNegotiator
 	vpnServerFactory         ServerFactory
+	sessionValidator         *openvpn_session.Validator
+	primitives               *tls.Primitives
+	configLock               sync.RWMutex
 	vpnServer                openvpn.Process
 
 	publicIP        string
@@ -74,14 +84,19 @@ func (m *Manager) Serve(providerID identity.Identity) (err error) {
 		return errors.Wrap(err, "failed to add NAT forwarding rule")
 	}
 
-	m.releasePorts = m.mapPort()
+	if m.publicIP != m.outboundIP {
+		m.releasePorts = m.portMapper.Map(m.serviceOptions.Protocol, m.serviceOptions.Port, "Myst node OpenVPN port mapping")
+	}
 
 	primitives, err := primitiveFactory(m.currentLocation, providerID.Address)
 	if err != nil {
 		return
 	}
 
+	m.configLock.Lock()
+	m.primitives = primitives
 	m.vpnServiceConfigProvider = m.sessionConfigNegotiatorFactory(primitives, m.outboundIP, m.publicIP)
+	m.configLock.Unlock()
 
 	vpnServerConfig := m.vpnServerConfigFactory(primitives)
 	m.vpnServer = m.vpnServerFactory(vpnServerConfig)
@@ -108,12 +123,51 @@ func (m *Manager) Stop() (err error) {
 
 // ProvideConfig provides the configuration to end consumer
 func (m *Manager) ProvideConfig(publicKey json.RawMessage) (session.ServiceConfiguration, session.DestroyCallback, error) {
-	if m.vpnServiceConfigProvider == nil {
+	m.configLock.RLock()
+	configProvider := m.vpnServiceConfigProvider
+	m.configLock.RUnlock()
+
+	if configProvider == nil {
 		log.Info(logPrefix, "Config provider not initialized")
 		return nil, nil, errors.New("Config provider not initialized")
 	}
 
-	return m.vpnServiceConfigProvider.ProvideConfig(publicKey)
+	return configProvider.ProvideConfig(publicKey)
+}
+
+// LastActivity returns current time while OpenVPN client of the session is connected and time of its disconnect afterwards
+func (m *Manager) LastActivity(sessionID session.ID, _ session.ServiceConfiguration) (time.Time, bool) {
+	if m.sessionValidator == nil {
+		return time.Time{}, false
+	}
+	return m.sessionValidator.LastActivity(sessionID)
+}
+
+// PortMappings returns status of the service port mapped on the gateway
+func (m *Manager) PortMappings() []mapping.Status {
+	if m.portMapper == nil {
+		return nil
+	}
+	return m.portMapper.Statuses()
+}
+
+// AdvertisedEndpoint returns network and address consumers connect to
+func (m *Manager) AdvertisedEndpoint() (network, address string) {
+	m.configLock.RLock()
+	defer m.configLock.RUnlock()
+	return m.serviceOptions.Protocol, net.JoinHostPort(m.publicIP, strconv.Itoa(m.serviceOptions.Port))
+}
+
+// UpdateLocation sets the changed provider location, session configs provided afterwards point to the new public IP
+func (m *Manager) UpdateLocation(location location.ServiceLocationInfo) {
+	m.configLock.Lock()
+	defer m.configLock.Unlock()
+
+	m.publicIP = location.PubIP
+	m.outboundIP = location.OutIP
+	if m.primitives != nil {
+		m.vpnServiceConfigProvider = m.sessionConfigNegotiatorFactory(m.primitives, m.outboundIP, m.publicIP)
+	}
 }
 
 func vpnStateCallback(state openvpn.State) {
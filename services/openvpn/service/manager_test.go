@@ -20,6 +20,10 @@ package service
 import (
 	"testing"
 
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/tls"
+	"github.com/mysteriumnetwork/node/core/location"
+	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,3 +32,38 @@ func TestManager_StopNotPanic(t *testing.T) {
 	err := m.Stop()
 	assert.NoError(t, err)
 }
+
+func TestManager_UpdateLocationReissuesSessionConfig(t *testing.T) {
+	m := Manager{
+		primitives: &tls.Primitives{},
+		sessionConfigNegotiatorFactory: func(secPrimitives *tls.Primitives, outboundIP, publicIP string) session.ConfigNegotiator {
+			return &OpenvpnConfigNegotiator{vpnConfig: openvpn_service.VPNConfig{RemoteIP: publicIP}}
+		},
+	}
+
+	m.UpdateLocation(location.ServiceLocationInfo{PubIP: "1.2.3.4", OutIP: "192.168.1.2"})
+
+	config, _, err := m.ProvideConfig(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3.4", config.(*openvpn_service.VPNConfig).RemoteIP)
+}
+
+func TestManager_UpdateLocationBeforeServe(t *testing.T) {
+	m := Manager{}
+
+	m.UpdateLocation(location.ServiceLocationInfo{PubIP: "1.2.3.4", OutIP: "192.168.1.2"})
+
+	assert.Equal(t, "1.2.3.4", m.publicIP)
+	_, _, err := m.ProvideConfig(nil)
+	assert.Error(t, err)
+}
+
+func TestManager_AdvertisedEndpointFollowsLocation(t *testing.T) {
+	m := Manager{serviceOptions: Options{Protocol: "tcp", Port: 1194}}
+
+	m.UpdateLocation(location.ServiceLocationInfo{PubIP: "1.2.3.4", OutIP: "192.168.1.2"})
+
+	network, address := m.AdvertisedEndpoint()
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "1.2.3.4:1194", address)
+}
@@ -20,6 +20,7 @@ package session
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/mysteriumnetwork/node/session"
 )
@@ -36,7 +37,12 @@ type clientMap struct {
 	sessions SessionMap
 	// TODO: use clientID to kill OpenVPN session (client-kill {clientID}) when promise processor instructs so
 	sessionClientIDs map[session.ID]int
-	sessionMapLock   sync.Mutex
+	// sessionDisconnects keeps time when client of the session has disconnected
+	sessionDisconnects map[session.ID]time.Time
+	// removeOnDisconnect tells to remove session as soon as its client disconnects,
+	// otherwise session is left to be destroyed by session manager once it becomes idle
+	removeOnDisconnect bool
+	sessionMapLock     sync.Mutex
 }
 
 // FindClientSession returns OpenVPN session instance by given session id
@@ -59,12 +65,13 @@ func (cm *clientMap) UpdateClientSession(clientID int, id session.ID) bool {
 	_, clientIDExist := cm.sessionClientIDs[id]
 	if !clientIDExist {
 		cm.sessionClientIDs[id] = clientID
+		delete(cm.sessionDisconnects, id)
 	}
 
 	return cm.sessionClientIDs[id] == clientID
 }
 
-// RemoveSession removes given session from underlying session managers
+// RemoveSession forgets client of the given session and removes the session itself, unless it is left to session manager
 func (cm *clientMap) RemoveSession(id session.ID) error {
 	cm.sessionMapLock.Lock()
 	defer cm.sessionMapLock.Unlock()
@@ -74,7 +81,34 @@ func (cm *clientMap) RemoveSession(id session.ID) error {
 		return errors.New("no underlying session exists: " + string(id))
 	}
 
-	cm.sessions.Remove(id)
 	delete(cm.sessionClientIDs, id)
+	if cm.removeOnDisconnect {
+		cm.sessions.Remove(id)
+		return nil
+	}
+
+	cm.pruneDisconnects()
+	cm.sessionDisconnects[id] = time.Now()
 	return nil
 }
+
+// pruneDisconnects forgets disconnects of the sessions already destroyed by session manager
+func (cm *clientMap) pruneDisconnects() {
+	for id := range cm.sessionDisconnects {
+		if _, exists := cm.sessions.Find(id); !exists {
+			delete(cm.sessionDisconnects, id)
+		}
+	}
+}
+
+// LastSeen returns current time for the session with connected client and time of the disconnect otherwise
+func (cm *clientMap) LastSeen(id session.ID) (time.Time, bool) {
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
+	if _, connected := cm.sessionClientIDs[id]; connected {
+		return time.Now(), true
+	}
+	disconnectedAt, disconnected := cm.sessionDisconnects[id]
+	return disconnectedAt, disconnected
+}
@@ -19,6 +19,7 @@ package session
 
 import (
 	"sync"
+	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/session"
@@ -33,13 +34,16 @@ type Validator struct {
 	identityExtractor identity.Extractor
 }
 
-// NewValidator return Validator instance
-func NewValidator(sessionMap SessionMap, extractor identity.Extractor) *Validator {
+// NewValidator return Validator instance. Sessions are removed once their clients disconnect
+// when removeOnDisconnect is set, otherwise they are left to be destroyed by session manager once idle.
+func NewValidator(sessionMap SessionMap, extractor identity.Extractor, removeOnDisconnect bool) *Validator {
 	return &Validator{
 		clientMap: &clientMap{
-			sessions:         sessionMap,
-			sessionClientIDs: make(map[session.ID]int),
-			sessionMapLock:   sync.Mutex{},
+			sessions:           sessionMap,
+			sessionClientIDs:   make(map[session.ID]int),
+			sessionDisconnects: make(map[session.ID]time.Time),
+			removeOnDisconnect: removeOnDisconnect,
+			sessionMapLock:     sync.Mutex{},
 		},
 		identityExtractor: extractor,
 	}
@@ -67,9 +71,14 @@ func (v *Validator) Validate(clientID int, sessionString, signatureString string
 	return currentSession.ConsumerID == extractedIdentity, nil
 }
 
-// Cleanup removes session from underlying session managers
+// Cleanup is called when client of the session disconnects
 func (v *Validator) Cleanup(sessionString string) error {
 	sessionID := session.ID(sessionString)
 
 	return v.clientMap.RemoveSession(sessionID)
 }
+
+// LastActivity returns current time while client of the session is connected and time of the disconnect afterwards
+func (v *Validator) LastActivity(sessionID session.ID) (time.Time, bool) {
+	return v.clientMap.LastSeen(sessionID)
+}
@@ -19,6 +19,7 @@ package session
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/session"
@@ -95,6 +96,52 @@ func TestCleanupReturnsNoErrorIfSessionIsCleared(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestLastActivityTracksClientDisconnect(t *testing.T) {
+	validator := mockValidatorWithSession(identityExisting, sessionExisting)
+
+	_, known := validator.LastActivity(sessionExisting.ID)
+	assert.False(t, known)
+
+	validator.Validate(1, sessionExistingString, "not important")
+	connectedAt, known := validator.LastActivity(sessionExisting.ID)
+	assert.True(t, known)
+
+	assert.NoError(t, validator.Cleanup(sessionExistingString))
+	disconnectedAt, known := validator.LastActivity(sessionExisting.ID)
+	assert.True(t, known)
+	assert.False(t, disconnectedAt.Before(connectedAt))
+
+	time.Sleep(time.Millisecond)
+	stillDisconnectedAt, _ := validator.LastActivity(sessionExisting.ID)
+	assert.Equal(t, disconnectedAt, stillDisconnectedAt)
+}
+
+func TestCleanupRemovesSessionWhenIdleSessionsAreNotDestroyed(t *testing.T) {
+	sessions := &mockSessions{sessionExisting, true}
+	validator := NewValidator(sessions, &mockIdentityExtractor{identityExisting, nil}, true)
+
+	validator.Validate(1, sessionExistingString, "not important")
+	assert.NoError(t, validator.Cleanup(sessionExistingString))
+
+	assert.False(t, sessions.OnFindReturnSuccess)
+	_, known := validator.LastActivity(sessionExisting.ID)
+	assert.False(t, known)
+}
+
+func TestCleanupForgetsDisconnectsOfDestroyedSessions(t *testing.T) {
+	sessions := &mockSessions{sessionExisting, true}
+	validator := NewValidator(sessions, &mockIdentityExtractor{identityExisting, nil}, false)
+
+	validator.Validate(1, sessionExistingString, "not important")
+	assert.NoError(t, validator.Cleanup(sessionExistingString))
+	assert.Len(t, validator.clientMap.sessionDisconnects, 1)
+
+	// session is destroyed by session manager, then client of another session disconnects
+	sessions.Remove(sessionExisting.ID)
+	validator.clientMap.pruneDisconnects()
+	assert.Empty(t, validator.clientMap.sessionDisconnects)
+}
+
 func TestCleanupReturnsErrorIfSessionNotExists(t *testing.T) {
 	validator := mockValidator(identityExisting)
 
@@ -27,6 +27,7 @@ import (
 	"github.com/mysteriumnetwork/node/consumer"
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/core/location"
+	"github.com/mysteriumnetwork/node/market"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 	endpoint "github.com/mysteriumnetwork/node/services/wireguard/endpoint"
 	"github.com/mysteriumnetwork/node/services/wireguard/key"
@@ -46,6 +47,8 @@ type Connection struct {
 
 	config             wg.ServiceConfig
 	connectionEndpoint wg.ConnectionEndpoint
+	resourceAllocator  resources.Allocator
+	natPuncher         wg.NATPuncher
 }
 
 // Start establish wireguard connection to the service provider.
@@ -57,14 +60,12 @@ func (c *Connection) Start(options connection.ConnectOptions) (err error) {
 	c.config.Provider = config.Provider
 	c.config.Consumer.IPAddress = config.Consumer.IPAddress
 
-	resourceAllocator := resources.NewAllocator()
-
 	// We do not need port mapping for consumer, since it initiates the session
 	fakePortMapper := func(port int) (releasePortMapping func()) {
 		return func() {}
 	}
 
-	c.connectionEndpoint, err = endpoint.NewConnectionEndpoint(location.ServiceLocationInfo{}, &resourceAllocator, fakePortMapper, 0)
+	c.connectionEndpoint, err = endpoint.NewConnectionEndpoint(location.ServiceLocationInfo{}, &c.resourceAllocator, fakePortMapper, 0)
 	if err != nil {
 		return errors.Wrap(err, "failed to create new connection endpoint")
 	}
@@ -72,18 +73,17 @@ func (c *Connection) Start(options connection.ConnectOptions) (err error) {
 	c.connection.Add(1)
 	c.stateChannel <- connection.Connecting
 
+	// Provider requests to delay consumer connection since it might be in a process of setting up NAT traversal for given consumer
+	if config.Consumer.ConnectDelay > 0 {
+		c.delayConnect(time.Duration(config.Consumer.ConnectDelay) * time.Millisecond)
+	}
+
 	if err := c.connectionEndpoint.Start(&c.config); err != nil {
 		c.stateChannel <- connection.NotConnected
 		c.connection.Done()
 		return errors.Wrap(err, "failed to start connection endpoint")
 	}
 
-	// Provider requests to delay consumer connection since it might be in a process of setting up NAT traversal for given consumer
-	if config.Consumer.ConnectDelay > 0 {
-		log.Infof("%s delaying connect for %v milliseconds", logPrefix, config.Consumer.ConnectDelay)
-		time.Sleep(time.Duration(config.Consumer.ConnectDelay) * time.Millisecond)
-	}
-
 	if err := c.connectionEndpoint.AddPeer(c.config.Provider.PublicKey, &c.config.Provider.Endpoint); err != nil {
 		c.stateChannel <- connection.NotConnected
 		c.connection.Done()
@@ -108,21 +108,74 @@ func (c *Connection) Start(options connection.ConnectOptions) (err error) {
 	return nil
 }
 
+// delayConnect waits before connecting to the provider behind NAT.
+// If consumer port was prepared for NAT traversal, NAT is punched towards the provider meanwhile.
+func (c *Connection) delayConnect(delay time.Duration) {
+	if c.natPuncher == nil || c.config.Consumer.ListenPort == 0 {
+		log.Infof("%s delaying connect for %v", logPrefix, delay)
+		time.Sleep(delay)
+		return
+	}
+
+	if err := c.natPuncher.Punch(c.config.Consumer.ListenPort, &c.config.Provider.Endpoint, delay); err != nil {
+		log.Warn(logPrefix, "Failed to punch NAT towards the provider: ", err)
+	}
+}
+
 // Wait blocks until wireguard connection not stopped.
 func (c *Connection) Wait() error {
 	c.connection.Wait()
 	return nil
 }
 
-// GetConfig returns the consumer configuration for session creation
-func (c *Connection) GetConfig() (connection.ConsumerConfig, error) {
+// GetConfig returns the consumer configuration for session creation,
+// public endpoint of the consumer is included only if provider of the proposal traverses NAT towards it
+func (c *Connection) GetConfig(proposal market.ServiceProposal) (connection.ConsumerConfig, error) {
 	publicKey, err := key.PrivateKeyToPublicKey(c.config.Consumer.PrivateKey)
 	if err != nil {
 		return nil, err
 	}
-	return wg.ConsumerConfig{
+	config := wg.ConsumerConfig{
 		PublicKey: publicKey,
-	}, nil
+	}
+	if !natTraversalAdvertised(proposal) {
+		return config, nil
+	}
+	if endpoint := c.prepareNATTraversal(); endpoint != nil {
+		config.Endpoint = endpoint.String()
+	}
+	return config, nil
+}
+
+func natTraversalAdvertised(proposal market.ServiceProposal) bool {
+	definition, ok := proposal.ServiceDefinition.(wg.ServiceDefinition)
+	return ok && definition.NATTraversal
+}
+
+// prepareNATTraversal allocates consumer port in advance and returns its public endpoint,
+// so that provider could punch its NAT towards it
+func (c *Connection) prepareNATTraversal() *net.UDPAddr {
+	if c.natPuncher == nil {
+		return nil
+	}
+
+	port, err := c.resourceAllocator.AllocatePort()
+	if err != nil {
+		log.Warn(logPrefix, "Failed to allocate port for NAT traversal: ", err)
+		return nil
+	}
+
+	endpoint, err := c.natPuncher.DetectEndpoint(port)
+	if err != nil {
+		log.Warn(logPrefix, "Failed to detect public endpoint, NAT traversal is disabled: ", err)
+		if err := c.resourceAllocator.ReleasePort(port); err != nil {
+			log.Warn(logPrefix, "Failed to release port: ", err)
+		}
+		return nil
+	}
+
+	c.config.Consumer.ListenPort = port
+	return endpoint
 }
 
 // Stop stops wireguard connection and closes connection endpoint.
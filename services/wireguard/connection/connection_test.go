@@ -0,0 +1,117 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/market"
+	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnection_GetConfigContainsPublicEndpoint(t *testing.T) {
+	puncher := &natPuncherFake{}
+	conn := newConnection(t, puncher)
+
+	config, err := conn.GetConfig(natTraversalProposal)
+	assert.NoError(t, err)
+
+	consumerConfig := config.(wg.ConsumerConfig)
+	assert.NotEmpty(t, consumerConfig.PublicKey)
+	assert.Equal(t, "1.2.3.4:52820", consumerConfig.Endpoint)
+	assert.Equal(t, 52820, conn.config.Consumer.ListenPort)
+}
+
+func TestConnection_GetConfigWithoutPublicEndpoint(t *testing.T) {
+	conn := newConnection(t, &natPuncherFake{detectErr: errors.New("no STUN servers")})
+
+	config, err := conn.GetConfig(natTraversalProposal)
+	assert.NoError(t, err)
+	assert.Empty(t, config.(wg.ConsumerConfig).Endpoint)
+	assert.Zero(t, conn.config.Consumer.ListenPort)
+	assert.Empty(t, conn.resourceAllocator.Ports)
+
+	conn = newConnection(t, nil)
+	config, err = conn.GetConfig(natTraversalProposal)
+	assert.NoError(t, err)
+	assert.Empty(t, config.(wg.ConsumerConfig).Endpoint)
+}
+
+func TestConnection_GetConfigDoesNotDetectEndpointForProviderWithoutNATTraversal(t *testing.T) {
+	puncher := &natPuncherFake{}
+	conn := newConnection(t, puncher)
+
+	config, err := conn.GetConfig(market.ServiceProposal{ServiceDefinition: wg.ServiceDefinition{}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, config.(wg.ConsumerConfig).PublicKey)
+	assert.Empty(t, config.(wg.ConsumerConfig).Endpoint)
+	assert.Zero(t, conn.config.Consumer.ListenPort)
+	assert.Zero(t, puncher.detections)
+}
+
+func TestConnection_DelayConnectPunchesNATTowardsProvider(t *testing.T) {
+	puncher := &natPuncherFake{}
+	conn := newConnection(t, puncher)
+	_, err := conn.GetConfig(natTraversalProposal)
+	assert.NoError(t, err)
+	conn.config.Provider.Endpoint = net.UDPAddr{IP: net.ParseIP("5.6.7.8"), Port: 52820}
+
+	conn.delayConnect(time.Second)
+
+	assert.Equal(t, 52820, puncher.port)
+	assert.Equal(t, "5.6.7.8:52820", puncher.peer.String())
+	assert.Equal(t, time.Second, puncher.duration)
+}
+
+var natTraversalProposal = market.ServiceProposal{
+	ServiceDefinition: wg.ServiceDefinition{NATTraversal: true},
+}
+
+func newConnection(t *testing.T, puncher wg.NATPuncher) *Connection {
+	conn, err := NewConnectionCreator(puncher).Create(make(connection.StateChannel), make(connection.StatisticsChannel))
+	assert.NoError(t, err)
+	return conn.(*Connection)
+}
+
+type natPuncherFake struct {
+	detectErr  error
+	detections int
+	port       int
+	peer       *net.UDPAddr
+	duration   time.Duration
+}
+
+func (puncher *natPuncherFake) DetectEndpoint(port int) (*net.UDPAddr, error) {
+	puncher.detections++
+	if puncher.detectErr != nil {
+		return nil, puncher.detectErr
+	}
+	return &net.UDPAddr{IP: net.ParseIP("1.2.3.4"), Port: port}, nil
+}
+
+func (puncher *natPuncherFake) Punch(port int, peer *net.UDPAddr, duration time.Duration) error {
+	puncher.port = port
+	puncher.peer = peer
+	puncher.duration = duration
+	return nil
+}
@@ -65,7 +65,7 @@ func (ce *connectionEndpoint) Start(config *wg.ServiceConfig) error {
 		return err
 	}
 
-	port, err := ce.resourceAllocator.AllocatePort()
+	port, err := ce.allocatePort(config)
 	if err != nil {
 		return err
 	}
@@ -149,6 +149,14 @@ func (ce *connectionEndpoint) Stop() error {
 	return ce.resourceAllocator.ReleaseInterface(ce.iface)
 }
 
+// allocatePort returns listen port of the endpoint, consumer may have the port allocated in advance for NAT traversal
+func (ce *connectionEndpoint) allocatePort(config *wg.ServiceConfig) (int, error) {
+	if config != nil && config.Consumer.ListenPort != 0 {
+		return config.Consumer.ListenPort, nil
+	}
+	return ce.resourceAllocator.AllocatePort()
+}
+
 func (ce *connectionEndpoint) cleanAbandonedInterfaces() error {
 	ifaces, err := ce.resourceAllocator.AbandonedInterfaces()
 	if err != nil {
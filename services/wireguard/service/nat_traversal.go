@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"net"
+
+	log "github.com/cihub/seelog"
+	wg "github.com/mysteriumnetwork/node/services/wireguard"
+)
+
+// tunnelPunchPort is the port of the consumer which the packet starting the wireguard handshake is sent to
+const tunnelPunchPort = 9
+
+// natTraversal lets the consumer connect directly to the session port of the provider behind NAT, when the gateway does not map the port.
+// Public endpoint of the port is detected before the tunnel is started and advertised in the session config.
+// Once the config is exchanged, both peers send packets towards the public endpoint of each other,
+// so that their NATs pass the traffic of the peer.
+type natTraversal struct {
+	puncher  wg.NATPuncher
+	consumer *net.UDPAddr
+	endpoint *net.UDPAddr
+	sendTo   func(address string) error
+}
+
+// detectEndpoint asks for the public endpoint which NAT maps the local session port to
+func (traversal *natTraversal) detectEndpoint(port int) {
+	endpoint, err := traversal.puncher.DetectEndpoint(port)
+	if err != nil {
+		log.Warn(logPrefix, "Failed to detect public endpoint of port ", port, ": ", err)
+		return
+	}
+	traversal.endpoint = endpoint
+}
+
+// publicEndpoint returns the detected public endpoint of the session port
+func (traversal *natTraversal) publicEndpoint() (net.UDPAddr, bool) {
+	if traversal.endpoint == nil {
+		return net.UDPAddr{}, false
+	}
+	return *traversal.endpoint, true
+}
+
+// punch sends packet to the consumer through the tunnel, so that wireguard starts the handshake with the consumer peer
+// and the packets sent to the public endpoint of the consumer open NAT of the provider.
+func (traversal *natTraversal) punch(consumerIP net.IP) {
+	address := (&net.UDPAddr{IP: consumerIP, Port: tunnelPunchPort}).String()
+	if err := traversal.sendTo(address); err != nil {
+		log.Warn(logPrefix, "Failed to punch NAT towards the consumer: ", err)
+	}
+}
+
+// sendThroughTunnel sends empty datagram to the address of the peer reachable through the tunnel
+func sendThroughTunnel(address string) error {
+	conn, err := net.Dial("udp4", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte{0})
+	return err
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"net"
+	"strconv"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/service"
+)
+
+// OpenProbeListener opens a port of the session ports range the same way as it is opened for the session,
+// so that reachability of the sessions can be probed from outside of the provider network
+func (manager *Manager) OpenProbeListener() (service.ProbeListener, error) {
+	port, err := manager.resourceAllocator.AllocatePort()
+	if err != nil {
+		return nil, err
+	}
+
+	location := manager.serviceLocation()
+	var traversal *natTraversal
+	if NATTraversalSupported(location, manager.natPuncher) {
+		traversal = &natTraversal{puncher: manager.natPuncher}
+	}
+	releasePortMapping := openPortFunc(manager.portMap, location, traversal)(port)
+	release := func() {
+		releasePortMapping()
+		if err := manager.resourceAllocator.ReleasePort(port); err != nil {
+			log.Warn(logPrefix, "Failed to release probe port: ", err)
+		}
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	endpoint := net.JoinHostPort(location.PubIP, strconv.Itoa(port))
+	if traversal != nil {
+		if publicEndpoint, ok := traversal.publicEndpoint(); ok {
+			endpoint = publicEndpoint.String()
+		}
+	}
+	return service.NewUDPProbeListener(conn, endpoint, release), nil
+}
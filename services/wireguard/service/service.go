@@ -19,7 +19,9 @@ package service
 
 import (
 	"encoding/json"
+	"net"
 	"sync"
+	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/core/location"
@@ -27,6 +29,7 @@ import (
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/nat"
+	"github.com/mysteriumnetwork/node/nat/mapping"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
 	"github.com/mysteriumnetwork/node/services/wireguard/endpoint"
 	"github.com/mysteriumnetwork/node/services/wireguard/resources"
@@ -38,35 +41,51 @@ const logPrefix = "[service-wireguard] "
 
 // NewManager creates new instance of Wireguard service
 func NewManager(
-	location location.ServiceLocationInfo,
+	serviceLocation location.ServiceLocationInfo,
 	natService nat.NATService,
-	portMap func(port int) (releasePortMapping func()),
+	portMapper mapping.PortMapper,
+	natPuncher wg.NATPuncher,
 	options Options) *Manager {
 
 	resourceAllocator := resources.NewAllocator()
+	portMap := func(port int) func() {
+		return portMapper.Map("UDP", port, "Myst node wireguard(tm) port mapping")
+	}
 	return &Manager{
-		natService: natService,
-
-		publicIP:        location.PubIP,
-		outboundIP:      location.OutIP,
-		currentLocation: location.OutIP,
-
-		connectionEndpointFactory: func() (wg.ConnectionEndpoint, error) {
-			return endpoint.NewConnectionEndpoint(location, &resourceAllocator, portMap, options.ConnectDelay)
+		natService:        natService,
+		portMapper:        portMapper,
+		portMap:           portMap,
+		natPuncher:        natPuncher,
+		resourceAllocator: &resourceAllocator,
+		location:          serviceLocation,
+		endpoints:         make(map[string]wg.ConnectionEndpoint),
+
+		connectionEndpointFactory: func(location location.ServiceLocationInfo, traversal *natTraversal) (wg.ConnectionEndpoint, error) {
+			openPort := openPortFunc(portMap, location, traversal)
+			return endpoint.NewConnectionEndpoint(location, &resourceAllocator, openPort, options.ConnectDelay)
 		},
+		tunnelSender: sendThroughTunnel,
 	}
 }
 
 // Manager represents an instance of Wireguard service
 type Manager struct {
-	wg         sync.WaitGroup
-	natService nat.NATService
+	wg                sync.WaitGroup
+	natService        nat.NATService
+	portMapper        mapping.PortMapper
+	portMap           func(port int) (releasePortMapping func())
+	natPuncher        wg.NATPuncher
+	resourceAllocator *resources.Allocator
+
+	connectionEndpointFactory func(location location.ServiceLocationInfo, traversal *natTraversal) (wg.ConnectionEndpoint, error)
+	tunnelSender              func(address string) error
 
-	connectionEndpointFactory func() (wg.ConnectionEndpoint, error)
+	locationLock sync.RWMutex
+	location     location.ServiceLocationInfo
 
-	publicIP        string
-	outboundIP      string
-	currentLocation string
+	// endpoints of the sessions by consumer IP address
+	endpoints     map[string]wg.ConnectionEndpoint
+	endpointsLock sync.Mutex
 
 	mu   sync.Mutex // TODO this is a temporary solution to cleanup oldest used wireguard resources.
 	list []*func()  // TODO it should be removed once payment bases session cleanup implemented.
@@ -82,7 +101,9 @@ func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.Servic
 
 	manager.cleanOldEndpoints()
 
-	connectionEndpoint, err := manager.connectionEndpointFactory()
+	location := manager.serviceLocation()
+	traversal := manager.newNATTraversal(location, parseConsumerEndpoint(key.Endpoint))
+	connectionEndpoint, err := manager.connectionEndpointFactory(location, traversal)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -91,7 +112,11 @@ func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.Servic
 		return nil, nil, err
 	}
 
-	if err := connectionEndpoint.AddPeer(key.PublicKey, nil); err != nil {
+	var consumerEndpoint *net.UDPAddr
+	if traversal != nil {
+		consumerEndpoint = traversal.consumer
+	}
+	if err := connectionEndpoint.AddPeer(key.PublicKey, consumerEndpoint); err != nil {
 		return nil, nil, err
 	}
 
@@ -99,13 +124,27 @@ func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.Servic
 	if err != nil {
 		return nil, nil, err
 	}
+	if traversal != nil {
+		if endpoint, ok := traversal.publicEndpoint(); ok {
+			config.Provider.Endpoint = endpoint
+		}
+	}
 
-	natRule := nat.RuleForwarding{SourceAddress: config.Consumer.IPAddress.String(), TargetIP: manager.outboundIP}
+	natRule := nat.RuleForwarding{SourceAddress: config.Consumer.IPAddress.String(), TargetIP: location.OutIP}
 	if err := manager.natService.Add(natRule); err != nil {
 		return nil, nil, errors.Wrap(err, "failed to add NAT forwarding rule")
 	}
 
+	consumerIP := config.Consumer.IPAddress.IP.String()
+	manager.endpointsLock.Lock()
+	manager.endpoints[consumerIP] = connectionEndpoint
+	manager.endpointsLock.Unlock()
+
 	destroy := func() {
+		manager.endpointsLock.Lock()
+		delete(manager.endpoints, consumerIP)
+		manager.endpointsLock.Unlock()
+
 		if err := manager.natService.Del(natRule); err != nil {
 			log.Error(logPrefix, "failed to delete NAT forwarding rule: ", err)
 		}
@@ -114,9 +153,107 @@ func (manager *Manager) ProvideConfig(publicKey json.RawMessage) (session.Servic
 		}
 	}
 
+	if traversal != nil {
+		// consumer punches its NAT once it gets the config, provider starts punching towards the consumer at the same time
+		go traversal.punch(config.Consumer.IPAddress.IP)
+	}
 	return config, manager.once(destroy), nil
 }
 
+// LastActivity returns time of the last handshake of the consumer peer, which is repeated every few minutes while the peer sends traffic
+func (manager *Manager) LastActivity(_ session.ID, config session.ServiceConfiguration) (time.Time, bool) {
+	serviceConfig, ok := config.(wg.ServiceConfig)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	manager.endpointsLock.Lock()
+	connectionEndpoint, found := manager.endpoints[serviceConfig.Consumer.IPAddress.IP.String()]
+	manager.endpointsLock.Unlock()
+	if !found {
+		return time.Time{}, false
+	}
+
+	stats, err := connectionEndpoint.PeerStats()
+	if err != nil || stats.LastHandshake.IsZero() {
+		return time.Time{}, false
+	}
+	return stats.LastHandshake, true
+}
+
+// NATTraversalSupported tells if provider in the given location punches its NAT towards consumers
+func NATTraversalSupported(location location.ServiceLocationInfo, natPuncher wg.NATPuncher) bool {
+	return location.PubIP != location.OutIP && natPuncher != nil
+}
+
+// newNATTraversal prepares NAT traversal for the consumer which provided its public endpoint,
+// nil is returned when provider is not behind NAT or the consumer does not take part in it.
+func (manager *Manager) newNATTraversal(location location.ServiceLocationInfo, consumerEndpoint *net.UDPAddr) *natTraversal {
+	if !NATTraversalSupported(location, manager.natPuncher) || consumerEndpoint == nil {
+		return nil
+	}
+	return &natTraversal{
+		puncher:  manager.natPuncher,
+		consumer: consumerEndpoint,
+		sendTo:   manager.tunnelSender,
+	}
+}
+
+// openPortFunc returns function which makes the endpoint port reachable for the consumer.
+// Port mapping is requested on the gateway if provider is behind NAT and, in case of NAT traversal,
+// public endpoint which NAT maps the port to is detected while the port is still free.
+func openPortFunc(
+	portMap func(port int) (releasePortMapping func()),
+	location location.ServiceLocationInfo,
+	traversal *natTraversal,
+) func(port int) (releasePortMapping func()) {
+	return func(port int) func() {
+		if location.PubIP == location.OutIP {
+			return func() {}
+		}
+
+		releasePortMapping := portMap(port)
+		if traversal != nil {
+			traversal.detectEndpoint(port)
+		}
+		return releasePortMapping
+	}
+}
+
+func parseConsumerEndpoint(endpoint string) *net.UDPAddr {
+	if endpoint == "" {
+		return nil
+	}
+
+	address, err := net.ResolveUDPAddr("udp", endpoint)
+	if err != nil {
+		log.Warn(logPrefix, "Invalid consumer endpoint: ", err)
+		return nil
+	}
+	return address
+}
+
+// UpdateLocation sets the changed provider location, it is used for the session configs provided afterwards
+func (manager *Manager) UpdateLocation(location location.ServiceLocationInfo) {
+	manager.locationLock.Lock()
+	defer manager.locationLock.Unlock()
+	manager.location = location
+}
+
+// PortMappings returns status of the session ports mapped on the gateway
+func (manager *Manager) PortMappings() []mapping.Status {
+	if manager.portMapper == nil {
+		return nil
+	}
+	return manager.portMapper.Statuses()
+}
+
+func (manager *Manager) serviceLocation() location.ServiceLocationInfo {
+	manager.locationLock.RLock()
+	defer manager.locationLock.RUnlock()
+	return manager.location
+}
+
 // TODO this is a temporary solution to cleanup oldest used wireguard resources.
 // TODO it should be removed once payment bases session cleanup implemented.
 func (manager *Manager) once(f func()) func() {
@@ -168,13 +305,15 @@ func (manager *Manager) Serve(providerID identity.Identity) error {
 	return nil
 }
 
-// GetProposal returns the proposal for wireguard service
-func GetProposal(country string) market.ServiceProposal {
+// GetProposal returns the proposal for wireguard service for given location,
+// natTraversal tells consumers that provider punches its NAT towards them
+func GetProposal(location market.Location, natTraversal bool) market.ServiceProposal {
 	return market.ServiceProposal{
 		ServiceType: wg.ServiceType,
 		ServiceDefinition: wg.ServiceDefinition{
-			Location:          market.Location{Country: country},
-			LocationOriginate: market.Location{Country: country},
+			Location:          location,
+			LocationOriginate: location,
+			NATTraversal:      natTraversal,
 		},
 		PaymentMethodType: wg.PaymentMethod,
 		PaymentMethod: wg.Payment{
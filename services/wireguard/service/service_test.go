@@ -23,11 +23,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/nat"
 	wg "github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/services/wireguard/resources"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,13 +43,15 @@ var (
 var connectionEndpointStub = &fakeConnectionEndpoint{}
 
 func Test_GetProposal(t *testing.T) {
+	location := market.Location{Country: country, City: "Vilnius", ASN: "AS8764", ISP: "Telia Lietuva, AB"}
 	assert.Exactly(
 		t,
 		market.ServiceProposal{
 			ServiceType: "wireguard",
 			ServiceDefinition: wg.ServiceDefinition{
-				Location:          market.Location{Country: country},
-				LocationOriginate: market.Location{Country: country},
+				Location:          location,
+				LocationOriginate: location,
+				NATTraversal:      true,
 			},
 			PaymentMethodType: "WG",
 			PaymentMethod: wg.Payment{
@@ -57,7 +61,7 @@ func Test_GetProposal(t *testing.T) {
 				},
 			},
 		},
-		GetProposal(country),
+		GetProposal(location, true),
 	)
 }
 
@@ -87,29 +91,199 @@ func Test_Manager_Stop(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_Manager_UpdateLocation(t *testing.T) {
+	manager := newManagerStub(pubIP, outIP, country)
+	var endpointLocation location.ServiceLocationInfo
+	manager.connectionEndpointFactory = func(location location.ServiceLocationInfo, traversal *natTraversal) (wg.ConnectionEndpoint, error) {
+		endpointLocation = location
+		return connectionEndpointStub, nil
+	}
+
+	newLocation := location.ServiceLocationInfo{PubIP: "1.2.3.4", OutIP: outIP, Country: "NL"}
+	manager.UpdateLocation(newLocation)
+
+	_, _, err := manager.ProvideConfig(json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
+	assert.NoError(t, err)
+	assert.Equal(t, newLocation, endpointLocation)
+}
+
+func Test_Manager_ProvideConfigTraversesNATWithConsumerEndpoint(t *testing.T) {
+	manager := newManagerStub("5.6.7.8", "192.168.1.2", country)
+	manager.natPuncher = &natPuncherFake{}
+	punched := make(chan string, 1)
+	manager.tunnelSender = func(address string) error {
+		punched <- address
+		return nil
+	}
+	connectionEndpoint := &fakeConnectionEndpoint{}
+	manager.connectionEndpointFactory = func(location location.ServiceLocationInfo, traversal *natTraversal) (wg.ConnectionEndpoint, error) {
+		connectionEndpoint.openPort = openPortFunc(func(int) func() { return func() {} }, location, traversal)
+		return connectionEndpoint, nil
+	}
+
+	sessionConfig, _, err := manager.ProvideConfig(json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk=", "Endpoint": "1.2.3.4:52820"}`))
+	assert.NoError(t, err)
+
+	config := sessionConfig.(wg.ServiceConfig)
+	assert.Equal(t, "1.2.3.4:52821", config.Provider.Endpoint.String())
+	assert.Equal(t, "1.2.3.4:52820", connectionEndpoint.peerEndpoint.String())
+	select {
+	case address := <-punched:
+		assert.Equal(t, "10.182.0.2:9", address)
+	case <-time.After(time.Second):
+		t.Fatal("NAT was not punched towards the consumer")
+	}
+}
+
+func Test_Manager_ProvideConfigWithoutConsumerEndpoint(t *testing.T) {
+	manager := newManagerStub("5.6.7.8", "192.168.1.2", country)
+	manager.natPuncher = &natPuncherFake{}
+	var endpointTraversal *natTraversal
+	manager.connectionEndpointFactory = func(location location.ServiceLocationInfo, traversal *natTraversal) (wg.ConnectionEndpoint, error) {
+		endpointTraversal = traversal
+		return connectionEndpointStub, nil
+	}
+
+	_, _, err := manager.ProvideConfig(json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
+	assert.NoError(t, err)
+	assert.Nil(t, endpointTraversal)
+}
+
+func Test_Manager_LastActivityReturnsPeerHandshake(t *testing.T) {
+	manager := newManagerStub(pubIP, outIP, country)
+
+	sessionConfig, destroy, err := manager.ProvideConfig(json.RawMessage(`{"PublicKey": "gZfkZArbw9lqfl4Yzr1Kv3nqGlhe/ynH9KKRbzPFMGk="}`))
+	assert.NoError(t, err)
+
+	lastActivity, known := manager.LastActivity("session-id", sessionConfig)
+	assert.True(t, known)
+	assert.WithinDuration(t, time.Now(), lastActivity, time.Second)
+
+	destroy()
+	_, known = manager.LastActivity("session-id", sessionConfig)
+	assert.False(t, known)
+}
+
+func Test_OpenPortFunc_DetectsPublicEndpointBehindNAT(t *testing.T) {
+	mapped := 0
+	portMap := func(port int) func() {
+		mapped = port
+		return func() {}
+	}
+	traversal := &natTraversal{puncher: &natPuncherFake{}}
+
+	openPort := openPortFunc(portMap, location.ServiceLocationInfo{PubIP: "5.6.7.8", OutIP: "192.168.1.2"}, traversal)
+	openPort(52821)
+
+	assert.Equal(t, 52821, mapped)
+	endpoint, ok := traversal.publicEndpoint()
+	assert.True(t, ok)
+	assert.Equal(t, "1.2.3.4:52821", endpoint.String())
+}
+
+func Test_OpenPortFunc_DoesNotDetectPublicEndpointWithoutNAT(t *testing.T) {
+	portMap := func(port int) func() { return func() {} }
+	traversal := &natTraversal{puncher: &natPuncherFake{}}
+
+	openPortFunc(portMap, location.ServiceLocationInfo{PubIP: "5.6.7.8", OutIP: "5.6.7.8"}, traversal)(52821)
+
+	_, ok := traversal.publicEndpoint()
+	assert.False(t, ok)
+}
+
+func Test_OpenPortFunc_DoesNotMapPortWithoutNAT(t *testing.T) {
+	mapped := false
+	portMap := func(port int) func() {
+		mapped = true
+		return func() {}
+	}
+
+	openPortFunc(portMap, location.ServiceLocationInfo{PubIP: "5.6.7.8", OutIP: "5.6.7.8"}, nil)(52821)
+
+	assert.False(t, mapped)
+}
+
 // usually time.Sleep call gives a chance for other goroutines to kick in important when testing async code
 func waitABit() {
 	time.Sleep(10 * time.Millisecond)
 }
 
-type fakeConnectionEndpoint struct{}
+type fakeConnectionEndpoint struct {
+	openPort     func(port int) func()
+	peerEndpoint *net.UDPAddr
+}
 
-func (fce *fakeConnectionEndpoint) Stop() error                            { return nil }
-func (fce *fakeConnectionEndpoint) Start(_ *wg.ServiceConfig) error        { return nil }
-func (fce *fakeConnectionEndpoint) Config() (wg.ServiceConfig, error)      { return wg.ServiceConfig{}, nil }
-func (fce *fakeConnectionEndpoint) AddPeer(_ string, _ *net.UDPAddr) error { return nil }
-func (fce *fakeConnectionEndpoint) ConfigureRoutes(_ net.IP) error         { return nil }
+func (fce *fakeConnectionEndpoint) Stop() error { return nil }
+func (fce *fakeConnectionEndpoint) Start(_ *wg.ServiceConfig) error {
+	if fce.openPort != nil {
+		fce.openPort(52821)
+	}
+	return nil
+}
+func (fce *fakeConnectionEndpoint) Config() (wg.ServiceConfig, error) {
+	var config wg.ServiceConfig
+	config.Provider.Endpoint = net.UDPAddr{IP: net.ParseIP("5.6.7.8"), Port: 52821}
+	config.Consumer.IPAddress = net.IPNet{IP: net.ParseIP("10.182.0.2"), Mask: net.CIDRMask(24, 32)}
+	return config, nil
+}
+func (fce *fakeConnectionEndpoint) AddPeer(_ string, endpoint *net.UDPAddr) error {
+	fce.peerEndpoint = endpoint
+	return nil
+}
+func (fce *fakeConnectionEndpoint) ConfigureRoutes(_ net.IP) error { return nil }
 func (fce *fakeConnectionEndpoint) PeerStats() (wg.Stats, error) {
 	return wg.Stats{LastHandshake: time.Now()}, nil
 }
 
+func Test_Manager_OpenProbeListenerReceivesProbeOnSessionPort(t *testing.T) {
+	allocator := resources.NewAllocator()
+	manager := newManagerStub(pubIP, outIP, country)
+	manager.resourceAllocator = &allocator
+
+	listener, err := manager.OpenProbeListener()
+	assert.NoError(t, err)
+	network, address := listener.Endpoint()
+	assert.Equal(t, "udp", network)
+	assert.Equal(t, "127.0.0.1:52820", address)
+
+	conn, err := net.Dial(network, address)
+	assert.NoError(t, err)
+	_, err = conn.Write([]byte("token"))
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, listener.WaitProbe([]byte("token"), time.Second))
+
+	assert.NoError(t, listener.Close())
+	assert.Empty(t, allocator.Ports)
+}
+
+func Test_Manager_OpenProbeListenerAdvertisesPublicEndpointBehindNAT(t *testing.T) {
+	allocator := resources.NewAllocator()
+	manager := newManagerStub("1.2.3.4", outIP, country)
+	manager.resourceAllocator = &allocator
+	manager.natPuncher = &natPuncherFake{}
+	var mapped, released []int
+	manager.portMap = func(port int) func() {
+		mapped = append(mapped, port)
+		return func() { released = append(released, port) }
+	}
+
+	listener, err := manager.OpenProbeListener()
+	assert.NoError(t, err)
+	_, address := listener.Endpoint()
+	assert.Equal(t, "1.2.3.4:52820", address)
+	assert.Equal(t, []int{52820}, mapped)
+
+	assert.NoError(t, listener.Close())
+	assert.Equal(t, []int{52820}, released)
+}
+
 func newManagerStub(pub, out, country string) *Manager {
 	return &Manager{
-		currentLocation: country,
-		publicIP:        pub,
-		outboundIP:      out,
-		natService:      &serviceFake{},
-		connectionEndpointFactory: func() (wg.ConnectionEndpoint, error) {
+		location:   location.ServiceLocationInfo{PubIP: pub, OutIP: out, Country: country},
+		natService: &serviceFake{},
+		endpoints:  make(map[string]wg.ConnectionEndpoint),
+		connectionEndpointFactory: func(location location.ServiceLocationInfo, traversal *natTraversal) (wg.ConnectionEndpoint, error) {
 			return connectionEndpointStub, nil
 		},
 	}
@@ -121,3 +295,13 @@ func (service *serviceFake) Add(rule nat.RuleForwarding) error { return nil }
 func (service *serviceFake) Del(rule nat.RuleForwarding) error { return nil }
 func (service *serviceFake) Enable() error                     { return nil }
 func (service *serviceFake) Disable() error                    { return nil }
+
+type natPuncherFake struct{}
+
+func (puncher *natPuncherFake) DetectEndpoint(port int) (*net.UDPAddr, error) {
+	return &net.UDPAddr{IP: net.ParseIP("1.2.3.4"), Port: port}, nil
+}
+
+func (puncher *natPuncherFake) Punch(port int, peer *net.UDPAddr, duration time.Duration) error {
+	return nil
+}
@@ -37,6 +37,10 @@ type ServiceDefinition struct {
 	// Approximate information on location where the actual tunnelled traffic will originate from.
 	// This is used by providers having their own means of setting tunnels to other remote exit points.
 	LocationOriginate market.Location `json:"location_originate"`
+
+	// NATTraversal tells that provider is behind NAT and punches it towards the public endpoint of the consumer,
+	// consumers detect their public endpoint only for such providers
+	NATTraversal bool `json:"nat_traversal,omitempty"`
 }
 
 // GetLocation returns geographic location of service definition provider
@@ -68,6 +72,12 @@ type ConnectionEndpoint interface {
 	Stop() error
 }
 
+// NATPuncher opens NAT of the local UDP port for the direct traffic of the peer.
+type NATPuncher interface {
+	DetectEndpoint(port int) (*net.UDPAddr, error)
+	Punch(port int, peer *net.UDPAddr, duration time.Duration) error
+}
+
 // DeviceConfig describes wireguard device configuration.
 type DeviceConfig interface {
 	PrivateKey() string
@@ -87,9 +97,11 @@ type Stats struct {
 	LastHandshake time.Time
 }
 
-// ConsumerConfig is used for sending the public key from consumer to provider
+// ConsumerConfig is used for sending the public key and public endpoint from consumer to provider
 type ConsumerConfig struct {
 	PublicKey string
+	// Endpoint is public UDP endpoint of the consumer, provider punches its NAT towards it
+	Endpoint string `json:",omitempty"`
 }
 
 // ConsumerPrivateKey represents the private part of the consumer key
@@ -107,6 +119,8 @@ type ServiceConfig struct {
 		PrivateKey   string `json:"-"`
 		IPAddress    net.IPNet
 		ConnectDelay int
+		// ListenPort is the port consumer punched NAT from, zero means any free port
+		ListenPort int `json:"-"`
 	}
 }
 
@@ -39,6 +39,7 @@ type createConsumer struct {
 	peerID         identity.Identity
 	configProvider ConfigProvider
 	promiseLoader  PromiseLoader
+	idleWatcher    *idleWatcher
 }
 
 // Creator defines method for session creation
@@ -80,9 +81,16 @@ func (consumer *createConsumer) Consume(requestPtr interface{}) (response interf
 				destroyCallback()
 			}()
 		}
+		if consumer.idleWatcher != nil {
+			go consumer.idleWatcher.watch(sessionInstance, config)
+		}
 		return responseWithSession(sessionInstance, config, consumer.promiseLoader.LoadPaymentInfo(consumer.peerID, consumer.receiverID, issuerID)), nil
 	case ErrorInvalidProposal:
 		return responseInvalidProposal, nil
+	case ErrorDraining:
+		return responseDraining, nil
+	case ErrorPaymentsNotSupported:
+		return responseNoPayments, nil
 	default:
 		return responseInternalError, nil
 	}
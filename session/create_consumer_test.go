@@ -88,6 +88,23 @@ func TestConsumer_ErrorInvalidProposal(t *testing.T) {
 	assert.Exactly(t, responseInvalidProposal, sessionResponse)
 }
 
+func TestConsumer_ErrorPaymentsNotSupported(t *testing.T) {
+	mockManager := &managerFake{
+		returnError: ErrorPaymentsNotSupported,
+	}
+	consumer := createConsumer{
+		sessionCreator: mockManager,
+		configProvider: mockConsumer,
+		promiseLoader:  mpl,
+	}
+
+	request := consumer.NewRequest().(*CreateRequest)
+	sessionResponse, err := consumer.Consume(request)
+
+	assert.NoError(t, err)
+	assert.Exactly(t, responseNoPayments, sessionResponse)
+}
+
 func TestConsumer_ErrorFatal(t *testing.T) {
 	mockManager := &managerFake{
 		returnError: errors.New("fatality"),
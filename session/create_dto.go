@@ -30,6 +30,8 @@ const endpointSessionCreate = communication.RequestEndpoint("session-create")
 var (
 	responseInvalidProposal = CreateResponse{Success: false, Message: "Invalid Proposal"}
 	responseInternalError   = CreateResponse{Success: false, Message: "Internal Error"}
+	responseDraining        = CreateResponse{Success: false, Message: "Service Is Draining"}
+	responseNoPayments      = CreateResponse{Success: false, Message: "Payments Are Required"}
 )
 
 // CreateRequest structure represents message from service consumer to initiate session for given proposal id
@@ -18,6 +18,7 @@
 package session
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 
@@ -47,14 +48,14 @@ func (producer *createProducer) Produce() (requestPtr interface{}) {
 	}
 }
 
-// RequestSessionCreate requests session creation and returns session DTO
-func RequestSessionCreate(sender communication.Sender, proposalID int, config interface{}, ci ConsumerInfo) (session SessionDto, pi *promise.PaymentInfo, err error) {
+// RequestSessionCreate requests session creation and returns session DTO, request is aborted once the context is done
+func RequestSessionCreate(ctx context.Context, sender communication.Sender, proposalID int, config interface{}, ci ConsumerInfo) (session SessionDto, pi *promise.PaymentInfo, err error) {
 	sessionCreateConfigJSON, err := json.Marshal(config)
 	if err != nil {
 		return
 	}
 
-	responsePtr, err := sender.Request(&createProducer{
+	responsePtr, err := sender.RequestContext(ctx, &createProducer{
 		ProposalID:   proposalID,
 		Config:       sessionCreateConfigJSON,
 		ConsumerInfo: &ci,
@@ -18,6 +18,7 @@
 package session
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -45,7 +46,7 @@ type fakeSessionConfig struct {
 
 func TestProducer_RequestSessionCreate(t *testing.T) {
 	sender := &fakeSender{}
-	sessionData, paymentInfo, err := RequestSessionCreate(sender, 123, []byte{}, ConsumerInfo{})
+	sessionData, paymentInfo, err := RequestSessionCreate(context.Background(), sender, 123, []byte{}, ConsumerInfo{})
 	assert.NoError(t, err)
 	assert.Exactly(t, succesfullSessionID, sessionData.ID)
 	assert.Exactly(t, succesfullSessionConfig, sessionData.Config)
@@ -60,7 +61,15 @@ func (sender *fakeSender) Send(producer communication.MessageProducer) error {
 	return nil
 }
 
+func (sender *fakeSender) SendContext(_ context.Context, producer communication.MessageProducer) error {
+	return sender.Send(producer)
+}
+
 func (sender *fakeSender) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	return sender.RequestContext(context.Background(), producer)
+}
+
+func (sender *fakeSender) RequestContext(_ context.Context, producer communication.RequestProducer) (responsePtr interface{}, err error) {
 	sender.lastRequest = producer
 	return &CreateResponse{
 		Success: true,
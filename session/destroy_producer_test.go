@@ -18,6 +18,7 @@
 package session
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mysteriumnetwork/node/communication"
@@ -32,7 +33,7 @@ var (
 
 func TestProducer_RequestSessionDestroy(t *testing.T) {
 	sender := &fakeSender{}
-	sessionData, _, err := RequestSessionCreate(sender, 123, []byte{}, ConsumerInfo{})
+	sessionData, _, err := RequestSessionCreate(context.Background(), sender, 123, []byte{}, ConsumerInfo{})
 	assert.NoError(t, err)
 
 	destroySender := &fakeDestroySender{}
@@ -47,6 +48,14 @@ func (sender *fakeDestroySender) Send(producer communication.MessageProducer) er
 	return nil
 }
 
+func (sender *fakeDestroySender) SendContext(context.Context, communication.MessageProducer) error {
+	return nil
+}
+
 func (sender *fakeDestroySender) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
 	return successfulSessionDestroyResponse, nil
 }
+
+func (sender *fakeDestroySender) RequestContext(context.Context, communication.RequestProducer) (responsePtr interface{}, err error) {
+	return successfulSessionDestroyResponse, nil
+}
@@ -18,20 +18,38 @@
 package session
 
 import (
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/identity"
 )
 
+// unusedDialogTimeout is how long a dialog may stay open without creating any session
+const unusedDialogTimeout = 10 * time.Minute
+
 // ManagerFactory initiates session Manager instance during runtime
 type ManagerFactory func(dialog communication.Dialog) *Manager
 
-// NewDialogHandler constructs handler which gets all incoming dialogs and starts handling them
-func NewDialogHandler(sessionManagerFactory ManagerFactory, configProvider ConfigProvider, promiseLoader PromiseLoader, receiverID identity.Identity) *handler {
+// NewDialogHandler constructs handler which gets all incoming dialogs and starts handling them.
+// Sessions idle for the idleTimeout are terminated, activityMonitor is optional and zero idleTimeout disables it.
+func NewDialogHandler(
+	sessionManagerFactory ManagerFactory,
+	configProvider ConfigProvider,
+	promiseLoader PromiseLoader,
+	receiverID identity.Identity,
+	activityMonitor ActivityMonitor,
+	idleTimeout time.Duration,
+) *handler {
 	return &handler{
 		sessionManagerFactory: sessionManagerFactory,
 		configProvider:        configProvider,
 		promiseLoader:         promiseLoader,
 		receiverID:            receiverID,
+		activityMonitor:       activityMonitor,
+		idleTimeout:           idleTimeout,
+		unusedTimeout:         unusedDialogTimeout,
 	}
 }
 
@@ -40,21 +58,133 @@ type handler struct {
 	configProvider        ConfigProvider
 	promiseLoader         PromiseLoader
 	receiverID            identity.Identity
+	activityMonitor       ActivityMonitor
+	idleTimeout           time.Duration
+	unusedTimeout         time.Duration
+
+	dialogs      []*handledDialog
+	draining     bool
+	managersLock sync.Mutex
+}
+
+// handledDialog keeps track of the session manager serving the dialog
+type handledDialog struct {
+	dialog  communication.Dialog
+	manager *Manager
+	started time.Time
 }
 
-// Handle starts serving services in given Dialog instance
+// Handle starts serving services in given Dialog instance, dialogs are rejected while draining
 func (handler *handler) Handle(dialog communication.Dialog) error {
-	return handler.subscribeSessionRequests(dialog)
+	manager, err := handler.newManager(dialog)
+	if err != nil {
+		return err
+	}
+	return handler.subscribeSessionRequests(dialog, manager)
+}
+
+// DrainSessions stops accepting new sessions, notifies consumers and waits until their sessions end.
+// Sessions still running after the timeout are terminated.
+func (handler *handler) DrainSessions(timeout time.Duration) {
+	handler.managersLock.Lock()
+	handler.draining = true
+	dialogs := append([]*handledDialog(nil), handler.dialogs...)
+	handler.managersLock.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	var sessions []Session
+	for _, handled := range dialogs {
+		sessions = append(sessions, handled.manager.NotifyDraining(deadline)...)
+	}
+
+	expired := time.After(timeout)
+	for _, sessionInstance := range sessions {
+		select {
+		case <-sessionInstance.Done:
+		case <-expired:
+			handler.TerminateSessions(TerminationReasonServiceStopped)
+			return
+		}
+	}
+}
+
+// TerminateSessions terminates all sessions of the handled dialogs and notifies consumers about the reason
+func (handler *handler) TerminateSessions(reason TerminationReason) {
+	handler.managersLock.Lock()
+	dialogs := handler.dialogs
+	handler.dialogs = nil
+	handler.managersLock.Unlock()
+
+	for _, handled := range dialogs {
+		handled.manager.TerminateAll(reason)
+	}
 }
 
-func (handler *handler) subscribeSessionRequests(dialog communication.Dialog) error {
+// newManager creates session manager of the dialog and keeps track of it, forgetting managers of finished dialogs.
+// Dialogs which have not created any session for too long are unsubscribed and forgotten too.
+func (handler *handler) newManager(dialog communication.Dialog) (*Manager, error) {
+	handler.managersLock.Lock()
+	if handler.draining {
+		handler.managersLock.Unlock()
+		return nil, ErrorDraining
+	}
+
+	var unused []communication.Dialog
+	dialogs := handler.dialogs[:0]
+	for _, handled := range handler.dialogs {
+		switch {
+		case handled.manager.finished():
+		case handled.manager.unused() && time.Since(handled.started) > handler.unusedTimeout:
+			unused = append(unused, handled.dialog)
+		default:
+			dialogs = append(dialogs, handled)
+		}
+	}
+
+	manager := handler.sessionManagerFactory(dialog)
+	handler.dialogs = append(dialogs, &handledDialog{dialog: dialog, manager: manager, started: time.Now()})
+	handler.managersLock.Unlock()
+
+	for _, unusedDialog := range unused {
+		log.Info(managerLogPrefix, "unsubscribing dialog without sessions of peer: ", unusedDialog.PeerID().Address)
+		unusedDialog.Unsubscribe()
+	}
+	return manager, nil
+}
+
+// forget stops tracking the manager of the dialog, which was unsubscribed
+func (handler *handler) forget(manager *Manager) {
+	handler.managersLock.Lock()
+	defer handler.managersLock.Unlock()
+
+	for i, handled := range handler.dialogs {
+		if handled.manager == manager {
+			handler.dialogs = append(handler.dialogs[:i], handler.dialogs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (handler *handler) subscribeSessionRequests(dialog communication.Dialog, manager *Manager) error {
+	// sessions of consumers which do not send keepalives can be watched only by their data plane activity
+	var watcher *idleWatcher
+	keepsAlive := dialog.PeerProtocol().Supports(communication.CapabilitySessionKeepalive)
+	if handler.idleTimeout > 0 && (keepsAlive || handler.activityMonitor != nil) {
+		watcher = &idleWatcher{
+			manager:         manager,
+			activityMonitor: handler.activityMonitor,
+			timeout:         handler.idleTimeout,
+		}
+	}
+
 	err := dialog.Respond(
 		&createConsumer{
-			sessionCreator: handler.sessionManagerFactory(dialog),
+			sessionCreator: manager,
 			peerID:         dialog.PeerID(),
 			configProvider: handler.configProvider,
 			promiseLoader:  handler.promiseLoader,
 			receiverID:     handler.receiverID,
+			idleWatcher:    watcher,
 		},
 	)
 
@@ -62,11 +192,23 @@ func (handler *handler) subscribeSessionRequests(dialog communication.Dialog) er
 		return err
 	}
 
+	err = dialog.Receive(NewKeepaliveConsumer(func(message KeepaliveMessage) {
+		if err := manager.KeepAlive(message.SessionID); err != nil {
+			log.Debug(managerLogPrefix, "keepalive of unknown session ignored: ", message.SessionID)
+		}
+	}))
+	if err != nil {
+		return err
+	}
+
 	return dialog.Respond(
 		&destroyConsumer{
 			SessionDestroyer: &sessionDestroyer{
-				destroyer:   handler.sessionManagerFactory(dialog),
-				unsubscribe: dialog.Unsubscribe,
+				destroyer: manager,
+				unsubscribe: func() {
+					dialog.Unsubscribe()
+					handler.forget(manager)
+				},
 			},
 			PeerID: dialog.PeerID(),
 		},
@@ -0,0 +1,155 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/stretchr/testify/assert"
+)
+
+type dialogFake struct {
+	communication.Sender
+	receivers    []communication.MessageConsumer
+	responders   []communication.RequestConsumer
+	protocol     communication.Protocol
+	unsubscribed bool
+}
+
+func (dialog *dialogFake) Receive(consumer communication.MessageConsumer) error {
+	dialog.receivers = append(dialog.receivers, consumer)
+	return nil
+}
+
+func (dialog *dialogFake) PeerID() identity.Identity {
+	return consumerID
+}
+
+func (dialog *dialogFake) PeerProtocol() communication.Protocol {
+	return dialog.protocol
+}
+
+func (dialog *dialogFake) Respond(consumer communication.RequestConsumer) error {
+	dialog.responders = append(dialog.responders, consumer)
+	return nil
+}
+
+func (dialog *dialogFake) Unsubscribe() {
+	dialog.unsubscribed = true
+}
+
+func (dialog *dialogFake) Close() error {
+	return nil
+}
+
+type promiseLoaderFake struct{}
+
+func (promiseLoaderFake) LoadPaymentInfo(consumerID, receiverID, issuerID identity.Identity) *promise.PaymentInfo {
+	return nil
+}
+
+func newHandlerWithSession(t *testing.T, sender *terminationSenderFake) (*handler, *Manager, Session) {
+	var manager *Manager
+	managerFactory := func(dialog communication.Dialog) *Manager {
+		manager = NewManager(currentProposal, generateSessionID, NewStorageMemory(), mockBalanceTrackerFactory, sender)
+		return manager
+	}
+	configProvider := func(json.RawMessage) (ServiceConfiguration, DestroyCallback, error) {
+		return nil, nil, nil
+	}
+	handler := NewDialogHandler(managerFactory, configProvider, promiseLoaderFake{}, identity.FromAddress("provider"), nil, 0)
+
+	assert.NoError(t, handler.Handle(&dialogFake{}))
+	sessionInstance, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+
+	return handler, manager, sessionInstance
+}
+
+func TestHandler_DrainSessions_WaitsForSessionsToEnd(t *testing.T) {
+	sender := &terminationSenderFake{}
+	handler, manager, sessionInstance := newHandlerWithSession(t, sender)
+
+	drained := make(chan struct{})
+	go func() {
+		handler.DrainSessions(time.Minute)
+		close(drained)
+	}()
+
+	for i := 0; i < 100 && len(sender.drainingMessages()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Len(t, sender.drainingMessages(), 1)
+	assert.Equal(t, ErrorDraining, handler.Handle(&dialogFake{}))
+
+	assert.NoError(t, manager.Destroy(consumerID, string(sessionInstance.ID)))
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("draining did not finish after session has ended")
+	}
+	assert.Empty(t, sender.messages())
+}
+
+func TestHandler_DrainSessions_TerminatesSessionsAfterTimeout(t *testing.T) {
+	sender := &terminationSenderFake{}
+	handler, _, sessionInstance := newHandlerWithSession(t, sender)
+
+	handler.DrainSessions(10 * time.Millisecond)
+
+	<-sessionInstance.Done
+	assert.Equal(t, []TerminateMessage{{SessionID: expectedID, Reason: TerminationReasonServiceStopped}}, sender.messages())
+}
+
+func TestHandler_Handle_ForgetsManagerOfUnsubscribedDialog(t *testing.T) {
+	handler, manager, sessionInstance := newHandlerWithSession(t, &terminationSenderFake{})
+	dialog := handler.dialogs[0].dialog.(*dialogFake)
+
+	destroyer := dialog.responders[len(dialog.responders)-1]
+	_, err := destroyer.Consume(&DestroyRequest{SessionID: string(sessionInstance.ID)})
+	assert.NoError(t, err)
+
+	assert.True(t, dialog.unsubscribed)
+	assert.Empty(t, handler.dialogs)
+	assert.True(t, manager.finished())
+}
+
+func TestHandler_Handle_UnsubscribesDialogsWithoutSessions(t *testing.T) {
+	managerFactory := func(dialog communication.Dialog) *Manager {
+		return NewManager(currentProposal, generateSessionID, NewStorageMemory(), mockBalanceTrackerFactory, &terminationSenderFake{})
+	}
+	handler := NewDialogHandler(managerFactory, nil, promiseLoaderFake{}, identity.FromAddress("provider"), nil, 0)
+	handler.unusedTimeout = time.Millisecond
+
+	unusedDialog := &dialogFake{}
+	assert.NoError(t, handler.Handle(unusedDialog))
+	time.Sleep(5 * time.Millisecond)
+
+	dialog := &dialogFake{}
+	assert.NoError(t, handler.Handle(dialog))
+
+	assert.True(t, unusedDialog.unsubscribed)
+	assert.False(t, dialog.unsubscribed)
+	assert.Len(t, handler.dialogs, 1)
+	assert.Equal(t, dialog, handler.dialogs[0].dialog)
+}
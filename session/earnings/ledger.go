@@ -0,0 +1,251 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package earnings
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session/promise"
+)
+
+const (
+	dayFormat          = promise.DayFormat
+	unknownServiceType = "unknown"
+)
+
+var csvHeader = []string{"date", "consumer", "service_type", "received", "consumed", "unconsumed", "cleared"}
+
+// PromiseStorage exposes the promises received by the provider
+type PromiseStorage interface {
+	GetAllKnownIssuers() []identity.Identity
+	GetAllPromisesFromIssuer(issuerID identity.Identity) ([]promise.StoredPromise, error)
+}
+
+// Summary holds the earned amounts
+type Summary struct {
+	Received   uint64
+	Consumed   uint64
+	Unconsumed uint64
+	Cleared    uint64
+}
+
+func (s *Summary) add(other Summary) {
+	s.Received += other.Received
+	s.Consumed += other.Consumed
+	s.Unconsumed += other.Unconsumed
+	s.Cleared += other.Cleared
+}
+
+// Entry holds the earnings for a single consumer and service type during a day
+type Entry struct {
+	Date        string
+	ConsumerID  string
+	ServiceType string
+	Summary
+}
+
+// Report holds the earnings of the provider, broken down by consumer, service type and day
+type Report struct {
+	Total         Summary
+	ByConsumer    map[string]Summary
+	ByServiceType map[string]Summary
+	ByDay         map[string]Summary
+	Entries       []Entry
+}
+
+// Ledger builds earnings reports from the stored promises
+type Ledger struct {
+	storage PromiseStorage
+}
+
+// NewLedger returns a new instance of earnings ledger
+func NewLedger(storage PromiseStorage) *Ledger {
+	return &Ledger{
+		storage: storage,
+	}
+}
+
+// Report calculates the earnings from all the promises received so far
+func (l *Ledger) Report() (Report, error) {
+	report := Report{
+		ByConsumer:    make(map[string]Summary),
+		ByServiceType: make(map[string]Summary),
+		ByDay:         make(map[string]Summary),
+		Entries:       make([]Entry, 0),
+	}
+
+	entryIndex := make(map[Entry]int)
+	for _, issuer := range l.storage.GetAllKnownIssuers() {
+		promises, err := l.storage.GetAllPromisesFromIssuer(issuer)
+		if err != nil {
+			return report, err
+		}
+
+		for _, p := range promises {
+			// promise ids are reserved before the consumer sends anything - nothing is earned for them yet
+			if p.Message == nil {
+				continue
+			}
+
+			for _, earned := range promiseEarnings(p) {
+				key := Entry{
+					Date:        earned.Date,
+					ConsumerID:  p.ConsumerID.Address,
+					ServiceType: promiseServiceType(p),
+				}
+				summary := earned.Summary
+
+				report.Total.add(summary)
+				report.ByConsumer[key.ConsumerID] = addTo(report.ByConsumer[key.ConsumerID], summary)
+				report.ByServiceType[key.ServiceType] = addTo(report.ByServiceType[key.ServiceType], summary)
+				report.ByDay[key.Date] = addTo(report.ByDay[key.Date], summary)
+
+				if i, ok := entryIndex[key]; ok {
+					report.Entries[i].add(summary)
+					continue
+				}
+				entry := key
+				entry.Summary = summary
+				entryIndex[key] = len(report.Entries)
+				report.Entries = append(report.Entries, entry)
+			}
+		}
+	}
+
+	sortEntries(report.Entries)
+	return report, nil
+}
+
+// WriteCSV writes the report entries to the given writer in CSV format, suitable for accounting
+func WriteCSV(w io.Writer, entries []Entry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		record := []string{
+			e.Date,
+			e.ConsumerID,
+			e.ServiceType,
+			strconv.FormatUint(e.Received, 10),
+			strconv.FormatUint(e.Consumed, 10),
+			strconv.FormatUint(e.Unconsumed, 10),
+			strconv.FormatUint(e.Cleared, 10),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// dayEarnings holds the earnings of a single promise during a day
+type dayEarnings struct {
+	Date string
+	Summary
+}
+
+// promiseEarnings splits the promised amount into the days the promise was extended on,
+// each day gets the increase of the amount since the previous day.
+// Unconsumed amount is the most recently received one, so it is assigned to the latest days.
+func promiseEarnings(p promise.StoredPromise) []dayEarnings {
+	days := make([]string, 0, len(p.AmountByDay))
+	for day := range p.AmountByDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	var earnings []dayEarnings
+	var previous uint64
+	for _, day := range days {
+		amount := p.AmountByDay[day]
+		if amount > previous && amount <= p.Message.Amount {
+			earnings = append(earnings, dayEarnings{Date: day, Summary: Summary{Received: amount - previous}})
+			previous = amount
+		}
+	}
+	// promises stored before the amounts were kept by day are assigned to the day they were updated on
+	if previous < p.Message.Amount || len(earnings) == 0 {
+		earnings = appendEarnings(earnings, promiseDay(p), p.Message.Amount-previous)
+	}
+
+	unconsumed := p.UnconsumedAmount
+	for i := len(earnings) - 1; i >= 0; i-- {
+		earned := &earnings[i].Summary
+		earned.Unconsumed = unconsumed
+		if i > 0 && unconsumed > earned.Received {
+			earned.Unconsumed = earned.Received
+		}
+		unconsumed -= earned.Unconsumed
+		if earned.Received > earned.Unconsumed {
+			earned.Consumed = earned.Received - earned.Unconsumed
+		}
+		if p.Cleared {
+			earned.Cleared = earned.Received
+		}
+	}
+	return earnings
+}
+
+// appendEarnings adds the amount received on the day, merging it with the latest earnings of the same day
+func appendEarnings(earnings []dayEarnings, day string, amount uint64) []dayEarnings {
+	if last := len(earnings) - 1; last >= 0 && earnings[last].Date == day {
+		earnings[last].Received += amount
+		return earnings
+	}
+	return append(earnings, dayEarnings{Date: day, Summary: Summary{Received: amount}})
+}
+
+func promiseDay(p promise.StoredPromise) string {
+	receivedAt := p.UpdatedAt
+	if receivedAt.IsZero() {
+		receivedAt = p.AddedAt
+	}
+	return receivedAt.UTC().Format(dayFormat)
+}
+
+func promiseServiceType(p promise.StoredPromise) string {
+	if p.ServiceType == "" {
+		return unknownServiceType
+	}
+	return p.ServiceType
+}
+
+func addTo(summary Summary, other Summary) Summary {
+	summary.add(other)
+	return summary
+}
+
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		if entries[i].ConsumerID != entries[j].ConsumerID {
+			return entries[i].ConsumerID < entries[j].ConsumerID
+		}
+		return entries[i].ServiceType < entries[j].ServiceType
+	})
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package earnings
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	issuer    = identity.FromAddress("0x1")
+	consumer1 = identity.FromAddress("0x2")
+	consumer2 = identity.FromAddress("0x3")
+	day1      = time.Date(2019, 4, 1, 10, 0, 0, 0, time.UTC)
+	day2      = time.Date(2019, 4, 2, 10, 0, 0, 0, time.UTC)
+)
+
+type mockPromiseStorage struct {
+	promises map[identity.Identity][]promise.StoredPromise
+	err      error
+}
+
+func (mps *mockPromiseStorage) GetAllKnownIssuers() []identity.Identity {
+	res := make([]identity.Identity, 0)
+	for k := range mps.promises {
+		res = append(res, k)
+	}
+	return res
+}
+
+func (mps *mockPromiseStorage) GetAllPromisesFromIssuer(issuerID identity.Identity) ([]promise.StoredPromise, error) {
+	return mps.promises[issuerID], mps.err
+}
+
+func newStorage() *mockPromiseStorage {
+	return &mockPromiseStorage{
+		promises: map[identity.Identity][]promise.StoredPromise{
+			issuer: {
+				{
+					SequenceID:       1,
+					Message:          &promise.Message{Amount: 100},
+					UnconsumedAmount: 20,
+					ConsumerID:       consumer1,
+					ServiceType:      "openvpn",
+					UpdatedAt:        day1,
+					Cleared:          true,
+				},
+				{
+					SequenceID:       2,
+					Message:          &promise.Message{Amount: 50},
+					UnconsumedAmount: 10,
+					ConsumerID:       consumer2,
+					ServiceType:      "wireguard",
+					UpdatedAt:        day2,
+				},
+				{
+					SequenceID:       3,
+					Message:          &promise.Message{Amount: 30},
+					UnconsumedAmount: 0,
+					ConsumerID:       consumer1,
+					ServiceType:      "openvpn",
+					UpdatedAt:        day1.Add(time.Hour),
+				},
+				{
+					SequenceID: 4,
+					ConsumerID: consumer2,
+					AddedAt:    day2,
+				},
+				{
+					SequenceID:       5,
+					Message:          &promise.Message{Amount: 5},
+					UnconsumedAmount: 5,
+					ConsumerID:       consumer2,
+					AddedAt:          day2,
+				},
+			},
+		},
+	}
+}
+
+func Test_Ledger_Report(t *testing.T) {
+	ledger := NewLedger(newStorage())
+
+	report, err := ledger.Report()
+	assert.Nil(t, err)
+
+	assert.Equal(t, Summary{Received: 185, Consumed: 150, Unconsumed: 35, Cleared: 100}, report.Total)
+	assert.Equal(t, Summary{Received: 130, Consumed: 110, Unconsumed: 20, Cleared: 100}, report.ByConsumer[consumer1.Address])
+	assert.Equal(t, Summary{Received: 55, Consumed: 40, Unconsumed: 15}, report.ByConsumer[consumer2.Address])
+	assert.Equal(t, Summary{Received: 130, Consumed: 110, Unconsumed: 20, Cleared: 100}, report.ByServiceType["openvpn"])
+	assert.Equal(t, Summary{Received: 50, Consumed: 40, Unconsumed: 10}, report.ByServiceType["wireguard"])
+	assert.Equal(t, Summary{Received: 5, Unconsumed: 5}, report.ByServiceType["unknown"])
+	assert.Equal(t, Summary{Received: 130, Consumed: 110, Unconsumed: 20, Cleared: 100}, report.ByDay["2019-04-01"])
+	assert.Equal(t, Summary{Received: 55, Consumed: 40, Unconsumed: 15}, report.ByDay["2019-04-02"])
+
+	assert.Equal(t, []Entry{
+		{
+			Date:        "2019-04-01",
+			ConsumerID:  consumer1.Address,
+			ServiceType: "openvpn",
+			Summary:     Summary{Received: 130, Consumed: 110, Unconsumed: 20, Cleared: 100},
+		},
+		{
+			Date:        "2019-04-02",
+			ConsumerID:  consumer2.Address,
+			ServiceType: "unknown",
+			Summary:     Summary{Received: 5, Unconsumed: 5},
+		},
+		{
+			Date:        "2019-04-02",
+			ConsumerID:  consumer2.Address,
+			ServiceType: "wireguard",
+			Summary:     Summary{Received: 50, Consumed: 40, Unconsumed: 10},
+		},
+	}, report.Entries)
+}
+
+func Test_Ledger_ReportSplitsExtendedPromiseByDay(t *testing.T) {
+	extended := promise.StoredPromise{SequenceID: 1, ConsumerID: consumer1, ServiceType: "openvpn", Cleared: true}
+	extended.Extend(&promise.Message{Amount: 60}, day1)
+	extended.Extend(&promise.Message{Amount: 100}, day1.Add(time.Hour))
+	extended.Extend(&promise.Message{Amount: 130}, day2)
+	extended.UnconsumedAmount = 40
+	extended.UpdatedAt = day2
+	storage := &mockPromiseStorage{
+		promises: map[identity.Identity][]promise.StoredPromise{issuer: {extended}},
+	}
+
+	report, err := NewLedger(storage).Report()
+	assert.Nil(t, err)
+
+	assert.Equal(t, Summary{Received: 130, Consumed: 90, Unconsumed: 40, Cleared: 130}, report.Total)
+	assert.Equal(t, []Entry{
+		{
+			Date:        "2019-04-01",
+			ConsumerID:  consumer1.Address,
+			ServiceType: "openvpn",
+			Summary:     Summary{Received: 100, Consumed: 90, Unconsumed: 10, Cleared: 100},
+		},
+		{
+			Date:        "2019-04-02",
+			ConsumerID:  consumer1.Address,
+			ServiceType: "openvpn",
+			Summary:     Summary{Received: 30, Unconsumed: 30, Cleared: 30},
+		},
+	}, report.Entries)
+}
+
+func Test_Ledger_ReportBubblesStorageError(t *testing.T) {
+	storage := newStorage()
+	storage.err = errors.New("boom")
+	ledger := NewLedger(storage)
+
+	_, err := ledger.Report()
+	assert.Equal(t, storage.err, err)
+}
+
+func Test_WriteCSV(t *testing.T) {
+	entries := []Entry{
+		{
+			Date:        "2019-04-01",
+			ConsumerID:  consumer1.Address,
+			ServiceType: "openvpn",
+			Summary:     Summary{Received: 130, Consumed: 110, Unconsumed: 20, Cleared: 100},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := WriteCSV(buf, entries)
+	assert.Nil(t, err)
+	assert.Equal(t,
+		"date,consumer,service_type,received,consumed,unconsumed,cleared\n"+
+			"2019-04-01,0x2,openvpn,130,110,20,100\n",
+		buf.String(),
+	)
+}
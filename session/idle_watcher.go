@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+// ActivityMonitor is implemented by services able to tell when consumer traffic was last seen in the data plane
+type ActivityMonitor interface {
+	// LastActivity returns time of the last consumer activity in the session, false is returned when it is unknown
+	LastActivity(sessionID ID, config ServiceConfiguration) (time.Time, bool)
+}
+
+// idleChecksPerTimeout defines how many times idleness of the session is checked during the idle timeout
+const idleChecksPerTimeout = 4
+
+// minIdleCheckInterval keeps idleness checks of the sessions with tiny idle timeouts from spinning
+const minIdleCheckInterval = time.Millisecond
+
+// idleWatcher terminates sessions of consumers, which neither send keepalives nor use the data plane for the idle timeout
+type idleWatcher struct {
+	manager         *Manager
+	activityMonitor ActivityMonitor
+	timeout         time.Duration
+}
+
+// watch blocks until the session is finished or terminates it once the session becomes idle
+func (watcher *idleWatcher) watch(sessionInstance Session, config ServiceConfiguration) {
+	interval := watcher.timeout / idleChecksPerTimeout
+	if interval < minIdleCheckInterval {
+		interval = minIdleCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sessionInstance.Done:
+			return
+		case <-ticker.C:
+			idle := time.Since(watcher.lastSeen(sessionInstance.ID, config))
+			if idle < watcher.timeout {
+				continue
+			}
+
+			log.Warnf("%ssession %s is idle for %s", managerLogPrefix, sessionInstance.ID, idle)
+			err := watcher.manager.Terminate(sessionInstance.ID, TerminationReasonIdle)
+			if err != nil && err != ErrorSessionNotExists {
+				log.Warn(managerLogPrefix, "failed to notify consumer about idle session: ", err)
+			}
+			return
+		}
+	}
+}
+
+func (watcher *idleWatcher) lastSeen(sessionID ID, config ServiceConfiguration) time.Time {
+	lastSeen := watcher.manager.lastKeepalive(sessionID)
+	if watcher.activityMonitor == nil {
+		return lastSeen
+	}
+
+	if lastActivity, known := watcher.activityMonitor.LastActivity(sessionID, config); known && lastActivity.After(lastSeen) {
+		return lastActivity
+	}
+	return lastSeen
+}
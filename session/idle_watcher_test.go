@@ -0,0 +1,150 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type activityMonitorFake struct {
+	sync.Mutex
+	lastActivity time.Time
+}
+
+func (monitor *activityMonitorFake) LastActivity(ID, ServiceConfiguration) (time.Time, bool) {
+	monitor.Lock()
+	defer monitor.Unlock()
+	return monitor.lastActivity, !monitor.lastActivity.IsZero()
+}
+
+func (monitor *activityMonitorFake) touch() {
+	monitor.Lock()
+	defer monitor.Unlock()
+	monitor.lastActivity = time.Now()
+}
+
+func TestIdleWatcher_TerminatesIdleSession(t *testing.T) {
+	sender := &terminationSenderFake{}
+	manager := NewManager(currentProposal, generateSessionID, NewStorageMemory(), mockBalanceTrackerFactory, sender)
+	sessionInstance, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+
+	watcher := &idleWatcher{manager: manager, activityMonitor: &activityMonitorFake{}, timeout: 20 * time.Millisecond}
+	watcher.watch(sessionInstance, nil)
+
+	select {
+	case <-sessionInstance.Done:
+	default:
+		t.Fatal("idle session was not destroyed")
+	}
+	assert.Equal(t, []TerminateMessage{{SessionID: expectedID, Reason: TerminationReasonIdle}}, sender.messages())
+}
+
+func TestIdleWatcher_TerminatesIdleSessionWithTinyTimeout(t *testing.T) {
+	sender := &terminationSenderFake{}
+	manager := NewManager(currentProposal, generateSessionID, NewStorageMemory(), mockBalanceTrackerFactory, sender)
+	sessionInstance, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+
+	watcher := &idleWatcher{manager: manager, activityMonitor: &activityMonitorFake{}, timeout: 3 * time.Nanosecond}
+	watcher.watch(sessionInstance, nil)
+
+	assert.Equal(t, []TerminateMessage{{SessionID: expectedID, Reason: TerminationReasonIdle}}, sender.messages())
+}
+
+func TestIdleWatcher_KeepsSessionWithDataPlaneActivity(t *testing.T) {
+	sender := &terminationSenderFake{}
+	manager := NewManager(currentProposal, generateSessionID, NewStorageMemory(), mockBalanceTrackerFactory, sender)
+	sessionInstance, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+
+	monitor := &activityMonitorFake{}
+	watcher := &idleWatcher{manager: manager, activityMonitor: monitor, timeout: 40 * time.Millisecond}
+	watched := make(chan struct{})
+	go func() {
+		watcher.watch(sessionInstance, nil)
+		close(watched)
+	}()
+
+	for i := 0; i < 10; i++ {
+		monitor.touch()
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Empty(t, sender.messages())
+
+	assert.NoError(t, manager.Destroy(consumerID, string(expectedID)))
+	select {
+	case <-watched:
+	case <-time.After(time.Second):
+		t.Fatal("watching did not stop after session has ended")
+	}
+	assert.Empty(t, sender.messages())
+}
+
+func TestHandler_KeepaliveRefreshesSession(t *testing.T) {
+	var manager *Manager
+	managerFactory := func(dialog communication.Dialog) *Manager {
+		manager = NewManager(currentProposal, generateSessionID, NewStorageMemory(), mockBalanceTrackerFactory, &terminationSenderFake{})
+		return manager
+	}
+	handler := NewDialogHandler(managerFactory, nil, promiseLoaderFake{}, identity.FromAddress("provider"), nil, time.Minute)
+
+	dialog := &dialogFake{}
+	assert.NoError(t, handler.Handle(dialog))
+	_, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+	createdAt := manager.lastKeepalive(expectedID)
+
+	time.Sleep(time.Millisecond)
+	for _, consumer := range dialog.receivers {
+		if consumer.GetMessageEndpoint() == endpointSessionKeepalive {
+			assert.NoError(t, consumer.Consume(&KeepaliveMessage{SessionID: expectedID}))
+		}
+	}
+
+	assert.True(t, manager.lastKeepalive(expectedID).After(createdAt))
+	assert.Equal(t, ErrorSessionNotExists, manager.KeepAlive("unknown-session"))
+}
+
+func TestHandler_WatchesIdleSessionsOfConsumersSendingKeepalives(t *testing.T) {
+	managerFactory := func(dialog communication.Dialog) *Manager {
+		return NewManager(currentProposal, generateSessionID, NewStorageMemory(), mockBalanceTrackerFactory, &terminationSenderFake{})
+	}
+	handler := NewDialogHandler(managerFactory, nil, promiseLoaderFake{}, identity.FromAddress("provider"), nil, time.Minute)
+
+	watcherOf := func(dialog *dialogFake) *idleWatcher {
+		assert.NoError(t, handler.Handle(dialog))
+		for _, responder := range dialog.responders {
+			if consumer, ok := responder.(*createConsumer); ok {
+				return consumer.idleWatcher
+			}
+		}
+		t.Fatal("session create consumer not found")
+		return nil
+	}
+
+	assert.NotNil(t, watcherOf(&dialogFake{protocol: communication.NewProtocol(communication.CapabilitySessionKeepalive)}))
+	// legacy consumers do not send keepalives and there is no data plane activity to watch
+	assert.Nil(t, watcherOf(&dialogFake{protocol: communication.LegacyProtocol()}))
+}
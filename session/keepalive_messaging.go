@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/communication"
+)
+
+const endpointSessionKeepalive = communication.MessageEndpoint("session-keepalive")
+
+// KeepaliveInterval defines how often consumer tells provider that it is still using the session
+const KeepaliveInterval = time.Minute
+
+// KeepaliveMessage structure represents message from service consumer telling that session is still in use
+type KeepaliveMessage struct {
+	SessionID ID `json:"session_id"`
+}
+
+// SendKeepalive tells provider over the dialog that the given session is still in use
+func SendKeepalive(sender communication.Sender, sessionID ID) error {
+	return sender.Send(&keepaliveProducer{
+		message: KeepaliveMessage{SessionID: sessionID},
+	})
+}
+
+// NewKeepaliveConsumer returns consumer of keepalive messages calling back on every received message
+func NewKeepaliveConsumer(callback func(message KeepaliveMessage)) communication.MessageConsumer {
+	return &keepaliveConsumer{callback: callback}
+}
+
+type keepaliveConsumer struct {
+	callback func(message KeepaliveMessage)
+}
+
+// GetMessageEndpoint returns endpoint where to receive messages
+func (consumer *keepaliveConsumer) GetMessageEndpoint() communication.MessageEndpoint {
+	return endpointSessionKeepalive
+}
+
+// NewMessage creates struct where message from endpoint will be serialized
+func (consumer *keepaliveConsumer) NewMessage() (messagePtr interface{}) {
+	return &KeepaliveMessage{}
+}
+
+// Consume handles messages from endpoint
+func (consumer *keepaliveConsumer) Consume(messagePtr interface{}) error {
+	consumer.callback(*messagePtr.(*KeepaliveMessage))
+	return nil
+}
+
+type keepaliveProducer struct {
+	message KeepaliveMessage
+}
+
+// GetMessageEndpoint returns endpoint where to send messages
+func (producer *keepaliveProducer) GetMessageEndpoint() communication.MessageEndpoint {
+	return endpointSessionKeepalive
+}
+
+// Produce creates message which will be serialized to endpoint
+func (producer *keepaliveProducer) Produce() (messagePtr interface{}) {
+	return &producer.message
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"sync"
+	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/identity"
@@ -34,6 +35,10 @@ var (
 	ErrorSessionNotExists = errors.New("session does not exists")
 	// ErrorWrongSessionOwner returned when consumer tries to destroy session that does not belongs to him
 	ErrorWrongSessionOwner = errors.New("wrong session owner")
+	// ErrorDraining returned when consumer tries to create session while service is being drained
+	ErrorDraining = errors.New("service is draining")
+	// ErrorPaymentsNotSupported returned when consumer is not able to pay for the session the way provider requires
+	ErrorPaymentsNotSupported = errors.New("consumer does not support payments")
 )
 
 const managerLogPrefix = "[session-manager] "
@@ -76,12 +81,16 @@ func NewManager(
 	idGenerator IDGenerator,
 	sessionStorage Storage,
 	balanceTrackerFactory BalanceTrackerFactory,
+	terminationSender TerminationSender,
 ) *Manager {
 	return &Manager{
 		currentProposal:       currentProposal,
 		generateID:            idGenerator,
 		sessionStorage:        sessionStorage,
 		balanceTrackerFactory: balanceTrackerFactory,
+		terminationSender:     terminationSender,
+		sessions:              make(map[ID]Session),
+		keepalives:            make(map[ID]time.Time),
 
 		creationLock: sync.Mutex{},
 	}
@@ -93,8 +102,14 @@ type Manager struct {
 	generateID            IDGenerator
 	sessionStorage        Storage
 	balanceTrackerFactory BalanceTrackerFactory
+	terminationSender     TerminationSender
 
-	creationLock sync.Mutex
+	// sessions created by the manager, which are not destroyed yet
+	sessions        map[ID]Session
+	keepalives      map[ID]time.Time
+	sessionsCreated int
+	draining        bool
+	creationLock    sync.Mutex
 }
 
 // Create creates session instance. Multiple sessions per peerID is possible in case different services are used
@@ -107,6 +122,11 @@ func (manager *Manager) Create(consumerID identity.Identity, issuerID identity.I
 		return
 	}
 
+	if manager.draining {
+		err = ErrorDraining
+		return
+	}
+
 	sessionInstance.ID, err = manager.generateID()
 	if err != nil {
 		return
@@ -129,14 +149,17 @@ func (manager *Manager) Create(consumerID identity.Identity, issuerID identity.I
 		err := balanceTracker.Start()
 		if err != nil {
 			log.Error(managerLogPrefix, "balance tracker error: ", err)
-			destroyErr := manager.Destroy(consumerID, string(sessionInstance.ID))
-			if destroyErr != nil {
-				log.Error(managerLogPrefix, "session cleanup failed: ", err)
+			terminateErr := manager.Terminate(sessionInstance.ID, TerminationReasonPaymentFailed)
+			if terminateErr != nil {
+				log.Error(managerLogPrefix, "session cleanup failed: ", terminateErr)
 			}
 		}
 	}()
 
 	manager.sessionStorage.Add(sessionInstance)
+	manager.sessions[sessionInstance.ID] = sessionInstance
+	manager.keepalives[sessionInstance.ID] = time.Now()
+	manager.sessionsCreated++
 	return sessionInstance, nil
 }
 
@@ -155,8 +178,103 @@ func (manager *Manager) Destroy(consumerID identity.Identity, sessionID string)
 		return ErrorWrongSessionOwner
 	}
 
-	manager.sessionStorage.Remove(ID(sessionID))
-	close(sessionInstance.Done)
+	manager.remove(sessionInstance)
+	return nil
+}
 
+// KeepAlive records that consumer still uses the session created by the manager
+func (manager *Manager) KeepAlive(sessionID ID) error {
+	manager.creationLock.Lock()
+	defer manager.creationLock.Unlock()
+
+	if _, created := manager.sessions[sessionID]; !created {
+		return ErrorSessionNotExists
+	}
+	manager.keepalives[sessionID] = time.Now()
 	return nil
 }
+
+// lastKeepalive returns time when consumer was last seen using the session, the session creation is counted too
+func (manager *Manager) lastKeepalive(sessionID ID) time.Time {
+	manager.creationLock.Lock()
+	defer manager.creationLock.Unlock()
+
+	return manager.keepalives[sessionID]
+}
+
+// Terminate destroys session created by the manager on behalf of provider and notifies consumer about the reason
+func (manager *Manager) Terminate(sessionID ID, reason TerminationReason) error {
+	manager.creationLock.Lock()
+	_, created := manager.sessions[sessionID]
+	sessionInstance, found := manager.sessionStorage.Find(sessionID)
+	if !created || !found {
+		manager.creationLock.Unlock()
+		return ErrorSessionNotExists
+	}
+	manager.remove(sessionInstance)
+	manager.creationLock.Unlock()
+
+	log.Infof("%ssession %s terminated: %s", managerLogPrefix, sessionID, reason)
+	return manager.terminationSender.SendTermination(sessionID, reason)
+}
+
+// TerminateAll terminates all sessions created by the manager
+func (manager *Manager) TerminateAll(reason TerminationReason) {
+	for _, sessionInstance := range manager.activeSessions() {
+		err := manager.Terminate(sessionInstance.ID, reason)
+		if err != nil && err != ErrorSessionNotExists {
+			log.Warn(managerLogPrefix, "failed to notify consumer about terminated session: ", err)
+		}
+	}
+}
+
+// NotifyDraining stops creating new sessions and notifies consumers of the sessions created by the manager,
+// that they will be terminated after the deadline. Notified sessions are returned.
+func (manager *Manager) NotifyDraining(deadline time.Time) []Session {
+	manager.creationLock.Lock()
+	manager.draining = true
+	manager.creationLock.Unlock()
+
+	sessions := manager.activeSessions()
+	for _, sessionInstance := range sessions {
+		err := manager.terminationSender.SendDraining(sessionInstance.ID, deadline)
+		if err != nil {
+			log.Warn(managerLogPrefix, "failed to notify consumer about draining session: ", err)
+		}
+	}
+	return sessions
+}
+
+func (manager *Manager) activeSessions() []Session {
+	manager.creationLock.Lock()
+	defer manager.creationLock.Unlock()
+
+	sessions := make([]Session, 0, len(manager.sessions))
+	for _, sessionInstance := range manager.sessions {
+		sessions = append(sessions, sessionInstance)
+	}
+	return sessions
+}
+
+// finished tells whether all sessions created by the manager are destroyed already
+func (manager *Manager) finished() bool {
+	manager.creationLock.Lock()
+	defer manager.creationLock.Unlock()
+
+	return manager.sessionsCreated > 0 && len(manager.sessions) == 0
+}
+
+// unused tells whether the manager has not created any session yet
+func (manager *Manager) unused() bool {
+	manager.creationLock.Lock()
+	defer manager.creationLock.Unlock()
+
+	return manager.sessionsCreated == 0
+}
+
+func (manager *Manager) remove(sessionInstance Session) {
+	manager.sessionStorage.Remove(sessionInstance.ID)
+	delete(manager.sessions, sessionInstance.ID)
+	delete(manager.keepalives, sessionInstance.ID)
+	close(sessionInstance.Done)
+}
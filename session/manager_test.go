@@ -18,7 +18,10 @@
 package session
 
 import (
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/market"
@@ -59,11 +62,43 @@ func mockBalanceTrackerFactory(consumer, provider, issuer identity.Identity) (Ba
 	return &mockBalanceTracker{}, nil
 }
 
+type terminationSenderFake struct {
+	sync.Mutex
+	sent    []TerminateMessage
+	drained []DrainingMessage
+}
+
+func (sender *terminationSenderFake) SendTermination(sessionID ID, reason TerminationReason) error {
+	sender.Lock()
+	defer sender.Unlock()
+	sender.sent = append(sender.sent, TerminateMessage{SessionID: sessionID, Reason: reason})
+	return nil
+}
+
+func (sender *terminationSenderFake) SendDraining(sessionID ID, deadline time.Time) error {
+	sender.Lock()
+	defer sender.Unlock()
+	sender.drained = append(sender.drained, DrainingMessage{SessionID: sessionID, Deadline: deadline})
+	return nil
+}
+
+func (sender *terminationSenderFake) drainingMessages() []DrainingMessage {
+	sender.Lock()
+	defer sender.Unlock()
+	return sender.drained
+}
+
+func (sender *terminationSenderFake) messages() []TerminateMessage {
+	sender.Lock()
+	defer sender.Unlock()
+	return sender.sent
+}
+
 func TestManager_Create_StoresSession(t *testing.T) {
 	expectedResult := expectedSession
 
 	sessionStore := NewStorageMemory()
-	manager := NewManager(currentProposal, generateSessionID, sessionStore, mockBalanceTrackerFactory)
+	manager := NewManager(currentProposal, generateSessionID, sessionStore, mockBalanceTrackerFactory, &terminationSenderFake{})
 
 	sessionInstance, err := manager.Create(consumerID, consumerID, currentProposalID)
 	expectedResult.Done = sessionInstance.Done
@@ -73,9 +108,76 @@ func TestManager_Create_StoresSession(t *testing.T) {
 
 func TestManager_Create_RejectsUnknownProposal(t *testing.T) {
 	sessionStore := NewStorageMemory()
-	manager := NewManager(currentProposal, generateSessionID, sessionStore, mockBalanceTrackerFactory)
+	manager := NewManager(currentProposal, generateSessionID, sessionStore, mockBalanceTrackerFactory, &terminationSenderFake{})
 
 	sessionInstance, err := manager.Create(consumerID, consumerID, 69)
 	assert.Exactly(t, err, ErrorInvalidProposal)
 	assert.Exactly(t, Session{}, sessionInstance)
 }
+
+func TestManager_Terminate_NotifiesConsumer(t *testing.T) {
+	sessionStore := NewStorageMemory()
+	sender := &terminationSenderFake{}
+	manager := NewManager(currentProposal, generateSessionID, sessionStore, mockBalanceTrackerFactory, sender)
+
+	sessionInstance, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+
+	err = manager.Terminate(sessionInstance.ID, TerminationReasonPaymentFailed)
+	assert.NoError(t, err)
+	assert.Equal(t, []TerminateMessage{{SessionID: expectedID, Reason: TerminationReasonPaymentFailed}}, sender.messages())
+	_, found := sessionStore.Find(expectedID)
+	assert.False(t, found)
+	<-sessionInstance.Done
+
+	assert.Equal(t, ErrorSessionNotExists, manager.Terminate(sessionInstance.ID, TerminationReasonPaymentFailed))
+	assert.Equal(t, ErrorSessionNotExists, manager.Destroy(consumerID, string(sessionInstance.ID)))
+}
+
+func TestManager_Terminate_IgnoresSessionsOfOtherManagers(t *testing.T) {
+	sessionStore := NewStorageMemory()
+	sender := &terminationSenderFake{}
+	manager := NewManager(currentProposal, generateSessionID, sessionStore, mockBalanceTrackerFactory, &terminationSenderFake{})
+	otherManager := NewManager(currentProposal, generateSessionID, sessionStore, mockBalanceTrackerFactory, sender)
+
+	_, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+
+	otherManager.TerminateAll(TerminationReasonServiceStopped)
+	assert.Empty(t, sender.messages())
+	assert.Equal(t, ErrorSessionNotExists, otherManager.Terminate(expectedID, TerminationReasonServiceStopped))
+	_, found := sessionStore.Find(expectedID)
+	assert.True(t, found)
+}
+
+func TestManager_Create_TerminatesSessionWhenPaymentFails(t *testing.T) {
+	sender := &terminationSenderFake{}
+	failingBalanceTrackerFactory := func(consumer, provider, issuer identity.Identity) (BalanceTracker, error) {
+		return &mockBalanceTracker{errorToReturn: errors.New("promise not received")}, nil
+	}
+	manager := NewManager(currentProposal, generateSessionID, NewStorageMemory(), failingBalanceTrackerFactory, sender)
+
+	_, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+
+	for i := 0; i < 100 && len(sender.messages()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, []TerminateMessage{{SessionID: expectedID, Reason: TerminationReasonPaymentFailed}}, sender.messages())
+}
+
+func TestManager_NotifyDraining_RejectsNewSessions(t *testing.T) {
+	sender := &terminationSenderFake{}
+	manager := NewManager(currentProposal, generateSessionID, NewStorageMemory(), mockBalanceTrackerFactory, sender)
+
+	sessionInstance, err := manager.Create(consumerID, consumerID, currentProposalID)
+	assert.NoError(t, err)
+
+	deadline := time.Now().Add(time.Minute)
+	sessions := manager.NotifyDraining(deadline)
+	assert.Equal(t, []Session{sessionInstance}, sessions)
+	assert.Equal(t, []DrainingMessage{{SessionID: expectedID, Deadline: deadline}}, sender.drainingMessages())
+
+	_, err = manager.Create(consumerID, consumerID, currentProposalID)
+	assert.Equal(t, ErrorDraining, err)
+}
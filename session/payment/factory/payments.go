@@ -35,39 +35,48 @@ import (
 )
 
 // PaymentIssuerFactoryFunc returns a factory for payment issuer. It will be noop if the experimental payment flag is not set
-func PaymentIssuerFactoryFunc(nodeOptions node.Options, signerFactory identity.SignerFactory) func(
+func PaymentIssuerFactoryFunc(nodeOptions node.Options, signerFactory identity.SignerFactory, publisher payment.Publisher) func(
 	initialState promise.PaymentInfo,
 	paymentDefinition dto.PaymentPerTime,
 	messageChan chan balance.Message,
 	dialog communication.Dialog,
-	consumer, provider identity.Identity) (connection.PaymentIssuer, error) {
+	consumer, provider identity.Identity,
+	sessionID session.ID) (connection.PaymentIssuer, error) {
 	if !nodeOptions.ExperimentPayments {
 		return noopPaymentIssuerFactory
 	}
-	return paymentIssuerFactory(signerFactory)
+	return paymentIssuerFactory(signerFactory, publisher)
 }
 
 func noopPaymentIssuerFactory(initialState promise.PaymentInfo,
 	paymentDefinition dto.PaymentPerTime,
 	messageChan chan balance.Message,
 	dialog communication.Dialog,
-	consumer, provider identity.Identity) (connection.PaymentIssuer, error) {
+	consumer, provider identity.Identity,
+	sessionID session.ID) (connection.PaymentIssuer, error) {
 	return noop.NewSessionBalance(), nil
 
 }
 
-func paymentIssuerFactory(signerFactory identity.SignerFactory) func(
+func paymentIssuerFactory(signerFactory identity.SignerFactory, publisher payment.Publisher) func(
 	initialState promise.PaymentInfo,
 	paymentDefinition dto.PaymentPerTime,
 	messageChan chan balance.Message,
 	dialog communication.Dialog,
-	consumer, provider identity.Identity) (connection.PaymentIssuer, error) {
+	consumer, provider identity.Identity,
+	sessionID session.ID) (connection.PaymentIssuer, error) {
 	return func(
 		initialState promise.PaymentInfo,
 		paymentDefinition dto.PaymentPerTime,
 		messageChan chan balance.Message,
 		dialog communication.Dialog,
-		consumer, provider identity.Identity) (connection.PaymentIssuer, error) {
+		consumer, provider identity.Identity,
+		sessionID session.ID) (connection.PaymentIssuer, error) {
+
+		// providers not supporting promise payments do not expect any promises
+		if !dialog.PeerProtocol().Supports(communication.CapabilityPromisePayments) {
+			return noop.NewSessionBalance(), nil
+		}
 
 		bl := balance.NewListener(messageChan)
 		ps := promise.NewSender(dialog)
@@ -79,7 +88,7 @@ func paymentIssuerFactory(signerFactory identity.SignerFactory) func(
 		amountCalc := session.AmountCalc{PaymentDef: paymentDefinition}
 
 		balanceTracker := balance.NewBalanceTracker(&timeTracker, amountCalc, initialState.FreeCredit)
-		payments := payment.NewSessionPayments(messageChan, ps, tracker, balanceTracker)
+		payments := payment.NewSessionPayments(messageChan, ps, tracker, balanceTracker, publisher, sessionID)
 		err := dialog.Receive(bl.GetConsumer())
 		return payments, errors.Wrap(err, "fail to receive from consumer")
 	}
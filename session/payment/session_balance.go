@@ -75,6 +75,7 @@ type SessionBalance struct {
 	promiseWaitTimeout time.Duration
 	promiseValidator   PromiseValidator
 	promiseStorage     PromiseStorage
+	serviceType        string
 	issuerID           identity.Identity
 	consumerID         identity.Identity
 	receiverID         identity.Identity
@@ -91,6 +92,7 @@ func NewSessionBalance(
 	promiseWaitTimeout time.Duration,
 	promiseValidator PromiseValidator,
 	promiseStorage PromiseStorage,
+	serviceType string,
 	consumerID, receiverID, issuerID identity.Identity) *SessionBalance {
 	return &SessionBalance{
 		stop:               make(chan struct{}),
@@ -101,6 +103,7 @@ func NewSessionBalance(
 		promiseWaitTimeout: promiseWaitTimeout,
 		promiseValidator:   promiseValidator,
 		promiseStorage:     promiseStorage,
+		serviceType:        serviceType,
 		consumerID:         consumerID,
 		receiverID:         receiverID,
 		issuerID:           issuerID,
@@ -171,12 +174,8 @@ func (sb *SessionBalance) sendBalance() error {
 		return fmt.Errorf("unconsumed amount is 0, while balance is %v", currentBalance)
 	}
 
-	err = sb.promiseStorage.Update(sb.issuerID, promise.StoredPromise{
-		SequenceID:       p.SequenceID,
-		UnconsumedAmount: currentBalance,
-		Message:          p.Message,
-		AddedAt:          p.AddedAt,
-	})
+	p.UnconsumedAmount = currentBalance
+	err = sb.promiseStorage.Update(sb.issuerID, p)
 	if err != nil {
 		return err
 	}
@@ -205,8 +204,9 @@ func (sb *SessionBalance) storePromiseAndUpdateBalance(pm promise.Message) error
 	amount := sb.calculateAmountToAdd(pm, p)
 	sb.balanceTracker.Add(amount)
 
-	p.Message = &pm
+	p.Extend(&pm, time.Now())
 	p.UnconsumedAmount += amount
+	p.ServiceType = sb.serviceType
 	err = sb.promiseStorage.Update(sb.issuerID, p)
 	return err
 }
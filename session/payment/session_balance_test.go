@@ -55,6 +55,7 @@ func NewMockSessionBalance(mpv *MockPromiseValidator, mps *MockPromiseStorage, m
 		time.Millisecond*1,
 		mpv,
 		mps,
+		"openvpn",
 		consumer,
 		receiver,
 		issuer,
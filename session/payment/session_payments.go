@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/session/balance"
 	"github.com/mysteriumnetwork/node/session/promise"
 	"github.com/mysteriumnetwork/payments/promises"
@@ -38,6 +39,21 @@ type PromiseTracker interface {
 	ExtendPromise(amountToAdd uint64) (promises.IssuedPromise, error)
 }
 
+// PromiseIssuedTopic is the topic for the events of promises issued by consumer
+const PromiseIssuedTopic = "PromiseIssued"
+
+// PromiseIssuedEvent represents the promise issued by consumer during the session
+type PromiseIssuedEvent struct {
+	SessionID session.ID
+	// Amount is the amount the promise was extended by
+	Amount uint64
+}
+
+// Publisher is responsible for publishing given events
+type Publisher interface {
+	Publish(topic string, args ...interface{})
+}
+
 // SessionPayments orchestrates the ping pong of balance received from provider -> promise sent to provider flow
 type SessionPayments struct {
 	stop              chan struct{}
@@ -45,16 +61,26 @@ type SessionPayments struct {
 	peerPromiseSender PeerPromiseSender
 	promiseTracker    PromiseTracker
 	balanceTracker    BalanceTracker
+	publisher         Publisher
+	sessionID         session.ID
 }
 
 // NewSessionPayments returns a new instance of consumer payment orchestrator
-func NewSessionPayments(balanceChan chan balance.Message, peerPromiseSender PeerPromiseSender, promiseTracker PromiseTracker, balanceTracker BalanceTracker) *SessionPayments {
+func NewSessionPayments(
+	balanceChan chan balance.Message,
+	peerPromiseSender PeerPromiseSender,
+	promiseTracker PromiseTracker,
+	balanceTracker BalanceTracker,
+	publisher Publisher,
+	sessionID session.ID) *SessionPayments {
 	return &SessionPayments{
 		stop:              make(chan struct{}),
 		balanceChan:       balanceChan,
 		peerPromiseSender: peerPromiseSender,
 		promiseTracker:    promiseTracker,
 		balanceTracker:    balanceTracker,
+		publisher:         publisher,
+		sessionID:         sessionID,
 	}
 }
 
@@ -112,6 +138,12 @@ func (cpo *SessionPayments) issuePromise(balance balance.Message) error {
 		return err
 	}
 	cpo.balanceTracker.Add(amountToExtend)
+	if amountToExtend > 0 {
+		cpo.publisher.Publish(PromiseIssuedTopic, PromiseIssuedEvent{
+			SessionID: cpo.sessionID,
+			Amount:    amountToExtend,
+		})
+	}
 	return nil
 }
 
@@ -24,6 +24,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/session/balance"
 	"github.com/mysteriumnetwork/node/session/promise"
 	"github.com/mysteriumnetwork/payments/promises"
@@ -70,12 +71,28 @@ func newPromiseSender() *MockPeerPromiseSender {
 	return &MockPeerPromiseSender{chanToWriteTo: make(chan promise.Message, 1)}
 }
 
+type MockPublisher struct {
+	published chan PromiseIssuedEvent
+}
+
+func (mp *MockPublisher) Publish(topic string, args ...interface{}) {
+	if topic == PromiseIssuedTopic {
+		mp.published <- args[0].(PromiseIssuedEvent)
+	}
+}
+
+func newPublisher() *MockPublisher {
+	return &MockPublisher{published: make(chan PromiseIssuedEvent, 1)}
+}
+
 func NewTestSessionPayments(bm chan balance.Message, ps PeerPromiseSender, pt PromiseTracker, bt BalanceTracker) *SessionPayments {
 	return NewSessionPayments(
 		bm,
 		ps,
 		pt,
 		bt,
+		newPublisher(),
+		session.ID("session"),
 	)
 }
 
@@ -102,6 +119,22 @@ func Test_SessionPayments_SendsPromiseOnBalance(t *testing.T) {
 	}
 }
 
+func Test_SessionPayments_PublishesIssuedPromise(t *testing.T) {
+	balanceChannel := make(chan balance.Message, 1)
+	publisher := newPublisher()
+	cpo := NewSessionPayments(balanceChannel, newPromiseSender(), promiseTracker, balanceTracker, publisher, session.ID("session"))
+	go cpo.Start()
+	defer cpo.Stop()
+
+	balanceChannel <- balance.Message{Balance: 0, SequenceID: 1}
+	select {
+	case event := <-publisher.published:
+		assert.Equal(t, PromiseIssuedEvent{SessionID: session.ID("session"), Amount: 100}, event)
+	case <-time.After(time.Second):
+		assert.Fail(t, "promise issued event expected")
+	}
+}
+
 func Test_SessionPayments_ReportsIssuingErrors(t *testing.T) {
 	balanceChannel := make(chan balance.Message, 1)
 	customTracker := *promiseTracker
@@ -32,9 +32,14 @@ type LocalIssuer struct {
 // NewLocalIssuer creates local issuer based on provided identity signer
 func NewLocalIssuer(signer identity.Signer) *LocalIssuer {
 	return &LocalIssuer{
-		paymentsSigner: paymentsSignerAdapter{
-			identitySigner: signer,
-		},
+		paymentsSigner: NewPaymentsSigner(signer),
+	}
+}
+
+// NewPaymentsSigner adapts identity.Signer to be usable for signing promises in payments package
+func NewPaymentsSigner(signer identity.Signer) payments_identity.Signer {
+	return paymentsSignerAdapter{
+		identitySigner: signer,
 	}
 }
 
@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package settlement
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/payments/contracts/abigen"
+	"github.com/mysteriumnetwork/payments/promises"
+)
+
+// TransactorFactory returns options for sending transactions on behalf of the given identity
+type TransactorFactory func(id identity.Identity) *bind.TransactOpts
+
+// TxSigner signs transactions with the account keys
+type TxSigner interface {
+	SignTx(a accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// NewKeystoreTransactorFactory returns transactor factory which signs transactions with the unlocked identity keys
+func NewKeystoreTransactorFactory(txSigner TxSigner) TransactorFactory {
+	return func(id identity.Identity) *bind.TransactOpts {
+		account := accounts.Account{Address: common.HexToAddress(id.Address)}
+		return &bind.TransactOpts{
+			From: account.Address,
+			Signer: func(signer types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+				if address != account.Address {
+					return nil, errors.New("not authorized to sign this account")
+				}
+				return txSigner.SignTx(account, tx, nil)
+			},
+		}
+	}
+}
+
+type contractClearer struct {
+	address           common.Address
+	contract          *abigen.IdentityPromises
+	transactorFactory TransactorFactory
+}
+
+// NewContractClearer returns promise clearer which uses payments contract on the blockchain
+func NewContractClearer(contractBackend bind.ContractBackend, paymentsAddress common.Address, transactorFactory TransactorFactory) (*contractClearer, error) {
+	contract, err := abigen.NewIdentityPromises(paymentsAddress, contractBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contractClearer{
+		address:           paymentsAddress,
+		contract:          contract,
+		transactorFactory: transactorFactory,
+	}, nil
+}
+
+// ClearReceivedPromise submits the promise to the contract, transaction is paid by the promise receiver
+func (cc *contractClearer) ClearReceivedPromise(promise *promises.ReceivedPromise) error {
+	transactor := cc.transactorFactory(identity.FromAddress(promise.Receiver.Hex()))
+	return promises.NewPromiseClearer(transactor, cc.contract, cc.address).ClearReceivedPromise(promise)
+}
+
+// LastClearedPromise returns the sequence of the last promise cleared between issuer and receiver
+func (cc *contractClearer) LastClearedPromise(issuer, receiver common.Address) (uint64, error) {
+	seq, err := cc.contract.ClearedPromises(&bind.CallOpts{Pending: false}, issuer, receiver)
+	if err != nil {
+		return 0, err
+	}
+	return seq.Uint64(), nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package settlement
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/mysteriumnetwork/node/session/promise/issuers"
+	"github.com/mysteriumnetwork/payments/mysttoken"
+	payments_promises "github.com/mysteriumnetwork/payments/promises"
+	"github.com/mysteriumnetwork/payments/registry"
+	"github.com/mysteriumnetwork/payments/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type identityHolder struct {
+	testIdentity
+}
+
+func (ih identityHolder) Sign(data ...[]byte) ([]byte, error) {
+	return issuers.NewPaymentsSigner(ih.signer).Sign(data...)
+}
+
+func (ih identityHolder) GetPublicKey() (ecdsa.PublicKey, error) {
+	return ih.signer.key.PublicKey, nil
+}
+
+var _ registry.IdentityHolder = identityHolder{}
+
+func Test_Settler_ClearsPromisesOnSimulatedBlockchain(t *testing.T) {
+	backend := test_utils.NewSimulatedBackend(test_utils.Deployer.Address, 10000000000)
+
+	mystErc20, err := mysttoken.DeployMystERC20(test_utils.Deployer.Transactor, 1000000, backend)
+	assert.NoError(t, err)
+
+	clearing, err := payments_promises.DeployPromiseClearer(test_utils.Deployer.Transactor, mystErc20.Address, 1000, backend)
+	assert.NoError(t, err)
+	backend.Commit()
+
+	_, err = mystErc20.Approve(clearing.Address, big.NewInt(3000))
+	assert.NoError(t, err)
+	backend.Commit()
+
+	issuer := newTestIdentity(t)
+	receiver := newTestIdentity(t)
+	err = clearing.RegisterIdentities(identityHolder{issuer}, identityHolder{receiver})
+	assert.NoError(t, err)
+	backend.Commit()
+
+	_, err = clearing.TopUp(common.HexToAddress(issuer.Address), big.NewInt(1000))
+	assert.NoError(t, err)
+	backend.Commit()
+
+	storage := &mockPromiseStorage{
+		promises: map[identity.Identity][]promise.StoredPromise{
+			issuer.Identity: {
+				issuePromise(t, issuer, receiver, 1, 100, now.Add(-time.Hour)),
+				issuePromise(t, issuer, receiver, 2, 50, now.Add(-time.Hour)),
+			},
+		},
+	}
+	transactorFactory := func(id identity.Identity) *bind.TransactOpts {
+		// receiver has no ether for gas in simulated chain, deployer pays for clearing
+		return test_utils.Deployer.Transactor
+	}
+	clearer, err := NewContractClearer(backend, clearing.Address, transactorFactory)
+	assert.NoError(t, err)
+
+	settler := newTestSettler(storage, clearer, signerFactory(receiver), 10)
+	assert.NoError(t, settler.Settle())
+	assert.False(t, storage.promises[issuer.Identity][0].Cleared)
+	assert.False(t, storage.promises[issuer.Identity][1].Cleared)
+
+	backend.Commit()
+	assert.NoError(t, settler.Settle())
+	assert.True(t, storage.promises[issuer.Identity][0].Cleared)
+	assert.True(t, storage.promises[issuer.Identity][1].Cleared)
+
+	balance, err := clearing.Balances(common.HexToAddress(receiver.Address))
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(150), balance)
+}
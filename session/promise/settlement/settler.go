@@ -0,0 +1,232 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package settlement
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/mysteriumnetwork/node/session/promise/issuers"
+	"github.com/mysteriumnetwork/payments/promises"
+)
+
+const settlerLogPrefix = "[promise-settler] "
+
+// PromiseStorage allows to fetch the promises received by provider and to mark them as cleared
+type PromiseStorage interface {
+	GetAllKnownIssuers() []identity.Identity
+	GetAllPromisesFromIssuer(issuerID identity.Identity) ([]promise.StoredPromise, error)
+	MarkCleared(issuerID identity.Identity, sequenceID uint64) error
+}
+
+// Clearer submits received promises to the payments contract and tells which of them are already cleared
+type Clearer interface {
+	ClearReceivedPromise(promise *promises.ReceivedPromise) error
+	LastClearedPromise(issuer, receiver common.Address) (uint64, error)
+}
+
+type pendingKey struct {
+	issuer     string
+	sequenceID uint64
+}
+
+// Settler periodically picks the uncleared promises and settles them against the payments contract.
+// Promises are marked as cleared only after the contract confirms them, failed ones are retried on the next round.
+type Settler struct {
+	storage             PromiseStorage
+	clearer             Clearer
+	signerFactory       identity.SignerFactory
+	batchSize           int
+	interval            time.Duration
+	idleTime            time.Duration
+	confirmationTimeout time.Duration
+	timeNow             func() time.Time
+
+	pending  map[pendingKey]time.Time
+	mu       sync.Mutex
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSettler returns a new instance of promise settler.
+// Promises are settled once they were not updated for idleTime, at most batchSize of them are submitted per round.
+// Submitted promises which are not confirmed within confirmationTimeout are submitted again.
+func NewSettler(
+	storage PromiseStorage,
+	clearer Clearer,
+	signerFactory identity.SignerFactory,
+	batchSize int,
+	interval, idleTime, confirmationTimeout time.Duration,
+) *Settler {
+	return &Settler{
+		storage:             storage,
+		clearer:             clearer,
+		signerFactory:       signerFactory,
+		batchSize:           batchSize,
+		interval:            interval,
+		idleTime:            idleTime,
+		confirmationTimeout: confirmationTimeout,
+		timeNow:             time.Now,
+		pending:             make(map[pendingKey]time.Time),
+		stop:                make(chan struct{}),
+	}
+}
+
+// Start starts periodical settlement of promises
+func (s *Settler) Start() {
+	go func() {
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(s.interval):
+				if err := s.Settle(); err != nil {
+					log.Warn(settlerLogPrefix, "Promise settlement failed, will retry later: ", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops periodical settlement of promises
+func (s *Settler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Settle marks the confirmed promises as cleared and submits a batch of the uncleared ones to the contract
+func (s *Settler) Settle() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submitted := 0
+	for _, issuer := range s.storage.GetAllKnownIssuers() {
+		storedPromises, err := s.storage.GetAllPromisesFromIssuer(issuer)
+		if err != nil {
+			return err
+		}
+		sortPromisesAsc(storedPromises)
+
+		// promises have to be cleared in order of sequence, so the failure blocks the later promises of the same receiver
+		blocked := make(map[string]bool)
+		lastCleared := make(map[string]uint64)
+		for _, sp := range storedPromises {
+			receiver := sp.Receiver.Address
+			if sp.Cleared || sp.Message == nil || blocked[receiver] {
+				continue
+			}
+			if !s.isIdle(sp) {
+				blocked[receiver] = true
+				continue
+			}
+
+			last, ok := lastCleared[receiver]
+			if !ok {
+				last, err = s.clearer.LastClearedPromise(common.HexToAddress(issuer.Address), common.HexToAddress(receiver))
+				if err != nil {
+					return err
+				}
+				lastCleared[receiver] = last
+			}
+
+			if sp.SequenceID <= last {
+				if err := s.markCleared(issuer, sp); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if s.isPending(issuer, sp) || submitted >= s.batchSize {
+				blocked[receiver] = true
+				continue
+			}
+
+			if err := s.submit(issuer, sp); err != nil {
+				log.Warn(settlerLogPrefix, "Failed to submit promise ", sp.SequenceID, " of issuer ", issuer.Address, ": ", err)
+				blocked[receiver] = true
+				continue
+			}
+			submitted++
+		}
+	}
+
+	return nil
+}
+
+func (s *Settler) isIdle(sp promise.StoredPromise) bool {
+	updatedAt := sp.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = sp.AddedAt
+	}
+	return s.timeNow().Sub(updatedAt) >= s.idleTime
+}
+
+func (s *Settler) isPending(issuer identity.Identity, sp promise.StoredPromise) bool {
+	submittedAt, ok := s.pending[pendingKey{issuer.Address, sp.SequenceID}]
+	return ok && s.timeNow().Sub(submittedAt) < s.confirmationTimeout
+}
+
+func (s *Settler) submit(issuer identity.Identity, sp promise.StoredPromise) error {
+	issuedPromise := promises.IssuedPromise{
+		Promise: promises.Promise{
+			Extra: promise.ExtraData{
+				ConsumerAddress: common.HexToAddress(sp.ConsumerID.Address),
+			},
+			Receiver: common.HexToAddress(sp.Receiver.Address),
+			SeqNo:    sp.SequenceID,
+			Amount:   sp.Message.Amount,
+		},
+		IssuerSignature: common.FromHex(sp.Message.Signature),
+	}
+
+	receiverSigner := issuers.NewPaymentsSigner(s.signerFactory(sp.Receiver))
+	receivedPromise, err := promises.SignByReceiver(&issuedPromise, receiverSigner)
+	if err != nil {
+		return err
+	}
+
+	if err := s.clearer.ClearReceivedPromise(receivedPromise); err != nil {
+		return err
+	}
+
+	log.Info(settlerLogPrefix, "Submitted promise ", sp.SequenceID, " of issuer ", issuer.Address, " for clearing")
+	s.pending[pendingKey{issuer.Address, sp.SequenceID}] = s.timeNow()
+	return nil
+}
+
+func (s *Settler) markCleared(issuer identity.Identity, sp promise.StoredPromise) error {
+	if err := s.storage.MarkCleared(issuer, sp.SequenceID); err != nil {
+		return err
+	}
+
+	log.Info(settlerLogPrefix, "Promise ", sp.SequenceID, " of issuer ", issuer.Address, " cleared")
+	delete(s.pending, pendingKey{issuer.Address, sp.SequenceID})
+	return nil
+}
+
+func sortPromisesAsc(storedPromises []promise.StoredPromise) {
+	sort.Slice(storedPromises, func(i, j int) bool {
+		return storedPromises[i].SequenceID < storedPromises[j].SequenceID
+	})
+}
@@ -0,0 +1,280 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package settlement
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session/promise"
+	"github.com/mysteriumnetwork/node/session/promise/issuers"
+	"github.com/mysteriumnetwork/payments/promises"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	now      = time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	idleTime = time.Minute
+)
+
+type keySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (ks keySigner) Sign(message []byte) (identity.Signature, error) {
+	signature, err := crypto.Sign(crypto.Keccak256(message), ks.key)
+	if err != nil {
+		return identity.Signature{}, err
+	}
+	return identity.SignatureBytes(signature), nil
+}
+
+type testIdentity struct {
+	identity.Identity
+	signer keySigner
+}
+
+func newTestIdentity(t *testing.T) testIdentity {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	return testIdentity{
+		Identity: identity.FromAddress(crypto.PubkeyToAddress(key.PublicKey).Hex()),
+		signer:   keySigner{key},
+	}
+}
+
+func signerFactory(ids ...testIdentity) identity.SignerFactory {
+	return func(id identity.Identity) identity.Signer {
+		for _, i := range ids {
+			if i.Identity == id {
+				return i.signer
+			}
+		}
+		return nil
+	}
+}
+
+func issuePromise(t *testing.T, issuer, receiver testIdentity, seq, amount uint64, updatedAt time.Time) promise.StoredPromise {
+	issued, err := issuers.NewLocalIssuer(issuer.signer).Issue(promises.Promise{
+		Extra: promise.ExtraData{
+			ConsumerAddress: common.HexToAddress(issuer.Address),
+		},
+		Receiver: common.HexToAddress(receiver.Address),
+		SeqNo:    seq,
+		Amount:   amount,
+	})
+	assert.NoError(t, err)
+
+	return promise.StoredPromise{
+		SequenceID: seq,
+		Message: &promise.Message{
+			Amount:     amount,
+			SequenceID: seq,
+			Signature:  common.ToHex(issued.IssuerSignature),
+		},
+		ConsumerID: issuer.Identity,
+		Receiver:   receiver.Identity,
+		UpdatedAt:  updatedAt,
+	}
+}
+
+type mockPromiseStorage struct {
+	promises map[identity.Identity][]promise.StoredPromise
+}
+
+func (mps *mockPromiseStorage) GetAllKnownIssuers() []identity.Identity {
+	res := make([]identity.Identity, 0)
+	for k := range mps.promises {
+		res = append(res, k)
+	}
+	return res
+}
+
+func (mps *mockPromiseStorage) GetAllPromisesFromIssuer(issuerID identity.Identity) ([]promise.StoredPromise, error) {
+	res := make([]promise.StoredPromise, len(mps.promises[issuerID]))
+	copy(res, mps.promises[issuerID])
+	return res, nil
+}
+
+func (mps *mockPromiseStorage) MarkCleared(issuerID identity.Identity, sequenceID uint64) error {
+	for i := range mps.promises[issuerID] {
+		if mps.promises[issuerID][i].SequenceID == sequenceID {
+			mps.promises[issuerID][i].Cleared = true
+			return nil
+		}
+	}
+	return promise.ErrPromiseNotFound
+}
+
+type mockClearer struct {
+	submitted   []*promises.ReceivedPromise
+	lastCleared uint64
+	errToReturn error
+}
+
+func (mc *mockClearer) ClearReceivedPromise(promise *promises.ReceivedPromise) error {
+	if mc.errToReturn != nil {
+		return mc.errToReturn
+	}
+	mc.submitted = append(mc.submitted, promise)
+	return nil
+}
+
+func (mc *mockClearer) LastClearedPromise(issuer, receiver common.Address) (uint64, error) {
+	return mc.lastCleared, nil
+}
+
+func newTestSettler(storage PromiseStorage, clearer Clearer, signerFactory identity.SignerFactory, batchSize int) *Settler {
+	settler := NewSettler(storage, clearer, signerFactory, batchSize, time.Minute, idleTime, time.Hour)
+	settler.timeNow = func() time.Time {
+		return now
+	}
+	return settler
+}
+
+func Test_Settler_SubmitsIdlePromisesInOrder(t *testing.T) {
+	issuer := newTestIdentity(t)
+	receiver := newTestIdentity(t)
+	storage := &mockPromiseStorage{
+		promises: map[identity.Identity][]promise.StoredPromise{
+			issuer.Identity: {
+				issuePromise(t, issuer, receiver, 2, 50, now.Add(-time.Hour)),
+				issuePromise(t, issuer, receiver, 1, 100, now.Add(-time.Hour)),
+				{SequenceID: 3, ConsumerID: issuer.Identity, Receiver: receiver.Identity},
+			},
+		},
+	}
+	clearer := &mockClearer{}
+
+	settler := newTestSettler(storage, clearer, signerFactory(receiver), 10)
+	assert.NoError(t, settler.Settle())
+
+	assert.Len(t, clearer.submitted, 2)
+	assert.Equal(t, uint64(1), clearer.submitted[0].SeqNo)
+	assert.Equal(t, uint64(100), clearer.submitted[0].Amount)
+	assert.Equal(t, uint64(2), clearer.submitted[1].SeqNo)
+
+	payer, err := clearer.submitted[0].IssuerAddress()
+	assert.NoError(t, err)
+	assert.Equal(t, common.HexToAddress(issuer.Address), payer)
+	assert.Len(t, clearer.submitted[0].ReceiverSignature, 65)
+
+	// submitted promises are not resubmitted until confirmation times out
+	assert.NoError(t, settler.Settle())
+	assert.Len(t, clearer.submitted, 2)
+
+	settler.timeNow = func() time.Time {
+		return now.Add(2 * time.Hour)
+	}
+	assert.NoError(t, settler.Settle())
+	assert.Len(t, clearer.submitted, 4)
+}
+
+func Test_Settler_MarksConfirmedPromisesCleared(t *testing.T) {
+	issuer := newTestIdentity(t)
+	receiver := newTestIdentity(t)
+	storage := &mockPromiseStorage{
+		promises: map[identity.Identity][]promise.StoredPromise{
+			issuer.Identity: {
+				issuePromise(t, issuer, receiver, 1, 100, now.Add(-time.Hour)),
+				issuePromise(t, issuer, receiver, 2, 50, now.Add(-time.Hour)),
+			},
+		},
+	}
+	clearer := &mockClearer{}
+
+	settler := newTestSettler(storage, clearer, signerFactory(receiver), 10)
+	assert.NoError(t, settler.Settle())
+	assert.False(t, storage.promises[issuer.Identity][0].Cleared)
+
+	clearer.lastCleared = 1
+	assert.NoError(t, settler.Settle())
+	assert.True(t, storage.promises[issuer.Identity][0].Cleared)
+	assert.False(t, storage.promises[issuer.Identity][1].Cleared)
+	assert.Equal(t, receiver.Identity, storage.promises[issuer.Identity][0].Receiver)
+	assert.Len(t, clearer.submitted, 2)
+}
+
+func Test_Settler_SkipsActivePromises(t *testing.T) {
+	issuer := newTestIdentity(t)
+	receiver := newTestIdentity(t)
+	storage := &mockPromiseStorage{
+		promises: map[identity.Identity][]promise.StoredPromise{
+			issuer.Identity: {
+				issuePromise(t, issuer, receiver, 1, 100, now.Add(-time.Second)),
+				issuePromise(t, issuer, receiver, 2, 50, now.Add(-time.Hour)),
+			},
+		},
+	}
+	clearer := &mockClearer{}
+
+	settler := newTestSettler(storage, clearer, signerFactory(receiver), 10)
+	assert.NoError(t, settler.Settle())
+	assert.Len(t, clearer.submitted, 0)
+}
+
+func Test_Settler_LimitsBatchSize(t *testing.T) {
+	issuer := newTestIdentity(t)
+	receiver := newTestIdentity(t)
+	storage := &mockPromiseStorage{
+		promises: map[identity.Identity][]promise.StoredPromise{
+			issuer.Identity: {
+				issuePromise(t, issuer, receiver, 1, 100, now.Add(-time.Hour)),
+				issuePromise(t, issuer, receiver, 2, 50, now.Add(-time.Hour)),
+				issuePromise(t, issuer, receiver, 3, 20, now.Add(-time.Hour)),
+			},
+		},
+	}
+	clearer := &mockClearer{}
+
+	settler := newTestSettler(storage, clearer, signerFactory(receiver), 2)
+	assert.NoError(t, settler.Settle())
+	assert.Len(t, clearer.submitted, 2)
+
+	clearer.lastCleared = 2
+	assert.NoError(t, settler.Settle())
+	assert.Len(t, clearer.submitted, 3)
+	assert.Equal(t, uint64(3), clearer.submitted[2].SeqNo)
+}
+
+func Test_Settler_RetriesFailedSubmissions(t *testing.T) {
+	issuer := newTestIdentity(t)
+	receiver := newTestIdentity(t)
+	storage := &mockPromiseStorage{
+		promises: map[identity.Identity][]promise.StoredPromise{
+			issuer.Identity: {
+				issuePromise(t, issuer, receiver, 1, 100, now.Add(-time.Hour)),
+				issuePromise(t, issuer, receiver, 2, 50, now.Add(-time.Hour)),
+			},
+		},
+	}
+	clearer := &mockClearer{errToReturn: errors.New("insufficient funds for gas")}
+
+	settler := newTestSettler(storage, clearer, signerFactory(receiver), 10)
+	assert.NoError(t, settler.Settle())
+	assert.Len(t, clearer.submitted, 0)
+
+	clearer.errToReturn = nil
+	assert.NoError(t, settler.Settle())
+	assert.Len(t, clearer.submitted, 2)
+}
@@ -28,6 +28,9 @@ import (
 	"github.com/mysteriumnetwork/node/identity"
 )
 
+// DayFormat is the format of the UTC dates the promised amounts are kept by
+const DayFormat = "2006-01-02"
+
 const promiseBucketPrefix = "stored-promise-"
 const firstPromiseID = uint64(1)
 const promiseLogPrefix = "[promise-storage] "
@@ -72,7 +75,19 @@ type StoredPromise struct {
 	UnconsumedAmount uint64
 	ConsumerID       identity.Identity
 	Receiver         identity.Identity
+	ServiceType      string
 	Cleared          bool
+	// AmountByDay holds the promised amount as it was at the end of each day the promise was extended on, keyed by UTC date
+	AmountByDay map[string]uint64
+}
+
+// Extend replaces the message of the promise with the extended one, remembering the promised amount of the day
+func (sp *StoredPromise) Extend(message *Message, at time.Time) {
+	if sp.AmountByDay == nil {
+		sp.AmountByDay = make(map[string]uint64)
+	}
+	sp.AmountByDay[at.UTC().Format(DayFormat)] = message.Amount
+	sp.Message = message
 }
 
 // GetNewSeqIDForIssuer returns a new sequenceID for the provided issuer.
@@ -128,6 +143,20 @@ func (s *Storage) Update(issuerID identity.Identity, sp StoredPromise) error {
 	return s.update(issuerID, sp)
 }
 
+// MarkCleared marks the stored promise as cleared, keeping the rest of it as currently stored
+func (s *Storage) MarkCleared(issuerID identity.Identity, sequenceID uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	sp, err := s.getPromiseByID(issuerID, sequenceID)
+	if err != nil {
+		return err
+	}
+
+	sp.Cleared = true
+	return s.update(issuerID, sp)
+}
+
 // GetLastPromise fetches the last promise for the provider
 func (s *Storage) GetLastPromise(issuerID identity.Identity) (StoredPromise, error) {
 	s.Lock()
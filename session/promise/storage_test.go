@@ -99,6 +99,33 @@ func Test_Storage_UpdateErrsOnNonExistingPromise(t *testing.T) {
 	assert.Equal(t, errNotFound, err)
 }
 
+func Test_Storage_MarkClearedKeepsStoredPromise(t *testing.T) {
+	ms := newMockStorage(nil)
+	s := NewStorage(ms)
+
+	err := s.Store(issuerID, StoredPromise{SequenceID: 1})
+	assert.Nil(t, err)
+
+	msg := &Message{Amount: 2}
+	err = s.Update(issuerID, StoredPromise{SequenceID: 1, Message: msg, UnconsumedAmount: 1})
+	assert.Nil(t, err)
+
+	err = s.MarkCleared(issuerID, 1)
+	assert.Nil(t, err)
+
+	promise, err := s.getPromiseByID(issuerID, 1)
+	assert.Nil(t, err)
+	assert.True(t, promise.Cleared)
+	assert.Equal(t, msg, promise.Message)
+	assert.Equal(t, uint64(1), promise.UnconsumedAmount)
+}
+
+func Test_Storage_MarkClearedErrsOnNonExistingPromise(t *testing.T) {
+	ms := newMockStorage(nil)
+	s := NewStorage(ms)
+	assert.Equal(t, errNotFound, s.MarkCleared(issuerID, 1))
+}
+
 func Test_Storage_Store(t *testing.T) {
 	ms := newMockStorage(nil)
 	s := NewStorage(ms)
@@ -553,6 +580,9 @@ func (ms *mockStorage) Update(bucket string, data interface{}) error {
 		for i := range ms.inMemStorage[bucket] {
 			if ms.inMemStorage[bucket][i].SequenceID == casted.SequenceID {
 				ms.inMemStorage[bucket][i].Message = casted.Message
+				ms.inMemStorage[bucket][i].UnconsumedAmount = casted.UnconsumedAmount
+				ms.inMemStorage[bucket][i].Cleared = casted.Cleared
+				ms.inMemStorage[bucket][i].AmountByDay = casted.AmountByDay
 				ms.inMemStorage[bucket][i].UpdatedAt = casted.UpdatedAt
 				break
 			}
@@ -45,6 +45,9 @@ func (storage *StorageMemory) Add(sessionInstance Session) {
 
 // Find returns underlying session instance
 func (storage *StorageMemory) Find(id ID) (Session, bool) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
 	sessionInstance, found := storage.sessionMap[id]
 	return sessionInstance, found
 }
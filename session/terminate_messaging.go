@@ -0,0 +1,161 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/communication"
+)
+
+const (
+	endpointSessionTerminate = communication.MessageEndpoint("session-terminate")
+	endpointSessionDraining  = communication.MessageEndpoint("session-draining")
+)
+
+// TerminationReason describes why provider has terminated the session
+type TerminationReason string
+
+const (
+	// TerminationReasonServiceStopped is sent when provider stops the service of the session
+	TerminationReasonServiceStopped = TerminationReason("service_stopped")
+	// TerminationReasonPaymentFailed is sent when consumer does not pay for the session
+	TerminationReasonPaymentFailed = TerminationReason("payment_failed")
+	// TerminationReasonIdle is sent when neither keepalives nor traffic of the consumer were seen for too long
+	TerminationReasonIdle = TerminationReason("idle")
+)
+
+// TerminateMessage structure represents message from service provider notifying consumer that session was terminated
+type TerminateMessage struct {
+	SessionID ID                `json:"session_id"`
+	Reason    TerminationReason `json:"reason"`
+}
+
+// DrainingMessage structure represents message from service provider notifying consumer
+// that session will be terminated after the deadline, as the service is being stopped
+type DrainingMessage struct {
+	SessionID ID        `json:"session_id"`
+	Deadline  time.Time `json:"deadline"`
+}
+
+// TerminationSender notifies consumers about sessions terminated by provider
+type TerminationSender interface {
+	SendTermination(sessionID ID, reason TerminationReason) error
+	SendDraining(sessionID ID, deadline time.Time) error
+}
+
+// NewTerminationSender returns sender of termination messages over the given dialog
+func NewTerminationSender(sender communication.Sender) TerminationSender {
+	return &terminationSender{sender: sender}
+}
+
+type terminationSender struct {
+	sender communication.Sender
+}
+
+// SendTermination sends termination message of the given session
+func (ts *terminationSender) SendTermination(sessionID ID, reason TerminationReason) error {
+	return ts.sender.Send(&terminateProducer{
+		message: TerminateMessage{SessionID: sessionID, Reason: reason},
+	})
+}
+
+// SendDraining sends notice that the given session will be terminated after the deadline
+func (ts *terminationSender) SendDraining(sessionID ID, deadline time.Time) error {
+	return ts.sender.Send(&drainingProducer{
+		message: DrainingMessage{SessionID: sessionID, Deadline: deadline},
+	})
+}
+
+// NewTerminationConsumer returns consumer of termination messages calling back on every received message
+func NewTerminationConsumer(callback func(message TerminateMessage)) communication.MessageConsumer {
+	return &terminateConsumer{callback: callback}
+}
+
+type terminateConsumer struct {
+	callback func(message TerminateMessage)
+}
+
+// GetMessageEndpoint returns endpoint where to receive messages
+func (consumer *terminateConsumer) GetMessageEndpoint() communication.MessageEndpoint {
+	return endpointSessionTerminate
+}
+
+// NewMessage creates struct where message from endpoint will be serialized
+func (consumer *terminateConsumer) NewMessage() (messagePtr interface{}) {
+	return &TerminateMessage{}
+}
+
+// Consume handles messages from endpoint
+func (consumer *terminateConsumer) Consume(messagePtr interface{}) error {
+	consumer.callback(*messagePtr.(*TerminateMessage))
+	return nil
+}
+
+type terminateProducer struct {
+	message TerminateMessage
+}
+
+// GetMessageEndpoint returns endpoint where to send messages
+func (producer *terminateProducer) GetMessageEndpoint() communication.MessageEndpoint {
+	return endpointSessionTerminate
+}
+
+// Produce creates message which will be serialized to endpoint
+func (producer *terminateProducer) Produce() (messagePtr interface{}) {
+	return &producer.message
+}
+
+// NewDrainingConsumer returns consumer of draining notices calling back on every received message
+func NewDrainingConsumer(callback func(message DrainingMessage)) communication.MessageConsumer {
+	return &drainingConsumer{callback: callback}
+}
+
+type drainingConsumer struct {
+	callback func(message DrainingMessage)
+}
+
+// GetMessageEndpoint returns endpoint where to receive messages
+func (consumer *drainingConsumer) GetMessageEndpoint() communication.MessageEndpoint {
+	return endpointSessionDraining
+}
+
+// NewMessage creates struct where message from endpoint will be serialized
+func (consumer *drainingConsumer) NewMessage() (messagePtr interface{}) {
+	return &DrainingMessage{}
+}
+
+// Consume handles messages from endpoint
+func (consumer *drainingConsumer) Consume(messagePtr interface{}) error {
+	consumer.callback(*messagePtr.(*DrainingMessage))
+	return nil
+}
+
+type drainingProducer struct {
+	message DrainingMessage
+}
+
+// GetMessageEndpoint returns endpoint where to send messages
+func (producer *drainingProducer) GetMessageEndpoint() communication.MessageEndpoint {
+	return endpointSessionDraining
+}
+
+// Produce creates message which will be serialized to endpoint
+func (producer *drainingProducer) Produce() (messagePtr interface{}) {
+	return &producer.message
+}
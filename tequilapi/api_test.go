@@ -19,7 +19,9 @@ package tequilapi
 
 import (
 	"testing"
+	"time"
 
+	"github.com/mysteriumnetwork/node/core/health"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -31,7 +33,7 @@ type tequilapiTestSuite struct {
 }
 
 func (testSuite *tequilapiTestSuite) SetupSuite() {
-	testSuite.server = NewServer("localhost", 0, NewAPIRouter(), RegexpCorsPolicy{})
+	testSuite.server = NewServer("localhost", 0, NewAPIRouter(health.NewRegistry(time.Second)), RegexpCorsPolicy{})
 
 	assert.NoError(testSuite.T(), testSuite.server.StartServing())
 	address, err := testSuite.server.Address()
@@ -18,28 +18,42 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 )
 
+// Version is the version of Tequilapi client, it is sent to the node in User-Agent header
+const Version = "0.2.0"
+
 // NewClient returns a new instance of Client
 func NewClient(ip string, port int) *Client {
 	return &Client{
 		http: newHTTPClient(
 			fmt.Sprintf("http://%s:%d", ip, port),
 			"[Tequilapi.Client] ",
-			"goclient-v0.1",
+			"goclient-v"+Version,
 		),
 	}
 }
 
-// Client is able perform remote requests to Tequilapi server
+// Client is able perform remote requests to Tequilapi server.
+// Failed requests return *Error, which can be inspected with IsNotFound, IsConflict and similar functions.
 type Client struct {
 	http httpClientInterface
 }
 
+// WithContext returns copy of the client which makes requests bound to the given context,
+// i.e. they are cancelled when the context is cancelled or its deadline passes
+func (client *Client) WithContext(ctx context.Context) *Client {
+	return &Client{http: client.http.WithContext(ctx)}
+}
+
 // GetIdentities returns a list of client identities
 func (client *Client) GetIdentities() (ids []IdentityDTO, err error) {
 	response, err := client.http.Get("identities", url.Values{})
@@ -84,7 +98,22 @@ func (client *Client) IdentityRegistrationStatus(address string) (RegistrationDa
 	return status, err
 }
 
-// Connect initiates a new connection to a host identified by providerID
+// IdentityBalance returns balance of identity in payments contract
+func (client *Client) IdentityBalance(address string) (BalanceDTO, error) {
+	response, err := client.http.Get("identities/"+address+"/balance", url.Values{})
+	if err != nil {
+		return BalanceDTO{}, err
+	}
+	defer response.Body.Close()
+
+	balance := BalanceDTO{}
+	err = parseResponseJSON(response, &balance)
+	return balance, err
+}
+
+// Connect initiates a new connection to a host identified by providerID.
+// Error satisfying IsAlreadyConnected is returned when connection exists already,
+// IsConnectionCancelled - when connecting was cancelled by disconnect.
 func (client *Client) Connect(consumerID, providerID, serviceType string, options ConnectOptions) (status StatusDTO, err error) {
 	payload := struct {
 		Identity    string         `json:"consumerId"`
@@ -98,18 +127,7 @@ func (client *Client) Connect(consumerID, providerID, serviceType string, option
 		Options:     options,
 	}
 	response, err := client.http.Put("connection", payload)
-
-	var errorMessage struct {
-		Message string `json:"message"`
-	}
-
 	if err != nil {
-		err = parseResponseJSON(response, &errorMessage)
-		if err != nil {
-			return
-		}
-
-		err = errors.New(errorMessage.Message)
 		return
 	}
 	defer response.Body.Close()
@@ -155,9 +173,14 @@ func (client *Client) Status() (StatusDTO, error) {
 	return status, err
 }
 
-// Healthcheck returns a healthcheck info
+// Healthcheck returns a healthcheck info, it is returned without error for the failing node too
 func (client *Client) Healthcheck() (healthcheck HealthcheckDTO, err error) {
 	response, err := client.http.Get("healthcheck", url.Values{})
+	if apiErr, ok := err.(*Error); ok && apiErr.StatusCode == http.StatusServiceUnavailable {
+		// failing node responds with health of its components as well
+		err = json.Unmarshal(apiErr.body, &healthcheck)
+		return healthcheck, err
+	}
 	if err != nil {
 		return
 	}
@@ -169,8 +192,26 @@ func (client *Client) Healthcheck() (healthcheck HealthcheckDTO, err error) {
 
 // ProposalsByType fetches proposals by given type
 func (client *Client) ProposalsByType(serviceType string) ([]ProposalDTO, error) {
+	return client.FindProposals(ProposalsFilter{ServiceType: serviceType})
+}
+
+// Proposals returns all available proposals for services
+func (client *Client) Proposals() ([]ProposalDTO, error) {
+	return client.FindProposals(ProposalsFilter{})
+}
+
+// FindProposals returns proposals matching the given filter
+func (client *Client) FindProposals(filter ProposalsFilter) ([]ProposalDTO, error) {
 	queryParams := url.Values{}
-	queryParams.Add("serviceType", serviceType)
+	if filter.ProviderID != "" {
+		queryParams.Add("providerId", filter.ProviderID)
+	}
+	if filter.ServiceType != "" {
+		queryParams.Add("serviceType", filter.ServiceType)
+	}
+	if filter.FetchConnectCounts {
+		queryParams.Add("fetchConnectCounts", strconv.FormatBool(filter.FetchConnectCounts))
+	}
 	response, err := client.http.Get("proposals", queryParams)
 	if err != nil {
 		return []ProposalDTO{}, err
@@ -182,17 +223,16 @@ func (client *Client) ProposalsByType(serviceType string) ([]ProposalDTO, error)
 	return proposals.Proposals, err
 }
 
-// Proposals returns all available proposals for services
-func (client *Client) Proposals() ([]ProposalDTO, error) {
-	response, err := client.http.Get("proposals", url.Values{})
+// Location returns original location of the node and the current one, which differs while connected
+func (client *Client) Location() (location NodeLocationDTO, err error) {
+	response, err := client.http.Get("location", url.Values{})
 	if err != nil {
-		return []ProposalDTO{}, err
+		return location, err
 	}
 	defer response.Body.Close()
 
-	var proposals ProposalList
-	err = parseResponseJSON(response, &proposals)
-	return proposals.Proposals, err
+	err = parseResponseJSON(response, &location)
+	return location, err
 }
 
 // GetIP returns public ip
@@ -242,8 +282,24 @@ func (client *Client) Stop() error {
 
 // GetSessions returns all sessions from history
 func (client *Client) GetSessions() (SessionsDTO, error) {
+	return client.Sessions(SessionsFilter{})
+}
+
+// Sessions returns sessions from history matching the given filter
+func (client *Client) Sessions(filter SessionsFilter) (SessionsDTO, error) {
+	queryParams := url.Values{}
+	if filter.ServiceType != "" {
+		queryParams.Add("serviceType", filter.ServiceType)
+	}
+	if filter.Status != "" {
+		queryParams.Add("status", filter.Status)
+	}
+	if filter.ProviderID != "" {
+		queryParams.Add("providerId", filter.ProviderID)
+	}
+
 	sessions := SessionsDTO{}
-	response, err := client.http.Get("sessions", url.Values{})
+	response, err := client.http.Get("sessions", queryParams)
 	if err != nil {
 		return sessions, err
 	}
@@ -253,6 +309,27 @@ func (client *Client) GetSessions() (SessionsDTO, error) {
 	return sessions, err
 }
 
+// Spending returns the amounts promised to providers during the sessions started within the given period.
+// Dates are expected in YYYY-MM-DD format, empty value leaves the period open on that side.
+func (client *Client) Spending(from, to string) (SpendingDTO, error) {
+	spending := SpendingDTO{}
+	queryParams := url.Values{}
+	if from != "" {
+		queryParams.Add("from", from)
+	}
+	if to != "" {
+		queryParams.Add("to", to)
+	}
+	response, err := client.http.Get("sessions/spending", queryParams)
+	if err != nil {
+		return spending, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &spending)
+	return spending, err
+}
+
 // Services returns all running services
 func (client *Client) Services() (services ServiceListDTO, err error) {
 	response, err := client.http.Get("services", url.Values{})
@@ -277,6 +354,18 @@ func (client *Client) Service(id string) (service ServiceInfoDTO, err error) {
 	return service, err
 }
 
+// ServiceOptions returns default options of every service type the node is able to run, keyed by service type
+func (client *Client) ServiceOptions() (options map[string]json.RawMessage, err error) {
+	response, err := client.http.Get("service-options", url.Values{})
+	if err != nil {
+		return options, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &options)
+	return options, err
+}
+
 // ServiceStart starts an instance of the service.
 func (client *Client) ServiceStart(providerID, serviceType string, options interface{}) (service ServiceInfoDTO, err error) {
 	opts, err := json.Marshal(options)
@@ -316,42 +405,68 @@ func (client *Client) ServiceStop(id string) error {
 	return nil
 }
 
-// filterSessionsByType removes all sessions of irrelevant types
-func filterSessionsByType(serviceType string, sessions SessionsDTO) SessionsDTO {
-	matches := 0
-	for _, s := range sessions.Sessions {
-		if s.ServiceType == serviceType {
-			sessions.Sessions[matches] = s
-			matches++
-		}
+// ServiceDrain stops the running service after its sessions end or the timeout expires.
+func (client *Client) ServiceDrain(id string, timeout time.Duration) error {
+	path := fmt.Sprintf("services/%s?drain=%s", id, url.QueryEscape(timeout.String()))
+	response, err := client.http.Delete(path, nil)
+	if err != nil {
+		return err
 	}
-	sessions.Sessions = sessions.Sessions[:matches]
-	return sessions
+	defer response.Body.Close()
+
+	return nil
 }
 
-// filterSessionsByStatus removes all sessions with non matching status
-func filterSessionsByStatus(status string, sessions SessionsDTO) SessionsDTO {
-	matches := 0
-	for _, s := range sessions.Sessions {
-		if s.Status == status {
-			sessions.Sessions[matches] = s
-			matches++
-		}
+// ServiceAutoStart enables or disables restoring of the service instance after node restart.
+func (client *Client) ServiceAutoStart(id string, autoStart bool) (service ServiceInfoDTO, err error) {
+	payload := struct {
+		AutoStart bool `json:"autostart"`
+	}{
+		autoStart,
 	}
-	sessions.Sessions = sessions.Sessions[:matches]
-	return sessions
+
+	response, err := client.http.Put("services/"+id+"/autostart", payload)
+	if err != nil {
+		return service, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &service)
+	return service, err
+}
+
+// Earnings returns the provider earnings report
+func (client *Client) Earnings() (EarningsDTO, error) {
+	earnings := EarningsDTO{}
+	response, err := client.http.Get("earnings", url.Values{})
+	if err != nil {
+		return earnings, err
+	}
+	defer response.Body.Close()
+
+	err = parseResponseJSON(response, &earnings)
+	return earnings, err
+}
+
+// EarningsCSV returns the provider earnings report entries in CSV format
+func (client *Client) EarningsCSV() ([]byte, error) {
+	queryParams := url.Values{}
+	queryParams.Add("format", "csv")
+	response, err := client.http.Get("earnings", queryParams)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	return ioutil.ReadAll(response.Body)
 }
 
 // GetSessionsByType returns sessions from history filtered by type
 func (client *Client) GetSessionsByType(serviceType string) (SessionsDTO, error) {
-	sessions, err := client.GetSessions()
-	sessions = filterSessionsByType(serviceType, sessions)
-	return sessions, err
+	return client.Sessions(SessionsFilter{ServiceType: serviceType})
 }
 
 // GetSessionsByStatus returns sessions from history filtered by their status
 func (client *Client) GetSessionsByStatus(status string) (SessionsDTO, error) {
-	sessions, err := client.GetSessions()
-	sessions = filterSessionsByStatus(status, sessions)
-	return sessions, err
+	return client.Sessions(SessionsFilter{Status: status})
 }
@@ -0,0 +1,329 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/consumer"
+	consumer_session "github.com/mysteriumnetwork/node/consumer/session"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/health"
+	"github.com/mysteriumnetwork/node/core/ip"
+	"github.com/mysteriumnetwork/node/core/location"
+	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/market"
+	"github.com/mysteriumnetwork/node/tequilapi"
+	"github.com/mysteriumnetwork/node/tequilapi/endpoints"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeServiceDefinition struct{}
+
+func (fakeServiceDefinition) GetLocation() market.Location {
+	return market.Location{Country: "LT"}
+}
+
+var fakeProposal = market.ServiceProposal{
+	ProviderID:        "0xprovider",
+	ServiceType:       "fake",
+	ServiceDefinition: fakeServiceDefinition{},
+}
+
+type connectionManagerFake struct {
+	lock       sync.Mutex
+	status     connection.Status
+	connectErr error
+	delay      time.Duration
+}
+
+func (cm *connectionManagerFake) Connect(_ identity.Identity, proposal market.ServiceProposal, _ connection.ConnectParams) error {
+	time.Sleep(cm.delay)
+
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	if cm.connectErr != nil {
+		return cm.connectErr
+	}
+	cm.status = connection.Status{State: connection.Connected, SessionID: "session", Proposal: proposal}
+	return nil
+}
+
+func (cm *connectionManagerFake) Status() connection.Status {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	return cm.status
+}
+
+func (cm *connectionManagerFake) Disconnect() error {
+	cm.setState(connection.NotConnected)
+	return nil
+}
+
+func (cm *connectionManagerFake) setState(state connection.State) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	cm.status = connection.Status{State: state}
+}
+
+type statisticsTrackerFake struct{}
+
+func (statisticsTrackerFake) Retrieve() consumer.SessionStatistics {
+	return consumer.SessionStatistics{}
+}
+
+func (statisticsTrackerFake) GetSessionDuration() time.Duration {
+	return 0
+}
+
+type proposalProviderFake struct{}
+
+func (proposalProviderFake) FindProposals(_, _ string) ([]market.ServiceProposal, error) {
+	return []market.ServiceProposal{fakeProposal}, nil
+}
+
+type sessionStorageFake struct {
+	sessions []consumer_session.History
+}
+
+func (ss *sessionStorageFake) GetAll() ([]consumer_session.History, error) {
+	return ss.sessions, nil
+}
+
+type serviceManagerFake struct{}
+
+func (serviceManagerFake) Start(identity.Identity, string, service.Options) (service.ID, error) {
+	return "", errors.New("not supported")
+}
+
+func (serviceManagerFake) Stop(service.ID) error {
+	return nil
+}
+
+func (serviceManagerFake) Service(service.ID) *service.Instance {
+	return nil
+}
+
+func (serviceManagerFake) Drain(service.ID, time.Duration) error {
+	return nil
+}
+
+func (serviceManagerFake) Kill() error {
+	return nil
+}
+
+func (serviceManagerFake) List() map[service.ID]*service.Instance {
+	return nil
+}
+
+func (serviceManagerFake) SetAutoStart(service.ID, bool) error {
+	return nil
+}
+
+type fakeOptions struct {
+	Port int `json:"port"`
+}
+
+func newTestClient(t *testing.T, manager connection.Manager, sessions ...consumer_session.History) (*Client, func()) {
+	router := httprouter.New()
+	endpoints.AddRoutesForConnection(router, manager, ip.NewResolverFake("1.2.3.4"), statisticsTrackerFake{}, proposalProviderFake{})
+	endpoints.AddRoutesForSession(router, &sessionStorageFake{sessions: sessions})
+	endpoints.AddRoutesForService(router, serviceManagerFake{}, map[string]service.OptionsParser{
+		"fake": func(*json.RawMessage) (service.Options, error) {
+			return fakeOptions{Port: 1194}, nil
+		},
+	})
+	endpoints.AddRoutesForLocation(router, manager, location.NewDetectorFake("5.6.7.8", "LT"), location.NewLocationCache(location.NewDetectorFake("1.2.3.4", "US")))
+
+	server := httptest.NewServer(router)
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	assert.NoError(t, err)
+
+	return NewClient(host, portNumber), server.Close
+}
+
+func TestConnectReturnsTypedErrors(t *testing.T) {
+	manager := &connectionManagerFake{connectErr: connection.ErrAlreadyExists}
+	client, stop := newTestClient(t, manager)
+	defer stop()
+
+	_, err := client.Connect("0xconsumer", "0xprovider", "fake", ConnectOptions{})
+	assert.True(t, IsAlreadyConnected(err))
+	assert.True(t, IsConflict(err))
+	assert.False(t, IsConnectionCancelled(err))
+
+	manager.connectErr = connection.ErrConnectionCancelled
+	_, err = client.Connect("0xconsumer", "0xprovider", "fake", ConnectOptions{})
+	assert.True(t, IsConnectionCancelled(err))
+	assert.Equal(t, StatusConnectionCancelled, StatusCode(err))
+	assert.Equal(t, "connection was cancelled", err.(*Error).Message)
+}
+
+func TestConnectReturnsValidationErrors(t *testing.T) {
+	client, stop := newTestClient(t, &connectionManagerFake{})
+	defer stop()
+
+	_, err := client.Connect("", "0xprovider", "fake", ConnectOptions{})
+	assert.True(t, IsValidationError(err))
+	assert.Equal(t, []FieldErrorDTO{{Code: "required", Message: "Field is required"}}, err.(*Error).ValidationErrors["consumerId"])
+}
+
+func TestConnectSucceeds(t *testing.T) {
+	client, stop := newTestClient(t, &connectionManagerFake{})
+	defer stop()
+
+	status, err := client.Connect("0xconsumer", "0xprovider", "fake", ConnectOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Connected", status.Status)
+	assert.Equal(t, "session", status.SessionID)
+	assert.Equal(t, "0xprovider", status.Proposal.ProviderID)
+}
+
+func TestWithContextCancelsRequest(t *testing.T) {
+	client, stop := newTestClient(t, &connectionManagerFake{delay: time.Second})
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.WithContext(ctx).Connect("0xconsumer", "0xprovider", "fake", ConnectOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, 0, StatusCode(err))
+}
+
+func TestSessionsAreFilteredByServer(t *testing.T) {
+	client, stop := newTestClient(t, &connectionManagerFake{},
+		consumer_session.History{SessionID: "1", ServiceType: "openvpn", Status: consumer_session.SessionStatusCompleted},
+		consumer_session.History{SessionID: "2", ServiceType: "wireguard", Status: consumer_session.SessionStatusCompleted},
+		consumer_session.History{SessionID: "3", ServiceType: "wireguard", Status: consumer_session.SessionStatusNew},
+	)
+	defer stop()
+
+	sessions, err := client.GetSessions()
+	assert.NoError(t, err)
+	assert.Len(t, sessions.Sessions, 3)
+
+	sessions, err = client.Sessions(SessionsFilter{ServiceType: "wireguard", Status: consumer_session.SessionStatusCompleted})
+	assert.NoError(t, err)
+	assert.Len(t, sessions.Sessions, 1)
+	assert.Equal(t, "2", sessions.Sessions[0].SessionID)
+}
+
+func TestServiceOptionsAndNotFoundService(t *testing.T) {
+	client, stop := newTestClient(t, &connectionManagerFake{})
+	defer stop()
+
+	options, err := client.ServiceOptions()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"port": 1194}`, string(options["fake"]))
+
+	_, err = client.Service("unknown")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestLocation(t *testing.T) {
+	manager := &connectionManagerFake{}
+	client, stop := newTestClient(t, manager)
+	defer stop()
+
+	_, err := client.Location()
+	assert.NoError(t, err)
+
+	manager.setState(connection.Connected)
+	nodeLocation, err := client.Location()
+	assert.NoError(t, err)
+	assert.Equal(t, "5.6.7.8", nodeLocation.Current.IP)
+	assert.Equal(t, "LT", nodeLocation.Current.Country)
+}
+
+func TestWatchConnectionStatusDeliversChanges(t *testing.T) {
+	manager := &connectionManagerFake{status: connection.Status{State: connection.NotConnected}}
+	client, stop := newTestClient(t, manager)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	statuses, errs := client.WatchConnectionStatus(ctx, 5*time.Millisecond)
+	assert.Equal(t, "NotConnected", (<-statuses).Status)
+
+	manager.setState(connection.Connecting)
+	assert.Equal(t, "Connecting", (<-statuses).Status)
+
+	manager.setState(connection.Connected)
+	status, err := client.WaitForConnectionStatus(ctx, "Connected", 5*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "Connected", status.Status)
+
+	cancel()
+	for range statuses {
+	}
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestWaitForConnectionStatusTimesOut(t *testing.T) {
+	client, stop := newTestClient(t, &connectionManagerFake{status: connection.Status{State: connection.NotConnected}})
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	status, err := client.WaitForConnectionStatus(ctx, "Connected", 5*time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, "NotConnected", status.Status)
+}
+
+func TestHealthcheckReturnsReportOfFailingNode(t *testing.T) {
+	registry := health.NewRegistry(time.Second)
+	registry.Register("storage", true, func() error {
+		return errors.New("database not open")
+	})
+	registry.Register("discovery", false, func() error {
+		return nil
+	})
+
+	server := httptest.NewServer(tequilapi.NewAPIRouter(registry))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	assert.NoError(t, err)
+
+	healthcheck, err := NewClient(host, portNumber).Healthcheck()
+	assert.NoError(t, err)
+	assert.Equal(t, "failing", healthcheck.Status)
+	assert.Len(t, healthcheck.Components, 2)
+	assert.Equal(t, "storage", healthcheck.Components[0].Name)
+	assert.Equal(t, "failing", healthcheck.Components[0].Status)
+	assert.Equal(t, "database not open", healthcheck.Components[0].Error)
+	assert.Equal(t, "ok", healthcheck.Components[1].Status)
+}
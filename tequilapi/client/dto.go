@@ -47,6 +47,15 @@ type ProposalDTO struct {
 	ProviderID        string               `json:"providerId"`
 	ServiceType       string               `json:"serviceType"`
 	ServiceDefinition ServiceDefinitionDTO `json:"serviceDefinition"`
+	Metrics           json.RawMessage      `json:"metrics,omitempty"`
+}
+
+// ProposalsFilter narrows down the proposals listed, empty fields do not filter
+type ProposalsFilter struct {
+	ProviderID  string
+	ServiceType string
+	// FetchConnectCounts adds connection success metrics of providers to the proposals
+	FetchConnectCounts bool
 }
 
 func (p ProposalDTO) String() string {
@@ -60,7 +69,18 @@ type ServiceDefinitionDTO struct {
 
 // LocationDTO describes location
 type LocationDTO struct {
+	IP      string `json:"ip,omitempty"`
+	ASN     string `json:"asn"`
 	Country string `json:"country"`
+	Region  string `json:"region"`
+	City    string `json:"city"`
+	ISP     string `json:"isp"`
+}
+
+// NodeLocationDTO holds original location of the node and the current one, which differs while connected
+type NodeLocationDTO struct {
+	Original LocationDTO `json:"original"`
+	Current  LocationDTO `json:"current"`
 }
 
 // IdentityDTO holds identity address
@@ -79,6 +99,21 @@ type HealthcheckDTO struct {
 	Process   int          `json:"process"`
 	Version   string       `json:"version"`
 	BuildInfo BuildInfoDTO `json:"buildInfo"`
+
+	Status     string               `json:"status"`
+	Components []ComponentHealthDTO `json:"components"`
+}
+
+// ComponentHealthDTO holds health of single node component
+type ComponentHealthDTO struct {
+	Name        string `json:"name"`
+	Critical    bool   `json:"critical"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	LastError   string `json:"lastError,omitempty"`
+	LastErrorAt string `json:"lastErrorAt,omitempty"`
+	CheckedAt   string `json:"checkedAt"`
+	Duration    string `json:"duration"`
 }
 
 // BuildInfoDTO holds info about build
@@ -95,6 +130,11 @@ type RegistrationDataDTO struct {
 	Signature  SignatureDTO      `json:"signature"`
 }
 
+// BalanceDTO holds identity balance in payments contract
+type BalanceDTO struct {
+	Balance uint64 `json:"balance"`
+}
+
 // PublicKeyPartsDTO holds public key parts in hex, split into 32 byte blocks
 type PublicKeyPartsDTO struct {
 	Part1 string `json:"part1"`
@@ -129,6 +169,22 @@ type SessionDTO struct {
 	BytesReceived   uint64 `json:"bytesReceived"`
 	Duration        uint64 `json:"duration"`
 	Status          string `json:"status"`
+	PromisedAmount  uint64 `json:"promisedAmount"`
+}
+
+// SessionsFilter narrows down the sessions listed, empty fields do not filter
+type SessionsFilter struct {
+	ServiceType string
+	Status      string
+	ProviderID  string
+}
+
+// SpendingDTO copied from tequilapi endpoint
+type SpendingDTO struct {
+	Total         uint64            `json:"total"`
+	Sessions      int               `json:"sessions"`
+	ByProvider    map[string]uint64 `json:"byProvider"`
+	ByServiceType map[string]uint64 `json:"byServiceType"`
 }
 
 // ServiceListDTO represents a list of running services on the node
@@ -136,10 +192,61 @@ type ServiceListDTO []ServiceInfoDTO
 
 // ServiceInfoDTO represents running service information
 type ServiceInfoDTO struct {
-	ID          string          `json:"id"`
-	ProviderID  string          `json:"providerId"`
-	ServiceType string          `json:"type"`
-	Options     json.RawMessage `json:"options"`
-	Status      string          `json:"status"`
-	Proposal    ProposalDTO     `json:"proposal"`
+	ID           string                  `json:"id"`
+	ProviderID   string                  `json:"providerId"`
+	ServiceType  string                  `json:"type"`
+	Options      json.RawMessage         `json:"options"`
+	Status       string                  `json:"status"`
+	AutoStart    bool                    `json:"autostart"`
+	Restarts     int                     `json:"restarts"`
+	LastError    string                  `json:"lastError"`
+	PortMappings []PortMappingStatusDTO  `json:"portMappings,omitempty"`
+	Reachability *ServiceReachabilityDTO `json:"reachability,omitempty"`
+	Proposal     ProposalDTO             `json:"proposal"`
+}
+
+// PortMappingStatusDTO holds status of the port mapped on the gateway by the service
+type PortMappingStatusDTO struct {
+	Protocol     string `json:"protocol"`
+	InternalPort int    `json:"internalPort"`
+	ExternalPort int    `json:"externalPort"`
+	RouterType   string `json:"routerType"`
+	Mapped       bool   `json:"mapped"`
+	Lease        string `json:"lease"`
+	LastError    string `json:"lastError,omitempty"`
+	UpdatedAt    string `json:"updatedAt"`
+}
+
+// ServiceReachabilityDTO holds outcome of the check whether service can be reached on its advertised endpoint
+type ServiceReachabilityDTO struct {
+	Endpoint  string `json:"endpoint"`
+	Status    string `json:"status"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+	CheckedAt string `json:"checkedAt"`
+}
+
+// EarningsSummaryDTO copied from tequilapi endpoint
+type EarningsSummaryDTO struct {
+	Received   uint64 `json:"received"`
+	Consumed   uint64 `json:"consumed"`
+	Unconsumed uint64 `json:"unconsumed"`
+	Cleared    uint64 `json:"cleared"`
+}
+
+// EarningsEntryDTO copied from tequilapi endpoint
+type EarningsEntryDTO struct {
+	Date        string `json:"date"`
+	ConsumerID  string `json:"consumerId"`
+	ServiceType string `json:"serviceType"`
+	EarningsSummaryDTO
+}
+
+// EarningsDTO copied from tequilapi endpoint
+type EarningsDTO struct {
+	Total         EarningsSummaryDTO            `json:"total"`
+	ByConsumer    map[string]EarningsSummaryDTO `json:"byConsumer"`
+	ByServiceType map[string]EarningsSummaryDTO `json:"byServiceType"`
+	ByDay         map[string]EarningsSummaryDTO `json:"byDay"`
+	Entries       []EarningsEntryDTO            `json:"entries"`
 }
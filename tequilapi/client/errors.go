@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// StatusConnectionCancelled is the HTTP status Tequilapi responds with when connecting was cancelled by disconnect
+const StatusConnectionCancelled = 499
+
+// Error is returned when Tequilapi responds with unsuccessful HTTP status
+type Error struct {
+	StatusCode int
+	Message    string
+	// ValidationErrors lists errors of the request fields keyed by field name, when the request failed validation
+	ValidationErrors map[string][]FieldErrorDTO
+
+	status string
+	url    string
+	body   []byte
+}
+
+// FieldErrorDTO describes validation error of the request field
+type FieldErrorDTO struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("server response invalid: %s (%s). Possible error: %s", e.status, e.url, e.Message)
+}
+
+// StatusCode returns HTTP status of the failed Tequilapi response, zero is returned for other errors
+func StatusCode(err error) int {
+	if apiErr, ok := err.(*Error); ok {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound tells whether the requested resource, e.g. service, does not exist
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IsConflict tells whether the request conflicts with the state of the node,
+// e.g. connection already exists when connecting or the same service is already running
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
+
+// IsAlreadyConnected tells whether connecting failed because connection already exists
+func IsAlreadyConnected(err error) bool {
+	return IsConflict(err)
+}
+
+// IsConnectionCancelled tells whether connecting was cancelled before connection got established
+func IsConnectionCancelled(err error) bool {
+	return StatusCode(err) == StatusConnectionCancelled
+}
+
+// IsValidationError tells whether the request parameters failed validation, see Error.ValidationErrors for details
+func IsValidationError(err error) bool {
+	return StatusCode(err) == http.StatusUnprocessableEntity
+}
+
+// IsUnavailable tells whether the node is unable to serve the request at the moment, e.g. it is unhealthy
+func IsUnavailable(err error) bool {
+	return StatusCode(err) == http.StatusServiceUnavailable
+}
@@ -19,6 +19,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -34,6 +35,7 @@ type httpClientInterface interface {
 	Post(path string, payload interface{}) (*http.Response, error)
 	Put(path string, payload interface{}) (*http.Response, error)
 	Delete(path string, payload interface{}) (*http.Response, error)
+	WithContext(ctx context.Context) httpClientInterface
 }
 
 type httpRequestInterface interface {
@@ -49,6 +51,7 @@ func newHTTPClient(baseURL string, logPrefix string, ua string) *httpClient {
 		baseURL:   baseURL,
 		logPrefix: logPrefix,
 		ua:        ua,
+		ctx:       context.Background(),
 	}
 }
 
@@ -57,6 +60,14 @@ type httpClient struct {
 	baseURL   string
 	logPrefix string
 	ua        string
+	ctx       context.Context
+}
+
+// WithContext returns copy of the client which binds requests to the given context
+func (client *httpClient) WithContext(ctx context.Context) httpClientInterface {
+	clientCopy := *client
+	clientCopy.ctx = ctx
+	return &clientCopy
 }
 
 func (client *httpClient) Get(path string, values url.Values) (*http.Response, error) {
@@ -96,32 +107,34 @@ func (client httpClient) doPayloadRequest(method, path string, payload interface
 
 func (client *httpClient) executeRequest(method, fullPath string, payloadJSON []byte) (*http.Response, error) {
 	request, err := http.NewRequest(method, fullPath, bytes.NewBuffer(payloadJSON))
-	request.Header.Set("User-Agent", client.ua)
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("Accept", "application/json")
 	if err != nil {
 		log.Critical(client.logPrefix, err)
 		return nil, err
 	}
+	request = request.WithContext(client.ctx)
+	request.Header.Set("User-Agent", client.ua)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
 
 	response, err := client.http.Do(request)
-
 	if err != nil {
 		log.Error(client.logPrefix, err)
-		return response, err
+		return nil, err
 	}
 
 	err = parseResponseError(response)
 	if err != nil {
+		response.Body.Close()
 		log.Error(client.logPrefix, err)
-		return response, err
+		return nil, err
 	}
 
 	return response, nil
 }
 
 type errorBody struct {
-	Message string `json:"message"`
+	Message          string                     `json:"message"`
+	ValidationErrors map[string][]FieldErrorDTO `json:"errors"`
 }
 
 func parseResponseError(response *http.Response) error {
@@ -129,13 +142,23 @@ func parseResponseError(response *http.Response) error {
 		//sometimes we can get json message with single "message" field which represents error - try to get that
 		var parsedBody errorBody
 		var message string
-		err := parseResponseJSON(response, &parsedBody)
+		body, err := ioutil.ReadAll(response.Body)
+		if err == nil {
+			err = json.Unmarshal(body, &parsedBody)
+		}
 		if err != nil {
 			message = err.Error()
 		} else {
 			message = parsedBody.Message
 		}
-		return fmt.Errorf("server response invalid: %s (%s). Possible error: %s", response.Status, response.Request.URL, message)
+		return &Error{
+			StatusCode:       response.StatusCode,
+			Message:          message,
+			ValidationErrors: parsedBody.ValidationErrors,
+			status:           response.Status,
+			url:              response.Request.URL.String(),
+			body:             body,
+		}
 	}
 
 	return nil
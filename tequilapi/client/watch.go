@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package client
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// WatchConnectionStatus polls connection status every interval and delivers it whenever it changes, starting with the current one.
+// Failed polls are delivered to the error channel without stopping the watch.
+// Both channels are closed once the context is done.
+func (client *Client) WatchConnectionStatus(ctx context.Context, interval time.Duration) (<-chan StatusDTO, <-chan error) {
+	statuses := make(chan StatusDTO)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(statuses)
+		defer close(errs)
+
+		poll(ctx, interval,
+			func() (interface{}, error) {
+				return client.WithContext(ctx).Status()
+			},
+			func(value interface{}) {
+				select {
+				case statuses <- value.(StatusDTO):
+				case <-ctx.Done():
+				}
+			},
+			errorDelivery(errs),
+		)
+	}()
+	return statuses, errs
+}
+
+// WatchService polls information of the service instance every interval and delivers it whenever it changes, starting with the current one.
+// Failed polls are delivered to the error channel without stopping the watch.
+// Both channels are closed once the context is done.
+func (client *Client) WatchService(ctx context.Context, id string, interval time.Duration) (<-chan ServiceInfoDTO, <-chan error) {
+	services := make(chan ServiceInfoDTO)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(services)
+		defer close(errs)
+
+		poll(ctx, interval,
+			func() (interface{}, error) {
+				return client.WithContext(ctx).Service(id)
+			},
+			func(value interface{}) {
+				select {
+				case services <- value.(ServiceInfoDTO):
+				case <-ctx.Done():
+				}
+			},
+			errorDelivery(errs),
+		)
+	}()
+	return services, errs
+}
+
+// WaitForConnectionStatus polls connection status every interval until it reaches the given one.
+// Error is returned when polling fails or the context is done before the status is reached,
+// in the latter case together with the last polled status.
+func (client *Client) WaitForConnectionStatus(ctx context.Context, status string, interval time.Duration) (StatusDTO, error) {
+	var last StatusDTO
+	for {
+		current, err := client.WithContext(ctx).Status()
+		if ctx.Err() != nil {
+			return last, ctx.Err()
+		}
+		if err != nil {
+			return last, err
+		}
+		if current.Status == status {
+			return current, nil
+		}
+		last = current
+
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// poll fetches the value every interval until the context is done and hands over the values differing from the previous one
+func poll(ctx context.Context, interval time.Duration, fetch func() (interface{}, error), changed func(value interface{}), failed func(err error)) {
+	var previous interface{}
+	for {
+		value, err := fetch()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			failed(err)
+		} else if !reflect.DeepEqual(value, previous) {
+			previous = value
+			changed(value)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// errorDelivery returns function delivering errors to the channel, errors are dropped while the previous one is not received
+func errorDelivery(errs chan<- error) func(err error) {
+	return func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/session/earnings"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+const earningsFormatCSV = "csv"
+
+// EarningsSummaryDTO holds the earned amounts
+// swagger:model EarningsSummaryDTO
+type EarningsSummaryDTO struct {
+	// total amount promised by consumers
+	// example: 1000
+	Received uint64 `json:"received"`
+
+	// amount consumed by the sessions
+	// example: 800
+	Consumed uint64 `json:"consumed"`
+
+	// amount promised, but not consumed yet
+	// example: 200
+	Unconsumed uint64 `json:"unconsumed"`
+
+	// amount of the promises which were cleared
+	// example: 500
+	Cleared uint64 `json:"cleared"`
+}
+
+// EarningsEntryDTO holds the earnings for a single consumer and service type during a day
+// swagger:model EarningsEntryDTO
+type EarningsEntryDTO struct {
+	// example: 2019-04-01
+	Date string `json:"date"`
+
+	// example: 0x0000000000000000000000000000000000000001
+	ConsumerID string `json:"consumerId"`
+
+	// example: openvpn
+	ServiceType string `json:"serviceType"`
+
+	EarningsSummaryDTO
+}
+
+// EarningsDTO holds the provider earnings report
+// swagger:model EarningsDTO
+type EarningsDTO struct {
+	Total         EarningsSummaryDTO            `json:"total"`
+	ByConsumer    map[string]EarningsSummaryDTO `json:"byConsumer"`
+	ByServiceType map[string]EarningsSummaryDTO `json:"byServiceType"`
+	ByDay         map[string]EarningsSummaryDTO `json:"byDay"`
+	Entries       []EarningsEntryDTO            `json:"entries"`
+}
+
+type earningsReporter interface {
+	Report() (earnings.Report, error)
+}
+
+type earningsEndpoint struct {
+	reporter earningsReporter
+}
+
+// NewEarningsEndpoint creates and returns earnings endpoint
+func NewEarningsEndpoint(reporter earningsReporter) *earningsEndpoint {
+	return &earningsEndpoint{
+		reporter: reporter,
+	}
+}
+
+// swagger:operation GET /earnings Earnings getEarnings
+// ---
+// summary: Returns provider earnings
+// description: Returns earnings of the provider, broken down by consumer, service type and day
+// parameters:
+//   - in: query
+//     name: format
+//     description: Set to "csv" to export the report entries as CSV
+//     type: string
+// responses:
+//   200:
+//     description: Earnings report
+//     schema:
+//       "$ref": "#/definitions/EarningsDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *earningsEndpoint) Get(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	report, err := endpoint.reporter.Report()
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	if request.URL.Query().Get("format") == earningsFormatCSV {
+		resp.Header().Set("Content-type", "text/csv; charset=utf-8")
+		resp.Header().Set("Content-Disposition", `attachment; filename="earnings.csv"`)
+		if err := earnings.WriteCSV(resp, report.Entries); err != nil {
+			utils.SendError(resp, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteAsJSON(toEarningsDTO(report), resp)
+}
+
+// AddRoutesForEarnings attaches earnings endpoints to router
+func AddRoutesForEarnings(router *httprouter.Router, reporter earningsReporter) {
+	earningsEndpoint := NewEarningsEndpoint(reporter)
+	router.GET("/earnings", earningsEndpoint.Get)
+}
+
+func toEarningsDTO(report earnings.Report) EarningsDTO {
+	entries := make([]EarningsEntryDTO, len(report.Entries))
+	for i, e := range report.Entries {
+		entries[i] = EarningsEntryDTO{
+			Date:               e.Date,
+			ConsumerID:         e.ConsumerID,
+			ServiceType:        e.ServiceType,
+			EarningsSummaryDTO: toEarningsSummaryDTO(e.Summary),
+		}
+	}
+
+	return EarningsDTO{
+		Total:         toEarningsSummaryDTO(report.Total),
+		ByConsumer:    toEarningsSummaryMap(report.ByConsumer),
+		ByServiceType: toEarningsSummaryMap(report.ByServiceType),
+		ByDay:         toEarningsSummaryMap(report.ByDay),
+		Entries:       entries,
+	}
+}
+
+func toEarningsSummaryMap(summaries map[string]earnings.Summary) map[string]EarningsSummaryDTO {
+	res := make(map[string]EarningsSummaryDTO, len(summaries))
+	for k, s := range summaries {
+		res[k] = toEarningsSummaryDTO(s)
+	}
+	return res
+}
+
+func toEarningsSummaryDTO(s earnings.Summary) EarningsSummaryDTO {
+	return EarningsSummaryDTO{
+		Received:   s.Received,
+		Consumed:   s.Consumed,
+		Unconsumed: s.Unconsumed,
+		Cleared:    s.Cleared,
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2019 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/session/earnings"
+	"github.com/stretchr/testify/assert"
+)
+
+var earningsReportMock = earnings.Report{
+	Total: earnings.Summary{Received: 100, Consumed: 80, Unconsumed: 20, Cleared: 50},
+	ByConsumer: map[string]earnings.Summary{
+		"0x1": {Received: 100, Consumed: 80, Unconsumed: 20, Cleared: 50},
+	},
+	ByServiceType: map[string]earnings.Summary{
+		"openvpn": {Received: 100, Consumed: 80, Unconsumed: 20, Cleared: 50},
+	},
+	ByDay: map[string]earnings.Summary{
+		"2019-04-01": {Received: 100, Consumed: 80, Unconsumed: 20, Cleared: 50},
+	},
+	Entries: []earnings.Entry{
+		{
+			Date:        "2019-04-01",
+			ConsumerID:  "0x1",
+			ServiceType: "openvpn",
+			Summary:     earnings.Summary{Received: 100, Consumed: 80, Unconsumed: 20, Cleared: 50},
+		},
+	},
+}
+
+type earningsReporterMock struct {
+	reportToReturn earnings.Report
+	errToReturn    error
+}
+
+func (erm *earningsReporterMock) Report() (earnings.Report, error) {
+	return erm.reportToReturn, erm.errToReturn
+}
+
+func TestEarningsEndpointReturnsJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/earnings", nil)
+	resp := httptest.NewRecorder()
+
+	NewEarningsEndpoint(&earningsReporterMock{reportToReturn: earningsReportMock}).Get(resp, req, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t,
+		`{
+			"total": {"received": 100, "consumed": 80, "unconsumed": 20, "cleared": 50},
+			"byConsumer": {"0x1": {"received": 100, "consumed": 80, "unconsumed": 20, "cleared": 50}},
+			"byServiceType": {"openvpn": {"received": 100, "consumed": 80, "unconsumed": 20, "cleared": 50}},
+			"byDay": {"2019-04-01": {"received": 100, "consumed": 80, "unconsumed": 20, "cleared": 50}},
+			"entries": [
+				{
+					"date": "2019-04-01",
+					"consumerId": "0x1",
+					"serviceType": "openvpn",
+					"received": 100,
+					"consumed": 80,
+					"unconsumed": 20,
+					"cleared": 50
+				}
+			]
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestEarningsEndpointReturnsCSV(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/earnings?format=csv", nil)
+	resp := httptest.NewRecorder()
+
+	NewEarningsEndpoint(&earningsReporterMock{reportToReturn: earningsReportMock}).Get(resp, req, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header().Get("Content-type"))
+	assert.Equal(t,
+		"date,consumer,service_type,received,consumed,unconsumed,cleared\n"+
+			"2019-04-01,0x1,openvpn,100,80,20,50\n",
+		resp.Body.String(),
+	)
+}
+
+func TestEarningsEndpointBubblesError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/earnings", nil)
+	resp := httptest.NewRecorder()
+
+	NewEarningsEndpoint(&earningsReporterMock{errToReturn: errors.New("something exploded")}).Get(resp, req, nil)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"message": "something exploded"}`, resp.Body.String())
+}
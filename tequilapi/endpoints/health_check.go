@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/health"
 	"github.com/mysteriumnetwork/node/metadata"
 	"github.com/mysteriumnetwork/node/tequilapi/utils"
 )
@@ -37,6 +38,41 @@ type healthCheckData struct {
 	// example: 0.0.6
 	Version   string    `json:"version"`
 	BuildInfo buildInfo `json:"buildInfo"`
+
+	// overall status, node is failing when at least one of critical components is failing
+	// example: ok
+	Status     string            `json:"status"`
+	Components []componentHealth `json:"components"`
+}
+
+// swagger:model ComponentHealthDTO
+type componentHealth struct {
+	// example: storage
+	Name string `json:"name"`
+
+	// failing critical component makes the whole node failing, failing non critical one only degrades it
+	// example: true
+	Critical bool `json:"critical"`
+
+	// example: ok
+	Status string `json:"status"`
+
+	// error of the latest check
+	// example: connection refused
+	Error string `json:"error,omitempty"`
+
+	// error of the latest failed check, kept after component recovers
+	// example: connection refused
+	LastError string `json:"lastError,omitempty"`
+
+	// example: 2019-04-01T12:00:00Z
+	LastErrorAt string `json:"lastErrorAt,omitempty"`
+
+	// example: 2019-04-01T12:05:00Z
+	CheckedAt string `json:"checkedAt"`
+
+	// example: 12.5ms
+	Duration string `json:"duration"`
 }
 
 // swagger:model BuildInfoDTO
@@ -51,32 +87,43 @@ type buildInfo struct {
 	BuildNumber string `json:"buildNumber"`
 }
 
+// HealthChecker provides the latest health report of node components, components are checked periodically
+type HealthChecker interface {
+	LastReport() health.Report
+}
+
 type healthCheckEndpoint struct {
 	startTime       time.Time
 	currentTimeFunc func() time.Time
 	processNumber   int
+	healthChecker   HealthChecker
 }
 
 /*
 HealthCheckEndpointFactory creates a structure with single HealthCheck method for healthcheck serving as http,
 currentTimeFunc is injected for easier testing
 */
-func HealthCheckEndpointFactory(currentTimeFunc func() time.Time, procID func() int) *healthCheckEndpoint {
+func HealthCheckEndpointFactory(currentTimeFunc func() time.Time, procID func() int, healthChecker HealthChecker) *healthCheckEndpoint {
 	startTime := currentTimeFunc()
 	return &healthCheckEndpoint{
 		startTime,
 		currentTimeFunc,
 		procID(),
+		healthChecker,
 	}
 }
 
 // swagger:operation GET /healthcheck Client healthCheck
 // ---
 // summary: Returns information about client
-// description: Returns health check information about client
+// description: Returns health check information about client, components are checked periodically and the latest results are returned
 // responses:
 //   200:
-//     description: Health check information
+//     description: Health check information, node is healthy or degraded
+//     schema:
+//       "$ref": "#/definitions/HealthCheckDTO"
+//   503:
+//     description: Health check information, at least one of critical components is failing
 //     schema:
 //       "$ref": "#/definitions/HealthCheckDTO"
 //   500:
@@ -84,6 +131,7 @@ func HealthCheckEndpointFactory(currentTimeFunc func() time.Time, procID func()
 //     schema:
 //       "$ref": "#/definitions/ErrorMessageDTO"
 func (hce *healthCheckEndpoint) HealthCheck(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	report := hce.healthChecker.LastReport()
 	status := healthCheckData{
 		Uptime:  hce.currentTimeFunc().Sub(hce.startTime).String(),
 		Process: hce.processNumber,
@@ -93,6 +141,32 @@ func (hce *healthCheckEndpoint) HealthCheck(writer http.ResponseWriter, request
 			metadata.BuildBranch,
 			metadata.BuildNumber,
 		},
+		Status:     string(report.Status),
+		Components: mapComponentsHealth(report.Components),
+	}
+
+	if report.Status == health.StatusFailing {
+		utils.SendErrorBody(writer, status, http.StatusServiceUnavailable)
+		return
 	}
 	utils.WriteAsJSON(status, writer)
 }
+
+func mapComponentsHealth(reports []health.ComponentReport) []componentHealth {
+	components := make([]componentHealth, len(reports))
+	for i, report := range reports {
+		components[i] = componentHealth{
+			Name:      report.Name,
+			Critical:  report.Critical,
+			Status:    string(report.Status),
+			Error:     errorToString(report.Error),
+			LastError: errorToString(report.LastError),
+			CheckedAt: report.CheckedAt.UTC().Format(time.RFC3339),
+			Duration:  report.Duration.String(),
+		}
+		if !report.LastErrorAt.IsZero() {
+			components[i].LastErrorAt = report.LastErrorAt.UTC().Format(time.RFC3339)
+		}
+	}
+	return components
+}
@@ -18,11 +18,15 @@
 package endpoints
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/health"
 	"github.com/mysteriumnetwork/node/metadata"
 	"github.com/stretchr/testify/assert"
 )
@@ -42,9 +46,11 @@ func TestHealthCheckReturnsExpectedJSONObject(t *testing.T) {
 	handlerFunc := HealthCheckEndpointFactory(
 		newMockTimer([]time.Time{tick1, tick2}).Now,
 		func() int { return 1 },
+		health.NewRegistry(time.Second),
 	).HealthCheck
 	handlerFunc(resp, req, httprouter.Params{})
 
+	assert.Equal(t, http.StatusOK, resp.Code)
 	assert.JSONEq(
 		t,
 		`{
@@ -55,11 +61,97 @@ func TestHealthCheckReturnsExpectedJSONObject(t *testing.T) {
                 "branch": "some",
                 "commit": "abc123",
                 "buildNumber": "travis build #"
-            }
+            },
+            "status": "ok",
+            "components": []
         }`,
 		resp.Body.String())
 }
 
+func TestHealthCheckReturnsComponentsHealth(t *testing.T) {
+	req := httptest.NewRequest("GET", "/irrelevant", nil)
+	resp := httptest.NewRecorder()
+
+	checkedAt := time.Date(2019, 4, 1, 12, 5, 0, 0, time.UTC)
+	checker := &healthCheckerFake{health.Report{
+		Status: health.StatusDegraded,
+		Components: []health.ComponentReport{
+			{
+				Name:      "storage",
+				Critical:  true,
+				Status:    health.StatusOK,
+				CheckedAt: checkedAt,
+				Duration:  time.Millisecond,
+			},
+			{
+				Name:        "discovery",
+				Status:      health.StatusFailing,
+				Error:       errors.New("connection refused"),
+				LastError:   errors.New("connection refused"),
+				LastErrorAt: checkedAt,
+				CheckedAt:   checkedAt,
+				Duration:    2 * time.Second,
+			},
+		},
+	}}
+
+	HealthCheckEndpointFactory(time.Now, func() int { return 1 }, checker).HealthCheck(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	parsedResponse := healthCheckData{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &parsedResponse))
+	assert.Equal(t, "degraded", parsedResponse.Status)
+	assert.Equal(
+		t,
+		[]componentHealth{
+			{
+				Name:      "storage",
+				Critical:  true,
+				Status:    "ok",
+				CheckedAt: "2019-04-01T12:05:00Z",
+				Duration:  "1ms",
+			},
+			{
+				Name:        "discovery",
+				Status:      "failing",
+				Error:       "connection refused",
+				LastError:   "connection refused",
+				LastErrorAt: "2019-04-01T12:05:00Z",
+				CheckedAt:   "2019-04-01T12:05:00Z",
+				Duration:    "2s",
+			},
+		},
+		parsedResponse.Components,
+	)
+}
+
+func TestHealthCheckReturnsServiceUnavailableWhenCriticalComponentFails(t *testing.T) {
+	req := httptest.NewRequest("GET", "/irrelevant", nil)
+	resp := httptest.NewRecorder()
+
+	registry := health.NewRegistry(time.Second)
+	registry.Register("storage", true, func() error {
+		return errors.New("database not open")
+	})
+
+	HealthCheckEndpointFactory(time.Now, func() int { return 1 }, registry).HealthCheck(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	parsedResponse := healthCheckData{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &parsedResponse))
+	assert.Equal(t, "failing", parsedResponse.Status)
+	assert.Len(t, parsedResponse.Components, 1)
+	assert.Equal(t, "database not open", parsedResponse.Components[0].Error)
+}
+
+type healthCheckerFake struct {
+	report health.Report
+}
+
+func (checker *healthCheckerFake) LastReport() health.Report {
+	return checker.report
+}
+
 type mockTimer struct {
 	values  []time.Time
 	current int
@@ -41,8 +41,15 @@ type locationRes struct {
 	// example: NL
 	Country string `json:"country,omitempty"`
 
+	// example: North Holland
+	Region string `json:"region,omitempty"`
+
 	// example: Amsterdam
 	City string `json:"city,omitempty"`
+
+	// organization which operates the autonomous system
+	// example: DigitalOcean, LLC
+	ISP string `json:"isp,omitempty"`
 }
 
 // swagger:model ServiceDefinitionDTO
@@ -80,7 +87,9 @@ func proposalToRes(p market.ServiceProposal) proposalRes {
 			LocationOriginate: locationRes{
 				ASN:     p.ServiceDefinition.GetLocation().ASN,
 				Country: p.ServiceDefinition.GetLocation().Country,
+				Region:  p.ServiceDefinition.GetLocation().Region,
 				City:    p.ServiceDefinition.GetLocation().City,
+				ISP:     p.ServiceDefinition.GetLocation().ISP,
 			},
 		},
 	}
@@ -20,10 +20,12 @@ package endpoints
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/mysteriumnetwork/node/core/service"
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/nat/mapping"
 	"github.com/mysteriumnetwork/node/tequilapi/utils"
 	"github.com/mysteriumnetwork/node/tequilapi/validation"
 )
@@ -69,18 +71,92 @@ type serviceInfo struct {
 	// example: Running
 	Status string `json:"status"`
 
+	// whether service is restored after node restart
+	// example: true
+	AutoStart bool `json:"autostart"`
+
+	// how many times service was restarted after failure
+	// example: 1
+	Restarts int `json:"restarts"`
+
+	// last error service failed with
+	// example: openvpn process exited
+	LastError string `json:"lastError,omitempty"`
+
+	// ports service mapped on the gateway of provider behind NAT
+	PortMappings []portMappingStatus `json:"portMappings,omitempty"`
+
+	// outcome of the latest check whether service can be reached on its advertised endpoint, missing when not checked
+	Reachability *serviceReachability `json:"reachability,omitempty"`
+
 	Proposal proposalRes `json:"proposal"`
 }
 
+// swagger:model PortMappingStatusDTO
+type portMappingStatus struct {
+	// example: UDP
+	Protocol string `json:"protocol"`
+
+	// example: 52820
+	InternalPort int `json:"internalPort"`
+
+	// example: 52820
+	ExternalPort int `json:"externalPort"`
+
+	// gateway and protocol the port is mapped with
+	// example: UPNP IGDv1-IP1
+	RouterType string `json:"routerType"`
+
+	// example: true
+	Mapped bool `json:"mapped"`
+
+	// lifetime of the mapping, zero stands for a permanent one
+	// example: 20m0s
+	Lease string `json:"lease"`
+
+	// error of the latest mapping attempt
+	// example: no UPnP or NAT-PMP router discovered
+	LastError string `json:"lastError,omitempty"`
+
+	// example: 2019-04-01T12:00:00Z
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// swagger:model ServiceReachabilityDTO
+type serviceReachability struct {
+	// example: 1.2.3.4:1194
+	Endpoint string `json:"endpoint"`
+
+	// reachable, unreachable or unsupported when the endpoint can not be checked
+	// example: unreachable
+	Status string `json:"status"`
+
+	// example: false
+	Reachable bool `json:"reachable"`
+
+	// example: dial tcp 1.2.3.4:1194: i/o timeout
+	Error string `json:"error,omitempty"`
+
+	// example: 2019-04-01T12:05:00Z
+	CheckedAt string `json:"checkedAt"`
+}
+
+// swagger:model ServiceOptionsDTO
+type serviceOptions map[string]interface{}
+
+// swagger:model ServiceAutoStartRequestDTO
+type serviceAutoStartRequest struct {
+	// required: true
+	// example: false
+	AutoStart *bool `json:"autostart"`
+}
+
 // ServiceEndpoint struct represents /service resource and it's sub-resources
 type ServiceEndpoint struct {
 	serviceManager ServiceManager
-	optionsParser  map[string]ServiceOptionsParser
+	optionsParser  map[string]service.OptionsParser
 }
 
-// ServiceOptionsParser parses request to service specific options
-type ServiceOptionsParser func(*json.RawMessage) (service.Options, error)
-
 var (
 	// serviceTypeInvalid represents service type which is unknown to node
 	serviceTypeInvalid = "<unknown>"
@@ -89,7 +165,7 @@ var (
 )
 
 // NewServiceEndpoint creates and returns service endpoint
-func NewServiceEndpoint(serviceManager ServiceManager, optionsParser map[string]ServiceOptionsParser) *ServiceEndpoint {
+func NewServiceEndpoint(serviceManager ServiceManager, optionsParser map[string]service.OptionsParser) *ServiceEndpoint {
 	return &ServiceEndpoint{
 		serviceManager: serviceManager,
 		optionsParser:  optionsParser,
@@ -113,6 +189,34 @@ func (se *ServiceEndpoint) ServiceList(resp http.ResponseWriter, _ *http.Request
 	utils.WriteAsJSON(statusResponse, resp)
 }
 
+// ServiceOptions provides default options of every service type the node is able to run.
+// swagger:operation GET /service-options Service serviceOptions
+// ---
+// summary: Default options of services
+// description: ServiceOptions provides default options of every supported service type, they are used when starting the service without options.
+// responses:
+//   200:
+//     description: Default options keyed by service type
+//     schema:
+//       "$ref": "#/definitions/ServiceOptionsDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (se *ServiceEndpoint) ServiceOptions(resp http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	options := make(serviceOptions, len(se.optionsParser))
+	for serviceType, parse := range se.optionsParser {
+		defaults, err := parse(nil)
+		if err != nil {
+			utils.SendError(resp, err, http.StatusInternalServerError)
+			return
+		}
+		options[serviceType] = defaults
+	}
+
+	utils.WriteAsJSON(options, resp)
+}
+
 // ServiceGet provides info for requested service on the node.
 // swagger:operation GET /services/:id Service serviceGet
 // ---
@@ -210,28 +314,61 @@ func (se *ServiceEndpoint) ServiceStart(resp http.ResponseWriter, req *http.Requ
 // swagger:operation DELETE /services/:id Service serviceStop
 // ---
 // summary: Stops service
-// description: Initiates service stop
+// description: Initiates service stop, which optionally waits for the sessions of connected consumers to end
+// parameters:
+//   - in: query
+//     name: drain
+//     description: Duration (e.g. 30s, 5m) to wait for sessions to end before stopping. While draining, service is unregistered and rejects new sessions
+//     type: string
 // responses:
 //   202:
 //     description: Service Stop initiated
+//   400:
+//     description: Bad request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
 //   404:
 //     description: No service exists
 //     schema:
 //       "$ref": "#/definitions/ErrorMessageDTO"
+//   409:
+//     description: Conflict. Service is draining already
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
 //   500:
 //     description: Internal server error
 //     schema:
 //       "$ref": "#/definitions/ErrorMessageDTO"
-func (se *ServiceEndpoint) ServiceStop(resp http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+func (se *ServiceEndpoint) ServiceStop(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
 	id := service.ID(params.ByName("id"))
 
+	var drain time.Duration
+	if value := req.URL.Query().Get("drain"); value != "" {
+		var err error
+		if drain, err = time.ParseDuration(value); err != nil || drain < 0 {
+			utils.SendErrorMessage(resp, "Invalid drain duration", http.StatusBadRequest)
+			return
+		}
+	}
+
 	instance := se.serviceManager.Service(id)
 	if instance == nil {
 		utils.SendErrorMessage(resp, "Service not found", http.StatusNotFound)
 		return
 	}
 
-	if err := se.serviceManager.Stop(id); err != nil {
+	var err error
+	if drain > 0 {
+		err = se.serviceManager.Drain(id, drain)
+	} else {
+		err = se.serviceManager.Stop(id)
+	}
+	switch err {
+	case nil:
+	case service.ErrInstanceDraining:
+		utils.SendError(resp, err, http.StatusConflict)
+		return
+	default:
 		utils.SendError(resp, err, http.StatusInternalServerError)
 		return
 	}
@@ -239,6 +376,67 @@ func (se *ServiceEndpoint) ServiceStop(resp http.ResponseWriter, _ *http.Request
 	resp.WriteHeader(http.StatusAccepted)
 }
 
+// ServiceAutoStart enables or disables service restore after node restart.
+// swagger:operation PUT /services/:id/autostart Service serviceAutoStart
+// ---
+// summary: Toggles service autostart
+// description: Enables or disables restoring of the service after node restart
+// parameters:
+//   - in: body
+//     name: body
+//     description: Autostart value
+//     schema:
+//       $ref: "#/definitions/ServiceAutoStartRequestDTO"
+// responses:
+//   200:
+//     description: Service detailed information
+//     schema:
+//       "$ref": "#/definitions/ServiceInfoDTO"
+//   400:
+//     description: Bad request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   404:
+//     description: Service not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (se *ServiceEndpoint) ServiceAutoStart(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	id := service.ID(params.ByName("id"))
+
+	var autoStartReq serviceAutoStartRequest
+	if err := json.NewDecoder(req.Body).Decode(&autoStartReq); err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	if autoStartReq.AutoStart == nil {
+		errorMap := validation.NewErrorMap()
+		errorMap.ForField("autostart").AddError("required", "Field is required")
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	err := se.serviceManager.SetAutoStart(id, *autoStartReq.AutoStart)
+	if err == service.ErrNoSuchInstance {
+		utils.SendErrorMessage(resp, "Service not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	instance := se.serviceManager.Service(id)
+	utils.WriteAsJSON(toServiceInfoResponse(id, instance), resp)
+}
+
 func (se *ServiceEndpoint) isAlreadyRunning(sr serviceRequest) bool {
 	for _, instance := range se.serviceManager.List() {
 		proposal := instance.Proposal()
@@ -250,13 +448,15 @@ func (se *ServiceEndpoint) isAlreadyRunning(sr serviceRequest) bool {
 }
 
 // AddRoutesForService adds service routes to given router
-func AddRoutesForService(router *httprouter.Router, serviceManager ServiceManager, optionsParser map[string]ServiceOptionsParser) {
+func AddRoutesForService(router *httprouter.Router, serviceManager ServiceManager, optionsParser map[string]service.OptionsParser) {
 	serviceEndpoint := NewServiceEndpoint(serviceManager, optionsParser)
 
 	router.GET("/services", serviceEndpoint.ServiceList)
 	router.POST("/services", serviceEndpoint.ServiceStart)
 	router.GET("/services/:id", serviceEndpoint.ServiceGet)
 	router.DELETE("/services/:id", serviceEndpoint.ServiceStop)
+	router.PUT("/services/:id/autostart", serviceEndpoint.ServiceAutoStart)
+	router.GET("/service-options", serviceEndpoint.ServiceOptions)
 }
 
 func (se *ServiceEndpoint) toServiceRequest(req *http.Request) (serviceRequest, error) {
@@ -312,8 +512,58 @@ func toServiceInfoResponse(id service.ID, instance *service.Instance) serviceInf
 		Type:       proposal.ServiceType,
 		Options:    instance.Options(),
 		Status:     string(instance.State()),
-		Proposal:   proposalToRes(instance.Proposal()),
+		AutoStart:  instance.AutoStart(),
+		Restarts:   instance.Restarts(),
+		LastError:  errorToString(instance.LastError()),
+
+		PortMappings: toPortMappingsResponse(instance.PortMappings()),
+		Reachability: toReachabilityResponse(instance.Reachability()),
+
+		Proposal: proposalToRes(instance.Proposal()),
+	}
+}
+
+func toPortMappingsResponse(statuses []mapping.Status) []portMappingStatus {
+	var res []portMappingStatus
+	for _, status := range statuses {
+		res = append(res, portMappingStatus{
+			Protocol:     status.Protocol,
+			InternalPort: status.InternalPort,
+			ExternalPort: status.ExternalPort,
+			RouterType:   status.RouterType,
+			Mapped:       status.Mapped,
+			Lease:        status.Lease.String(),
+			LastError:    errorToString(status.LastError),
+			UpdatedAt:    status.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return res
+}
+
+func toReachabilityResponse(reachability *service.Reachability) *serviceReachability {
+	if reachability == nil {
+		return nil
+	}
+	status := "unsupported"
+	if reachability.Supported && reachability.Reachable {
+		status = "reachable"
+	} else if reachability.Supported {
+		status = "unreachable"
+	}
+	return &serviceReachability{
+		Endpoint:  reachability.Endpoint,
+		Status:    status,
+		Reachable: reachability.Reachable,
+		Error:     errorToString(reachability.Error),
+		CheckedAt: reachability.CheckedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func errorToString(err error) string {
+	if err == nil {
+		return ""
 	}
+	return err.Error()
 }
 
 func toServiceListResponse(instances map[service.ID]*service.Instance) serviceList {
@@ -345,7 +595,9 @@ func validateServiceRequest(sr serviceRequest) *validation.FieldErrorMap {
 type ServiceManager interface {
 	Start(providerID identity.Identity, serviceType string, options service.Options) (service.ID, error)
 	Stop(id service.ID) error
+	Drain(id service.ID, timeout time.Duration) error
 	Service(id service.ID) *service.Instance
 	Kill() error
 	List() map[service.ID]*service.Instance
+	SetAutoStart(id service.ID, autoStart bool) error
 }
@@ -24,6 +24,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/mysteriumnetwork/node/core/service"
@@ -51,12 +52,19 @@ type fancyServiceOptions struct {
 	Foo string `json:"foo"`
 }
 
-type mockServiceManager struct{}
+type mockServiceManager struct {
+	drainedID      service.ID
+	drainedTimeout time.Duration
+}
 
 func (sm *mockServiceManager) Start(providerID identity.Identity, serviceType string, options service.Options) (service.ID, error) {
 	return mockServiceID, nil
 }
 func (sm *mockServiceManager) Stop(id service.ID) error { return nil }
+func (sm *mockServiceManager) Drain(id service.ID, timeout time.Duration) error {
+	sm.drainedID, sm.drainedTimeout = id, timeout
+	return nil
+}
 func (sm *mockServiceManager) Service(id service.ID) *service.Instance {
 	if id == "6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
 		return mockServiceRunning
@@ -69,8 +77,14 @@ func (sm *mockServiceManager) List() map[service.ID]*service.Instance {
 	}
 }
 func (sm *mockServiceManager) Kill() error { return nil }
+func (sm *mockServiceManager) SetAutoStart(id service.ID, autoStart bool) error {
+	if id == mockServiceID {
+		return nil
+	}
+	return service.ErrNoSuchInstance
+}
 
-var fakeOptionsParser = map[string]ServiceOptionsParser{
+var fakeOptionsParser = map[string]service.OptionsParser{
 	"testprotocol": func(opts *json.RawMessage) (service.Options, error) {
 		return nil, nil
 	},
@@ -101,6 +115,8 @@ func Test_AddRoutesForServiceAddsRoutes(t *testing.T) {
 				"type": "testprotocol",
 				"options": {"foo": "bar"},
 				"status": "NotRunning",
+				"autostart": false,
+				"restarts": 0,
 				"proposal": {
 					"id": 1,
 					"providerId": "0xProviderId",
@@ -122,6 +138,8 @@ func Test_AddRoutesForServiceAddsRoutes(t *testing.T) {
 				"type": "testprotocol",
 				"options": {"foo": "bar"},
 				"status": "Running",
+				"autostart": false,
+				"restarts": 0,
 				"proposal": {
 					"id": 1,
 					"providerId": "0xProviderId",
@@ -143,6 +161,8 @@ func Test_AddRoutesForServiceAddsRoutes(t *testing.T) {
 				"type": "testprotocol",
 				"options": {"foo": "bar"},
 				"status": "Running",
+				"autostart": false,
+				"restarts": 0,
 				"proposal": {
 					"id": 1,
 					"providerId": "0xProviderId",
@@ -153,6 +173,33 @@ func Test_AddRoutesForServiceAddsRoutes(t *testing.T) {
 				}
 			}`,
 		},
+		{
+			http.MethodPut,
+			"/services/6ba7b810-9dad-11d1-80b4-00c04fd430c8/autostart",
+			`{"autostart": true}`,
+			http.StatusOK,
+			`{
+				"id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				"providerId": "0xProviderId",
+				"type": "testprotocol",
+				"options": {"foo": "bar"},
+				"status": "Running",
+				"autostart": false,
+				"restarts": 0,
+				"proposal": {
+					"id": 1,
+					"providerId": "0xProviderId",
+					"serviceType": "testprotocol",
+					"serviceDefinition": {
+						"locationOriginate": {"asn": "LT", "country": "Lithuania", "city": "Vilnius"}
+					}
+				}
+			}`,
+		},
+		{
+			http.MethodPut, "/services/00000000-9dad-11d1-80b4-00c04fd43000/autostart", `{"autostart": true}`,
+			http.StatusNotFound, `{"message":"Service not found"}`,
+		},
 		{
 			http.MethodDelete, "/services/6ba7b810-9dad-11d1-80b4-00c04fd430c8", "",
 			http.StatusAccepted, "",
@@ -161,6 +208,14 @@ func Test_AddRoutesForServiceAddsRoutes(t *testing.T) {
 			http.MethodDelete, "/services/00000000-9dad-11d1-80b4-00c04fd43000", "",
 			http.StatusNotFound, `{"message":"Service not found"}`,
 		},
+		{
+			http.MethodDelete, "/services/6ba7b810-9dad-11d1-80b4-00c04fd430c8?drain=30s", "",
+			http.StatusAccepted, "",
+		},
+		{
+			http.MethodDelete, "/services/6ba7b810-9dad-11d1-80b4-00c04fd430c8?drain=soon", "",
+			http.StatusBadRequest, `{"message":"Invalid drain duration"}`,
+		},
 	}
 
 	for _, test := range tests {
@@ -299,6 +354,26 @@ func Test_ServiceStatus_NotFoundIsReturnedWhenNotStarted(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, resp.Code)
 }
 
+func Test_ServiceOptionsReturnsDefaultsOfEveryServiceType(t *testing.T) {
+	optionsParser := map[string]service.OptionsParser{
+		"noop": func(opts *json.RawMessage) (service.Options, error) {
+			return nil, nil
+		},
+		"testprotocol": func(opts *json.RawMessage) (service.Options, error) {
+			return fancyServiceOptions{Foo: "bar"}, nil
+		},
+	}
+	serviceEndpoint := NewServiceEndpoint(&mockServiceManager{}, optionsParser)
+
+	req := httptest.NewRequest(http.MethodGet, "/service-options", nil)
+	resp := httptest.NewRecorder()
+
+	serviceEndpoint.ServiceOptions(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"noop": null, "testprotocol": {"foo": "bar"}}`, resp.Body.String())
+}
+
 func Test_ServiceGetReturnsServiceInfo(t *testing.T) {
 	serviceEndpoint := NewServiceEndpoint(&mockServiceManager{}, fakeOptionsParser)
 
@@ -316,6 +391,8 @@ func Test_ServiceGetReturnsServiceInfo(t *testing.T) {
 			"type": "testprotocol",
 			"options": {"foo": "bar"},
 			"status": "Running",
+			"autostart": false,
+			"restarts": 0,
 			"proposal": {
 				"id": 1,
 				"providerId": "0xProviderId",
@@ -370,3 +447,57 @@ func Test_ServiceCreate_Returns422ErrorIfRequestBodyIsMissingFieldValues(t *test
 		resp.Body.String(),
 	)
 }
+
+func Test_ServiceAutoStart_Returns422ErrorIfValueIsMissing(t *testing.T) {
+	serviceEndpoint := NewServiceEndpoint(&mockServiceManager{}, fakeOptionsParser)
+
+	req := httptest.NewRequest(http.MethodPut, "/irrelevant", strings.NewReader("{}"))
+	resp := httptest.NewRecorder()
+
+	serviceEndpoint.ServiceAutoStart(resp, req, httprouter.Params{{Key: "id", Value: string(mockServiceID)}})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message": "validation_error",
+			"errors": {
+				"autostart": [ {"code": "required", "message": "Field is required"} ]
+			}
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func Test_ServiceStopDrainsService(t *testing.T) {
+	serviceManager := &mockServiceManager{}
+	router := httprouter.New()
+	AddRoutesForService(router, serviceManager, fakeOptionsParser)
+
+	req := httptest.NewRequest(http.MethodDelete, "/services/6ba7b810-9dad-11d1-80b4-00c04fd430c8?drain=5m", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(t, service.ID("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), serviceManager.drainedID)
+	assert.Equal(t, 5*time.Minute, serviceManager.drainedTimeout)
+}
+
+func Test_ToReachabilityResponseReportsStatus(t *testing.T) {
+	assert.Nil(t, toReachabilityResponse(nil))
+
+	checkedAt := time.Date(2019, 4, 1, 12, 5, 0, 0, time.UTC)
+	tests := []struct {
+		reachability service.Reachability
+		status       string
+	}{
+		{service.Reachability{Supported: true, Reachable: true, CheckedAt: checkedAt}, "reachable"},
+		{service.Reachability{Supported: true, Error: errors.New("i/o timeout"), CheckedAt: checkedAt}, "unreachable"},
+		{service.Reachability{CheckedAt: checkedAt}, "unsupported"},
+	}
+	for _, test := range tests {
+		response := toReachabilityResponse(&test.reachability)
+		assert.Equal(t, test.status, response.Status)
+		assert.Equal(t, "2019-04-01T12:05:00Z", response.CheckedAt)
+	}
+}
@@ -19,13 +19,18 @@ package endpoints
 
 import (
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/mysteriumnetwork/node/consumer/session"
 	"github.com/mysteriumnetwork/node/tequilapi/utils"
+	"github.com/mysteriumnetwork/node/tequilapi/validation"
 )
 
+const spendingDateFormat = "2006-01-02"
+
 // SessionsDTO defines session list representable as json
 // swagger:model SessionsDTO
 type SessionsDTO struct {
@@ -62,6 +67,24 @@ type SessionDTO struct {
 
 	// example: Completed
 	Status string `json:"status"`
+
+	// amount promised to provider during the session
+	// example: 500
+	PromisedAmount uint64 `json:"promisedAmount"`
+}
+
+// SpendingDTO holds the amounts promised to providers
+// swagger:model SpendingDTO
+type SpendingDTO struct {
+	// example: 1000
+	Total uint64 `json:"total"`
+
+	// number of sessions within the period
+	// example: 2
+	Sessions int `json:"sessions"`
+
+	ByProvider    map[string]uint64 `json:"byProvider"`
+	ByServiceType map[string]uint64 `json:"byServiceType"`
 }
 
 type sessionsEndpoint struct {
@@ -83,6 +106,19 @@ func NewSessionsEndpoint(sessionStorage sessionStorageGet) *sessionsEndpoint {
 // ---
 // summary: Returns sessions history
 // description: Returns list of sessions history
+// parameters:
+//   - in: query
+//     name: serviceType
+//     description: Service type to filter sessions by
+//     type: string
+//   - in: query
+//     name: status
+//     description: Session status to filter sessions by
+//     type: string
+//   - in: query
+//     name: providerId
+//     description: Provider identity to filter sessions by
+//     type: string
 // responses:
 //   200:
 //     description: List of sessions
@@ -98,14 +134,88 @@ func (endpoint *sessionsEndpoint) List(resp http.ResponseWriter, request *http.R
 		utils.SendError(resp, err, http.StatusInternalServerError)
 		return
 	}
+	sessions = filterSessions(sessions, request.URL.Query())
 	sessionsSerializable := SessionsDTO{Sessions: mapSessions(sessions, toHistoryView)}
 	utils.WriteAsJSON(sessionsSerializable, resp)
 }
 
+// swagger:operation GET /sessions/spending Session getSpending
+// ---
+// summary: Returns spending summary
+// description: Returns amounts promised to providers during the sessions started within the given period
+// parameters:
+//   - in: query
+//     name: from
+//     description: Start of the period (inclusive), in YYYY-MM-DD or RFC3339 format
+//     type: string
+//   - in: query
+//     name: to
+//     description: End of the period (inclusive for dates, exclusive for RFC3339 times), in YYYY-MM-DD or RFC3339 format
+//     type: string
+// responses:
+//   200:
+//     description: Spending summary
+//     schema:
+//       "$ref": "#/definitions/SpendingDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *sessionsEndpoint) Spending(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	errs := validation.NewErrorMap()
+	from, _, err := parseSpendingTime(request.URL.Query().Get("from"))
+	if err != nil {
+		errs.ForField("from").AddError("invalid", "Expected YYYY-MM-DD or RFC3339 time")
+	}
+	to, isDate, err := parseSpendingTime(request.URL.Query().Get("to"))
+	if err != nil {
+		errs.ForField("to").AddError("invalid", "Expected YYYY-MM-DD or RFC3339 time")
+	}
+	if errs.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errs)
+		return
+	}
+	if isDate {
+		// the whole day is included into the period
+		to = to.AddDate(0, 0, 1)
+	}
+
+	sessions, err := endpoint.sessionStorage.GetAll()
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	spending := session.NewSpending(sessions, from, to)
+	utils.WriteAsJSON(SpendingDTO{
+		Total:         spending.Total,
+		Sessions:      spending.Sessions,
+		ByProvider:    spending.ByProvider,
+		ByServiceType: spending.ByServiceType,
+	}, resp)
+}
+
 // AddRoutesForSession attaches sessions endpoints to router
 func AddRoutesForSession(router *httprouter.Router, sessionStorage sessionStorageGet) {
 	sessionsEndpoint := NewSessionsEndpoint(sessionStorage)
 	router.GET("/sessions", sessionsEndpoint.List)
+	router.GET("/sessions/spending", sessionsEndpoint.Spending)
+}
+
+// parseSpendingTime parses the given date or time, empty value is parsed as zero time
+func parseSpendingTime(value string) (t time.Time, isDate bool, err error) {
+	if value == "" {
+		return t, false, nil
+	}
+	if t, err = time.Parse(spendingDateFormat, value); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	return t, false, err
 }
 
 func toHistoryView(se session.History) SessionDTO {
@@ -119,7 +229,28 @@ func toHistoryView(se session.History) SessionDTO {
 		BytesReceived:   se.DataStats.BytesReceived,
 		Duration:        se.GetDuration(),
 		Status:          se.Status,
+		PromisedAmount:  se.PromisedAmount,
+	}
+}
+
+// filterSessions keeps sessions matching all the given query filters
+func filterSessions(sessions []session.History, query url.Values) []session.History {
+	serviceType, status, providerID := query.Get("serviceType"), query.Get("status"), query.Get("providerId")
+
+	filtered := make([]session.History, 0, len(sessions))
+	for _, se := range sessions {
+		if serviceType != "" && se.ServiceType != serviceType {
+			continue
+		}
+		if status != "" && se.Status != status {
+			continue
+		}
+		if providerID != "" && !strings.EqualFold(se.ProviderID.Address, providerID) {
+			continue
+		}
+		filtered = append(filtered, se)
 	}
+	return filtered
 }
 
 func mapSessions(sessions []session.History, f func(session.History) SessionDTO) []SessionDTO {
@@ -98,6 +98,30 @@ func TestListEndpoint(t *testing.T) {
 	assert.EqualValues(t, toHistoryView(SessionMock), parsedResponse.Sessions[0])
 }
 
+func TestListEndpointFiltersSessions(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/sessions?serviceType=wireguard&status=Completed", nil)
+	assert.Nil(t, err)
+
+	completed := SessionMock
+	completed.ServiceType = "wireguard"
+	completed.Status = session.SessionStatusCompleted
+	otherType := completed
+	otherType.ServiceType = "openvpn"
+	otherStatus := completed
+	otherStatus.Status = "New"
+	ssm := &sessionStorageMock{
+		sessionsToReturn: []session.History{otherType, completed, otherStatus},
+	}
+
+	resp := httptest.NewRecorder()
+	NewSessionsEndpoint(ssm).List(resp, req, nil)
+
+	parsedResponse := &SessionsDTO{}
+	err = json.Unmarshal(resp.Body.Bytes(), parsedResponse)
+	assert.Nil(t, err)
+	assert.Equal(t, []SessionDTO{toHistoryView(completed)}, parsedResponse.Sessions)
+}
+
 func TestListEndpointBubblesError(t *testing.T) {
 	req, err := http.NewRequest(
 		http.MethodGet,
@@ -123,6 +147,50 @@ func TestListEndpointBubblesError(t *testing.T) {
 	)
 }
 
+func TestSpendingEndpointFiltersByDates(t *testing.T) {
+	day := time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)
+	ssm := &sessionStorageMock{
+		sessionsToReturn: []session.History{
+			{ProviderID: ProviderID, ServiceType: ServiceType, Started: day.Add(-time.Hour), PromisedAmount: 1000},
+			{ProviderID: ProviderID, ServiceType: ServiceType, Started: day.Add(time.Hour), PromisedAmount: 100},
+			{ProviderID: ProviderID, ServiceType: ServiceType, Started: day.Add(23 * time.Hour), PromisedAmount: 50},
+			{ProviderID: ProviderID, ServiceType: ServiceType, Started: day.Add(25 * time.Hour), PromisedAmount: 10},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/sessions/spending?from=2019-04-01&to=2019-04-01", nil)
+	resp := httptest.NewRecorder()
+	NewSessionsEndpoint(ssm).Spending(resp, req, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	parsedResponse := SpendingDTO{}
+	err := json.Unmarshal(resp.Body.Bytes(), &parsedResponse)
+	assert.Nil(t, err)
+	assert.Equal(t, SpendingDTO{
+		Total:         150,
+		Sessions:      2,
+		ByProvider:    map[string]uint64{ProviderID.Address: 150},
+		ByServiceType: map[string]uint64{ServiceType: 150},
+	}, parsedResponse)
+}
+
+func TestSpendingEndpointValidatesDates(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/sessions/spending?from=yesterday", nil)
+	resp := httptest.NewRecorder()
+	NewSessionsEndpoint(&sessionStorageMock{}).Spending(resp, req, nil)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(t,
+		`{
+			"message": "validation_error",
+			"errors": {
+				"from": [{"code": "invalid", "message": "Expected YYYY-MM-DD or RFC3339 time"}]
+			}
+		}`,
+		resp.Body.String(),
+	)
+}
+
 type sessionStorageMock struct {
 	sessionsToReturn []session.History
 	errToReturn      error